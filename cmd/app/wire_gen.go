@@ -1,139 +0,0 @@
-// Code generated by Wire. DO NOT EDIT.
-
-//go:generate go run -mod=mod github.com/google/wire/cmd/wire
-//go:build !wireinject
-// +build !wireinject
-
-package main
-
-import (
-	"github.com/MaisamV/wallet/internal/probes"
-	http2 "github.com/MaisamV/wallet/internal/probes/presentation/http"
-	"github.com/MaisamV/wallet/internal/swagger"
-	http3 "github.com/MaisamV/wallet/internal/swagger/presentation/http"
-	"github.com/MaisamV/wallet/internal/wallet"
-	"github.com/MaisamV/wallet/internal/wallet/infrastructure/repo"
-	http4 "github.com/MaisamV/wallet/internal/wallet/presentation/http"
-	"github.com/MaisamV/wallet/platform"
-	"github.com/MaisamV/wallet/platform/config"
-	"github.com/MaisamV/wallet/platform/http"
-	"github.com/MaisamV/wallet/platform/logger"
-)
-
-// Injectors from wire.go:
-
-// InitializeApplication creates and initializes the application with all dependencies
-func InitializeApplication() (*Application, error) {
-	config, err := platform.ProvideConfig()
-	if err != nil {
-		return nil, err
-	}
-	logger := platform.ProvideLogger(config)
-	server := platform.ProvideHTTPServer(config, logger)
-	pingQueryHandler := probes.ProvidePingQueryHandler(logger)
-	pingHandler := probes.ProvidePingHandler(logger, pingQueryHandler)
-	pool, err := platform.ProvideDatabase(config, logger)
-	if err != nil {
-		return nil, err
-	}
-	databaseChecker := probes.ProvideDatabaseChecker(config, logger, pool)
-	getHealthQueryHandler := probes.ProvideHealthQueryHandler(logger, databaseChecker)
-	healthService := probes.ProvideHealthService(logger, getHealthQueryHandler)
-	getLivenessQueryHandler := probes.ProvideLivenessQueryHandler(logger)
-	livenessService := probes.ProvideLivenessService(logger, getLivenessQueryHandler)
-	healthHandler := probes.ProvideHealthHandler(logger, healthService, livenessService)
-	probesModule := ProvideProbesModule(pingHandler, healthHandler)
-	swaggerLoader, err := swagger.ProvideSwaggerLoader(logger, config)
-	if err != nil {
-		return nil, err
-	}
-	swaggerQueryHandler := swagger.ProvideSwaggerQueryHandler(logger, swaggerLoader)
-	docsHandler := swagger.ProvideDocsHandler(logger, swaggerQueryHandler)
-	swaggerModule := ProvideSwaggerModule(docsHandler)
-	pgxWalletRepo := user.ProvideWalletRepository(logger, pool)
-	debitCommandHandler := user.ProvideDebitCommandHandler(logger, pgxWalletRepo)
-	chargeCommandHandler := user.ProvideChargeCommandHandler(logger, pgxWalletRepo)
-	getBalanceQueryHandler := user.ProvideGetBalanceQueryHandler(logger, pgxWalletRepo)
-	getTransactionPageQueryHandler := user.ProvideGetTransactionPageQueryHandler(logger, pgxWalletRepo)
-	walletHandler := user.ProvideWalletHandler(logger, debitCommandHandler, chargeCommandHandler, getBalanceQueryHandler, getTransactionPageQueryHandler)
-	walletModule := ProvideWalletModule(walletHandler, pgxWalletRepo)
-	application := ProvideApplication(config, logger, server, probesModule, swaggerModule, walletModule)
-	return application, nil
-}
-
-// wire.go:
-
-// Application holds all the application dependencies
-type Application struct {
-	Config     *config.Config
-	Logger     logger.Logger
-	HTTPServer *http.Server
-	Probes     *ProbesModule
-	Swagger    *SwaggerModule
-	Wallet     *WalletModule
-}
-
-// ProbesModule holds all probes-related dependencies
-type ProbesModule struct {
-	PingHandler   *http2.PingHandler
-	HealthHandler *http2.HealthHandler
-}
-
-// SwaggerModule holds all swagger-related dependencies
-type SwaggerModule struct {
-	DocsHandler *http3.DocsHandler
-}
-
-type WalletModule struct {
-	WalletHandler *http4.WalletHandler
-	Repo          *infrastructure.PgxWalletRepo
-}
-
-// ProvideProbesModule provides the probes module
-func ProvideProbesModule(
-	pingHandler *http2.PingHandler,
-	healthHandler *http2.HealthHandler,
-) *ProbesModule {
-	return &ProbesModule{
-		PingHandler:   pingHandler,
-		HealthHandler: healthHandler,
-	}
-}
-
-// ProvideSwaggerModule provides the swagger module
-func ProvideSwaggerModule(
-	docsHandler *http3.DocsHandler,
-) *SwaggerModule {
-	return &SwaggerModule{
-		DocsHandler: docsHandler,
-	}
-}
-
-// ProvideSwaggerModule provides the swagger module
-func ProvideWalletModule(
-	handler *http4.WalletHandler,
-	repo *infrastructure.PgxWalletRepo,
-) *WalletModule {
-	return &WalletModule{
-		WalletHandler: handler,
-		Repo:          repo,
-	}
-}
-
-// ProvideApplication provides the main application structure
-func ProvideApplication(config2 *config.Config, logger2 logger.Logger,
-
-	httpServer *http.Server,
-	probesModule *ProbesModule,
-	swaggerModule *SwaggerModule,
-	walletModule *WalletModule,
-) *Application {
-	return &Application{
-		Config:     config2,
-		Logger:     logger2,
-		HTTPServer: httpServer,
-		Probes:     probesModule,
-		Swagger:    swaggerModule,
-		Wallet:     walletModule,
-	}
-}
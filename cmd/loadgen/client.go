@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// response is the envelope every wallet API response is wrapped in
+type response[T any] struct {
+	Result  *T      `json:"result"`
+	Message *string `json:"message"`
+	Error   *string `json:"error"`
+	Code    *string `json:"code"`
+}
+
+// apiError is a response with a non 2xx status, the code or status is used to group errors in the report
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *apiError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("status %d (%s): %s", e.Status, e.Code, e.Message)
+	}
+	return fmt.Sprintf("status %d: %s", e.Status, e.Message)
+}
+
+type wallet struct {
+	TotalBalance     int64  `json:"total_balance"`
+	AvailableBalance int64  `json:"available_balance"`
+	Status           string `json:"status"`
+}
+
+type transaction struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	Source   string `json:"source"`
+	Amount   int64  `json:"amount"`
+	Released bool   `json:"released"`
+}
+
+type transactionPage struct {
+	TransactionList []transaction `json:"transaction_list"`
+	Cursor          *string       `json:"cursor"`
+}
+
+type destination struct {
+	ID        int64  `json:"id"`
+	Verified  bool   `json:"verified"`
+	IsDefault bool   `json:"is_default"`
+	Number    string `json:"number"`
+	Type      string `json:"type"`
+}
+
+type client struct {
+	baseURL  string
+	adminKey string
+	http     *http.Client
+}
+
+func newClient(baseURL string, adminKey string, timeout time.Duration, connections int) *client {
+	return &client{
+		baseURL:  baseURL,
+		adminKey: adminKey,
+		http: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:        connections,
+				MaxIdleConnsPerHost: connections,
+			},
+		},
+	}
+}
+
+func (c *client) charge(ctx context.Context, userId int64, amount int64, idempotency string) (string, error) {
+	body := map[string]any{"Amount": amount, "Idempotency": idempotency}
+	var id string
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/wallet/%d/charge", userId), body, false, &id)
+	return id, err
+}
+
+func (c *client) withdraw(ctx context.Context, userId int64, amount int64, idempotency string, releaseTime time.Time) (string, error) {
+	body := map[string]any{"Amount": amount, "Idempotency": idempotency, "release_time": releaseTime}
+	var id string
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/wallet/%d/withdraw", userId), body, false, &id)
+	return id, err
+}
+
+func (c *client) balance(ctx context.Context, userId int64) (*wallet, error) {
+	var w wallet
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/wallet/%d", userId), nil, false, &w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (c *client) history(ctx context.Context, userId int64, cursor *string, limit int) (*transactionPage, error) {
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if cursor != nil {
+		query.Set("cursor", *cursor)
+	}
+	var page transactionPage
+	path := fmt.Sprintf("/api/v1/wallet/%d/transactions?%s", userId, query.Encode())
+	if err := c.do(ctx, http.MethodGet, path, nil, false, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (c *client) destinations(ctx context.Context, userId int64) ([]destination, error) {
+	var destinations []destination
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/wallet/%d/destinations/", userId), nil, false, &destinations)
+	return destinations, err
+}
+
+func (c *client) addDestination(ctx context.Context, userId int64, number string) (*destination, error) {
+	var d destination
+	body := map[string]string{"type": "card", "number": number}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/wallet/%d/destinations/", userId), body, false, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (c *client) verifyDestination(ctx context.Context, id int64) error {
+	var d destination
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/admin/destinations/%d/verify", id), nil, true, &d)
+}
+
+// do sends a request and decodes the result of the response envelope into result
+func (c *client) do(ctx context.Context, method string, path string, body any, admin bool, result any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if admin {
+		req.Header.Set(apiKeyHeader, c.adminKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var envelope response[json.RawMessage]
+	if err := json.Unmarshal(payload, &envelope); err != nil && resp.StatusCode < 300 {
+		return fmt.Errorf("could not decode response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		e := &apiError{Status: resp.StatusCode, Message: string(payload)}
+		if envelope.Error != nil {
+			e.Message = *envelope.Error
+		}
+		if envelope.Code != nil {
+			e.Code = *envelope.Code
+		}
+		return e
+	}
+	if envelope.Result == nil || result == nil {
+		return nil
+	}
+	return json.Unmarshal(*envelope.Result, result)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gofrs/uuid/v5"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The load generator drives the wallet HTTP API with a mix of charges, withdrawals, balance and history reads
+// at a target rate, prints latency percentiles and an error breakdown, and then checks the wallets it wrote to
+// against the transactions the API acknowledged. Requests are sent open loop, a request finding every worker
+// busy is dropped and counted instead of slowing the rate down.
+//
+// Withdrawals need a verified payout destination, every user gets a card which is verified through the admin
+// API when an admin key is given. Use a user offset no real wallet has, the run adds money to the wallets.
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "base URL of the wallet API")
+	adminKey := flag.String("admin-key", "", "admin API key used to verify the payout destinations of the users")
+	rps := flag.Float64("rps", 50, "target requests per second")
+	duration := flag.Duration("duration", 30*time.Second, "duration of the run")
+	workers := flag.Int("workers", 64, "maximum number of requests in flight")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout of a single request")
+	mixFlag := flag.String("mix", "charge=40,withdraw=20,balance=30,history=10", "relative weight of every operation")
+	users := flag.Int64("users", 100, "number of users")
+	userOffset := flag.Int64("user-offset", 9_000_000, "id of the first user")
+	distribution := flag.String("distribution", "uniform", "user distribution, uniform or hot")
+	hotUsers := flag.Float64("hot-users", 0.1, "fraction of the users which are hot")
+	hotTraffic := flag.Float64("hot-traffic", 0.9, "fraction of the requests sent to the hot users")
+	chargeMax := flag.Int64("charge-max", 1_000_000, "largest charge, amounts are picked uniformly from 1")
+	withdrawMax := flag.Int64("withdraw-max", 500_000, "largest withdrawal, amounts are picked uniformly from 1")
+	withdrawDelay := flag.Duration("withdraw-delay", 5*time.Second, "time from a withdrawal to its release")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed of the request sequence")
+	verify := flag.Bool("verify", true, "check the wallets against the acknowledged transactions after the run")
+	settle := flag.Duration("settle", 0, "time given to the workers before verifying")
+	flag.Parse()
+
+	m, err := parseMix(*mixFlag)
+	if err != nil {
+		log.Fatalf("invalid mix: %v", err)
+	}
+	picker := userPicker{Offset: *userOffset, Users: *users, Distribution: *distribution, HotUsers: *hotUsers, HotTraffic: *hotTraffic}
+	if err := picker.validate(); err != nil {
+		log.Fatalf("invalid users: %v", err)
+	}
+	if *rps <= 0 || *workers <= 0 || *chargeMax <= 0 || *withdrawMax <= 0 {
+		log.Fatalf("rps, workers, charge-max and withdraw-max must be positive")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	c := newClient(*baseURL, *adminKey, *timeout, *workers)
+
+	if m[opWithdraw] > 0 {
+		log.Printf("setting up payout destinations of %d users", *users)
+		if err := setUpDestinations(ctx, c, picker, *workers); err != nil {
+			log.Fatalf("could not set up payout destinations: %v", err)
+		}
+	}
+
+	r := &run{
+		client:        c,
+		mix:           m,
+		picker:        picker,
+		chargeMax:     *chargeMax,
+		withdrawMax:   *withdrawMax,
+		withdrawDelay: *withdrawDelay,
+		stats:         newStats(),
+		ledger:        newLedger(),
+	}
+	log.Printf("running at %.1f rps for %s", *rps, *duration)
+	elapsed := r.start(ctx, *rps, *duration, *workers, *seed)
+	fmt.Println()
+	r.stats.print(os.Stdout, elapsed)
+
+	if !*verify {
+		return
+	}
+	if *settle > 0 {
+		log.Printf("waiting %s for the workers to settle", *settle)
+		select {
+		case <-time.After(*settle):
+		case <-ctx.Done():
+		}
+	}
+	v := r.ledger.verify(context.Background(), c)
+	fmt.Printf("\nverified %d users: %d charged, %d withdrawn, %d of failed or cancelled withdrawals\n",
+		v.Users, v.Charged, v.Withdrawn, v.FailedWithdrawals)
+	if len(v.Problems) == 0 {
+		fmt.Println("balances match the acknowledged transactions")
+		return
+	}
+	for _, problem := range v.Problems {
+		fmt.Println("  " + problem)
+	}
+	os.Exit(1)
+}
+
+type run struct {
+	client        *client
+	mix           mix
+	picker        userPicker
+	chargeMax     int64
+	withdrawMax   int64
+	withdrawDelay time.Duration
+	stats         *stats
+	ledger        *ledger
+}
+
+type request struct {
+	op     operation
+	userId int64
+	amount int64
+}
+
+// start sends requests at rps until duration is over or ctx is done and returns how long it ran
+func (r *run) start(ctx context.Context, rps float64, duration time.Duration, workers int, seed int64) time.Duration {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	requests := make(chan request)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for req := range requests {
+				r.send(req)
+			}
+		}()
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rps))
+	defer ticker.Stop()
+	started := time.Now()
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			req := request{op: r.mix.pick(rnd), userId: r.picker.pick(rnd)}
+			switch req.op {
+			case opCharge:
+				req.amount = 1 + rnd.Int63n(r.chargeMax)
+			case opWithdraw:
+				req.amount = 1 + rnd.Int63n(r.withdrawMax)
+			}
+			select {
+			case requests <- req:
+			default:
+				r.stats.drop()
+			}
+		}
+	}
+	elapsed := time.Since(started)
+	close(requests)
+	wg.Wait()
+	return elapsed
+}
+
+// send makes a single request, requests in flight are finished even when the run is over
+func (r *run) send(req request) {
+	ctx := context.Background()
+	started := time.Now()
+	var err error
+	switch req.op {
+	case opCharge:
+		var id string
+		if id, err = r.client.charge(ctx, req.userId, req.amount, uuid.Must(uuid.NewV4()).String()); err == nil {
+			r.ledger.add(req.userId, id, req.amount)
+		}
+	case opWithdraw:
+		var id string
+		releaseTime := time.Now().Add(r.withdrawDelay)
+		if id, err = r.client.withdraw(ctx, req.userId, req.amount, uuid.Must(uuid.NewV4()).String(), releaseTime); err == nil {
+			r.ledger.add(req.userId, id, -req.amount)
+		}
+	case opBalance:
+		_, err = r.client.balance(ctx, req.userId)
+	case opHistory:
+		_, err = r.client.history(ctx, req.userId, nil, 20)
+	}
+	r.stats.add(req.op, time.Since(started), err)
+}
+
+// setUpDestinations makes sure every user has a verified default destination, an existing one is kept
+func setUpDestinations(ctx context.Context, c *client, picker userPicker, workers int) error {
+	userIds := make(chan int64)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for userId := range userIds {
+				if err := setUpDestination(ctx, c, userId); err != nil {
+					select {
+					case errs <- fmt.Errorf("user %d: %w", userId, err):
+					default:
+					}
+				}
+			}
+		}()
+	}
+	for i := int64(0); i < picker.Users; i++ {
+		userIds <- picker.Offset + i
+	}
+	close(userIds)
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+func setUpDestination(ctx context.Context, c *client, userId int64) error {
+	destinations, err := c.destinations(ctx, userId)
+	if err != nil {
+		return err
+	}
+	var d *destination
+	for i := range destinations {
+		if destinations[i].IsDefault {
+			d = &destinations[i]
+		}
+	}
+	if d == nil {
+		if d, err = c.addDestination(ctx, userId, cardNumber(userId)); err != nil {
+			return err
+		}
+	}
+	if d.Verified {
+		return nil
+	}
+	if c.adminKey == "" {
+		return errors.New("destination is not verified and no admin key is given")
+	}
+	return c.verifyDestination(ctx, d.ID)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// stats collects the latency and outcome of every request, errors are grouped by operation and class
+type stats struct {
+	mu        sync.Mutex
+	latencies map[operation][]time.Duration
+	errors    map[operation]map[string]int
+	dropped   int
+}
+
+func newStats() *stats {
+	return &stats{
+		latencies: make(map[operation][]time.Duration),
+		errors:    make(map[operation]map[string]int),
+	}
+}
+
+func (s *stats) add(op operation, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies[op] = append(s.latencies[op], latency)
+	if err == nil {
+		return
+	}
+	if s.errors[op] == nil {
+		s.errors[op] = make(map[string]int)
+	}
+	s.errors[op][errorClass(err)]++
+}
+
+// drop counts a request which was not sent because every worker was busy
+func (s *stats) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped++
+}
+
+// errorClass names the kind of a failed request, API errors by their code or status
+func errorClass(err error) string {
+	var apiErr *apiError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Code != "":
+		return fmt.Sprintf("%d %s", apiErr.Status, apiErr.Code)
+	case errors.As(err, &apiErr):
+		return fmt.Sprintf("%d", apiErr.Status)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "transport"
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+func (s *stats) print(w io.Writer, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\trequests\terrors\trps\tp50\tp90\tp99\tmax\t")
+	total := 0
+	for _, op := range operations {
+		latencies := s.latencies[op]
+		if len(latencies) == 0 {
+			continue
+		}
+		total += len(latencies)
+		sorted := make([]time.Duration, len(latencies))
+		copy(sorted, latencies)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		failed := 0
+		for _, n := range s.errors[op] {
+			failed += n
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", op, len(sorted), failed,
+			float64(len(sorted))/elapsed.Seconds(), round(percentile(sorted, 50)), round(percentile(sorted, 90)),
+			round(percentile(sorted, 99)), round(sorted[len(sorted)-1]))
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d requests in %s (%.1f rps), %d dropped because every worker was busy\n",
+		total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds(), s.dropped)
+
+	if len(s.errors) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nerrors:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, op := range operations {
+		classes := make([]string, 0, len(s.errors[op]))
+		for class := range s.errors[op] {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			fmt.Fprintf(tw, "  %s\t%s\t%d\n", op, class, s.errors[op][class])
+		}
+	}
+	tw.Flush()
+}
+
+func round(d time.Duration) time.Duration {
+	if d > time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
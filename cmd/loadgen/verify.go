@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ledger keeps the transactions the API acknowledged during the run, signed the way the wallet stores them
+type ledger struct {
+	mu           sync.Mutex
+	transactions map[int64]map[string]int64
+	charged      int64
+	withdrawn    int64
+}
+
+func newLedger() *ledger {
+	return &ledger{transactions: make(map[int64]map[string]int64)}
+}
+
+func (l *ledger) add(userId int64, id string, amount int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.transactions[userId] == nil {
+		l.transactions[userId] = make(map[string]int64)
+	}
+	l.transactions[userId][id] = amount
+	if amount > 0 {
+		l.charged += amount
+	} else {
+		l.withdrawn -= amount
+	}
+}
+
+func (l *ledger) users() []int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	users := make([]int64, 0, len(l.transactions))
+	for userId := range l.transactions {
+		users = append(users, userId)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+	return users
+}
+
+// verification is the outcome of checking the run against the wallets, the totals are taken from the histories
+type verification struct {
+	Users             int
+	Charged           int64
+	Withdrawn         int64
+	FailedWithdrawals int64
+	Problems          []string
+}
+
+// verify checks every user the run wrote to. Every acknowledged transaction must be in the history with its
+// amount, and the history must add up to the balance: credits count in the total balance at once and in the
+// available balance when released, debits the other way around, failed and cancelled ones not at all.
+func (l *ledger) verify(ctx context.Context, c *client) *verification {
+	v := &verification{}
+	for _, userId := range l.users() {
+		v.Users++
+		l.mu.Lock()
+		expected := l.transactions[userId]
+		l.mu.Unlock()
+
+		history, w, err := readConsistent(ctx, c, userId)
+		if err != nil {
+			v.Problems = append(v.Problems, fmt.Sprintf("user %d: %v", userId, err))
+			continue
+		}
+
+		var total, available int64
+		seen := make(map[string]bool, len(expected))
+		for _, t := range history {
+			if amount, ok := expected[t.ID]; ok {
+				seen[t.ID] = true
+				if t.Amount != amount {
+					v.Problems = append(v.Problems, fmt.Sprintf("user %d: transaction %s has amount %d, %d was requested", userId, t.ID, t.Amount, amount))
+				}
+				switch {
+				case t.Type == "credit":
+					v.Charged += t.Amount
+				case t.Status == "failed" || t.Status == "cancelled":
+					v.FailedWithdrawals -= t.Amount
+				default:
+					v.Withdrawn -= t.Amount
+				}
+			}
+			if t.Status == "failed" || t.Status == "cancelled" {
+				continue
+			}
+			if t.Type == "credit" {
+				total += t.Amount
+				if t.Released {
+					available += t.Amount
+				}
+				continue
+			}
+			available += t.Amount
+			if t.Released {
+				total += t.Amount
+			}
+		}
+		for id := range expected {
+			if !seen[id] {
+				v.Problems = append(v.Problems, fmt.Sprintf("user %d: acknowledged transaction %s is missing from the history", userId, id))
+			}
+		}
+		if total != w.TotalBalance || available != w.AvailableBalance {
+			v.Problems = append(v.Problems, fmt.Sprintf("user %d: history adds up to %d total and %d available, the wallet has %d and %d",
+				userId, total, available, w.TotalBalance, w.AvailableBalance))
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if v.Charged != l.charged {
+		v.Problems = append(v.Problems, fmt.Sprintf("%d was charged in total, the histories hold %d", l.charged, v.Charged))
+	}
+	if v.Withdrawn+v.FailedWithdrawals != l.withdrawn {
+		v.Problems = append(v.Problems, fmt.Sprintf("%d was withdrawn in total, the histories hold %d",
+			l.withdrawn, v.Withdrawn+v.FailedWithdrawals))
+	}
+	return v
+}
+
+// readConsistent reads the history of a user between two balance reads and retries while the workers
+// change the wallet in between
+func readConsistent(ctx context.Context, c *client, userId int64) ([]transaction, *wallet, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		before, err := c.balance(ctx, userId)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read balance: %w", err)
+		}
+		var history []transaction
+		var cursor *string
+		for {
+			page, err := c.history(ctx, userId, cursor, 100)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not read history: %w", err)
+			}
+			history = append(history, page.TransactionList...)
+			if page.Cursor == nil {
+				break
+			}
+			cursor = page.Cursor
+		}
+		after, err := c.balance(ctx, userId)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read balance: %w", err)
+		}
+		if *before == *after {
+			return history, after, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("balance kept changing while the history was read")
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+type operation = string
+
+const (
+	opCharge   operation = "charge"
+	opWithdraw           = "withdraw"
+	opBalance            = "balance"
+	opHistory            = "history"
+)
+
+var operations = []operation{opCharge, opWithdraw, opBalance, opHistory}
+
+// mix is the relative weight of every operation
+type mix map[operation]int
+
+// parseMix reads weights like "charge=40,withdraw=20,balance=30,history=10", left out operations are not run
+func parseMix(s string) (mix, error) {
+	m := make(mix)
+	total := 0
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not operation=weight", part)
+		}
+		if !isOperation(name) {
+			return nil, fmt.Errorf("unknown operation %q", name)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight of %s must be a non negative number", name)
+		}
+		m[name] = weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("at least one operation must have a positive weight")
+	}
+	return m, nil
+}
+
+func isOperation(name string) bool {
+	for _, op := range operations {
+		if op == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m mix) pick(rnd *rand.Rand) operation {
+	total := 0
+	for _, op := range operations {
+		total += m[op]
+	}
+	roll := rnd.Intn(total)
+	for _, op := range operations {
+		if roll < m[op] {
+			return op
+		}
+		roll -= m[op]
+	}
+	return operations[len(operations)-1]
+}
+
+// userPicker spreads requests over users, with the hot distribution HotTraffic of the requests go to the
+// first HotUsers fraction of the users
+type userPicker struct {
+	Offset       int64
+	Users        int64
+	Distribution string
+	HotUsers     float64
+	HotTraffic   float64
+}
+
+func (p userPicker) validate() error {
+	if p.Users <= 0 {
+		return fmt.Errorf("users must be positive")
+	}
+	switch p.Distribution {
+	case "uniform":
+	case "hot":
+		if p.HotUsers <= 0 || p.HotUsers >= 1 || p.HotTraffic < 0 || p.HotTraffic > 1 {
+			return fmt.Errorf("hot users must be between 0 and 1 exclusive and hot traffic between 0 and 1")
+		}
+	default:
+		return fmt.Errorf("unknown user distribution %q", p.Distribution)
+	}
+	return nil
+}
+
+func (p userPicker) pick(rnd *rand.Rand) int64 {
+	if p.Distribution == "hot" {
+		hot := max(1, int64(float64(p.Users)*p.HotUsers))
+		if rnd.Float64() < p.HotTraffic || hot == p.Users {
+			return p.Offset + rnd.Int63n(hot)
+		}
+		return p.Offset + hot + rnd.Int63n(p.Users-hot)
+	}
+	return p.Offset + rnd.Int63n(p.Users)
+}
+
+// cardNumber derives a card number passing the Luhn check from the user id, so every user gets its own
+func cardNumber(userId int64) string {
+	digits := fmt.Sprintf("6037%011d", userId%100000000000)
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return digits + strconv.Itoa((10-sum%10)%10)
+}
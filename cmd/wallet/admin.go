@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	auditCommand "github.com/MaisamV/wallet/internal/audit/application/command"
+	integrityCommand "github.com/MaisamV/wallet/internal/integrity/application/command"
+	integrityEntity "github.com/MaisamV/wallet/internal/integrity/entity"
+	reconcileCommand "github.com/MaisamV/wallet/internal/reconciliation/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/gofrs/uuid/v5"
+	"os"
+	osuser "os/user"
+	"path/filepath"
+	"time"
+)
+
+const adminUsage = `usage: wallet admin <task> [flags]
+
+tasks:
+  balance    print the balance of a wallet
+  adjust     request a manual adjustment, another operator approves it through the admin API
+  reconcile  reconcile a bank settlement file against the withdrawals of a period
+  release    release a wallet quarantined by the integrity check once its balances match its transactions
+  audit      verify the hash chain of the audit log, exits with status 1 when the chain is broken
+
+Run wallet admin <task> -h for the flags of a task.
+adjust and release act as the operator whose admin API key is in ` + operatorKeyEnv + `.`
+
+// operatorKeyEnv holds the admin API key of the operator running adjust and release, it is read from the
+// environment rather than a flag so it does not show up in the process list
+const operatorKeyEnv = "WALLET_OPERATOR_API_KEY"
+
+// runAdmin runs a single operator task and prints its result as JSON
+func runAdmin(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, adminUsage)
+		return 2
+	}
+	switch args[0] {
+	case "balance":
+		return runAdminBalance(args[1:])
+	case "adjust":
+		return runAdminAdjust(args[1:])
+	case "reconcile":
+		return runAdminReconcile(args[1:])
+	case "release":
+		return runAdminRelease(args[1:])
+	case "audit":
+		return runAdminAudit(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, adminUsage)
+		return 2
+	}
+}
+
+func runAdminBalance(args []string) int {
+	flags := flag.NewFlagSet("admin balance", flag.ContinueOnError)
+	userId := flags.Int64("user", 0, "id of the user")
+	if !parseFlags(flags, newConfigFlags(flags), args) {
+		return 2
+	}
+	if *userId <= 0 {
+		fmt.Fprintln(os.Stderr, "user id should be positive")
+		return 2
+	}
+
+	app, err := InitializeAdminApplication()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize application: %v\n", err)
+		return 1
+	}
+	defer app.Repo.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	wallet, err := app.BalanceHandler.Handle(ctx, query.GetBalanceQuery{UserID: *userId})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return printJSON(wallet)
+}
+
+func runAdminAdjust(args []string) int {
+	flags := flag.NewFlagSet("admin adjust", flag.ContinueOnError)
+	userId := flags.Int64("user", 0, "id of the user")
+	transactionType := flags.String("type", "", "credit or debit")
+	amount := flags.Int64("amount", 0, "amount of the adjustment")
+	reason := flags.String("reason", "", "reason code: goodwill, correction, chargeback, fee_refund or other")
+	note := flags.String("note", "", "note of the adjustment, required when the reason is other")
+	idempotency := flags.String("idempotency", "", "idempotency key, a new one is generated when empty")
+	if !parseFlags(flags, newConfigFlags(flags), args) {
+		return 2
+	}
+
+	app, err := InitializeAdminApplication()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize application: %v\n", err)
+		return 1
+	}
+	defer app.Repo.Close()
+
+	info, err := operator(app.Config.Admin.Users)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	key := uuid.Must(uuid.NewV4())
+	if *idempotency != "" {
+		parsed, err := uuid.FromString(*idempotency)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid idempotency key: %v\n", err)
+			return 2
+		}
+		key = parsed
+	}
+	cmd := command.RequestAdjustmentCommand{
+		UserId:      *userId,
+		Type:        entity.TransactionType(*transactionType),
+		Amount:      *amount,
+		ReasonCode:  *reason,
+		Note:        *note,
+		Idempotency: &key,
+		Actor:       info.Actor,
+	}
+	if err := cmd.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid adjustment: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(audit.NewContext(context.Background(), info), 30*time.Second)
+	defer cancel()
+	adjustment, err := app.AdjustmentHandler.Handle(ctx, cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return printJSON(adjustment)
+}
+
+// runAdminRelease lets a quarantined wallet be debited again and prints its recomputed balances
+func runAdminRelease(args []string) int {
+	flags := flag.NewFlagSet("admin release", flag.ContinueOnError)
+	userId := flags.Int64("user", 0, "id of the user")
+	force := flags.Bool("force", false, "release the wallet even when its balances do not match its transactions")
+	if !parseFlags(flags, newConfigFlags(flags), args) {
+		return 2
+	}
+
+	app, err := InitializeAdminApplication()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize application: %v\n", err)
+		return 1
+	}
+	defer app.Repo.Close()
+
+	info, err := operator(app.Config.Admin.Users)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	cmd := integrityCommand.ReleaseQuarantineCommand{UserID: *userId, Force: *force, Actor: info.Actor}
+	if err := cmd.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid release: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(audit.NewContext(context.Background(), info), 30*time.Second)
+	defer cancel()
+	balances, err := app.QuarantineHandler.Handle(ctx, cmd)
+	if errors.Is(err, integrityEntity.ErrBalanceMismatch) {
+		fmt.Fprintln(os.Stderr, err)
+		printJSON(balances)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return printJSON(balances)
+}
+
+// runAdminReconcile ingests a single bank settlement file and stores the reconciliation result.
+// By default the file is reconciled against the withdrawals created during the previous UTC day.
+func runAdminReconcile(args []string) int {
+	flags := flag.NewFlagSet("admin reconcile", flag.ContinueOnError)
+	filePath := flags.String("file", "", "path of the bank settlement file")
+	from := flags.String("from", "", "start of the settlement period (RFC3339), the previous UTC day when empty")
+	to := flags.String("to", "", "end of the settlement period (RFC3339), the previous UTC day when empty")
+	if !parseFlags(flags, newConfigFlags(flags), args) {
+		return 2
+	}
+
+	periodStart, err := parsePeriod(*from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid period start: %v\n", err)
+		return 2
+	}
+	periodEnd, err := parsePeriod(*to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid period end: %v\n", err)
+		return 2
+	}
+	file, err := os.Open(*filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not open settlement file: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+
+	app, err := InitializeAdminApplication()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize application: %v\n", err)
+		return 1
+	}
+	defer app.Repo.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	run, err := app.ReconcileHandler.Handle(ctx, reconcileCommand.ReconcileCommand{
+		FileName: filepath.Base(*filePath),
+		File:     file,
+		From:     periodStart,
+		To:       periodEnd,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reconciliation failed: %v\n", err)
+		return 1
+	}
+	return printJSON(run)
+}
+
+// runAdminAudit walks the audit log hash chain from its first entry up to its head and prints the tampered entries
+func runAdminAudit(args []string) int {
+	flags := flag.NewFlagSet("admin audit", flag.ContinueOnError)
+	batchSize := flags.Int("batch", 1000, "number of audit entries read per query")
+	if !parseFlags(flags, newConfigFlags(flags), args) {
+		return 2
+	}
+
+	app, err := InitializeAdminApplication()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize application: %v\n", err)
+		return 1
+	}
+	defer app.Repo.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	result, err := app.AuditHandler.Handle(ctx, auditCommand.VerifyChainCommand{BatchSize: *batchSize})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit chain verification failed: %v\n", err)
+		return 1
+	}
+	if status := printJSON(result); status != 0 {
+		return status
+	}
+	if !result.Intact() {
+		return 1
+	}
+	return 0
+}
+
+// operator authenticates the operator by the admin API key in the environment, the same key the admin API takes,
+// so the four eyes check compares the operators the keys belong to. The OS user and host running the task are
+// recorded as the source of its audit entries.
+func operator(users []config.AdminUser) (audit.Info, error) {
+	key := os.Getenv(operatorKeyEnv)
+	if key == "" {
+		return audit.Info{}, fmt.Errorf("%s is not set", operatorKeyEnv)
+	}
+	user, ok := config.AdminUserByKey(users, key)
+	if !ok {
+		return audit.Info{}, fmt.Errorf("%s does not belong to an admin user", operatorKeyEnv)
+	}
+	return audit.Info{Actor: user.Name, SourceIP: invoker()}, nil
+}
+
+// invoker names the OS user and host running the process, cut to fit the source column of the audit log
+func invoker() string {
+	name := "unknown"
+	if current, err := osuser.Current(); err == nil {
+		name = current.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	source := "cli:" + name + "@" + host
+	if len(source) > 64 {
+		source = source[:64]
+	}
+	return source
+}
+
+// parsePeriod parses a bound of the settlement period, an empty bound is zero and left to the reconciliation
+func parsePeriod(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func printJSON(v any) int {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,646 @@
+package main
+
+import (
+	"context"
+	"flag"
+	integrityCommand "github.com/MaisamV/wallet/internal/integrity/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/platform"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/MaisamV/wallet/platform/schedule"
+	"log"
+	"time"
+)
+
+// runSnapshotWorker records the closing balances of every day until it is interrupted
+func runSnapshotWorker(args []string) int {
+	flags, overrides := jobFlags("snapshot-worker", "snapshot_worker")
+	if !parseFlags(flags, overrides, args) {
+		return 2
+	}
+
+	app, err := InitializeJobApplication()
+	if err != nil {
+		log.Printf("Failed to initialize application: %v", err)
+		return 1
+	}
+	defer app.Repo.Close()
+	app.Logger.Info().Msg("Starting snapshot worker")
+	snapshotConfig := app.Config.Snapshot
+	snapshotSchedule := schedule.New(snapshotConfig.Interval, snapshotConfig.BatchSize)
+
+	worker := NewSnapshotWorker(
+		app.SnapshotHandler,
+		app.Logger,
+		snapshotSchedule,
+	)
+	worker.Start()
+	platform.WatchConfig(app.Config, app.Logger, func(next *config.Config) {
+		snapshotSchedule.Set(next.Snapshot.Interval, next.Snapshot.BatchSize)
+	})
+
+	waitForSignal()
+	worker.Stop()
+	app.Logger.Info().Msg("Finished gracefully")
+	return 0
+}
+
+// runHoldExpiryWorker expires the holds which were neither captured nor voided until it is interrupted
+func runHoldExpiryWorker(args []string) int {
+	flags, overrides := workerFlags("hold-expiry-worker", "hold_expiry_worker")
+	if !parseFlags(flags, overrides, args) {
+		return 2
+	}
+
+	app, err := InitializeJobApplication()
+	if err != nil {
+		log.Printf("Failed to initialize application: %v", err)
+		return 1
+	}
+	defer app.Repo.Close()
+	app.Logger.Info().Msg("Starting hold expiry worker")
+	expiryConfig := app.Config.HoldExpiry
+	expirySchedule := schedule.New(expiryConfig.Interval, expiryConfig.BatchSize)
+
+	worker := NewHoldExpiryWorker(
+		app.ExpireHoldsHandler,
+		app.Logger,
+		expirySchedule,
+		expiryConfig.WorkerCount,
+	)
+	worker.Start()
+	platform.WatchConfig(app.Config, app.Logger, func(next *config.Config) {
+		expirySchedule.Set(next.HoldExpiry.Interval, next.HoldExpiry.BatchSize)
+	})
+
+	waitForSignal()
+	worker.Stop()
+	app.Logger.Info().Msg("Finished gracefully")
+	return 0
+}
+
+// runPromoExpiryWorker takes back the unspent promotional credit of expired grants until it is interrupted
+func runPromoExpiryWorker(args []string) int {
+	flags, overrides := workerFlags("promo-expiry-worker", "promo_expiry_worker")
+	if !parseFlags(flags, overrides, args) {
+		return 2
+	}
+
+	app, err := InitializeJobApplication()
+	if err != nil {
+		log.Printf("Failed to initialize application: %v", err)
+		return 1
+	}
+	defer app.Repo.Close()
+	app.Logger.Info().Msg("Starting promo expiry worker")
+	expiryConfig := app.Config.PromoExpiry
+	expirySchedule := schedule.New(expiryConfig.Interval, expiryConfig.BatchSize)
+
+	worker := NewPromoExpiryWorker(
+		app.ExpirePromosHandler,
+		app.Logger,
+		expirySchedule,
+		expiryConfig.WorkerCount,
+	)
+	worker.Start()
+	platform.WatchConfig(app.Config, app.Logger, func(next *config.Config) {
+		expirySchedule.Set(next.PromoExpiry.Interval, next.PromoExpiry.BatchSize)
+	})
+
+	waitForSignal()
+	worker.Stop()
+	app.Logger.Info().Msg("Finished gracefully")
+	return 0
+}
+
+// runSchedulerWorker runs the due occurrences of recurring charges and payouts until it is interrupted
+func runSchedulerWorker(args []string) int {
+	flags, overrides := jobFlags("scheduler-worker", "scheduler_worker")
+	if !parseFlags(flags, overrides, args) {
+		return 2
+	}
+
+	app, err := InitializeJobApplication()
+	if err != nil {
+		log.Printf("Failed to initialize application: %v", err)
+		return 1
+	}
+	defer app.Repo.Close()
+	app.Logger.Info().Msg("Starting scheduler worker")
+	schedulerConfig := app.Config.Scheduler
+	schedulerSchedule := schedule.New(schedulerConfig.Interval, schedulerConfig.BatchSize)
+
+	worker := NewSchedulerWorker(
+		app.RunSchedulesHandler,
+		app.Logger,
+		schedulerSchedule,
+		schedulerConfig.ClaimFor,
+	)
+	worker.Start()
+	platform.WatchConfig(app.Config, app.Logger, func(next *config.Config) {
+		schedulerSchedule.Set(next.Scheduler.Interval, next.Scheduler.BatchSize)
+		app.RunSchedulesHandler.SetMaxCatchUp(next.Scheduler.MaxCatchUp)
+	})
+
+	waitForSignal()
+	worker.Stop()
+	app.Logger.Info().Msg("Finished gracefully")
+	return 0
+}
+
+// runBatchWorker applies the uploaded batches until it is interrupted
+func runBatchWorker(args []string) int {
+	flags := flag.NewFlagSet("batch-worker", flag.ContinueOnError)
+	overrides := newConfigFlags(flags)
+	overrides.String("chunk-size", "batch_worker.chunk_size", "batch rows applied in one transaction")
+	overrides.String("interval", "batch_worker.interval", "time between two looks for waiting batches")
+	if !parseFlags(flags, overrides, args) {
+		return 2
+	}
+
+	app, err := InitializeJobApplication()
+	if err != nil {
+		log.Printf("Failed to initialize application: %v", err)
+		return 1
+	}
+	defer app.Repo.Close()
+	app.Logger.Info().Msg("Starting batch worker")
+	batchConfig := app.Config.Batch
+	batchSchedule := schedule.New(batchConfig.Interval, batchConfig.ChunkSize)
+
+	worker := NewBatchWorker(
+		app.ProcessBatchHandler,
+		app.Logger,
+		batchSchedule,
+		batchConfig.ClaimFor,
+	)
+	worker.Start()
+	platform.WatchConfig(app.Config, app.Logger, func(next *config.Config) {
+		batchSchedule.Set(next.Batch.Interval, next.Batch.ChunkSize)
+	})
+
+	waitForSignal()
+	worker.Stop()
+	app.Logger.Info().Msg("Finished gracefully")
+	return 0
+}
+
+// runIntegrityWorker recomputes the wallet balances from their transactions until it is interrupted
+func runIntegrityWorker(args []string) int {
+	flags, overrides := jobFlags("integrity-worker", "integrity_worker")
+	if !parseFlags(flags, overrides, args) {
+		return 2
+	}
+
+	app, err := InitializeJobApplication()
+	if err != nil {
+		log.Printf("Failed to initialize application: %v", err)
+		return 1
+	}
+	defer app.Repo.Close()
+	app.Logger.Info().Msg("Starting integrity worker")
+	integrityConfig := app.Config.Integrity
+	integritySchedule := schedule.New(integrityConfig.Interval, integrityConfig.BatchSize)
+
+	worker := NewIntegrityWorker(
+		app.CheckIntegrityHandler,
+		app.Logger,
+		integritySchedule,
+		integrityConfig.AutoQuarantine,
+	)
+	worker.Start()
+	platform.WatchConfig(app.Config, app.Logger, func(next *config.Config) {
+		integritySchedule.Set(next.Integrity.Interval, next.Integrity.BatchSize)
+	})
+
+	waitForSignal()
+	worker.Stop()
+	app.Logger.Info().Msg("Finished gracefully")
+	return 0
+}
+
+// SnapshotWorker records the closing balances of the previous day once the day is over.
+// Snapshots are only taken for wallets missing one, so running it on every tick is safe.
+type SnapshotWorker struct {
+	handler  *command.SnapshotCommandHandler
+	logger   logger.Logger
+	schedule *schedule.Schedule
+	stop     chan any
+}
+
+func NewSnapshotWorker(
+	handler *command.SnapshotCommandHandler,
+	logger logger.Logger,
+	schedule *schedule.Schedule,
+) *SnapshotWorker {
+	return &SnapshotWorker{
+		handler:  handler,
+		logger:   logger,
+		schedule: schedule,
+		stop:     make(chan any),
+	}
+}
+
+func (w *SnapshotWorker) Start() {
+	go w.workerLoop()
+}
+
+func (w *SnapshotWorker) Stop() {
+	close(w.stop)
+}
+
+func (w *SnapshotWorker) workerLoop() {
+	interval, changed := w.schedule.Interval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.logger.Info().Msg("started")
+
+	w.snapshot()
+	for {
+		select {
+		case <-ticker.C:
+			w.snapshot()
+
+		case <-changed:
+			interval, changed = w.schedule.Interval()
+			ticker.Reset(interval)
+
+		case <-w.stop:
+			w.logger.Info().Msg("stopped")
+			return
+		}
+	}
+}
+
+func (w *SnapshotWorker) snapshot() {
+	interval, _ := w.schedule.Interval()
+	ctx, cancel := context.WithTimeout(context.Background(), interval)
+	defer cancel()
+
+	// the zero date takes the balances of the previous day
+	cmd := command.SnapshotCommand{BatchSize: w.schedule.BatchSize()}
+	if _, err := w.handler.Handle(ctx, cmd); err != nil {
+		w.logger.Error().Err(err).Msg("snapshot failed")
+	}
+}
+
+// HoldExpiryWorker gives the amount of holds which were neither captured nor voided
+// before their expiry back to the available balance
+type HoldExpiryWorker struct {
+	handler     *command.ExpireHoldsCommandHandler
+	logger      logger.Logger
+	schedule    *schedule.Schedule
+	workerCount int
+	stop        chan any
+}
+
+func NewHoldExpiryWorker(
+	handler *command.ExpireHoldsCommandHandler,
+	logger logger.Logger,
+	schedule *schedule.Schedule,
+	workerCount int,
+) *HoldExpiryWorker {
+	return &HoldExpiryWorker{
+		handler:     handler,
+		logger:      logger,
+		schedule:    schedule,
+		workerCount: workerCount,
+		stop:        make(chan any),
+	}
+}
+
+func (w *HoldExpiryWorker) Start() {
+	for i := 0; i < w.workerCount; i++ {
+		go w.workerLoop(i)
+	}
+}
+
+func (w *HoldExpiryWorker) Stop() {
+	close(w.stop)
+}
+
+func (w *HoldExpiryWorker) workerLoop(id int) {
+	interval, changed := w.schedule.Interval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.logger.Info().Int("worker", id).Msg("started")
+
+	for {
+		select {
+		case <-ticker.C:
+			w.expire(id)
+
+		case <-changed:
+			interval, changed = w.schedule.Interval()
+			ticker.Reset(interval)
+
+		case <-w.stop:
+			w.logger.Info().Int("worker", id).Msg("stopped")
+			return
+		}
+	}
+}
+
+func (w *HoldExpiryWorker) expire(id int) {
+	ctx, cancel := context.WithTimeout(audit.NewContext(context.Background(), audit.Info{Actor: "hold_expiry_worker"}), 3*time.Second)
+	defer cancel()
+
+	cmd := command.ExpireHoldsCommand{BatchSize: w.schedule.BatchSize()}
+	if _, err := w.handler.Handle(ctx, cmd); err != nil {
+		w.logger.Error().Err(err).Int("worker", id).Msg("hold expiry failed")
+	}
+}
+
+// PromoExpiryWorker takes back the promotional credit which was not spent before its grant expired
+type PromoExpiryWorker struct {
+	handler     *command.ExpirePromoGrantsCommandHandler
+	logger      logger.Logger
+	schedule    *schedule.Schedule
+	workerCount int
+	stop        chan any
+}
+
+func NewPromoExpiryWorker(
+	handler *command.ExpirePromoGrantsCommandHandler,
+	logger logger.Logger,
+	schedule *schedule.Schedule,
+	workerCount int,
+) *PromoExpiryWorker {
+	return &PromoExpiryWorker{
+		handler:     handler,
+		logger:      logger,
+		schedule:    schedule,
+		workerCount: workerCount,
+		stop:        make(chan any),
+	}
+}
+
+func (w *PromoExpiryWorker) Start() {
+	for i := 0; i < w.workerCount; i++ {
+		go w.workerLoop(i)
+	}
+}
+
+func (w *PromoExpiryWorker) Stop() {
+	close(w.stop)
+}
+
+func (w *PromoExpiryWorker) workerLoop(id int) {
+	interval, changed := w.schedule.Interval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.logger.Info().Int("worker", id).Msg("started")
+
+	for {
+		select {
+		case <-ticker.C:
+			w.expire(id)
+
+		case <-changed:
+			interval, changed = w.schedule.Interval()
+			ticker.Reset(interval)
+
+		case <-w.stop:
+			w.logger.Info().Int("worker", id).Msg("stopped")
+			return
+		}
+	}
+}
+
+func (w *PromoExpiryWorker) expire(id int) {
+	ctx, cancel := context.WithTimeout(audit.NewContext(context.Background(), audit.Info{Actor: "promo_expiry_worker"}), 3*time.Second)
+	defer cancel()
+
+	cmd := command.ExpirePromoGrantsCommand{BatchSize: w.schedule.BatchSize()}
+	if _, err := w.handler.Handle(ctx, cmd); err != nil {
+		w.logger.Error().Err(err).Int("worker", id).Msg("promo expiry failed")
+	}
+}
+
+// SchedulerWorker runs the due occurrences of recurring charges and payouts. Schedules are claimed
+// before they run, so several scheduler workers can run side by side.
+type SchedulerWorker struct {
+	handler  *command.RunSchedulesCommandHandler
+	logger   logger.Logger
+	schedule *schedule.Schedule
+	timeout  time.Duration
+	stop     chan any
+	done     chan any
+}
+
+func NewSchedulerWorker(
+	handler *command.RunSchedulesCommandHandler,
+	logger logger.Logger,
+	schedule *schedule.Schedule,
+	timeout time.Duration,
+) *SchedulerWorker {
+	return &SchedulerWorker{
+		handler:  handler,
+		logger:   logger,
+		schedule: schedule,
+		timeout:  timeout,
+		stop:     make(chan any),
+		done:     make(chan any),
+	}
+}
+
+func (w *SchedulerWorker) Start() {
+	go w.workerLoop()
+}
+
+// Stop waits for the running batch, an interrupted batch is only picked up again once its claim is over
+func (w *SchedulerWorker) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+func (w *SchedulerWorker) workerLoop() {
+	defer close(w.done)
+	interval, changed := w.schedule.Interval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.logger.Info().Msg("started")
+
+	for {
+		select {
+		case <-ticker.C:
+			w.run()
+
+		case <-changed:
+			interval, changed = w.schedule.Interval()
+			ticker.Reset(interval)
+
+		case <-w.stop:
+			w.logger.Info().Msg("stopped")
+			return
+		}
+	}
+}
+
+func (w *SchedulerWorker) run() {
+	// the batch has to finish before the claim on its schedules is over
+	ctx, cancel := context.WithTimeout(audit.NewContext(context.Background(), audit.Info{Actor: "scheduler_worker"}), w.timeout)
+	defer cancel()
+
+	cmd := command.RunSchedulesCommand{BatchSize: w.schedule.BatchSize()}
+	if _, err := w.handler.Handle(ctx, cmd); err != nil {
+		w.logger.Error().Err(err).Msg("running schedules failed")
+	}
+}
+
+// BatchWorker applies the uploaded batches one after the other. A batch is claimed before it is
+// processed, so several batch workers can run side by side.
+type BatchWorker struct {
+	handler  *command.ProcessBatchCommandHandler
+	logger   logger.Logger
+	schedule *schedule.Schedule
+	timeout  time.Duration
+	stop     chan any
+	done     chan any
+}
+
+func NewBatchWorker(
+	handler *command.ProcessBatchCommandHandler,
+	logger logger.Logger,
+	schedule *schedule.Schedule,
+	timeout time.Duration,
+) *BatchWorker {
+	return &BatchWorker{
+		handler:  handler,
+		logger:   logger,
+		schedule: schedule,
+		timeout:  timeout,
+		stop:     make(chan any),
+		done:     make(chan any),
+	}
+}
+
+func (w *BatchWorker) Start() {
+	go w.workerLoop()
+}
+
+// Stop waits for the batch being processed, an interrupted batch continues once its claim is over
+func (w *BatchWorker) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+func (w *BatchWorker) workerLoop() {
+	defer close(w.done)
+	interval, changed := w.schedule.Interval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.logger.Info().Msg("started")
+
+	for {
+		select {
+		case <-ticker.C:
+			w.drain()
+
+		case <-changed:
+			interval, changed = w.schedule.Interval()
+			ticker.Reset(interval)
+
+		case <-w.stop:
+			w.logger.Info().Msg("stopped")
+			return
+		}
+	}
+}
+
+// drain processes the waiting batches one after the other until none is left or the worker stops
+func (w *BatchWorker) drain() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+		if !w.process() {
+			return
+		}
+	}
+}
+
+// process handles a single batch and tells whether one was found
+func (w *BatchWorker) process() bool {
+	// the batch has to finish before its claim is over
+	ctx, cancel := context.WithTimeout(audit.NewContext(context.Background(), audit.Info{Actor: "batch_worker"}), w.timeout)
+	defer cancel()
+
+	cmd := command.ProcessBatchCommand{ChunkSize: w.schedule.BatchSize()}
+	batch, err := w.handler.Handle(ctx, cmd)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("batch processing failed")
+		return false
+	}
+	return batch != nil
+}
+
+// IntegrityWorker periodically recomputes every wallet balance from its transactions
+type IntegrityWorker struct {
+	handler    *integrityCommand.CheckIntegrityCommandHandler
+	logger     logger.Logger
+	schedule   *schedule.Schedule
+	quarantine bool
+	stop       chan any
+}
+
+func NewIntegrityWorker(
+	handler *integrityCommand.CheckIntegrityCommandHandler,
+	logger logger.Logger,
+	schedule *schedule.Schedule,
+	quarantine bool,
+) *IntegrityWorker {
+	return &IntegrityWorker{
+		handler:    handler,
+		logger:     logger,
+		schedule:   schedule,
+		quarantine: quarantine,
+		stop:       make(chan any),
+	}
+}
+
+func (w *IntegrityWorker) Start() {
+	go w.workerLoop()
+}
+
+func (w *IntegrityWorker) Stop() {
+	close(w.stop)
+}
+
+func (w *IntegrityWorker) workerLoop() {
+	interval, changed := w.schedule.Interval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.logger.Info().Msg("started")
+
+	w.check()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+
+		case <-changed:
+			interval, changed = w.schedule.Interval()
+			ticker.Reset(interval)
+
+		case <-w.stop:
+			w.logger.Info().Msg("stopped")
+			return
+		}
+	}
+}
+
+func (w *IntegrityWorker) check() {
+	interval, _ := w.schedule.Interval()
+	ctx, cancel := context.WithTimeout(audit.NewContext(context.Background(), audit.Info{Actor: "integrity_worker"}), interval)
+	defer cancel()
+
+	cmd := integrityCommand.CheckIntegrityCommand{BatchSize: w.schedule.BatchSize(), Quarantine: w.quarantine}
+	if _, err := w.handler.Handle(ctx, cmd); err != nil {
+		w.logger.Error().Err(err).Msg("integrity check failed")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/MaisamV/wallet/platform/config"
+	"os"
+	"strings"
+)
+
+const usage = `usage: wallet [flags] <command> [command flags]
+
+commands:
+  serve                run the HTTP API
+  release-worker       release the debits and credits whose release time has passed
+  withdraw-worker      pay the released withdrawals out through the bank
+  snapshot-worker      record the closing balances of the previous day
+  hold-expiry-worker   give the amount of expired holds back to the available balance
+  promo-expiry-worker  take back the unspent promotional credit of expired grants
+  scheduler-worker     run the due recurring charges and payouts
+  batch-worker         apply the uploaded batches
+  integrity-worker     recompute the wallet balances from their transactions
+  migrate              apply, revert or inspect the database migrations
+  admin                run an operator task: balance, adjust, reconcile, release or audit
+
+Run wallet <command> -h for the flags of a command.
+
+flags:`
+
+// The wallet binary runs every role of the service, the command picks the role. Global flags and the flags
+// of a command override the config file and the WALLET_ environment variables, so one image serves them all.
+func main() {
+	flags := flag.CommandLine
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	configFile := flags.String("config", "", "config file to read instead of resources/config.yaml")
+	var settings settingsFlag
+	flags.Var(&settings, "set", "override a config key, e.g. -set release_worker.interval=5s, repeatable")
+	overrides := newConfigFlags(flags)
+	overrides.String("profile", "profile", "profile layered on the config file: dev, staging or prod")
+	overrides.String("log-level", "logging.level", "log level")
+	overrides.String("db-host", "database.host", "database host")
+	overrides.String("db-port", "database.port", "database port")
+	overrides.String("db-name", "database.dbname", "database name")
+	overrides.Bool("auto-migrate", "database.auto_migrate", "apply pending migrations on startup")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *configFile != "" {
+		config.SetFile(*configFile)
+	}
+	settings.apply()
+	overrides.apply()
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	switch command {
+	case "serve":
+		os.Exit(runServe(args))
+	case "release-worker":
+		os.Exit(runReleaseWorker(args))
+	case "withdraw-worker":
+		os.Exit(runWithdrawWorker(args))
+	case "snapshot-worker":
+		os.Exit(runSnapshotWorker(args))
+	case "hold-expiry-worker":
+		os.Exit(runHoldExpiryWorker(args))
+	case "promo-expiry-worker":
+		os.Exit(runPromoExpiryWorker(args))
+	case "scheduler-worker":
+		os.Exit(runSchedulerWorker(args))
+	case "batch-worker":
+		os.Exit(runBatchWorker(args))
+	case "integrity-worker":
+		os.Exit(runIntegrityWorker(args))
+	case "migrate":
+		os.Exit(runMigrate(args))
+	case "admin":
+		os.Exit(runAdmin(args))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// configFlags binds flags to config keys, only the flags given on the command line override the config
+type configFlags struct {
+	flags *flag.FlagSet
+	keys  map[string]string
+}
+
+func newConfigFlags(flags *flag.FlagSet) *configFlags {
+	return &configFlags{flags: flags, keys: make(map[string]string)}
+}
+
+func (f *configFlags) String(name string, key string, usage string) {
+	f.flags.String(name, "", usage+", overrides "+key)
+	f.keys[name] = key
+}
+
+func (f *configFlags) Bool(name string, key string, usage string) {
+	f.flags.Bool(name, false, usage+", overrides "+key)
+	f.keys[name] = key
+}
+
+// apply overrides the config with the bound flags which were set, it must run before the config is loaded
+func (f *configFlags) apply() {
+	f.flags.Visit(func(set *flag.Flag) {
+		if key, ok := f.keys[set.Name]; ok {
+			config.Override(key, set.Value.String())
+		}
+	})
+}
+
+// settingsFlag collects the key=value pairs of the repeatable -set flag
+type settingsFlag []string
+
+func (s *settingsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *settingsFlag) Set(value string) error {
+	key, _, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("%q is not key=value", value)
+	}
+	*s = append(*s, value)
+	return nil
+}
+
+func (s *settingsFlag) apply() {
+	for _, setting := range *s {
+		key, value, _ := strings.Cut(setting, "=")
+		config.Override(key, value)
+	}
+}
+
+// parseFlags parses the flags of a command and overrides the config with the ones bound to config keys
+func parseFlags(flags *flag.FlagSet, overrides *configFlags, args []string) bool {
+	if err := flags.Parse(args); err != nil {
+		return false
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments %v\n", flags.Args())
+		flags.Usage()
+		return false
+	}
+	overrides.apply()
+	return true
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/MaisamV/wallet/platform/database"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/MaisamV/wallet/platform/migrate"
+	"github.com/MaisamV/wallet/scripts/migrations"
+	"os"
+	"strconv"
+	"time"
+)
+
+const migrateUsage = `usage: wallet migrate <command>
+
+commands:
+  up              apply every pending migration
+  down [-steps n] revert the last n migrations, 1 by default
+  status          print the applied version and the pending migrations
+  force <version> set the version without running migrations, -1 empties it`
+
+// runMigrate runs the migrate subcommand against the configured database and returns the exit status.
+// It connects without the schema check every other command starts with.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not load config: %v\n", err)
+		return 1
+	}
+	log := logger.NewWithLevel(cfg.Logging.Level)
+	pool, err := database.NewConnection(cfg.Database, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not connect to the database: %v\n", err)
+		return 1
+	}
+	defer pool.Close()
+	migrator, err := migrate.New(log, pool, migrations.FS)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate up failed after %d migrations: %v\n", applied, err)
+			return 1
+		}
+		fmt.Printf("applied %d migrations\n", applied)
+	case "down":
+		flags := flag.NewFlagSet("down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if *steps <= 0 {
+			fmt.Fprintln(os.Stderr, "steps must be positive")
+			return 2
+		}
+		reverted, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate down failed after %d migrations: %v\n", reverted, err)
+			return 1
+		}
+		fmt.Printf("reverted %d migrations\n", reverted)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("version %d, latest %d, dirty %t\n", status.Version, status.Latest, status.Dirty)
+		for _, m := range status.Pending {
+			fmt.Printf("pending %d_%s\n", m.Version, m.Name)
+		}
+	case "force":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < migrate.NoVersion {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			return 2
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("forced version %d\n", version)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
@@ -1,17 +1,30 @@
 package main
 
 import (
+	"flag"
+	"github.com/MaisamV/wallet/platform"
+	"github.com/MaisamV/wallet/platform/config"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
-func main() {
+// runServe runs the HTTP API until it is interrupted
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	overrides := newConfigFlags(flags)
+	overrides.String("port", "server.port", "port the API listens on")
+	overrides.Bool("swagger", "swagger.enabled", "serve the API documentation")
+	if !parseFlags(flags, overrides, args) {
+		return 2
+	}
+
 	// Initialize application with wire-generated dependency injection
 	app, err := InitializeApplication()
 	if err != nil {
-		log.Fatalf("Failed to initialize application: %v", err)
+		log.Printf("Failed to initialize application: %v", err)
+		return 1
 	}
 	defer app.Wallet.Repo.Close()
 	app.Logger.Info().Msg("Starting application")
@@ -19,6 +32,7 @@ func main() {
 
 	// Get the fiber app instance
 	fiberApp := app.HTTPServer.GetApp()
+	adminRouter := app.HTTPServer.GetAdminRouter()
 
 	// Register routes
 	app.Logger.Info().Msg("Registering routes")
@@ -26,7 +40,21 @@ func main() {
 	app.Probes.HealthHandler.RegisterRoutes(fiberApp)
 	app.Swagger.DocsHandler.RegisterRoutes(fiberApp, app.Config.Swagger.Enabled)
 	app.Wallet.WalletHandler.RegisterRoutes(fiberApp)
+	app.Wallet.DestinationHandler.RegisterRoutes(fiberApp)
+	app.Wallet.HoldHandler.RegisterRoutes(fiberApp)
+	app.Wallet.ScheduleHandler.RegisterRoutes(fiberApp)
+	app.Wallet.WalletAdminHandler.RegisterRoutes(adminRouter)
+	app.Wallet.AdjustmentHandler.RegisterRoutes(adminRouter)
+	app.Wallet.LimitsHandler.RegisterRoutes(adminRouter)
+	app.Wallet.RiskReviewHandler.RegisterRoutes(adminRouter)
+	app.Wallet.DestinationHandler.RegisterAdminRoutes(adminRouter)
+	app.Wallet.BatchHandler.RegisterRoutes(adminRouter)
+	app.Wallet.PromoHandler.RegisterRoutes(adminRouter)
+	app.Reconcile.ReconciliationHandler.RegisterRoutes(adminRouter)
 	app.Logger.Info().Msg("Routes registered successfully")
+	platform.WatchConfig(app.Config, app.Logger, func(next *config.Config) {
+		app.Wallet.CreateBatchHandler.SetMaxRows(next.Batch.MaxRows)
+	})
 
 	// Start server
 	app.Logger.Info().Str("port", app.Config.Server.Port).Msg("Starting HTTP server")
@@ -49,4 +77,5 @@ func main() {
 	}
 
 	app.Logger.Info().Msg("Server exited")
+	return 0
 }
@@ -0,0 +1,336 @@
+//go:build wireinject
+// +build wireinject
+
+package main
+
+import (
+	"github.com/MaisamV/wallet/internal/audit"
+	auditCommand "github.com/MaisamV/wallet/internal/audit/application/command"
+	"github.com/MaisamV/wallet/internal/integrity"
+	integrityCommand "github.com/MaisamV/wallet/internal/integrity/application/command"
+	"github.com/MaisamV/wallet/internal/probes"
+	probesHttp "github.com/MaisamV/wallet/internal/probes/presentation/http"
+	"github.com/MaisamV/wallet/internal/reconciliation"
+	reconcileCommand "github.com/MaisamV/wallet/internal/reconciliation/application/command"
+	reconciliationHttp "github.com/MaisamV/wallet/internal/reconciliation/presentation/http"
+	"github.com/MaisamV/wallet/internal/swagger"
+	swaggerHttp "github.com/MaisamV/wallet/internal/swagger/presentation/http"
+	wallet "github.com/MaisamV/wallet/internal/wallet"
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	infrastructure "github.com/MaisamV/wallet/internal/wallet/infrastructure/repo"
+	walletHttp "github.com/MaisamV/wallet/internal/wallet/presentation/http"
+	"github.com/MaisamV/wallet/platform"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/MaisamV/wallet/platform/http"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/google/wire"
+)
+
+// Application holds all the application dependencies
+type Application struct {
+	Config     *config.Config
+	Logger     logger.Logger
+	HTTPServer *http.Server
+	Probes     *ProbesModule
+	Swagger    *SwaggerModule
+	Wallet     *WalletModule
+	Reconcile  *ReconciliationModule
+}
+
+// ProbesModule holds all probes-related dependencies
+type ProbesModule struct {
+	PingHandler   *probesHttp.PingHandler
+	HealthHandler *probesHttp.HealthHandler
+}
+
+// SwaggerModule holds all swagger-related dependencies
+type SwaggerModule struct {
+	DocsHandler *swaggerHttp.DocsHandler
+}
+
+type WalletModule struct {
+	WalletHandler      *walletHttp.WalletHandler
+	WalletAdminHandler *walletHttp.WalletAdminHandler
+	AdjustmentHandler  *walletHttp.AdjustmentHandler
+	LimitsHandler      *walletHttp.LimitsHandler
+	RiskReviewHandler  *walletHttp.RiskReviewHandler
+	DestinationHandler *walletHttp.DestinationHandler
+	HoldHandler        *walletHttp.HoldHandler
+	ScheduleHandler    *walletHttp.ScheduleHandler
+	BatchHandler       *walletHttp.BatchHandler
+	PromoHandler       *walletHttp.PromoHandler
+	CreateBatchHandler *command.CreateBatchCommandHandler
+	Repo               *infrastructure.PgxWalletRepo
+}
+
+// ReconciliationModule holds all reconciliation-related dependencies
+type ReconciliationModule struct {
+	ReconciliationHandler *reconciliationHttp.ReconciliationHandler
+}
+
+// ReleaseApplication holds the dependencies of the release worker
+type ReleaseApplication struct {
+	Config         *config.Config
+	Logger         logger.Logger
+	ReleaseHandler *command.ReleaseCommandHandler
+	Repo           *infrastructure.PgxWalletRepo
+}
+
+// WithdrawApplication holds the dependencies of the withdraw worker
+type WithdrawApplication struct {
+	Config          *config.Config
+	Logger          logger.Logger
+	WithdrawHandler *command.WithdrawCommandHandler
+	Repo            *infrastructure.PgxWalletRepo
+}
+
+// AdminApplication holds the dependencies of the operator tasks
+type AdminApplication struct {
+	Config            *config.Config
+	Logger            logger.Logger
+	BalanceHandler    *query.GetBalanceQueryHandler
+	AdjustmentHandler *command.RequestAdjustmentCommandHandler
+	ReconcileHandler  *reconcileCommand.ReconcileCommandHandler
+	QuarantineHandler *integrityCommand.ReleaseQuarantineCommandHandler
+	AuditHandler      *auditCommand.VerifyChainCommandHandler
+	Repo              *infrastructure.PgxWalletRepo
+}
+
+// JobApplication holds the dependencies of the periodic jobs
+type JobApplication struct {
+	Config                *config.Config
+	Logger                logger.Logger
+	SnapshotHandler       *command.SnapshotCommandHandler
+	ExpireHoldsHandler    *command.ExpireHoldsCommandHandler
+	ExpirePromosHandler   *command.ExpirePromoGrantsCommandHandler
+	RunSchedulesHandler   *command.RunSchedulesCommandHandler
+	ProcessBatchHandler   *command.ProcessBatchCommandHandler
+	CheckIntegrityHandler *integrityCommand.CheckIntegrityCommandHandler
+	Repo                  *infrastructure.PgxWalletRepo
+}
+
+// InitializeApplication creates and initializes the application with all dependencies
+func InitializeApplication() (*Application, error) {
+	wire.Build(
+		// Platform providers
+		platform.PlatformSet,
+
+		// Internal module providers
+		probes.ProbesSet,
+		swagger.SwaggerSet,
+		wallet.WalletSet,
+		reconciliation.ReconciliationSet,
+
+		// Application structure providers
+		ProvideProbesModule,
+		ProvideSwaggerModule,
+		ProvideWalletModule,
+		ProvideReconciliationModule,
+		ProvideApplication,
+	)
+	return &Application{}, nil
+}
+
+// InitializeReleaseApplication creates the release worker with its dependencies
+func InitializeReleaseApplication() (*ReleaseApplication, error) {
+	wire.Build(
+		platform.PlatformSet,
+		wallet.WalletSet,
+		ProvideReleaseApplication,
+	)
+	return &ReleaseApplication{}, nil
+}
+
+// InitializeWithdrawApplication creates the withdraw worker with its dependencies
+func InitializeWithdrawApplication() (*WithdrawApplication, error) {
+	wire.Build(
+		platform.PlatformSet,
+		wallet.WalletSet,
+		ProvideWithdrawApplication,
+	)
+	return &WithdrawApplication{}, nil
+}
+
+// InitializeAdminApplication creates the operator tasks with their dependencies
+func InitializeAdminApplication() (*AdminApplication, error) {
+	wire.Build(
+		platform.PlatformSet,
+		wallet.WalletSet,
+		reconciliation.ReconciliationSet,
+		integrity.IntegritySet,
+		audit.AuditSet,
+		ProvideAdminApplication,
+	)
+	return &AdminApplication{}, nil
+}
+
+// InitializeJobApplication creates the periodic jobs with their dependencies
+func InitializeJobApplication() (*JobApplication, error) {
+	wire.Build(
+		platform.PlatformSet,
+		wallet.WalletSet,
+		integrity.IntegritySet,
+		ProvideJobApplication,
+	)
+	return &JobApplication{}, nil
+}
+
+// ProvideProbesModule provides the probes module
+func ProvideProbesModule(
+	pingHandler *probesHttp.PingHandler,
+	healthHandler *probesHttp.HealthHandler,
+) *ProbesModule {
+	return &ProbesModule{
+		PingHandler:   pingHandler,
+		HealthHandler: healthHandler,
+	}
+}
+
+// ProvideSwaggerModule provides the swagger module
+func ProvideSwaggerModule(
+	docsHandler *swaggerHttp.DocsHandler,
+) *SwaggerModule {
+	return &SwaggerModule{
+		DocsHandler: docsHandler,
+	}
+}
+
+// ProvideWalletModule provides the wallet module
+func ProvideWalletModule(
+	handler *walletHttp.WalletHandler,
+	adminHandler *walletHttp.WalletAdminHandler,
+	adjustmentHandler *walletHttp.AdjustmentHandler,
+	limitsHandler *walletHttp.LimitsHandler,
+	riskReviewHandler *walletHttp.RiskReviewHandler,
+	destinationHandler *walletHttp.DestinationHandler,
+	holdHandler *walletHttp.HoldHandler,
+	scheduleHandler *walletHttp.ScheduleHandler,
+	batchHandler *walletHttp.BatchHandler,
+	promoHandler *walletHttp.PromoHandler,
+	createBatchHandler *command.CreateBatchCommandHandler,
+	repo *infrastructure.PgxWalletRepo,
+) *WalletModule {
+	return &WalletModule{
+		WalletHandler:      handler,
+		WalletAdminHandler: adminHandler,
+		AdjustmentHandler:  adjustmentHandler,
+		LimitsHandler:      limitsHandler,
+		RiskReviewHandler:  riskReviewHandler,
+		DestinationHandler: destinationHandler,
+		HoldHandler:        holdHandler,
+		ScheduleHandler:    scheduleHandler,
+		BatchHandler:       batchHandler,
+		PromoHandler:       promoHandler,
+		CreateBatchHandler: createBatchHandler,
+		Repo:               repo,
+	}
+}
+
+// ProvideReconciliationModule provides the reconciliation module
+func ProvideReconciliationModule(
+	handler *reconciliationHttp.ReconciliationHandler,
+) *ReconciliationModule {
+	return &ReconciliationModule{
+		ReconciliationHandler: handler,
+	}
+}
+
+// ProvideApplication provides the main application structure
+func ProvideApplication(
+	config *config.Config,
+	logger logger.Logger,
+	httpServer *http.Server,
+	probesModule *ProbesModule,
+	swaggerModule *SwaggerModule,
+	walletModule *WalletModule,
+	reconciliationModule *ReconciliationModule,
+) *Application {
+	return &Application{
+		Config:     config,
+		Logger:     logger,
+		HTTPServer: httpServer,
+		Probes:     probesModule,
+		Swagger:    swaggerModule,
+		Wallet:     walletModule,
+		Reconcile:  reconciliationModule,
+	}
+}
+
+// ProvideReleaseApplication provides the release worker structure
+func ProvideReleaseApplication(
+	config *config.Config,
+	logger logger.Logger,
+	handler *command.ReleaseCommandHandler,
+	repo *infrastructure.PgxWalletRepo,
+) *ReleaseApplication {
+	return &ReleaseApplication{
+		Config:         config,
+		Logger:         logger,
+		ReleaseHandler: handler,
+		Repo:           repo,
+	}
+}
+
+// ProvideWithdrawApplication provides the withdraw worker structure
+func ProvideWithdrawApplication(
+	config *config.Config,
+	logger logger.Logger,
+	handler *command.WithdrawCommandHandler,
+	repo *infrastructure.PgxWalletRepo,
+) *WithdrawApplication {
+	return &WithdrawApplication{
+		Config:          config,
+		Logger:          logger,
+		WithdrawHandler: handler,
+		Repo:            repo,
+	}
+}
+
+// ProvideAdminApplication provides the operator tasks structure
+func ProvideAdminApplication(
+	config *config.Config,
+	logger logger.Logger,
+	balanceHandler *query.GetBalanceQueryHandler,
+	adjustmentHandler *command.RequestAdjustmentCommandHandler,
+	reconcileHandler *reconcileCommand.ReconcileCommandHandler,
+	quarantineHandler *integrityCommand.ReleaseQuarantineCommandHandler,
+	auditHandler *auditCommand.VerifyChainCommandHandler,
+	repo *infrastructure.PgxWalletRepo,
+) *AdminApplication {
+	return &AdminApplication{
+		Config:            config,
+		Logger:            logger,
+		BalanceHandler:    balanceHandler,
+		AdjustmentHandler: adjustmentHandler,
+		ReconcileHandler:  reconcileHandler,
+		QuarantineHandler: quarantineHandler,
+		AuditHandler:      auditHandler,
+		Repo:              repo,
+	}
+}
+
+// ProvideJobApplication provides the periodic jobs structure
+func ProvideJobApplication(
+	config *config.Config,
+	logger logger.Logger,
+	snapshotHandler *command.SnapshotCommandHandler,
+	expireHoldsHandler *command.ExpireHoldsCommandHandler,
+	expirePromosHandler *command.ExpirePromoGrantsCommandHandler,
+	runSchedulesHandler *command.RunSchedulesCommandHandler,
+	processBatchHandler *command.ProcessBatchCommandHandler,
+	checkIntegrityHandler *integrityCommand.CheckIntegrityCommandHandler,
+	repo *infrastructure.PgxWalletRepo,
+) *JobApplication {
+	return &JobApplication{
+		Config:                config,
+		Logger:                logger,
+		SnapshotHandler:       snapshotHandler,
+		ExpireHoldsHandler:    expireHoldsHandler,
+		ExpirePromosHandler:   expirePromosHandler,
+		RunSchedulesHandler:   runSchedulesHandler,
+		ProcessBatchHandler:   processBatchHandler,
+		CheckIntegrityHandler: checkIntegrityHandler,
+		Repo:                  repo,
+	}
+}
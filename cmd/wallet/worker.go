@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/platform"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/MaisamV/wallet/platform/schedule"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// workerFlags binds the flags every worker shares to the config section of the worker
+func workerFlags(name string, section string) (*flag.FlagSet, *configFlags) {
+	flags, overrides := jobFlags(name, section)
+	overrides.String("workers", section+".worker_count", "number of workers")
+	return flags, overrides
+}
+
+// jobFlags binds the flags of a worker running a single loop to the config section of the worker
+func jobFlags(name string, section string) (*flag.FlagSet, *configFlags) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	overrides := newConfigFlags(flags)
+	overrides.String("batch-size", section+".batch_size", "records read at once")
+	overrides.String("interval", section+".interval", "time between two batches")
+	return flags, overrides
+}
+
+// runReleaseWorker releases the due transactions until it is interrupted
+func runReleaseWorker(args []string) int {
+	flags, overrides := workerFlags("release-worker", "release_worker")
+	if !parseFlags(flags, overrides, args) {
+		return 2
+	}
+
+	app, err := InitializeReleaseApplication()
+	if err != nil {
+		log.Printf("Failed to initialize application: %v", err)
+		return 1
+	}
+	defer app.Repo.Close()
+	app.Logger.Info().Msg("Starting release worker")
+	releaseConfig := app.Config.Release
+	releaseSchedule := schedule.New(releaseConfig.Interval, releaseConfig.BatchSize)
+
+	worker := NewReleaseWorker(
+		app.ReleaseHandler,
+		app.Logger,
+		releaseSchedule,
+		releaseConfig.WorkerCount,
+	)
+	worker.Start()
+	platform.WatchConfig(app.Config, app.Logger, func(next *config.Config) {
+		releaseSchedule.Set(next.Release.Interval, next.Release.BatchSize)
+	})
+
+	waitForSignal()
+	worker.Stop()
+	app.Logger.Info().Msg("Finished gracefully")
+	return 0
+}
+
+// runWithdrawWorker pays the released withdrawals out until it is interrupted
+func runWithdrawWorker(args []string) int {
+	flags, overrides := workerFlags("withdraw-worker", "withdraw_worker")
+	if !parseFlags(flags, overrides, args) {
+		return 2
+	}
+
+	app, err := InitializeWithdrawApplication()
+	if err != nil {
+		log.Printf("Failed to initialize application: %v", err)
+		return 1
+	}
+	defer app.Repo.Close()
+	app.Logger.Info().Msg("Starting withdraw worker")
+	withdrawConfig := app.Config.Withdraw
+	withdrawSchedule := schedule.New(withdrawConfig.Interval, withdrawConfig.BatchSize)
+
+	worker := NewWithdrawWorker(
+		app.WithdrawHandler,
+		app.Logger,
+		withdrawSchedule,
+	)
+	worker.Start()
+	platform.WatchConfig(app.Config, app.Logger, func(next *config.Config) {
+		withdrawSchedule.Set(next.Withdraw.Interval, next.Withdraw.BatchSize)
+	})
+
+	waitForSignal()
+	worker.Stop()
+	app.Logger.Info().Msg("Finished gracefully")
+	return 0
+}
+
+func waitForSignal() {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+}
+
+type ReleaseWorker struct {
+	handler     *command.ReleaseCommandHandler
+	logger      logger.Logger
+	schedule    *schedule.Schedule
+	workerCount int
+	stop        chan any
+}
+
+func NewReleaseWorker(
+	handler *command.ReleaseCommandHandler,
+	logger logger.Logger,
+	schedule *schedule.Schedule,
+	workerCount int,
+) *ReleaseWorker {
+	return &ReleaseWorker{
+		handler:     handler,
+		logger:      logger,
+		schedule:    schedule,
+		workerCount: workerCount,
+		stop:        make(chan any),
+	}
+}
+
+func (w *ReleaseWorker) Start() {
+	for i := 0; i < w.workerCount; i++ {
+		go w.workerLoop(i)
+	}
+}
+
+func (w *ReleaseWorker) Stop() {
+	close(w.stop)
+}
+
+func (w *ReleaseWorker) workerLoop(id int) {
+	interval, changed := w.schedule.Interval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.logger.Info().Int("worker", id).Msg("started")
+
+	for {
+		select {
+		case <-ticker.C:
+			w.release(id)
+
+		case <-changed:
+			interval, changed = w.schedule.Interval()
+			ticker.Reset(interval)
+
+		case <-w.stop:
+			w.logger.Info().Int("worker", id).Msg("stopped")
+			return
+		}
+	}
+}
+
+func (w *ReleaseWorker) release(id int) {
+	ctx, cancel := context.WithTimeout(audit.NewContext(context.Background(), audit.Info{Actor: "release_worker"}), 3*time.Second)
+	defer cancel()
+
+	cmd := command.ReleaseCommand{BatchSize: w.schedule.BatchSize()}
+	transactions, err := w.handler.Handle(ctx, cmd)
+	if err != nil {
+		w.logger.Error().Err(err).Int("worker", id).Msg("release failed")
+		return
+	}
+
+	for _, txn := range transactions {
+		w.logger.Info().Str("ID", txn.ID.String()).Msg("transaction released")
+	}
+}
+
+// WithdrawWorker claims released withdrawals on every tick and hands them to the payout workers of the
+// handler, withdraw_worker.worker_count of them are started once
+type WithdrawWorker struct {
+	handler  *command.WithdrawCommandHandler
+	logger   logger.Logger
+	schedule *schedule.Schedule
+	stop     chan any
+}
+
+func NewWithdrawWorker(handler *command.WithdrawCommandHandler, logger logger.Logger, schedule *schedule.Schedule) *WithdrawWorker {
+	return &WithdrawWorker{
+		handler:  handler,
+		logger:   logger,
+		schedule: schedule,
+		stop:     make(chan any),
+	}
+}
+
+func (w *WithdrawWorker) Start() {
+	w.handler.Start()
+	go w.workerLoop()
+}
+
+func (w *WithdrawWorker) Stop() {
+	close(w.stop)
+}
+
+func (w *WithdrawWorker) workerLoop() {
+	interval, changed := w.schedule.Interval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.logger.Info().Msg("started")
+
+	for {
+		select {
+		case <-ticker.C:
+			w.withdraw()
+
+		case <-changed:
+			interval, changed = w.schedule.Interval()
+			ticker.Reset(interval)
+
+		case <-w.stop:
+			w.logger.Info().Msg("stopped")
+			return
+		}
+	}
+}
+
+func (w *WithdrawWorker) withdraw() {
+	ctx, cancel := context.WithTimeout(audit.NewContext(context.Background(), audit.Info{Actor: "withdraw_worker"}), 10*time.Second)
+	defer cancel()
+
+	cmd := command.WithdrawCommand{Limit: w.schedule.BatchSize()}
+	err := w.handler.Handle(ctx, cmd)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("withdraw failed")
+		return
+	}
+}
@@ -0,0 +1,79 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/audit/entity"
+	"github.com/MaisamV/wallet/internal/audit/ports"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type VerifyChainCommand struct {
+	BatchSize int
+}
+
+func (vc *VerifyChainCommand) Err() error {
+	if vc.BatchSize <= 0 {
+		return errors.New("batch size must be positive")
+	}
+	return nil
+}
+
+type VerifyChainCommandHandler struct {
+	logger logger.Logger
+	repo   ports.AuditRepo
+}
+
+func NewVerifyChainCommandHandler(logger logger.Logger, repo ports.AuditRepo) *VerifyChainCommandHandler {
+	return &VerifyChainCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// Handle walks the whole audit chain from the first entry up to its head and reports every entry that was
+// tampered with. Entries written while the chain is walked are left to the next run.
+func (h *VerifyChainCommandHandler) Handle(ctx context.Context, command VerifyChainCommand) (*entity.VerifyResult, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+
+	head, err := h.repo.GetHead(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit chain head: %w", err)
+	}
+	result := &entity.VerifyResult{Breaks: make([]entity.ChainBreak, 0)}
+	prev := entity.GenesisLink()
+	for {
+		entries, err := h.repo.GetEntries(ctx, result.LastID, head.ID, command.BatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit entries: %w", err)
+		}
+		var breaks []entity.ChainBreak
+		breaks, prev = entity.Verify(prev, entries, head.GaplessAfter)
+		h.report(breaks)
+		result.Breaks = append(result.Breaks, breaks...)
+		result.Checked += int64(len(entries))
+		if len(entries) > 0 {
+			result.LastID = entries[len(entries)-1].ID
+		}
+		if len(entries) < command.BatchSize {
+			break
+		}
+	}
+	breaks := entity.VerifyHead(prev, *head)
+	h.report(breaks)
+	result.Breaks = append(result.Breaks, breaks...)
+	result.LastHash = entity.HexHash(prev.Hash)
+
+	h.logger.Info().Int64("checked", result.Checked).Int("breaks", len(result.Breaks)).
+		Str("last_hash", result.LastHash).Msg("audit chain verification finished")
+	return result, nil
+}
+
+func (h *VerifyChainCommandHandler) report(breaks []entity.ChainBreak) {
+	for _, b := range breaks {
+		h.logger.Warn().Int64("entry_id", b.EntryID).Str("type", b.Type).Msg("audit chain broken")
+	}
+}
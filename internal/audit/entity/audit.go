@@ -0,0 +1,102 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/hex"
+)
+
+// GenesisHash is the previous hash of the first entry in the chain
+var GenesisHash = make([]byte, 32)
+
+// Entry is the part of an audit log row needed to verify the chain.
+// ExpectedHash is recomputed by the database from the stored fields.
+type Entry struct {
+	ID           int64
+	PrevHash     []byte
+	Hash         []byte
+	ExpectedHash []byte
+}
+
+// Link is the entry a run of entries is chained to, the genesis link has id 0 and the genesis hash
+type Link struct {
+	ID   int64
+	Hash []byte
+}
+
+// GenesisLink is followed by the first entry of the chain
+func GenesisLink() Link {
+	return Link{Hash: GenesisHash}
+}
+
+// Head is the last entry of the chain as recorded by the writers. Ids are handed out one after the other from
+// the head after GaplessAfter, ids up to it were taken from a sequence and may skip a rolled back write.
+type Head struct {
+	Link
+	GaplessAfter int64
+}
+
+type BreakType = string
+
+const (
+	// CONTENT_CHANGED means the stored fields no longer produce the stored hash
+	CONTENT_CHANGED BreakType = "content_changed"
+	// LINK_BROKEN means an entry does not point to the hash of the entry before it,
+	// which happens when entries are removed, reordered or inserted
+	LINK_BROKEN = "link_broken"
+	// ID_GAP means the entries right before this one are missing
+	ID_GAP = "id_gap"
+	// TAIL_MISSING means the chain ends before the head, the entries from the last one found up to the head are missing
+	TAIL_MISSING = "tail_missing"
+)
+
+type ChainBreak struct {
+	EntryID int64     `json:"entry_id"`
+	Type    BreakType `json:"type"`
+}
+
+// VerifyResult is the outcome of walking the audit chain
+type VerifyResult struct {
+	Checked  int64        `json:"checked"`
+	LastID   int64        `json:"last_id"`
+	LastHash string       `json:"last_hash"`
+	Breaks   []ChainBreak `json:"breaks"`
+}
+
+// Intact reports whether no tampering was found
+func (r *VerifyResult) Intact() bool {
+	return len(r.Breaks) == 0
+}
+
+// Verify checks a consecutive run of entries ordered by id which follows prev, an entry whose id does not
+// follow the one before it is reported as a gap once ids are gapless. It returns the breaks found and the
+// link the next run has to follow.
+func Verify(prev Link, entries []Entry, gaplessAfter int64) ([]ChainBreak, Link) {
+	var breaks []ChainBreak
+	for _, e := range entries {
+		if e.ID > gaplessAfter && e.ID != prev.ID+1 {
+			breaks = append(breaks, ChainBreak{EntryID: e.ID, Type: ID_GAP})
+		}
+		if !bytes.Equal(e.Hash, e.ExpectedHash) {
+			breaks = append(breaks, ChainBreak{EntryID: e.ID, Type: CONTENT_CHANGED})
+		}
+		if !bytes.Equal(e.PrevHash, prev.Hash) {
+			breaks = append(breaks, ChainBreak{EntryID: e.ID, Type: LINK_BROKEN})
+		}
+		prev = Link{ID: e.ID, Hash: e.Hash}
+	}
+	return breaks, prev
+}
+
+// VerifyHead checks that the chain walked up to last ends at the head, removing the newest entries leaves
+// no broken link behind and is only found this way
+func VerifyHead(last Link, head Head) []ChainBreak {
+	if last.ID != head.ID || !bytes.Equal(last.Hash, head.Hash) {
+		return []ChainBreak{{EntryID: head.ID, Type: TAIL_MISSING}}
+	}
+	return nil
+}
+
+// HexHash formats a hash the way it is reported to operators
+func HexHash(hash []byte) string {
+	return hex.EncodeToString(hash)
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"testing"
+)
+
+// chain returns n intact entries starting at id 1
+func chain(n int) []Entry {
+	entries := make([]Entry, 0, n)
+	prev := GenesisHash
+	for i := 1; i <= n; i++ {
+		hash := []byte{byte(i)}
+		entries = append(entries, Entry{ID: int64(i), PrevHash: prev, Hash: hash, ExpectedHash: hash})
+		prev = hash
+	}
+	return entries
+}
+
+func expectBreaks(t *testing.T, breaks []ChainBreak, expected ...ChainBreak) {
+	t.Helper()
+	if len(breaks) != len(expected) {
+		t.Fatalf("expected breaks %v, got %v", expected, breaks)
+	}
+	for i := range expected {
+		if breaks[i] != expected[i] {
+			t.Fatalf("expected breaks %v, got %v", expected, breaks)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	intact := chain(3)
+	breaks, last := Verify(GenesisLink(), intact, 0)
+	expectBreaks(t, breaks)
+	if last.ID != 3 || string(last.Hash) != string(intact[2].Hash) {
+		t.Fatalf("expected the last link to be entry 3, got %+v", last)
+	}
+	expectBreaks(t, VerifyHead(last, Head{Link: last}))
+
+	edited := []Entry{intact[0], {ID: 2, PrevHash: intact[0].Hash, Hash: intact[1].Hash, ExpectedHash: []byte{9}}, intact[2]}
+	breaks, _ = Verify(GenesisLink(), edited, 0)
+	expectBreaks(t, breaks, ChainBreak{EntryID: 2, Type: CONTENT_CHANGED})
+
+	// a run which does not follow the previous one breaks the chain
+	breaks, _ = Verify(Link{ID: 1, Hash: intact[0].Hash}, intact[2:], 0)
+	expectBreaks(t, breaks, ChainBreak{EntryID: 3, Type: ID_GAP}, ChainBreak{EntryID: 3, Type: LINK_BROKEN})
+}
+
+func TestVerify_removedEntries(t *testing.T) {
+	entries := chain(6)
+	head := Head{Link: Link{ID: 6, Hash: entries[5].Hash}}
+
+	// the entries of one record removed from the middle of the chain
+	breaks, last := Verify(GenesisLink(), []Entry{entries[0], entries[3], entries[4], entries[5]}, 0)
+	expectBreaks(t, breaks, ChainBreak{EntryID: 4, Type: ID_GAP}, ChainBreak{EntryID: 4, Type: LINK_BROKEN})
+	expectBreaks(t, VerifyHead(last, head))
+
+	// the newest entries leave no broken link, only the head is left behind
+	breaks, last = Verify(GenesisLink(), entries[:4], 0)
+	expectBreaks(t, breaks)
+	expectBreaks(t, VerifyHead(last, head), ChainBreak{EntryID: 6, Type: TAIL_MISSING})
+
+	// every entry removed
+	breaks, last = Verify(GenesisLink(), nil, 0)
+	expectBreaks(t, breaks)
+	expectBreaks(t, VerifyHead(last, head), ChainBreak{EntryID: 6, Type: TAIL_MISSING})
+	expectBreaks(t, VerifyHead(last, Head{Link: GenesisLink()}))
+}
+
+func TestVerify_gapsBeforeGaplessIDs(t *testing.T) {
+	// id 3 was skipped by a rolled back write while ids were taken from a sequence
+	h1, h2, h4, h5 := []byte{1}, []byte{2}, []byte{4}, []byte{5}
+	entries := []Entry{
+		{ID: 1, PrevHash: GenesisHash, Hash: h1, ExpectedHash: h1},
+		{ID: 2, PrevHash: h1, Hash: h2, ExpectedHash: h2},
+		{ID: 4, PrevHash: h2, Hash: h4, ExpectedHash: h4},
+		{ID: 5, PrevHash: h4, Hash: h5, ExpectedHash: h5},
+	}
+
+	breaks, _ := Verify(GenesisLink(), entries, 4)
+	expectBreaks(t, breaks)
+	breaks, _ = Verify(GenesisLink(), entries, 3)
+	expectBreaks(t, breaks, ChainBreak{EntryID: 4, Type: ID_GAP})
+}
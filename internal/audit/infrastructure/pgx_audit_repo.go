@@ -0,0 +1,75 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/audit/entity"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type PgxAuditRepo struct {
+	logger logger.Logger
+	db     *pgxpool.Pool
+}
+
+func NewPgxAuditRepo(logger logger.Logger, db *pgxpool.Pool) *PgxAuditRepo {
+	return &PgxAuditRepo{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// GetHead reads the head of the chain, the last entry every writer chains its entry to
+func (r *PgxAuditRepo) GetHead(ctx context.Context) (*entity.Head, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	head := entity.Head{}
+	if err := r.db.QueryRow(opCtx, getHead).Scan(&head.ID, &head.Hash, &head.GaplessAfter); err != nil {
+		return nil, fmt.Errorf("get audit chain head failed: %w", err)
+	}
+
+	return &head, nil
+}
+
+// GetEntries reads a batch of the audit chain. The expected hash is computed by the same
+// database function the audit trigger uses, so both sides always agree on the serialization.
+func (r *PgxAuditRepo) GetEntries(ctx context.Context, afterID int64, untilID int64, limit int) ([]entity.Entry, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(opCtx, getEntries, afterID, untilID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get audit entries failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.Entry, 0, limit)
+	for rows.Next() {
+		e := entity.Entry{}
+		if err := rows.Scan(&e.ID, &e.PrevHash, &e.Hash, &e.ExpectedHash); err != nil {
+			return nil, fmt.Errorf("error in reading audit entry row: %w", err)
+		}
+		list = append(list, e)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading audit entries: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+const (
+	getHead = `
+SELECT last_id, last_hash, gapless_after
+FROM audit_chain_head
+`
+	getEntries = `
+SELECT a.id, a.prev_hash, a.hash, audit_log_hash(a)
+FROM audit_log a
+WHERE a.id > $1 AND a.id <= $2
+ORDER BY a.id
+LIMIT $3
+`
+)
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/audit/entity"
+)
+
+type AuditRepo interface {
+	// GetHead returns the last entry of the chain as recorded by the writers
+	GetHead(ctx context.Context) (*entity.Head, error)
+	// GetEntries returns at most limit entries after afterID up to untilID, ordered by id
+	GetEntries(ctx context.Context, afterID int64, untilID int64, limit int) ([]entity.Entry, error)
+}
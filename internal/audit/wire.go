@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"github.com/MaisamV/wallet/internal/audit/application/command"
+	"github.com/MaisamV/wallet/internal/audit/infrastructure"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/google/wire"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ProvideAuditRepository provides the audit log repository
+func ProvideAuditRepository(logger logger.Logger, db *pgxpool.Pool) *infrastructure.PgxAuditRepo {
+	return infrastructure.NewPgxAuditRepo(logger, db)
+}
+
+// ProvideVerifyChainCommandHandler provides an audit chain verification command handler
+func ProvideVerifyChainCommandHandler(logger logger.Logger, repo *infrastructure.PgxAuditRepo) *command.VerifyChainCommandHandler {
+	return command.NewVerifyChainCommandHandler(logger, repo)
+}
+
+// AuditSet is a wire provider set for all audit dependencies
+var AuditSet = wire.NewSet(
+	ProvideAuditRepository,
+	ProvideVerifyChainCommandHandler,
+)
@@ -0,0 +1,93 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/integrity/entity"
+	"github.com/MaisamV/wallet/internal/integrity/ports"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type CheckIntegrityCommand struct {
+	BatchSize int
+	// Quarantine blocks debits of the wallets whose balances do not match their transactions
+	Quarantine bool
+}
+
+func (cc *CheckIntegrityCommand) Err() error {
+	if cc.BatchSize <= 0 {
+		return errors.New("batch size must be positive")
+	}
+	return nil
+}
+
+type CheckIntegrityCommandHandler struct {
+	logger logger.Logger
+	repo   ports.IntegrityRepo
+	clock  clock.Clock
+}
+
+func NewCheckIntegrityCommandHandler(logger logger.Logger, repo ports.IntegrityRepo, clock clock.Clock) *CheckIntegrityCommandHandler {
+	return &CheckIntegrityCommandHandler{
+		logger: logger,
+		repo:   repo,
+		clock:  clock,
+	}
+}
+
+// Handle walks all wallets in batches, compares their balances with their transactions and stores the result
+func (h *CheckIntegrityCommandHandler) Handle(ctx context.Context, command CheckIntegrityCommand) (*entity.CheckRun, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+
+	run := &entity.CheckRun{StartedAt: h.clock.Now()}
+	var lastWalletID int64
+	for {
+		balances, err := h.repo.RecomputeBalances(ctx, lastWalletID, command.BatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recompute balances: %w", err)
+		}
+		for _, b := range balances {
+			if b.Consistent() {
+				continue
+			}
+			h.logger.Warn().Int64("wallet_id", b.WalletID).Int64("user_id", b.UserID).
+				Int64("total_balance", b.TotalBalance).Int64("expected_total_balance", b.ExpectedTotalBalance).
+				Int64("available_balance", b.AvailableBalance).Int64("expected_available_balance", b.ExpectedAvailableBalance).
+				Msg("wallet balance discrepancy")
+			run.Discrepancies = append(run.Discrepancies, b)
+		}
+		run.CheckedWallets += len(balances)
+		if len(balances) < command.BatchSize {
+			break
+		}
+		lastWalletID = balances[len(balances)-1].WalletID
+	}
+
+	if command.Quarantine {
+		for _, d := range run.Discrepancies {
+			quarantined, err := h.repo.Quarantine(ctx, d.WalletID, "balance does not match transactions")
+			if err != nil {
+				h.logger.Error().Err(err).Int64("wallet_id", d.WalletID).Msg("couldn't quarantine wallet")
+				continue
+			}
+			if quarantined {
+				run.Quarantined++
+			}
+		}
+	}
+
+	run.FinishedAt = h.clock.Now()
+	id, err := h.repo.SaveRun(ctx, run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save integrity check result: %w", err)
+	}
+	run.ID = id
+
+	h.logger.Info().Int("checked_wallets", run.CheckedWallets).Int("discrepancies", len(run.Discrepancies)).
+		Int("quarantined", run.Quarantined).Msg("integrity check finished")
+	return run, nil
+}
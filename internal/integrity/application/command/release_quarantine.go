@@ -0,0 +1,67 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/integrity/entity"
+	"github.com/MaisamV/wallet/internal/integrity/ports"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type ReleaseQuarantineCommand struct {
+	UserID int64
+	// Force releases the wallet even when its balances still do not match its transactions
+	Force bool
+	Actor string
+}
+
+func (cc *ReleaseQuarantineCommand) Err() error {
+	if cc.UserID <= 0 {
+		return errors.New("user id must be positive")
+	}
+	if cc.Actor == "" {
+		return errors.New("actor cannot be empty")
+	}
+	return nil
+}
+
+type ReleaseQuarantineCommandHandler struct {
+	logger logger.Logger
+	repo   ports.IntegrityRepo
+}
+
+func NewReleaseQuarantineCommandHandler(logger logger.Logger, repo ports.IntegrityRepo) *ReleaseQuarantineCommandHandler {
+	return &ReleaseQuarantineCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// Handle lets a quarantined wallet be debited again once an operator corrected it. The balances are recomputed
+// first and a wallet which still does not match its transactions stays quarantined unless the release is forced.
+func (h *ReleaseQuarantineCommandHandler) Handle(ctx context.Context, command ReleaseQuarantineCommand) (*entity.WalletBalances, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+
+	balances, err := h.repo.RecomputeBalance(ctx, command.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !balances.Consistent() && !command.Force {
+		return balances, entity.ErrBalanceMismatch
+	}
+
+	released, err := h.repo.ReleaseQuarantine(ctx, balances.WalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release quarantine: %w", err)
+	}
+	if !released {
+		return balances, entity.ErrNotQuarantined
+	}
+
+	h.logger.Info().Int64("wallet_id", balances.WalletID).Int64("user_id", balances.UserID).
+		Bool("consistent", balances.Consistent()).Str("actor", command.Actor).Msg("wallet quarantine released")
+	return balances, nil
+}
@@ -0,0 +1,9 @@
+package entity
+
+import "errors"
+
+var (
+	ErrWalletNotFound  = errors.New("wallet not found")
+	ErrNotQuarantined  = errors.New("wallet is not quarantined")
+	ErrBalanceMismatch = errors.New("wallet balance does not match its transactions")
+)
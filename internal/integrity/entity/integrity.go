@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+// WalletBalances holds the stored balances of a wallet next to the balances recomputed from its transactions
+type WalletBalances struct {
+	WalletID                 int64 `json:"wallet_id"`
+	UserID                   int64 `json:"user_id"`
+	TotalBalance             int64 `json:"total_balance"`
+	ExpectedTotalBalance     int64 `json:"expected_total_balance"`
+	AvailableBalance         int64 `json:"available_balance"`
+	ExpectedAvailableBalance int64 `json:"expected_available_balance"`
+}
+
+// Consistent reports whether the stored balances match the recomputed ones
+func (wb *WalletBalances) Consistent() bool {
+	return wb.TotalBalance == wb.ExpectedTotalBalance && wb.AvailableBalance == wb.ExpectedAvailableBalance
+}
+
+type CheckRun struct {
+	ID             int64            `json:"id"`
+	CheckedWallets int              `json:"checked_wallets"`
+	Quarantined    int              `json:"quarantined"`
+	StartedAt      time.Time        `json:"started_at"`
+	FinishedAt     time.Time        `json:"finished_at"`
+	Discrepancies  []WalletBalances `json:"discrepancies,omitempty"`
+}
@@ -0,0 +1,201 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/integrity/entity"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type PgxIntegrityRepo struct {
+	logger logger.Logger
+	db     *pgxpool.Pool
+	clock  clock.Clock
+}
+
+func NewPgxIntegrityRepo(logger logger.Logger, db *pgxpool.Pool, clock clock.Clock) *PgxIntegrityRepo {
+	return &PgxIntegrityRepo{
+		logger: logger,
+		db:     db,
+		clock:  clock,
+	}
+}
+
+// RecomputeBalances reads a batch of wallets and rebuilds their balances from the transactions in the same snapshot
+func (r *PgxIntegrityRepo) RecomputeBalances(ctx context.Context, afterWalletID int64, limit int) ([]entity.WalletBalances, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(opCtx, recomputeBalances, afterWalletID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("recompute balances failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.WalletBalances, 0, limit)
+	for rows.Next() {
+		b := entity.WalletBalances{}
+		if err := rows.Scan(&b.WalletID, &b.UserID, &b.TotalBalance, &b.AvailableBalance, &b.ExpectedTotalBalance, &b.ExpectedAvailableBalance); err != nil {
+			return nil, fmt.Errorf("error in reading wallet balances row: %w", err)
+		}
+		list = append(list, b)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading wallet balances: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+// RecomputeBalance rebuilds the balances of the wallet of the user from its transactions
+func (r *PgxIntegrityRepo) RecomputeBalance(ctx context.Context, userID int64) (*entity.WalletBalances, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	b := &entity.WalletBalances{}
+	err := r.db.QueryRow(opCtx, recomputeBalance, userID).
+		Scan(&b.WalletID, &b.UserID, &b.TotalBalance, &b.AvailableBalance, &b.ExpectedTotalBalance, &b.ExpectedAvailableBalance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("recompute balance failed: %w", err)
+	}
+
+	return b, nil
+}
+
+// Quarantine blocks debits of the wallet, it returns false if the wallet was already quarantined
+func (r *PgxIntegrityRepo) Quarantine(ctx context.Context, walletID int64, reason string) (bool, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var affected int64
+	err := audit.InTx(opCtx, r.db, "quarantine", func(tx pgx.Tx) error {
+		tag, err := tx.Exec(opCtx, quarantineWallet, walletID, reason, r.clock.Now())
+		affected = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("quarantine wallet failed: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// ReleaseQuarantine lets the wallet be debited again, it returns false if the wallet was not quarantined
+func (r *PgxIntegrityRepo) ReleaseQuarantine(ctx context.Context, walletID int64) (bool, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var affected int64
+	err := audit.InTx(opCtx, r.db, "release_quarantine", func(tx pgx.Tx) error {
+		tag, err := tx.Exec(opCtx, releaseQuarantine, walletID, r.clock.Now())
+		affected = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("release quarantine failed: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// SaveRun stores an integrity check run together with its discrepancies
+func (r *PgxIntegrityRepo) SaveRun(ctx context.Context, run *entity.CheckRun) (int64, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(opCtx)
+	if err != nil {
+		return 0, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback(opCtx)
+
+	var runID int64
+	err = tx.QueryRow(opCtx, insertRun, run.CheckedWallets, len(run.Discrepancies), run.Quarantined, run.StartedAt,
+		run.FinishedAt).Scan(&runID)
+	if err != nil {
+		return 0, fmt.Errorf("insert integrity check run failed: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, d := range run.Discrepancies {
+		batch.Queue(insertDiscrepancy, runID, d.WalletID, d.UserID, d.TotalBalance, d.ExpectedTotalBalance,
+			d.AvailableBalance, d.ExpectedAvailableBalance)
+	}
+	if err := tx.SendBatch(opCtx, batch).Close(); err != nil {
+		return 0, fmt.Errorf("insert integrity discrepancies failed: %w", err)
+	}
+
+	if err := tx.Commit(opCtx); err != nil {
+		return 0, fmt.Errorf("could not commit integrity check run: %w", err)
+	}
+	return runID, nil
+}
+
+const (
+	// Credits count towards the total balance immediately and towards the available balance once released.
+	// Debits reserve the available balance immediately, also while in review or pending at the bank, and leave
+	// the total balance once released. A fee is a debit of its own next to its withdrawal, and a refunded fee
+	// counts like any other debit with its refund counting like any other credit.
+	// Failed debits must have been given back and rolled back batch credits taken back, so they do not count at all.
+	// Active holds reserve the available balance until they are captured, voided or expire.
+	recomputeBalances = `
+WITH batch AS (
+    SELECT id, user_id, total_balance, available_balance
+    FROM wallets
+    WHERE id > $1
+    ORDER BY id
+    LIMIT $2
+)
+` + expectedBalances
+	recomputeBalance = `
+WITH batch AS (
+    SELECT id, user_id, total_balance, available_balance
+    FROM wallets
+    WHERE user_id = $1
+)
+` + expectedBalances
+	expectedBalances = `
+SELECT
+    b.id, b.user_id, b.total_balance, b.available_balance,
+    COALESCE(SUM(t.amount) FILTER (
+        WHERE (t.type = 'credit' AND t.status <> 'cancelled')
+           OR (t.type = 'debit' AND t.released AND t.status NOT IN ('failed', 'cancelled'))
+    ), 0) AS expected_total_balance,
+    COALESCE(SUM(t.amount) FILTER (
+        WHERE (t.type = 'credit' AND t.released)
+           OR (t.type = 'debit' AND t.status NOT IN ('failed', 'cancelled'))
+    ), 0) - COALESCE((
+        SELECT SUM(h.amount) FROM holds h WHERE h.wallet_id = b.id AND h.status = 'active'
+    ), 0) AS expected_available_balance
+FROM batch b
+LEFT JOIN transactions t ON t.wallet_id = b.id
+GROUP BY b.id, b.user_id, b.total_balance, b.available_balance
+ORDER BY b.id
+`
+	releaseQuarantine = `
+UPDATE wallet_quarantines
+SET released_at = $2::timestamptz
+WHERE wallet_id = $1 AND released_at IS NULL
+`
+	quarantineWallet = `
+INSERT INTO wallet_quarantines (wallet_id, reason, created_at)
+VALUES ($1, $2, $3::timestamptz)
+ON CONFLICT (wallet_id) WHERE released_at IS NULL DO NOTHING
+`
+	insertRun = `
+INSERT INTO integrity_check_runs (checked_wallets, discrepancies, quarantined, started_at, finished_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id
+`
+	insertDiscrepancy = `
+INSERT INTO integrity_discrepancies
+    (run_id, wallet_id, user_id, total_balance, expected_total_balance, available_balance, expected_available_balance)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+)
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/integrity/entity"
+)
+
+type IntegrityRepo interface {
+	// RecomputeBalances returns at most limit wallets with an id greater than afterWalletID,
+	// ordered by id, with their balances recomputed from the transactions table
+	RecomputeBalances(ctx context.Context, afterWalletID int64, limit int) ([]entity.WalletBalances, error)
+	// RecomputeBalance returns the balances of the wallet of the user recomputed from the transactions table
+	RecomputeBalance(ctx context.Context, userID int64) (*entity.WalletBalances, error)
+	Quarantine(ctx context.Context, walletID int64, reason string) (bool, error)
+	ReleaseQuarantine(ctx context.Context, walletID int64) (bool, error)
+	SaveRun(ctx context.Context, run *entity.CheckRun) (int64, error)
+}
@@ -0,0 +1,32 @@
+package integrity
+
+import (
+	"github.com/MaisamV/wallet/internal/integrity/application/command"
+	"github.com/MaisamV/wallet/internal/integrity/infrastructure"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/google/wire"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ProvideIntegrityRepository provides the integrity repository
+func ProvideIntegrityRepository(logger logger.Logger, db *pgxpool.Pool, clock clock.Clock) *infrastructure.PgxIntegrityRepo {
+	return infrastructure.NewPgxIntegrityRepo(logger, db, clock)
+}
+
+// ProvideCheckIntegrityCommandHandler provides an integrity check command handler
+func ProvideCheckIntegrityCommandHandler(logger logger.Logger, repo *infrastructure.PgxIntegrityRepo, clock clock.Clock) *command.CheckIntegrityCommandHandler {
+	return command.NewCheckIntegrityCommandHandler(logger, repo, clock)
+}
+
+// ProvideReleaseQuarantineCommandHandler provides a release quarantine command handler
+func ProvideReleaseQuarantineCommandHandler(logger logger.Logger, repo *infrastructure.PgxIntegrityRepo) *command.ReleaseQuarantineCommandHandler {
+	return command.NewReleaseQuarantineCommandHandler(logger, repo)
+}
+
+// IntegritySet is a wire provider set for all integrity dependencies
+var IntegritySet = wire.NewSet(
+	ProvideIntegrityRepository,
+	ProvideCheckIntegrityCommandHandler,
+	ProvideReleaseQuarantineCommandHandler,
+)
@@ -2,6 +2,7 @@ package query
 
 import (
 	"context"
+	"time"
 
 	"github.com/MaisamV/wallet/internal/probes/entity"
 	"github.com/MaisamV/wallet/internal/probes/ports"
@@ -13,15 +14,17 @@ type GetHealthQuery struct{}
 
 // GetHealthQueryHandler handles health check queries
 type GetHealthQueryHandler struct {
-	logger          logger.Logger
-	databaseChecker ports.DatabaseChecker
+	logger           logger.Logger
+	databaseChecker  ports.DatabaseChecker
+	integrityChecker ports.IntegrityChecker
 }
 
 // NewGetHealthQueryHandler creates a new health query handler
-func NewGetHealthQueryHandler(logger logger.Logger, databaseChecker ports.DatabaseChecker) *GetHealthQueryHandler {
+func NewGetHealthQueryHandler(logger logger.Logger, databaseChecker ports.DatabaseChecker, integrityChecker ports.IntegrityChecker) *GetHealthQueryHandler {
 	return &GetHealthQueryHandler{
-		logger:          logger,
-		databaseChecker: databaseChecker,
+		logger:           logger,
+		databaseChecker:  databaseChecker,
+		integrityChecker: integrityChecker,
 	}
 }
 
@@ -49,6 +52,11 @@ func (h *GetHealthQueryHandler) Handle(ctx context.Context, query GetHealthQuery
 		}
 	}
 
+	// Report the last balance integrity check
+	if h.integrityChecker != nil {
+		h.checkIntegrity(ctx, response)
+	}
+
 	// Determine overall status
 	response.DetermineOverallStatus()
 	h.logger.Info().Bool("is_healthy", response.IsHealthy()).Msg("Health check completed")
@@ -75,3 +83,31 @@ func (s *HealthService) GetHealthStatus(ctx context.Context) (*entity.HealthResp
 	s.logger.Debug().Msg("Health status requested")
 	return s.queryHandler.Handle(ctx, GetHealthQuery{})
 }
+
+// checkIntegrity adds the last balance integrity check result, discrepancies degrade but do not fail the health check
+func (h *GetHealthQueryHandler) checkIntegrity(ctx context.Context, response *entity.HealthResponse) {
+	h.logger.Debug().Msg("Reading last balance integrity check")
+	start := time.Now()
+	result, err := h.integrityChecker.LastIntegrityCheck(ctx)
+	duration := time.Since(start).Milliseconds()
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Balance integrity health check failed")
+		response.AddCheck("balance_integrity", entity.CheckStatusDegraded, duration)
+		return
+	}
+	if result == nil {
+		return
+	}
+
+	status := entity.CheckStatusUp
+	if !result.IsConsistent() {
+		status = entity.CheckStatusDegraded
+		h.logger.Warn().Int("discrepancies", result.Discrepancies).Msg("Wallet balances are not consistent with transactions")
+	}
+	response.AddCheckWithDetails("balance_integrity", status, duration, map[string]any{
+		"checked_wallets": result.CheckedWallets,
+		"discrepancies":   result.Discrepancies,
+		"quarantined":     result.Quarantined,
+		"finished_at":     result.FinishedAt,
+	})
+}
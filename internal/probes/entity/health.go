@@ -16,12 +16,15 @@ type CheckStatus string
 const (
 	CheckStatusUp   CheckStatus = "up"
 	CheckStatusDown CheckStatus = "down"
+	// CheckStatusDegraded reports a problem that does not make the service unhealthy
+	CheckStatusDegraded CheckStatus = "degraded"
 )
 
 // Check represents an individual health check result
 type Check struct {
-	Status         CheckStatus `json:"status"`
-	ResponseTimeMs int64       `json:"response_time_ms"`
+	Status         CheckStatus    `json:"status"`
+	ResponseTimeMs int64          `json:"response_time_ms"`
+	Details        map[string]any `json:"details,omitempty"`
 }
 
 // HealthResponse represents the complete health check response
@@ -47,6 +50,15 @@ func (hr *HealthResponse) AddCheck(name string, status CheckStatus, responseTime
 	}
 }
 
+// AddCheckWithDetails adds a check result with additional details to the health response
+func (hr *HealthResponse) AddCheckWithDetails(name string, status CheckStatus, responseTimeMs int64, details map[string]any) {
+	hr.Checks[name] = Check{
+		Status:         status,
+		ResponseTimeMs: responseTimeMs,
+		Details:        details,
+	}
+}
+
 // DetermineOverallStatus determines the overall health status based on individual checks
 func (hr *HealthResponse) DetermineOverallStatus() {
 	for _, check := range hr.Checks {
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// IntegrityCheck represents the result of the last balance integrity check
+type IntegrityCheck struct {
+	CheckedWallets int
+	Discrepancies  int
+	Quarantined    int
+	FinishedAt     time.Time
+}
+
+// IsConsistent returns true if no wallet balance differed from its transactions
+func (ic *IntegrityCheck) IsConsistent() bool {
+	return ic.Discrepancies == 0
+}
@@ -0,0 +1,54 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MaisamV/wallet/internal/probes/entity"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IntegrityChecker implements the IntegrityChecker port
+type IntegrityChecker struct {
+	logger  logger.Logger
+	db      *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewIntegrityChecker creates a new integrity checker
+func NewIntegrityChecker(logger logger.Logger, db *pgxpool.Pool, timeout time.Duration) *IntegrityChecker {
+	return &IntegrityChecker{
+		logger:  logger,
+		db:      db,
+		timeout: timeout,
+	}
+}
+
+// LastIntegrityCheck returns the result of the last balance integrity check, or nil if it never ran
+func (ic *IntegrityChecker) LastIntegrityCheck(ctx context.Context) (*entity.IntegrityCheck, error) {
+	checkCtx, cancel := context.WithTimeout(ctx, ic.timeout)
+	defer cancel()
+
+	result := &entity.IntegrityCheck{}
+	err := ic.db.QueryRow(checkCtx, lastIntegrityCheck).Scan(&result.CheckedWallets, &result.Discrepancies, &result.Quarantined, &result.FinishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		ic.logger.Debug().Msg("No integrity check has run yet")
+		return nil, nil
+	}
+	if err != nil {
+		ic.logger.Error().Err(err).Msg("Reading last integrity check failed")
+		return nil, err
+	}
+
+	return result, nil
+}
+
+const lastIntegrityCheck = `
+SELECT checked_wallets, discrepancies, quarantined, finished_at
+FROM integrity_check_runs
+ORDER BY id DESC
+LIMIT 1
+`
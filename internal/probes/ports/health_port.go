@@ -3,9 +3,16 @@ package ports
 import (
 	"context"
 	"time"
+
+	"github.com/MaisamV/wallet/internal/probes/entity"
 )
 
 // DatabaseChecker defines the interface for checking database connectivity
 type DatabaseChecker interface {
 	CheckDatabase(ctx context.Context) (bool, time.Duration, error)
 }
+
+// IntegrityChecker defines the interface for reading the result of the last balance integrity check
+type IntegrityChecker interface {
+	LastIntegrityCheck(ctx context.Context) (*entity.IntegrityCheck, error)
+}
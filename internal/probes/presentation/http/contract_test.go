@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/probes/application/query"
+	"github.com/MaisamV/wallet/internal/probes/entity"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/MaisamV/wallet/platform/openapi"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const specFile = "../../../../resources/openapi.yaml"
+
+type fakeDatabaseChecker struct {
+	healthy bool
+	err     error
+}
+
+func (f fakeDatabaseChecker) CheckDatabase(ctx context.Context) (bool, time.Duration, error) {
+	return f.healthy, 5 * time.Millisecond, f.err
+}
+
+type fakeIntegrityChecker struct {
+	check *entity.IntegrityCheck
+}
+
+func (f fakeIntegrityChecker) LastIntegrityCheck(ctx context.Context) (*entity.IntegrityCheck, error) {
+	return f.check, nil
+}
+
+func newProbesApp(databaseChecker fakeDatabaseChecker, integrityChecker fakeIntegrityChecker) *fiber.App {
+	log := logger.NewNoopLogger()
+	health := query.NewHealthService(log, query.NewGetHealthQueryHandler(log, databaseChecker, integrityChecker))
+	liveness := query.NewLivenessService(log, query.NewGetLivenessQueryHandler(log))
+	app := fiber.New()
+	NewHealthHandler(log, health, liveness).RegisterRoutes(app)
+	NewPingHandler(log, query.NewPingQueryHandler(log)).RegisterRoutes(app)
+	return app
+}
+
+func TestProbes_contract(t *testing.T) {
+	spec, err := openapi.Load(specFile)
+	if err != nil {
+		t.Fatalf("could not load openapi document: %v", err)
+	}
+	consistent := fakeIntegrityChecker{check: &entity.IntegrityCheck{CheckedWallets: 10, FinishedAt: time.Now()}}
+	inconsistent := fakeIntegrityChecker{check: &entity.IntegrityCheck{CheckedWallets: 10, Discrepancies: 2, Quarantined: 2, FinishedAt: time.Now()}}
+
+	tests := []struct {
+		name   string
+		app    *fiber.App
+		path   string
+		status int
+	}{
+		{"ping", newProbesApp(fakeDatabaseChecker{healthy: true}, consistent), "/ping", http.StatusOK},
+		{"healthy", newProbesApp(fakeDatabaseChecker{healthy: true}, consistent), "/health", http.StatusOK},
+		{"integrity discrepancies", newProbesApp(fakeDatabaseChecker{healthy: true}, inconsistent), "/health", http.StatusOK},
+		{"database down", newProbesApp(fakeDatabaseChecker{}, consistent), "/health", http.StatusServiceUnavailable},
+		{"database unreachable", newProbesApp(fakeDatabaseChecker{err: errors.New("connection refused")}, consistent), "/health", http.StatusServiceUnavailable},
+		{"liveness", newProbesApp(fakeDatabaseChecker{healthy: true}, consistent), "/liveness", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if err := spec.ValidateRequest(req, nil); err != nil {
+				t.Fatalf("request does not match the contract: %v", err)
+			}
+			resp, err := tt.app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if err := spec.ValidateResponse(req, resp); err != nil {
+				t.Fatalf("response does not match the contract: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -27,9 +27,14 @@ func ProvideDatabaseChecker(cfg *config.Config, logger logger.Logger, db *pgxpoo
 	return healthInfra.NewDatabaseChecker(logger, db, cfg.Health.DatabaseTimeout)
 }
 
+// ProvideIntegrityChecker provides a balance integrity checker
+func ProvideIntegrityChecker(cfg *config.Config, logger logger.Logger, db *pgxpool.Pool) *healthInfra.IntegrityChecker {
+	return healthInfra.NewIntegrityChecker(logger, db, cfg.Health.DatabaseTimeout)
+}
+
 // ProvideHealthQueryHandler provides a health query handler
-func ProvideHealthQueryHandler(logger logger.Logger, databaseChecker *healthInfra.DatabaseChecker) *healthQuery.GetHealthQueryHandler {
-	return healthQuery.NewGetHealthQueryHandler(logger, databaseChecker)
+func ProvideHealthQueryHandler(logger logger.Logger, databaseChecker *healthInfra.DatabaseChecker, integrityChecker *healthInfra.IntegrityChecker) *healthQuery.GetHealthQueryHandler {
+	return healthQuery.NewGetHealthQueryHandler(logger, databaseChecker, integrityChecker)
 }
 
 // ProvideHealthService provides a health service
@@ -57,6 +62,7 @@ var ProbesSet = wire.NewSet(
 	ProvidePingQueryHandler,
 	ProvidePingHandler,
 	ProvideDatabaseChecker,
+	ProvideIntegrityChecker,
 	ProvideHealthQueryHandler,
 	ProvideHealthService,
 	ProvideLivenessQueryHandler,
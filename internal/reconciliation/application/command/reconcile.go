@@ -0,0 +1,92 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/reconciliation/entity"
+	"github.com/MaisamV/wallet/internal/reconciliation/ports"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"io"
+	"time"
+)
+
+type ReconcileCommand struct {
+	FileName string
+	File     io.Reader
+	// From and To bound the creation time of the local withdrawals covered by the file,
+	// both zero covers the previous UTC day of the clock
+	From time.Time
+	To   time.Time
+}
+
+func (rc *ReconcileCommand) Err() error {
+	if rc.File == nil {
+		return errors.New("settlement file cannot be null")
+	}
+	if rc.FileName == "" {
+		return errors.New("settlement file name cannot be empty")
+	}
+	if rc.From.IsZero() || rc.To.IsZero() {
+		return errors.New("reconciliation period needs both a start and an end")
+	}
+	if !rc.From.Before(rc.To) {
+		return errors.New("reconciliation period start must be before its end")
+	}
+	return nil
+}
+
+type ReconcileCommandHandler struct {
+	logger logger.Logger
+	repo   ports.ReconciliationRepo
+	reader ports.SettlementReader
+	clock  clock.Clock
+}
+
+func NewReconcileCommandHandler(logger logger.Logger, repo ports.ReconciliationRepo, reader ports.SettlementReader, clock clock.Clock) *ReconcileCommandHandler {
+	return &ReconcileCommandHandler{
+		logger: logger,
+		repo:   repo,
+		reader: reader,
+		clock:  clock,
+	}
+}
+
+func (h *ReconcileCommandHandler) Handle(ctx context.Context, command ReconcileCommand) (*entity.ReconciliationRun, error) {
+	if command.From.IsZero() && command.To.IsZero() {
+		command.To = h.clock.Now().UTC().Truncate(24 * time.Hour)
+		command.From = command.To.AddDate(0, 0, -1)
+	}
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+
+	records, err := h.reader.Read(ctx, command.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settlement file: %w", err)
+	}
+	withdrawals, err := h.repo.GetWithdrawals(ctx, command.From, command.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load withdrawals: %w", err)
+	}
+
+	matched, items := entity.Match(withdrawals, records)
+	run := &entity.ReconciliationRun{
+		FileName:     command.FileName,
+		PeriodStart:  command.From,
+		PeriodEnd:    command.To,
+		BankRecords:  len(records),
+		LocalRecords: len(withdrawals),
+		Matched:      matched,
+		Mismatched:   len(items),
+		Items:        items,
+	}
+	run.ID, err = h.repo.SaveRun(ctx, run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save reconciliation result: %w", err)
+	}
+
+	h.logger.Info().Int64("run", run.ID).Int("matched", run.Matched).Int("mismatched", run.Mismatched).Msg("reconciliation finished")
+	return run, nil
+}
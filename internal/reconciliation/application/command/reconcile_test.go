@@ -0,0 +1,34 @@
+package command
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReconcileCommand_Err(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+	file := strings.NewReader("")
+
+	tests := []struct {
+		name    string
+		command ReconcileCommand
+		valid   bool
+	}{
+		{"valid", ReconcileCommand{FileName: "settlement.csv", File: file, From: from, To: to}, true},
+		{"no file", ReconcileCommand{FileName: "settlement.csv", From: from, To: to}, false},
+		{"no file name", ReconcileCommand{File: file, From: from, To: to}, false},
+		{"no start", ReconcileCommand{FileName: "settlement.csv", File: file, To: to}, false},
+		{"no end", ReconcileCommand{FileName: "settlement.csv", File: file, From: from}, false},
+		{"empty period", ReconcileCommand{FileName: "settlement.csv", File: file, From: from, To: from}, false},
+		{"reversed period", ReconcileCommand{FileName: "settlement.csv", File: file, From: to, To: from}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.command.Err(); (err == nil) != tt.valid {
+				t.Fatalf("expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/reconciliation/entity"
+	"github.com/MaisamV/wallet/internal/reconciliation/ports"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type GetRunQuery struct {
+	ID int64
+}
+
+type GetRunQueryHandler struct {
+	logger logger.Logger
+	repo   ports.ReconciliationRepo
+}
+
+func NewGetRunQueryHandler(logger logger.Logger, repo ports.ReconciliationRepo) *GetRunQueryHandler {
+	return &GetRunQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetRunQueryHandler) Handle(ctx context.Context, query GetRunQuery) (*entity.ReconciliationRun, error) {
+	run, err := h.repo.GetRun(ctx, query.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reconciliation run: %w", err)
+	}
+	return run, nil
+}
@@ -0,0 +1,36 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/reconciliation/entity"
+	"github.com/MaisamV/wallet/internal/reconciliation/ports"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type GetRunsQuery struct {
+	Limit int
+}
+
+type GetRunsQueryHandler struct {
+	logger logger.Logger
+	repo   ports.ReconciliationRepo
+}
+
+func NewGetRunsQueryHandler(logger logger.Logger, repo ports.ReconciliationRepo) *GetRunsQueryHandler {
+	return &GetRunsQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetRunsQueryHandler) Handle(ctx context.Context, query GetRunsQuery) ([]entity.ReconciliationRun, error) {
+	if query.Limit <= 0 || query.Limit > 100 {
+		query.Limit = 20
+	}
+	runs, err := h.repo.GetRuns(ctx, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reconciliation runs: %w", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,83 @@
+package entity
+
+// Match pairs local withdrawals with bank settlement records and classifies every difference.
+// Records are paired by idempotency key first and by bank reference when the bank did not
+// echo the key back. Local transactions that never succeeded are not expected at the bank.
+func Match(local []LocalTransaction, bank []SettlementRecord) (matched int, items []ReconciliationItem) {
+	byIdempotency := make(map[string]int, len(local))
+	byReference := make(map[string]int, len(local))
+	for i, tx := range local {
+		byIdempotency[tx.Idempotency.String()] = i
+		if tx.BankReference != "" {
+			byReference[tx.BankReference] = i
+		}
+	}
+
+	seen := make([]bool, len(local))
+	for _, record := range bank {
+		idx, ok := -1, false
+		if record.Idempotency != nil {
+			idx, ok = byIdempotency[record.Idempotency.String()]
+		}
+		if !ok && record.BankReference != "" {
+			idx, ok = byReference[record.BankReference]
+		}
+		if !ok || seen[idx] {
+			items = append(items, ReconciliationItem{
+				Type:          MissingLocally,
+				Idempotency:   record.Idempotency,
+				BankReference: record.BankReference,
+				BankAmount:    &record.Amount,
+				BankStatus:    record.Status,
+			})
+			continue
+		}
+		seen[idx] = true
+
+		tx := local[idx]
+		localAmount := abs(tx.Amount)
+		item := ReconciliationItem{
+			TransactionID: &tx.ID,
+			Idempotency:   &tx.Idempotency,
+			BankReference: record.BankReference,
+			LocalAmount:   &localAmount,
+			BankAmount:    &record.Amount,
+			LocalStatus:   tx.Status,
+			BankStatus:    record.Status,
+		}
+		switch {
+		case localAmount != record.Amount:
+			item.Type = AmountMismatch
+		case tx.Status != record.Status:
+			item.Type = StatusMismatch
+		default:
+			matched++
+			continue
+		}
+		items = append(items, item)
+	}
+
+	for i, tx := range local {
+		if seen[i] || tx.Status != SETTLED {
+			continue
+		}
+		localAmount := abs(tx.Amount)
+		items = append(items, ReconciliationItem{
+			Type:          MissingAtBank,
+			TransactionID: &local[i].ID,
+			Idempotency:   &local[i].Idempotency,
+			BankReference: tx.BankReference,
+			LocalAmount:   &localAmount,
+			LocalStatus:   tx.Status,
+		})
+	}
+
+	return matched, items
+}
+
+func abs(amount int64) int64 {
+	if amount < 0 {
+		return -amount
+	}
+	return amount
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"github.com/gofrs/uuid/v5"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	settled := LocalTransaction{ID: uuid.Must(uuid.NewV7()), Idempotency: uuid.Must(uuid.NewV7()), BankReference: "ref-1", Amount: -1000, Status: SETTLED}
+	wrongAmount := LocalTransaction{ID: uuid.Must(uuid.NewV7()), Idempotency: uuid.Must(uuid.NewV7()), BankReference: "ref-2", Amount: -2000, Status: SETTLED}
+	wrongStatus := LocalTransaction{ID: uuid.Must(uuid.NewV7()), Idempotency: uuid.Must(uuid.NewV7()), Amount: -3000, Status: REJECTED}
+	notAtBank := LocalTransaction{ID: uuid.Must(uuid.NewV7()), Idempotency: uuid.Must(uuid.NewV7()), BankReference: "ref-4", Amount: -4000, Status: SETTLED}
+	neverSent := LocalTransaction{ID: uuid.Must(uuid.NewV7()), Idempotency: uuid.Must(uuid.NewV7()), Amount: -5000, Status: REJECTED}
+	unknown := uuid.Must(uuid.NewV7())
+
+	local := []LocalTransaction{settled, wrongAmount, wrongStatus, notAtBank, neverSent}
+	bank := []SettlementRecord{
+		{BankReference: "ref-1", Amount: 1000, Status: SETTLED},
+		{Idempotency: &wrongAmount.Idempotency, BankReference: "ref-2", Amount: 2500, Status: SETTLED},
+		{Idempotency: &wrongStatus.Idempotency, BankReference: "ref-3", Amount: 3000, Status: SETTLED},
+		{Idempotency: &unknown, BankReference: "ref-6", Amount: 6000, Status: SETTLED},
+	}
+
+	matched, items := Match(local, bank)
+	if matched != 1 {
+		t.Errorf("expected 1 matched record, got %d", matched)
+	}
+
+	types := make(map[MismatchType]int)
+	for _, item := range items {
+		types[item.Type]++
+	}
+	expected := map[MismatchType]int{AmountMismatch: 1, StatusMismatch: 1, MissingLocally: 1, MissingAtBank: 1}
+	for typ, count := range expected {
+		if types[typ] != count {
+			t.Errorf("expected %d %s items, got %d", count, typ, types[typ])
+		}
+	}
+	if len(items) != 4 {
+		t.Errorf("expected 4 mismatches, got %d", len(items))
+	}
+}
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"errors"
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+var ErrRunNotFound = errors.New("reconciliation run not found")
+
+type MismatchType = string
+
+const (
+	MissingAtBank  MismatchType = "missing_at_bank"
+	MissingLocally              = "missing_locally"
+	AmountMismatch              = "amount_mismatch"
+	StatusMismatch              = "status_mismatch"
+)
+
+// SettlementStatus is the normalized status of a bank settlement record
+type SettlementStatus = string
+
+const (
+	SETTLED  SettlementStatus = "success"
+	REJECTED                  = "failed"
+)
+
+// SettlementRecord is a single row of a bank settlement file
+type SettlementRecord struct {
+	Line          int
+	Idempotency   *uuid.UUID
+	BankReference string
+	Amount        int64
+	Status        SettlementStatus
+}
+
+// LocalTransaction is a withdrawal sent to the bank as recorded in our transactions table
+type LocalTransaction struct {
+	ID            uuid.UUID
+	UserID        int64
+	Idempotency   uuid.UUID
+	BankReference string
+	Amount        int64
+	Status        string
+}
+
+type ReconciliationItem struct {
+	ID            int64        `json:"id"`
+	RunID         int64        `json:"run_id"`
+	Type          MismatchType `json:"type"`
+	TransactionID *uuid.UUID   `json:"transaction_id,omitempty"`
+	Idempotency   *uuid.UUID   `json:"idempotency,omitempty"`
+	BankReference string       `json:"bank_reference,omitempty"`
+	LocalAmount   *int64       `json:"local_amount,omitempty"`
+	BankAmount    *int64       `json:"bank_amount,omitempty"`
+	LocalStatus   string       `json:"local_status,omitempty"`
+	BankStatus    string       `json:"bank_status,omitempty"`
+}
+
+type ReconciliationRun struct {
+	ID           int64                `json:"id"`
+	FileName     string               `json:"file_name"`
+	PeriodStart  time.Time            `json:"period_start"`
+	PeriodEnd    time.Time            `json:"period_end"`
+	BankRecords  int                  `json:"bank_records"`
+	LocalRecords int                  `json:"local_records"`
+	Matched      int                  `json:"matched"`
+	Mismatched   int                  `json:"mismatched"`
+	CreatedAt    time.Time            `json:"created_at"`
+	Items        []ReconciliationItem `json:"items,omitempty"`
+}
@@ -0,0 +1,102 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/reconciliation/entity"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CsvSettlementReader reads settlement files whose columns are located by their header names
+type CsvSettlementReader struct {
+	logger          logger.Logger
+	delimiter       rune
+	columns         config.SettlementColumns
+	successStatuses map[string]bool
+}
+
+func NewCsvSettlementReader(logger logger.Logger, cfg config.ReconcileConfig) *CsvSettlementReader {
+	delimiter := ','
+	if cfg.Delimiter != "" {
+		delimiter = []rune(cfg.Delimiter)[0]
+	}
+	successStatuses := make(map[string]bool, len(cfg.SuccessStatuses))
+	for _, status := range cfg.SuccessStatuses {
+		successStatuses[strings.ToLower(status)] = true
+	}
+	return &CsvSettlementReader{
+		logger:          logger,
+		delimiter:       delimiter,
+		columns:         cfg.Columns,
+		successStatuses: successStatuses,
+	}
+}
+
+// Read parses the whole settlement file, failing on the first malformed row
+func (r *CsvSettlementReader) Read(ctx context.Context, in io.Reader) ([]entity.SettlementRecord, error) {
+	reader := csv.NewReader(in)
+	reader.Comma = r.delimiter
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read settlement file header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+	idempotencyCol, okIdempotency := index[r.columns.Idempotency]
+	referenceCol, okReference := index[r.columns.BankReference]
+	amountCol, okAmount := index[r.columns.Amount]
+	statusCol, okStatus := index[r.columns.Status]
+	if !okAmount || !okStatus || (!okIdempotency && !okReference) {
+		return nil, errors.New("settlement file header does not contain the configured columns")
+	}
+
+	records := make([]entity.SettlementRecord, 0)
+	for line := 2; ; line++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read settlement file line %d: %w", line, err)
+		}
+
+		record := entity.SettlementRecord{Line: line}
+		if okIdempotency && strings.TrimSpace(row[idempotencyCol]) != "" {
+			idempotency, err := uuid.FromString(strings.TrimSpace(row[idempotencyCol]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid idempotency key on line %d: %w", line, err)
+			}
+			record.Idempotency = &idempotency
+		}
+		if okReference {
+			record.BankReference = strings.TrimSpace(row[referenceCol])
+		}
+		record.Amount, err = strconv.ParseInt(strings.TrimSpace(row[amountCol]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount on line %d: %w", line, err)
+		}
+		if r.successStatuses[strings.ToLower(strings.TrimSpace(row[statusCol]))] {
+			record.Status = entity.SETTLED
+		} else {
+			record.Status = entity.REJECTED
+		}
+		records = append(records, record)
+	}
+
+	r.logger.Debug().Int("records", len(records)).Msg("settlement file parsed")
+	return records, nil
+}
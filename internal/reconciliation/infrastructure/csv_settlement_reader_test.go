@@ -0,0 +1,62 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/reconciliation/entity"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/MaisamV/wallet/platform/logger"
+	"strings"
+	"testing"
+)
+
+func newReader() *CsvSettlementReader {
+	return NewCsvSettlementReader(logger.NewNoopLogger(), config.ReconcileConfig{
+		Columns: config.SettlementColumns{
+			Idempotency:   "tracking_id",
+			BankReference: "reference",
+			Amount:        "amount",
+			Status:        "status",
+		},
+		SuccessStatuses: []string{"Settled"},
+	})
+}
+
+func TestCsvSettlementReader_Read(t *testing.T) {
+	file := "tracking_id,reference,amount,status\n" +
+		"0190b3c4-5d6e-7f80-9a1b-2c3d4e5f6071,ref-1,1000,settled\n" +
+		",ref-2,2000,returned\n"
+	records, err := newReader().Read(context.Background(), strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if records[0].Idempotency == nil || records[0].Amount != 1000 || records[0].Status != entity.SETTLED {
+		t.Fatalf("unexpected first record %+v", records[0])
+	}
+	if records[1].Idempotency != nil || records[1].BankReference != "ref-2" || records[1].Status != entity.REJECTED || records[1].Line != 3 {
+		t.Fatalf("unexpected second record %+v", records[1])
+	}
+}
+
+func TestCsvSettlementReader_Read_invalidFile(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"empty file", ""},
+		{"missing amount column", "tracking_id,reference,status\n"},
+		{"missing both keys", "amount,status\n1000,settled\n"},
+		{"invalid amount", "tracking_id,reference,amount,status\n,ref-1,ten,settled\n"},
+		{"invalid idempotency", "tracking_id,reference,amount,status\nabc,ref-1,1000,settled\n"},
+		{"short row", "tracking_id,reference,amount,status\n,ref-1,1000\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newReader().Read(context.Background(), strings.NewReader(tt.file)); err == nil {
+				t.Fatalf("expected the file to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/reconciliation/entity"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type PgxReconciliationRepo struct {
+	logger logger.Logger
+	db     *pgxpool.Pool
+}
+
+func NewPgxReconciliationRepo(logger logger.Logger, db *pgxpool.Pool) *PgxReconciliationRepo {
+	return &PgxReconciliationRepo{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// GetWithdrawals return all withdrawals created in the given period
+func (r *PgxReconciliationRepo) GetWithdrawals(ctx context.Context, from time.Time, to time.Time) ([]entity.LocalTransaction, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(opCtx, getWithdrawals, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("get withdrawals failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.LocalTransaction, 0)
+	for rows.Next() {
+		t := entity.LocalTransaction{}
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Idempotency, &t.BankReference, &t.Amount, &t.Status); err != nil {
+			return nil, fmt.Errorf("error in reading withdrawal row: %w", err)
+		}
+		list = append(list, t)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading withdrawal list: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+// SaveRun stores a reconciliation run together with its mismatches
+func (r *PgxReconciliationRepo) SaveRun(ctx context.Context, run *entity.ReconciliationRun) (int64, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(opCtx)
+	if err != nil {
+		return 0, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback(opCtx)
+
+	var runID int64
+	err = tx.QueryRow(opCtx, insertRun, run.FileName, run.PeriodStart, run.PeriodEnd,
+		run.BankRecords, run.LocalRecords, run.Matched, run.Mismatched).Scan(&runID)
+	if err != nil {
+		return 0, fmt.Errorf("insert reconciliation run failed: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, item := range run.Items {
+		batch.Queue(insertItem, runID, item.Type, item.TransactionID, item.Idempotency, item.BankReference,
+			item.LocalAmount, item.BankAmount, item.LocalStatus, item.BankStatus)
+	}
+	if err := tx.SendBatch(opCtx, batch).Close(); err != nil {
+		return 0, fmt.Errorf("insert reconciliation items failed: %w", err)
+	}
+
+	if err := tx.Commit(opCtx); err != nil {
+		return 0, fmt.Errorf("could not commit reconciliation run: %w", err)
+	}
+	return runID, nil
+}
+
+// GetRuns return the latest reconciliation runs without their items
+func (r *PgxReconciliationRepo) GetRuns(ctx context.Context, limit int) ([]entity.ReconciliationRun, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(opCtx, getRuns, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get reconciliation runs failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.ReconciliationRun, 0, limit)
+	for rows.Next() {
+		run := entity.ReconciliationRun{}
+		if err := scanRun(rows, &run); err != nil {
+			return nil, fmt.Errorf("error in reading reconciliation run row: %w", err)
+		}
+		list = append(list, run)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading reconciliation runs: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+// GetRun return a reconciliation run with all of its mismatches
+func (r *PgxReconciliationRepo) GetRun(ctx context.Context, id int64) (*entity.ReconciliationRun, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	run := entity.ReconciliationRun{}
+	err := scanRun(r.db.QueryRow(opCtx, getRun, id), &run)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.ErrRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reconciliation run failed: %w", err)
+	}
+
+	rows, err := r.db.Query(opCtx, getRunItems, id)
+	if err != nil {
+		return nil, fmt.Errorf("get reconciliation items failed: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item := entity.ReconciliationItem{}
+		if err := rows.Scan(&item.ID, &item.RunID, &item.Type, &item.TransactionID, &item.Idempotency,
+			&item.BankReference, &item.LocalAmount, &item.BankAmount, &item.LocalStatus, &item.BankStatus); err != nil {
+			return nil, fmt.Errorf("error in reading reconciliation item row: %w", err)
+		}
+		run.Items = append(run.Items, item)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading reconciliation items: %w", rows.Err())
+	}
+
+	return &run, nil
+}
+
+func scanRun(row pgx.Row, run *entity.ReconciliationRun) error {
+	return row.Scan(&run.ID, &run.FileName, &run.PeriodStart, &run.PeriodEnd,
+		&run.BankRecords, &run.LocalRecords, &run.Matched, &run.Mismatched, &run.CreatedAt)
+}
+
+const (
+	getWithdrawals = `
+SELECT id, user_id, idempotency_key, COALESCE(bank_response_id::text, ''), amount, status
+FROM transactions
+WHERE type = 'debit'
+  AND source = 'user'
+  AND status <> 'cancelled'
+  AND created_at >= $1
+  AND created_at < $2
+`
+	insertRun = `
+INSERT INTO reconciliation_runs
+    (file_name, period_start, period_end, bank_records, local_records, matched, mismatched)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id
+`
+	insertItem = `
+INSERT INTO reconciliation_items
+    (run_id, type, transaction_id, idempotency_key, bank_reference, local_amount, bank_amount, local_status, bank_status)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+`
+	getRuns = `
+SELECT id, file_name, period_start, period_end, bank_records, local_records, matched, mismatched, created_at
+FROM reconciliation_runs
+ORDER BY id DESC
+LIMIT $1
+`
+	getRun = `
+SELECT id, file_name, period_start, period_end, bank_records, local_records, matched, mismatched, created_at
+FROM reconciliation_runs
+WHERE id = $1
+`
+	getRunItems = `
+SELECT id, run_id, type, transaction_id, idempotency_key, bank_reference, local_amount, bank_amount, local_status, bank_status
+FROM reconciliation_items
+WHERE run_id = $1
+ORDER BY id
+`
+)
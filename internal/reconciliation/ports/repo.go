@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/reconciliation/entity"
+	"time"
+)
+
+type ReconciliationRepo interface {
+	GetWithdrawals(ctx context.Context, from time.Time, to time.Time) ([]entity.LocalTransaction, error)
+	SaveRun(ctx context.Context, run *entity.ReconciliationRun) (int64, error)
+	GetRuns(ctx context.Context, limit int) ([]entity.ReconciliationRun, error)
+	GetRun(ctx context.Context, id int64) (*entity.ReconciliationRun, error)
+}
@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/reconciliation/entity"
+	"io"
+)
+
+// SettlementReader parses a bank settlement file into settlement records
+type SettlementReader interface {
+	Read(ctx context.Context, r io.Reader) ([]entity.SettlementRecord, error)
+}
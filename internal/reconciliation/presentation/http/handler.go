@@ -0,0 +1,117 @@
+package http
+
+import (
+	"errors"
+	"github.com/MaisamV/wallet/internal/reconciliation/application/command"
+	"github.com/MaisamV/wallet/internal/reconciliation/application/query"
+	"github.com/MaisamV/wallet/internal/reconciliation/entity"
+	"github.com/MaisamV/wallet/internal/reconciliation/presentation/dto"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ReconciliationHandler struct {
+	logger           logger.Logger
+	reconcileHandler *command.ReconcileCommandHandler
+	runsHandler      *query.GetRunsQueryHandler
+	runHandler       *query.GetRunQueryHandler
+}
+
+func NewReconciliationHandler(logger logger.Logger, reconcileHandler *command.ReconcileCommandHandler,
+	runsHandler *query.GetRunsQueryHandler, runHandler *query.GetRunQueryHandler) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		logger:           logger,
+		reconcileHandler: reconcileHandler,
+		runsHandler:      runsHandler,
+		runHandler:       runHandler,
+	}
+}
+
+// RegisterRoutes registers the reconciliation routes on the authenticated admin router
+func (h *ReconciliationHandler) RegisterRoutes(admin fiber.Router) {
+	group := admin.Group("/reconciliations")
+	h.logger.Info().Msg("Registering reconciliation routes")
+	group.Get("/", h.GetRuns)
+	group.Get("/:id", h.GetRun)
+	group.Post("/", h.Reconcile)
+	h.logger.Info().Msg("reconciliation routes registered successfully")
+}
+
+func (h *ReconciliationHandler) GetRuns(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not parse limit")
+	}
+
+	runs, err := h.runsHandler.Handle(ctx, query.GetRunsQuery{Limit: limit})
+	if err != nil {
+		return h.respondError(c, http.StatusInternalServerError, err, "Could not fetch reconciliation runs")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(runs))
+}
+
+func (h *ReconciliationHandler) GetRun(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not parse id")
+	}
+
+	run, err := h.runHandler.Handle(ctx, query.GetRunQuery{ID: id})
+	if errors.Is(err, entity.ErrRunNotFound) {
+		return h.respondError(c, http.StatusNotFound, err, "Could not fetch reconciliation run")
+	}
+	if err != nil {
+		return h.respondError(c, http.StatusInternalServerError, err, "Could not fetch reconciliation run")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(run))
+}
+
+// Reconcile ingests a settlement file uploaded as the multipart field "file"
+func (h *ReconciliationHandler) Reconcile(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	from, err := time.Parse(time.RFC3339, c.FormValue("from"))
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not parse from")
+	}
+	to, err := time.Parse(time.RFC3339, c.FormValue("to"))
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not parse to")
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not read settlement file")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not open settlement file")
+	}
+	defer file.Close()
+
+	cmd := command.ReconcileCommand{
+		FileName: header.Filename,
+		File:     file,
+		From:     from,
+		To:       to,
+	}
+	run, err := h.reconcileHandler.Handle(ctx, cmd)
+	if err != nil {
+		return h.respondError(c, http.StatusInternalServerError, err, "Could not reconcile settlement file")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(run))
+}
+
+func (h *ReconciliationHandler) respondError(c *fiber.Ctx, status int, err error, message string) error {
+	h.logger.Error().Err(err).Msg(message)
+	return c.Status(status).JSON(dto.ToErrorWithMessage(err, message))
+}
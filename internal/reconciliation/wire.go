@@ -0,0 +1,55 @@
+package reconciliation
+
+import (
+	"github.com/MaisamV/wallet/internal/reconciliation/application/command"
+	"github.com/MaisamV/wallet/internal/reconciliation/application/query"
+	"github.com/MaisamV/wallet/internal/reconciliation/infrastructure"
+	"github.com/MaisamV/wallet/internal/reconciliation/presentation/http"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/google/wire"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ProvideReconciliationRepository provides the reconciliation repository
+func ProvideReconciliationRepository(logger logger.Logger, db *pgxpool.Pool) *infrastructure.PgxReconciliationRepo {
+	return infrastructure.NewPgxReconciliationRepo(logger, db)
+}
+
+// ProvideSettlementReader provides a settlement file reader using the configured column mapping
+func ProvideSettlementReader(logger logger.Logger, cfg *config.Config) *infrastructure.CsvSettlementReader {
+	return infrastructure.NewCsvSettlementReader(logger, cfg.Reconcile)
+}
+
+// ProvideReconcileCommandHandler provides a reconcile command handler
+func ProvideReconcileCommandHandler(logger logger.Logger, repo *infrastructure.PgxReconciliationRepo, reader *infrastructure.CsvSettlementReader,
+	clock clock.Clock) *command.ReconcileCommandHandler {
+	return command.NewReconcileCommandHandler(logger, repo, reader, clock)
+}
+
+// ProvideGetRunsQueryHandler provides a reconciliation runs query handler
+func ProvideGetRunsQueryHandler(logger logger.Logger, repo *infrastructure.PgxReconciliationRepo) *query.GetRunsQueryHandler {
+	return query.NewGetRunsQueryHandler(logger, repo)
+}
+
+// ProvideGetRunQueryHandler provides a reconciliation run query handler
+func ProvideGetRunQueryHandler(logger logger.Logger, repo *infrastructure.PgxReconciliationRepo) *query.GetRunQueryHandler {
+	return query.NewGetRunQueryHandler(logger, repo)
+}
+
+// ProvideReconciliationHandler provides a reconciliation HTTP handler
+func ProvideReconciliationHandler(logger logger.Logger, reconcileHandler *command.ReconcileCommandHandler,
+	runsHandler *query.GetRunsQueryHandler, runHandler *query.GetRunQueryHandler) *http.ReconciliationHandler {
+	return http.NewReconciliationHandler(logger, reconcileHandler, runsHandler, runHandler)
+}
+
+// ReconciliationSet is a wire provider set for all reconciliation dependencies
+var ReconciliationSet = wire.NewSet(
+	ProvideReconciliationRepository,
+	ProvideSettlementReader,
+	ProvideReconcileCommandHandler,
+	ProvideGetRunsQueryHandler,
+	ProvideGetRunQueryHandler,
+	ProvideReconciliationHandler,
+)
@@ -0,0 +1,147 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+	"strings"
+)
+
+type RequestAdjustmentCommand struct {
+	UserId      int64
+	Type        entity.TransactionType
+	Amount      int64
+	ReasonCode  entity.ReasonCode
+	Note        string
+	Idempotency *uuid.UUID
+	Actor       string
+}
+
+func (rc *RequestAdjustmentCommand) Err() error {
+	if rc.UserId <= 0 {
+		return errors.New("user id should be positive")
+	}
+	if rc.Type != entity.CREDIT && rc.Type != entity.DEBIT {
+		return errors.New("type must be credit or debit")
+	}
+	if rc.Amount <= 0 {
+		return errors.New("amount should be positive")
+	}
+	if !entity.IsValidReasonCode(rc.ReasonCode) {
+		return errors.New("unknown reason code")
+	}
+	if rc.ReasonCode == entity.OTHER && strings.TrimSpace(rc.Note) == "" {
+		return errors.New("note is required when reason code is other")
+	}
+	if rc.Idempotency == nil || rc.Idempotency.IsNil() {
+		return errors.New("idempotency cannot be empty")
+	}
+	if strings.TrimSpace(rc.Actor) == "" {
+		return errors.New("actor cannot be empty")
+	}
+	return nil
+}
+
+type RequestAdjustmentCommandHandler struct {
+	logger logger.Logger
+	repo   repo.WalletAdminRepo
+}
+
+func NewRequestAdjustmentCommandHandler(logger logger.Logger, repo repo.WalletAdminRepo) *RequestAdjustmentCommandHandler {
+	return &RequestAdjustmentCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *RequestAdjustmentCommandHandler) Handle(ctx context.Context, command RequestAdjustmentCommand) (*entity.Adjustment, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	adjustment := &entity.Adjustment{
+		UserID:      command.UserId,
+		Type:        command.Type,
+		Amount:      command.Amount,
+		ReasonCode:  command.ReasonCode,
+		Note:        command.Note,
+		Status:      entity.ADJUSTMENT_PENDING,
+		Idempotency: *command.Idempotency,
+		RequestedBy: command.Actor,
+	}
+	id, err := h.repo.CreateAdjustment(ctx, adjustment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request adjustment: %w", err)
+	}
+	adjustment.ID = id
+	h.logger.Info().Int64("id", id).Int64("user_id", command.UserId).Str("type", command.Type).
+		Int64("amount", command.Amount).Str("actor", command.Actor).Msg("adjustment requested")
+	return adjustment, nil
+}
+
+// DecideAdjustmentCommand approves or rejects a pending adjustment
+type DecideAdjustmentCommand struct {
+	ID    int64
+	Actor string
+}
+
+func (dc *DecideAdjustmentCommand) Err() error {
+	if dc.ID <= 0 {
+		return errors.New("adjustment id should be positive")
+	}
+	if strings.TrimSpace(dc.Actor) == "" {
+		return errors.New("actor cannot be empty")
+	}
+	return nil
+}
+
+type ApproveAdjustmentCommandHandler struct {
+	logger logger.Logger
+	repo   repo.WalletAdminRepo
+}
+
+func NewApproveAdjustmentCommandHandler(logger logger.Logger, repo repo.WalletAdminRepo) *ApproveAdjustmentCommandHandler {
+	return &ApproveAdjustmentCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *ApproveAdjustmentCommandHandler) Handle(ctx context.Context, command DecideAdjustmentCommand) (*entity.Adjustment, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	adjustment, err := h.repo.ApproveAdjustment(ctx, command.ID, command.Actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve adjustment: %w", err)
+	}
+	h.logger.Info().Int64("id", command.ID).Str("actor", command.Actor).Msg("adjustment approved")
+	return adjustment, nil
+}
+
+type RejectAdjustmentCommandHandler struct {
+	logger logger.Logger
+	repo   repo.WalletAdminRepo
+}
+
+func NewRejectAdjustmentCommandHandler(logger logger.Logger, repo repo.WalletAdminRepo) *RejectAdjustmentCommandHandler {
+	return &RejectAdjustmentCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *RejectAdjustmentCommandHandler) Handle(ctx context.Context, command DecideAdjustmentCommand) (*entity.Adjustment, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	adjustment, err := h.repo.RejectAdjustment(ctx, command.ID, command.Actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject adjustment: %w", err)
+	}
+	h.logger.Info().Int64("id", command.ID).Str("actor", command.Actor).Msg("adjustment rejected")
+	return adjustment, nil
+}
@@ -0,0 +1,247 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/internal/wallet/ports/service"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+type CreateBatchCommand struct {
+	FileName string
+	Format   entity.BatchFormat
+	File     io.Reader
+	Mode     entity.BatchMode
+	Actor    string
+}
+
+func (cc *CreateBatchCommand) Err() error {
+	if cc.File == nil {
+		return errors.New("batch file cannot be null")
+	}
+	if cc.FileName == "" {
+		return errors.New("batch file name cannot be empty")
+	}
+	if cc.Format != entity.BATCH_CSV && cc.Format != entity.BATCH_JSON {
+		return fmt.Errorf("format must be %s or %s", entity.BATCH_CSV, entity.BATCH_JSON)
+	}
+	if cc.Mode != entity.BATCH_PARTIAL && cc.Mode != entity.BATCH_ALL_OR_NOTHING {
+		return fmt.Errorf("mode must be %s or %s", entity.BATCH_PARTIAL, entity.BATCH_ALL_OR_NOTHING)
+	}
+	if cc.Actor == "" {
+		return errors.New("actor cannot be empty")
+	}
+	return nil
+}
+
+type CreateBatchCommandHandler struct {
+	logger  logger.Logger
+	repo    repo.BatchRepo
+	reader  service.BatchReader
+	maxRows atomic.Int64
+}
+
+func NewCreateBatchCommandHandler(logger logger.Logger, repo repo.BatchRepo, reader service.BatchReader, maxRows int) *CreateBatchCommandHandler {
+	h := &CreateBatchCommandHandler{
+		logger: logger,
+		repo:   repo,
+		reader: reader,
+	}
+	h.maxRows.Store(int64(maxRows))
+	return h
+}
+
+// SetMaxRows changes the row limit of the files uploaded from now on
+func (h *CreateBatchCommandHandler) SetMaxRows(maxRows int) {
+	h.maxRows.Store(int64(maxRows))
+}
+
+// Handle validates every row of the file and stores the batch for the batch worker. Nothing is stored
+// when a row is invalid, the returned entity.BatchValidationError lists every invalid row.
+func (h *CreateBatchCommandHandler) Handle(ctx context.Context, command CreateBatchCommand) (*entity.Batch, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+
+	rows, err := h.reader.Read(ctx, command.Format, command.File)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", entity.ErrInvalidBatch, err)
+	}
+	if maxRows := int(h.maxRows.Load()); len(rows) > maxRows {
+		return nil, fmt.Errorf("%w: batch file has %d rows, at most %d are allowed", entity.ErrInvalidBatch, len(rows), maxRows)
+	}
+	if err := entity.ValidateBatchRows(rows); err != nil {
+		return nil, err
+	}
+
+	used, err := h.repo.UsedIdempotencies(ctx, rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check idempotencies: %w", err)
+	}
+	if len(used) > 0 {
+		return nil, usedIdempotencyError(rows, used)
+	}
+
+	batch, err := h.repo.CreateBatch(ctx, &entity.Batch{
+		FileName:  command.FileName,
+		Mode:      command.Mode,
+		CreatedBy: command.Actor,
+	}, rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+
+	h.logger.Info().Int64("batch", batch.ID).Int("rows", batch.TotalRows).Str("mode", batch.Mode).
+		Str("actor", command.Actor).Msg("batch created")
+	return batch, nil
+}
+
+func usedIdempotencyError(rows []entity.BatchRow, used []uuid.UUID) error {
+	usedKeys := make(map[uuid.UUID]bool, len(used))
+	for _, key := range used {
+		usedKeys[key] = true
+	}
+	invalid := make([]entity.BatchRowError, 0, len(used))
+	for _, row := range rows {
+		if usedKeys[row.Idempotency] {
+			invalid = append(invalid, entity.BatchRowError{Line: row.Line, Error: entity.ErrIdempotencyUsed.Error()})
+		}
+	}
+	return &entity.BatchValidationError{Rows: invalid}
+}
+
+type ProcessBatchCommand struct {
+	// ChunkSize is the number of rows read at once, and of an all or nothing batch applied in one database transaction
+	ChunkSize int
+}
+
+// ProcessBatchCommandHandler applies the rows of the oldest unfinished batch. The rows of a partial batch are
+// applied one by one. The rows of an all or nothing batch are staged in chunks, and once no row is pending the
+// staged rows are released, or rolled back when a row was rejected. Every step is decided from the row states,
+// so a batch interrupted by a stopped worker or a database error continues where it stopped once its claim is over.
+// Debit rows are charged the fee and screened like the withdrawals of the debit API.
+type ProcessBatchCommandHandler struct {
+	logger   logger.Logger
+	repo     repo.BatchRepo
+	risk     service.RiskEvaluator
+	fees     service.FeeCalculator
+	claimFor time.Duration
+}
+
+func NewProcessBatchCommandHandler(logger logger.Logger, repo repo.BatchRepo, risk service.RiskEvaluator,
+	fees service.FeeCalculator, claimFor time.Duration) *ProcessBatchCommandHandler {
+	return &ProcessBatchCommandHandler{
+		logger:   logger,
+		repo:     repo,
+		risk:     risk,
+		fees:     fees,
+		claimFor: claimFor,
+	}
+}
+
+func (h *ProcessBatchCommandHandler) debits() repo.BatchDebits {
+	return repo.BatchDebits{
+		Quote: func(ctx context.Context, amount int64) (int64, error) {
+			quote, err := h.fees.Quote(ctx, amount)
+			if err != nil {
+				return 0, err
+			}
+			return quote.Fee, nil
+		},
+		Screen: func(amount int64) *repo.DebitScreen {
+			return riskScreen(h.risk, amount)
+		},
+	}
+}
+
+// Handle returns the processed batch, or nil when no batch is waiting
+func (h *ProcessBatchCommandHandler) Handle(ctx context.Context, command ProcessBatchCommand) (*entity.Batch, error) {
+	if command.ChunkSize <= 0 {
+		return nil, errors.New("input variables are not correct: chunk size must be positive")
+	}
+	batch, err := h.repo.ClaimBatch(ctx, h.claimFor)
+	if err != nil || batch == nil {
+		return nil, err
+	}
+
+	if batch.Mode == entity.BATCH_ALL_OR_NOTHING {
+		batch, err = h.processAllOrNothing(ctx, batch.ID, command.ChunkSize)
+	} else {
+		batch, err = h.processRows(ctx, batch.ID, command.ChunkSize)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to process batch: %w", err)
+	}
+
+	h.logger.Info().Int64("batch", batch.ID).Str("status", batch.Status).Int("succeeded", batch.Succeeded).
+		Int("failed", batch.Failed).Msg("batch processed")
+	return batch, nil
+}
+
+func (h *ProcessBatchCommandHandler) processRows(ctx context.Context, batchID int64, chunkSize int) (*entity.Batch, error) {
+	for {
+		rows, err := h.repo.GetBatchRows(ctx, batchID, entity.BATCH_ROW_PENDING, 0, chunkSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return h.repo.CompleteBatch(ctx, batchID)
+		}
+		for _, row := range rows {
+			_, err := h.repo.ApplyBatchRow(ctx, row, h.debits())
+			if err == nil {
+				continue
+			}
+			if !entity.IsRejection(err) {
+				return nil, err
+			}
+			if err := h.repo.FailBatchRow(ctx, row.ID, err.Error()); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (h *ProcessBatchCommandHandler) processAllOrNothing(ctx context.Context, batchID int64, chunkSize int) (*entity.Batch, error) {
+	stage := func(ctx context.Context, batchID int64, limit int) (int, error) {
+		return h.repo.StageBatchRows(ctx, batchID, limit, h.debits())
+	}
+	if err := h.inChunks(ctx, batchID, chunkSize, stage); err != nil {
+		return nil, err
+	}
+	batch, err := h.repo.GetBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Failed > 0 {
+		if err := h.inChunks(ctx, batchID, chunkSize, h.repo.RollBackBatchRows); err != nil {
+			return nil, err
+		}
+		return h.repo.FailBatch(ctx, batchID)
+	}
+	if err := h.inChunks(ctx, batchID, chunkSize, h.repo.ReleaseBatchRows); err != nil {
+		return nil, err
+	}
+	return h.repo.CompleteBatch(ctx, batchID)
+}
+
+// inChunks runs step until it finds no row left
+func (h *ProcessBatchCommandHandler) inChunks(ctx context.Context, batchID int64, chunkSize int,
+	step func(ctx context.Context, batchID int64, limit int) (int, error)) error {
+	for {
+		processed, err := step(ctx, batchID, chunkSize)
+		if err != nil {
+			return err
+		}
+		if processed == 0 {
+			return nil
+		}
+	}
+}
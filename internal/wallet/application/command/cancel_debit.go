@@ -0,0 +1,47 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+)
+
+type CancelDebitCommand struct {
+	UserId        int64
+	TransactionID *uuid.UUID
+	Actor         string
+}
+
+func (cc *CancelDebitCommand) Err() error {
+	if cc.TransactionID == nil || cc.TransactionID.IsNil() {
+		return errors.New("transaction id cannot be empty")
+	}
+	return nil
+}
+
+type CancelDebitCommandHandler struct {
+	logger logger.Logger
+	repo   repo.WalletWriter
+}
+
+func NewCancelDebitCommandHandler(logger logger.Logger, repo repo.WalletWriter) *CancelDebitCommandHandler {
+	return &CancelDebitCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *CancelDebitCommandHandler) Handle(ctx context.Context, command CancelDebitCommand) error {
+	if err := command.Err(); err != nil {
+		return fmt.Errorf("input variables are not correct: %w", err)
+	}
+	if err := h.repo.CancelDebit(ctx, command.UserId, command.TransactionID); err != nil {
+		return fmt.Errorf("failed to cancel debit: %w", err)
+	}
+	h.logger.Info().Int64("user_id", command.UserId).Str("id", command.TransactionID.String()).
+		Str("actor", command.Actor).Msg("debit cancelled")
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
 	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/clock"
 	"github.com/MaisamV/wallet/platform/logger"
 	"github.com/gofrs/uuid/v5"
 	"time"
@@ -17,15 +19,16 @@ type ChargeCommand struct {
 	ReleaseTime *time.Time
 }
 
-func (cc *ChargeCommand) Err() error {
+// Err validates the command, now is the current time release times are compared with
+func (cc *ChargeCommand) Err(now time.Time) error {
 	if cc.Amount <= 0 {
 		return errors.New("amount cannot be negative or zero")
 	}
 	if cc.Idempotency == nil {
 		return errors.New("idempotency cannot be null")
 	}
-	if cc.ReleaseTime != nil && cc.ReleaseTime.Before(time.Now()) {
-		return errors.New("release time must not be in the past")
+	if cc.ReleaseTime != nil && cc.ReleaseTime.Before(now) {
+		return fmt.Errorf("%w: release time must not be in the past", entity.ErrInvalidReleaseTime)
 	}
 	return nil
 }
@@ -33,17 +36,19 @@ func (cc *ChargeCommand) Err() error {
 type ChargeCommandHandler struct {
 	logger logger.Logger
 	repo   repo.WalletWriter
+	clock  clock.Clock
 }
 
-func NewChargeCommandHandler(logger logger.Logger, repo repo.WalletWriter) *ChargeCommandHandler {
+func NewChargeCommandHandler(logger logger.Logger, repo repo.WalletWriter, clock clock.Clock) *ChargeCommandHandler {
 	return &ChargeCommandHandler{
 		logger: logger,
 		repo:   repo,
+		clock:  clock,
 	}
 }
 
 func (h *ChargeCommandHandler) Handle(ctx context.Context, command ChargeCommand) (*uuid.UUID, error) {
-	if err := command.Err(); err != nil {
+	if err := command.Err(h.clock.Now()); err != nil {
 		return nil, fmt.Errorf("input variables are not correct: %w", err)
 	}
 	txnID, err := h.repo.Charge(ctx, command.UserId, command.Idempotency, command.Amount, command.ReleaseTime)
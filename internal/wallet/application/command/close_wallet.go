@@ -0,0 +1,46 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+)
+
+type CloseWalletCommand struct {
+	UserId int64
+	Reason string
+	Actor  string
+	// PayoutIdempotency requests a final payout of the remaining balance, without it only empty wallets are closed
+	PayoutIdempotency *uuid.UUID
+}
+
+func (cc *CloseWalletCommand) Err() error {
+	return validateAdminAction(cc.Reason, cc.Actor)
+}
+
+type CloseWalletCommandHandler struct {
+	logger logger.Logger
+	repo   repo.WalletWriter
+}
+
+func NewCloseWalletCommandHandler(logger logger.Logger, repo repo.WalletWriter) *CloseWalletCommandHandler {
+	return &CloseWalletCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// Handle closes the wallet and returns the final payout transaction id if one was created
+func (h *CloseWalletCommandHandler) Handle(ctx context.Context, command CloseWalletCommand) (*uuid.UUID, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	payoutID, err := h.repo.CloseWallet(ctx, command.UserId, command.Reason, command.Actor, command.PayoutIdempotency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close wallet: %w", err)
+	}
+	h.logger.Info().Int64("user_id", command.UserId).Str("actor", command.Actor).Bool("final_payout", payoutID != nil).Msg("wallet closed")
+	return payoutID, nil
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
 	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/internal/wallet/ports/service"
+	"github.com/MaisamV/wallet/platform/clock"
 	"github.com/MaisamV/wallet/platform/logger"
 	"github.com/gofrs/uuid/v5"
+	"strings"
 	"time"
 )
 
@@ -15,40 +19,98 @@ type DebitCommand struct {
 	Amount      int64
 	Idempotency *uuid.UUID
 	ReleaseTime *time.Time
+	// DestinationID selects where the amount is paid out to, nil uses the default destination
+	DestinationID *int64
 }
 
-func (cc *DebitCommand) Err() error {
+func (cc *DebitCommand) Err(now time.Time) error {
 	if cc.Amount <= 0 {
 		return errors.New("amount cannot be negative or zero")
 	}
 	if cc.Idempotency == nil {
 		return errors.New("idempotency cannot be null")
 	}
-	if cc.ReleaseTime == nil || cc.ReleaseTime.Before(time.Now()) {
-		return errors.New("release time must not be in the past")
+	if cc.ReleaseTime == nil {
+		return fmt.Errorf("%w: release time cannot be null", entity.ErrInvalidReleaseTime)
+	}
+	if cc.ReleaseTime.Before(now) {
+		return fmt.Errorf("%w: release time must not be in the past", entity.ErrInvalidReleaseTime)
 	}
 	return nil
 }
 
+// DebitResult tells whether the withdrawal goes ahead or waits for review and the fee charged for it
+type DebitResult struct {
+	TransactionID *uuid.UUID
+	Status        entity.Status
+	Fee           int64
+}
+
 type DebitCommandHandler struct {
-	logger logger.Logger
-	repo   repo.WalletWriter
+	logger       logger.Logger
+	repo         repo.WalletWriter
+	destinations repo.DestinationRepo
+	risk         service.RiskEvaluator
+	fees         service.FeeCalculator
+	clock        clock.Clock
 }
 
-func NewDebitCommandHandler(logger logger.Logger, repo repo.WalletWriter) *DebitCommandHandler {
+func NewDebitCommandHandler(logger logger.Logger, repo repo.WalletWriter, destinations repo.DestinationRepo,
+	risk service.RiskEvaluator, fees service.FeeCalculator, clock clock.Clock) *DebitCommandHandler {
 	return &DebitCommandHandler{
-		logger: logger,
-		repo:   repo,
+		logger:       logger,
+		repo:         repo,
+		destinations: destinations,
+		risk:         risk,
+		fees:         fees,
+		clock:        clock,
 	}
 }
 
-func (h *DebitCommandHandler) Handle(ctx context.Context, command DebitCommand) (*uuid.UUID, error) {
-	if err := command.Err(); err != nil {
+// riskScreen hands the risk evaluator to the debit, which runs it once the wallet is locked. Without an
+// evaluator every withdrawal is allowed.
+func riskScreen(risk service.RiskEvaluator, amount int64) *repo.DebitScreen {
+	if risk == nil {
+		return nil
+	}
+	return &repo.DebitScreen{
+		Lookback: risk.Lookback(),
+		Decide: func(profile entity.RiskProfile, now time.Time) entity.RiskAssessment {
+			return risk.Evaluate(profile, amount, now)
+		},
+	}
+}
+
+func (h *DebitCommandHandler) Handle(ctx context.Context, command DebitCommand) (*DebitResult, error) {
+	if err := command.Err(h.clock.Now()); err != nil {
 		return nil, fmt.Errorf("input variables are not correct: %w", err)
 	}
-	txnID, err := h.repo.Debit(ctx, command.UserId, command.Idempotency, command.Amount, command.ReleaseTime)
+
+	destination, err := h.destinations.GetDestination(ctx, command.UserId, command.DestinationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payout destination: %w", err)
+	}
+	if !destination.Verified {
+		return nil, fmt.Errorf("failed to debit wallet: %w", entity.ErrDestinationNotVerified)
+	}
+
+	quote, err := h.fees.Quote(ctx, command.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote withdrawal fee: %w", err)
+	}
+
+	txnID, assessment, err := h.repo.Debit(ctx, command.UserId, command.Idempotency, command.Amount, quote.Fee, command.ReleaseTime, &destination.ID, riskScreen(h.risk, command.Amount))
+	if assessment != nil && assessment.Decision != entity.ALLOW {
+		h.logger.Warn().Int64("user_id", command.UserId).Int64("amount", command.Amount).
+			Str("decision", assessment.Decision).Str("reasons", strings.Join(assessment.Reasons, ",")).
+			Msg("withdrawal flagged by risk screening")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to debit wallet: %w", err)
 	}
-	return txnID, nil
+	status := entity.PENDING
+	if assessment.Decision == entity.REVIEW {
+		status = entity.IN_REVIEW
+	}
+	return &DebitResult{TransactionID: txnID, Status: status, Fee: quote.Fee}, nil
 }
@@ -0,0 +1,114 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+	"strings"
+)
+
+// AddDestinationCommand registers an IBAN (Sheba) or card number a user can withdraw to
+type AddDestinationCommand struct {
+	UserId int64
+	Type   entity.DestinationType
+	Number string
+}
+
+func (ac *AddDestinationCommand) Err() error {
+	return entity.ValidateDestination(ac.Type, entity.NormalizeDestination(ac.Number))
+}
+
+type AddDestinationCommandHandler struct {
+	logger logger.Logger
+	repo   repo.DestinationRepo
+}
+
+func NewAddDestinationCommandHandler(logger logger.Logger, repo repo.DestinationRepo) *AddDestinationCommandHandler {
+	return &AddDestinationCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *AddDestinationCommandHandler) Handle(ctx context.Context, command AddDestinationCommand) (*entity.Destination, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	destination, err := h.repo.AddDestination(ctx, &entity.Destination{
+		UserID: command.UserId,
+		Type:   command.Type,
+		Number: entity.NormalizeDestination(command.Number),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add destination: %w", err)
+	}
+	h.logger.Info().Int64("id", destination.ID).Int64("user_id", command.UserId).Str("type", command.Type).Msg("destination added")
+	return destination, nil
+}
+
+type SetDefaultDestinationCommand struct {
+	UserId        int64
+	DestinationID int64
+}
+
+type SetDefaultDestinationCommandHandler struct {
+	logger logger.Logger
+	repo   repo.DestinationRepo
+}
+
+func NewSetDefaultDestinationCommandHandler(logger logger.Logger, repo repo.DestinationRepo) *SetDefaultDestinationCommandHandler {
+	return &SetDefaultDestinationCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *SetDefaultDestinationCommandHandler) Handle(ctx context.Context, command SetDefaultDestinationCommand) error {
+	if err := h.repo.SetDefaultDestination(ctx, command.UserId, command.DestinationID); err != nil {
+		return fmt.Errorf("failed to set default destination: %w", err)
+	}
+	return nil
+}
+
+// VerifyDestinationCommand confirms a destination belongs to its user, only verified destinations are paid out to
+type VerifyDestinationCommand struct {
+	DestinationID int64
+	Actor         string
+}
+
+func (vc *VerifyDestinationCommand) Err() error {
+	if vc.DestinationID <= 0 {
+		return errors.New("destination id should be positive")
+	}
+	if strings.TrimSpace(vc.Actor) == "" {
+		return errors.New("actor cannot be empty")
+	}
+	return nil
+}
+
+type VerifyDestinationCommandHandler struct {
+	logger logger.Logger
+	repo   repo.DestinationRepo
+}
+
+func NewVerifyDestinationCommandHandler(logger logger.Logger, repo repo.DestinationRepo) *VerifyDestinationCommandHandler {
+	return &VerifyDestinationCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *VerifyDestinationCommandHandler) Handle(ctx context.Context, command VerifyDestinationCommand) (*entity.Destination, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	destination, err := h.repo.VerifyDestination(ctx, command.DestinationID, command.Actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify destination: %w", err)
+	}
+	h.logger.Info().Int64("id", command.DestinationID).Str("actor", command.Actor).Msg("destination verified")
+	return destination, nil
+}
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+	"strings"
+)
+
+type FreezeWalletCommand struct {
+	UserId int64
+	// Status is either entity.FROZEN_DEBIT or entity.FROZEN_ALL
+	Status entity.WalletStatus
+	Reason string
+	Actor  string
+}
+
+func (fc *FreezeWalletCommand) Err() error {
+	if fc.Status != entity.FROZEN_DEBIT && fc.Status != entity.FROZEN_ALL {
+		return fmt.Errorf("%w: status must be frozen_debit or frozen_all", entity.ErrInvalidStatusChange)
+	}
+	return validateAdminAction(fc.Reason, fc.Actor)
+}
+
+type FreezeWalletCommandHandler struct {
+	logger logger.Logger
+	repo   repo.WalletWriter
+}
+
+func NewFreezeWalletCommandHandler(logger logger.Logger, repo repo.WalletWriter) *FreezeWalletCommandHandler {
+	return &FreezeWalletCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *FreezeWalletCommandHandler) Handle(ctx context.Context, command FreezeWalletCommand) error {
+	if err := command.Err(); err != nil {
+		return fmt.Errorf("input variables are not correct: %w", err)
+	}
+	from, err := h.repo.UpdateWalletStatus(ctx, command.UserId, command.Status, command.Reason, command.Actor)
+	if err != nil {
+		return fmt.Errorf("failed to freeze wallet: %w", err)
+	}
+	h.logger.Info().Int64("user_id", command.UserId).Str("from", from).Str("to", command.Status).
+		Str("actor", command.Actor).Msg("wallet frozen")
+	return nil
+}
+
+type UnfreezeWalletCommand struct {
+	UserId int64
+	Reason string
+	Actor  string
+}
+
+func (uc *UnfreezeWalletCommand) Err() error {
+	return validateAdminAction(uc.Reason, uc.Actor)
+}
+
+type UnfreezeWalletCommandHandler struct {
+	logger logger.Logger
+	repo   repo.WalletWriter
+}
+
+func NewUnfreezeWalletCommandHandler(logger logger.Logger, repo repo.WalletWriter) *UnfreezeWalletCommandHandler {
+	return &UnfreezeWalletCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *UnfreezeWalletCommandHandler) Handle(ctx context.Context, command UnfreezeWalletCommand) error {
+	if err := command.Err(); err != nil {
+		return fmt.Errorf("input variables are not correct: %w", err)
+	}
+	from, err := h.repo.UpdateWalletStatus(ctx, command.UserId, entity.ACTIVE, command.Reason, command.Actor)
+	if err != nil {
+		return fmt.Errorf("failed to unfreeze wallet: %w", err)
+	}
+	h.logger.Info().Int64("user_id", command.UserId).Str("from", from).Str("actor", command.Actor).Msg("wallet unfrozen")
+	return nil
+}
+
+// validateAdminAction makes sure every administrative change can be traced back to a person and a reason.
+// The actor comes from the admin authentication, only a missing reason is the fault of the request.
+func validateAdminAction(reason string, actor string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("%w: reason cannot be empty", entity.ErrInvalidStatusChange)
+	}
+	if strings.TrimSpace(actor) == "" {
+		return errors.New("actor cannot be empty")
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package command
+
+import (
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"testing"
+)
+
+func TestFreezeWalletCommand_Err(t *testing.T) {
+	tests := []struct {
+		name    string
+		command FreezeWalletCommand
+		err     error
+	}{
+		{"valid", FreezeWalletCommand{UserId: 1, Status: entity.FROZEN_ALL, Reason: "fraud", Actor: "analyst"}, nil},
+		{"closed status", FreezeWalletCommand{UserId: 1, Status: entity.CLOSED, Reason: "fraud", Actor: "analyst"}, entity.ErrInvalidStatusChange},
+		{"active status", FreezeWalletCommand{UserId: 1, Status: entity.ACTIVE, Reason: "fraud", Actor: "analyst"}, entity.ErrInvalidStatusChange},
+		{"blank reason", FreezeWalletCommand{UserId: 1, Status: entity.FROZEN_DEBIT, Reason: " ", Actor: "analyst"}, entity.ErrInvalidStatusChange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.command.Err(); !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	unfreeze := UnfreezeWalletCommand{UserId: 1, Actor: "analyst"}
+	if err := unfreeze.Err(); !errors.Is(err, entity.ErrInvalidStatusChange) {
+		t.Fatalf("expected an unfreeze without reason to fail, got %v", err)
+	}
+	freeze := FreezeWalletCommand{UserId: 1, Status: entity.FROZEN_ALL, Reason: "fraud"}
+	if err := freeze.Err(); err == nil {
+		t.Fatalf("expected a freeze without actor to fail")
+	}
+}
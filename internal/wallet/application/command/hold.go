@@ -0,0 +1,166 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+type PlaceHoldCommand struct {
+	UserId      int64
+	Amount      int64
+	Idempotency *uuid.UUID
+	Reference   string
+	ExpiresAt   time.Time
+}
+
+func (cc *PlaceHoldCommand) Err(now time.Time) error {
+	if cc.Amount <= 0 {
+		return errors.New("amount cannot be negative or zero")
+	}
+	if cc.Idempotency == nil {
+		return errors.New("idempotency cannot be null")
+	}
+	if len(cc.Reference) > 100 {
+		return errors.New("reference cannot be longer than 100 characters")
+	}
+	if !cc.ExpiresAt.After(now) {
+		return errors.New("expiry must be in the future")
+	}
+	return nil
+}
+
+type PlaceHoldCommandHandler struct {
+	logger logger.Logger
+	repo   repo.HoldRepo
+	clock  clock.Clock
+}
+
+func NewPlaceHoldCommandHandler(logger logger.Logger, repo repo.HoldRepo, clock clock.Clock) *PlaceHoldCommandHandler {
+	return &PlaceHoldCommandHandler{
+		logger: logger,
+		repo:   repo,
+		clock:  clock,
+	}
+}
+
+func (h *PlaceHoldCommandHandler) Handle(ctx context.Context, command PlaceHoldCommand) (*entity.Hold, error) {
+	if err := command.Err(h.clock.Now()); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	hold, err := h.repo.PlaceHold(ctx, command.UserId, command.Idempotency, command.Amount, command.Reference, command.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place hold: %w", err)
+	}
+	return hold, nil
+}
+
+type CaptureHoldCommand struct {
+	UserId int64
+	HoldID *uuid.UUID
+	// Amount is the part of the hold which is debited, the rest goes back to the available balance
+	Amount int64
+}
+
+func (cc *CaptureHoldCommand) Err() error {
+	if cc.HoldID == nil || cc.HoldID.IsNil() {
+		return errors.New("hold id cannot be empty")
+	}
+	if cc.Amount <= 0 {
+		return errors.New("amount cannot be negative or zero")
+	}
+	return nil
+}
+
+type CaptureHoldCommandHandler struct {
+	logger logger.Logger
+	repo   repo.HoldRepo
+}
+
+func NewCaptureHoldCommandHandler(logger logger.Logger, repo repo.HoldRepo) *CaptureHoldCommandHandler {
+	return &CaptureHoldCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *CaptureHoldCommandHandler) Handle(ctx context.Context, command CaptureHoldCommand) (*entity.Hold, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	hold, err := h.repo.CaptureHold(ctx, command.UserId, command.HoldID, command.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture hold: %w", err)
+	}
+	return hold, nil
+}
+
+type VoidHoldCommand struct {
+	UserId int64
+	HoldID *uuid.UUID
+}
+
+func (cc *VoidHoldCommand) Err() error {
+	if cc.HoldID == nil || cc.HoldID.IsNil() {
+		return errors.New("hold id cannot be empty")
+	}
+	return nil
+}
+
+type VoidHoldCommandHandler struct {
+	logger logger.Logger
+	repo   repo.HoldRepo
+}
+
+func NewVoidHoldCommandHandler(logger logger.Logger, repo repo.HoldRepo) *VoidHoldCommandHandler {
+	return &VoidHoldCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *VoidHoldCommandHandler) Handle(ctx context.Context, command VoidHoldCommand) (*entity.Hold, error) {
+	if err := command.Err(); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	hold, err := h.repo.VoidHold(ctx, command.UserId, command.HoldID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to void hold: %w", err)
+	}
+	return hold, nil
+}
+
+type ExpireHoldsCommand struct {
+	BatchSize int
+}
+
+type ExpireHoldsCommandHandler struct {
+	logger logger.Logger
+	repo   repo.HoldRepo
+}
+
+func NewExpireHoldsCommandHandler(logger logger.Logger, repo repo.HoldRepo) *ExpireHoldsCommandHandler {
+	return &ExpireHoldsCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *ExpireHoldsCommandHandler) Handle(ctx context.Context, command ExpireHoldsCommand) (int64, error) {
+	count, err := h.repo.ExpireHolds(ctx, command.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire holds: %w", err)
+	}
+
+	if count > 0 {
+		h.logger.Info().Int64("count", count).Msg("expired holds")
+	}
+
+	return count, nil
+}
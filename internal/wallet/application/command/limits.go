@@ -0,0 +1,110 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type SetDefaultLimitsCommand struct {
+	Limits entity.Limits
+	Actor  string
+}
+
+func (sc *SetDefaultLimitsCommand) Err() error {
+	return validateLimits(sc.Limits)
+}
+
+type SetDefaultLimitsCommandHandler struct {
+	logger logger.Logger
+	repo   repo.LimitsRepo
+}
+
+func NewSetDefaultLimitsCommandHandler(logger logger.Logger, repo repo.LimitsRepo) *SetDefaultLimitsCommandHandler {
+	return &SetDefaultLimitsCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *SetDefaultLimitsCommandHandler) Handle(ctx context.Context, command SetDefaultLimitsCommand) error {
+	if err := command.Err(); err != nil {
+		return fmt.Errorf("input variables are not correct: %w", err)
+	}
+	if err := h.repo.SetDefaultLimits(ctx, command.Limits); err != nil {
+		return fmt.Errorf("failed to set default limits: %w", err)
+	}
+	h.logger.Info().Str("actor", command.Actor).Msg("default limits changed")
+	return nil
+}
+
+type SetUserLimitsCommand struct {
+	UserId int64
+	Limits entity.Limits
+	Actor  string
+}
+
+func (sc *SetUserLimitsCommand) Err() error {
+	return validateLimits(sc.Limits)
+}
+
+type SetUserLimitsCommandHandler struct {
+	logger logger.Logger
+	repo   repo.LimitsRepo
+}
+
+func NewSetUserLimitsCommandHandler(logger logger.Logger, repo repo.LimitsRepo) *SetUserLimitsCommandHandler {
+	return &SetUserLimitsCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *SetUserLimitsCommandHandler) Handle(ctx context.Context, command SetUserLimitsCommand) error {
+	if err := command.Err(); err != nil {
+		return fmt.Errorf("input variables are not correct: %w", err)
+	}
+	if err := h.repo.SetUserLimits(ctx, command.UserId, command.Limits); err != nil {
+		return fmt.Errorf("failed to set user limits: %w", err)
+	}
+	h.logger.Info().Int64("user_id", command.UserId).Str("actor", command.Actor).Msg("user limits changed")
+	return nil
+}
+
+type DeleteUserLimitsCommand struct {
+	UserId int64
+	Actor  string
+}
+
+type DeleteUserLimitsCommandHandler struct {
+	logger logger.Logger
+	repo   repo.LimitsRepo
+}
+
+func NewDeleteUserLimitsCommandHandler(logger logger.Logger, repo repo.LimitsRepo) *DeleteUserLimitsCommandHandler {
+	return &DeleteUserLimitsCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *DeleteUserLimitsCommandHandler) Handle(ctx context.Context, command DeleteUserLimitsCommand) error {
+	if err := h.repo.DeleteUserLimits(ctx, command.UserId); err != nil {
+		return fmt.Errorf("failed to delete user limits: %w", err)
+	}
+	h.logger.Info().Int64("user_id", command.UserId).Str("actor", command.Actor).Msg("user limits removed")
+	return nil
+}
+
+func validateLimits(limits entity.Limits) error {
+	for _, l := range []*int64{limits.MaxSingleWithdrawal, limits.DailyWithdrawalTotal, limits.MonthlyWithdrawalTotal,
+		limits.MaxBalance, limits.HourlyWithdrawalCount} {
+		if l != nil && *l <= 0 {
+			return errors.New("limits must be positive, leave a limit empty to not enforce it")
+		}
+	}
+	return nil
+}
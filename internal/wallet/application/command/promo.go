@@ -0,0 +1,105 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+type GrantPromoCommand struct {
+	UserId      int64
+	Amount      int64
+	Reason      string
+	ExpiresAt   time.Time
+	Idempotency *uuid.UUID
+	Actor       string
+}
+
+func (cc *GrantPromoCommand) Err(now time.Time) error {
+	if cc.Amount <= 0 {
+		return fmt.Errorf("%w: amount cannot be negative or zero", entity.ErrInvalidPromo)
+	}
+	if cc.Reason == "" || len(cc.Reason) > 255 {
+		return fmt.Errorf("%w: reason must be between 1 and 255 characters", entity.ErrInvalidPromo)
+	}
+	if !cc.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: expiry must be in the future", entity.ErrInvalidPromo)
+	}
+	if cc.Idempotency == nil {
+		return errors.New("idempotency cannot be null")
+	}
+	if cc.Actor == "" {
+		return errors.New("actor cannot be empty")
+	}
+	return nil
+}
+
+type GrantPromoCommandHandler struct {
+	logger logger.Logger
+	repo   repo.PromoRepo
+	clock  clock.Clock
+}
+
+func NewGrantPromoCommandHandler(logger logger.Logger, repo repo.PromoRepo, clock clock.Clock) *GrantPromoCommandHandler {
+	return &GrantPromoCommandHandler{
+		logger: logger,
+		repo:   repo,
+		clock:  clock,
+	}
+}
+
+func (h *GrantPromoCommandHandler) Handle(ctx context.Context, command GrantPromoCommand) (*entity.PromoGrant, error) {
+	if err := command.Err(h.clock.Now()); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	grant, err := h.repo.GrantPromo(ctx, &entity.PromoGrant{
+		UserID:      command.UserId,
+		Amount:      command.Amount,
+		Reason:      command.Reason,
+		CreatedBy:   command.Actor,
+		Idempotency: *command.Idempotency,
+		ExpiresAt:   command.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant promo: %w", err)
+	}
+
+	h.logger.Info().Int64("user_id", grant.UserID).Int64("grant", grant.ID).Int64("amount", grant.Amount).
+		Str("actor", command.Actor).Msg("promo granted")
+	return grant, nil
+}
+
+type ExpirePromoGrantsCommand struct {
+	BatchSize int
+}
+
+type ExpirePromoGrantsCommandHandler struct {
+	logger logger.Logger
+	repo   repo.PromoRepo
+}
+
+func NewExpirePromoGrantsCommandHandler(logger logger.Logger, repo repo.PromoRepo) *ExpirePromoGrantsCommandHandler {
+	return &ExpirePromoGrantsCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *ExpirePromoGrantsCommandHandler) Handle(ctx context.Context, command ExpirePromoGrantsCommand) (int64, error) {
+	count, err := h.repo.ExpirePromoGrants(ctx, command.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire promo grants: %w", err)
+	}
+
+	if count > 0 {
+		h.logger.Info().Int64("count", count).Msg("expired promo grants")
+	}
+
+	return count, nil
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+)
+
+// ReinquireCommand pushes a stuck pending withdrawal back to the withdraw job without waiting for the retry backoff
+type ReinquireCommand struct {
+	TransactionID *uuid.UUID
+	Actor         string
+}
+
+func (rc *ReinquireCommand) Err() error {
+	if rc.TransactionID == nil || rc.TransactionID.IsNil() {
+		return errors.New("transaction id cannot be empty")
+	}
+	return nil
+}
+
+type ReinquireCommandHandler struct {
+	logger logger.Logger
+	repo   repo.WalletAdminRepo
+}
+
+func NewReinquireCommandHandler(logger logger.Logger, repo repo.WalletAdminRepo) *ReinquireCommandHandler {
+	return &ReinquireCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *ReinquireCommandHandler) Handle(ctx context.Context, command ReinquireCommand) error {
+	if err := command.Err(); err != nil {
+		return fmt.Errorf("input variables are not correct: %w", err)
+	}
+	if err := h.repo.ReinquireTransaction(ctx, command.TransactionID); err != nil {
+		return fmt.Errorf("failed to reinquire transaction: %w", err)
+	}
+	h.logger.Info().Str("id", command.TransactionID.String()).Str("actor", command.Actor).Msg("withdrawal queued for reinquiry")
+	return nil
+}
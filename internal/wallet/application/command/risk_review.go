@@ -0,0 +1,73 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+	"strings"
+)
+
+// DecideRiskReviewCommand approves or rejects a withdrawal held by risk screening
+type DecideRiskReviewCommand struct {
+	TransactionID *uuid.UUID
+	Actor         string
+}
+
+func (dc *DecideRiskReviewCommand) Err() error {
+	if dc.TransactionID == nil || dc.TransactionID.IsNil() {
+		return errors.New("transaction id cannot be empty")
+	}
+	if strings.TrimSpace(dc.Actor) == "" {
+		return errors.New("actor cannot be empty")
+	}
+	return nil
+}
+
+type ApproveRiskReviewCommandHandler struct {
+	logger logger.Logger
+	repo   repo.RiskRepo
+}
+
+func NewApproveRiskReviewCommandHandler(logger logger.Logger, repo repo.RiskRepo) *ApproveRiskReviewCommandHandler {
+	return &ApproveRiskReviewCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *ApproveRiskReviewCommandHandler) Handle(ctx context.Context, command DecideRiskReviewCommand) error {
+	if err := command.Err(); err != nil {
+		return fmt.Errorf("input variables are not correct: %w", err)
+	}
+	if err := h.repo.ApproveRiskReview(ctx, command.TransactionID, command.Actor); err != nil {
+		return fmt.Errorf("failed to approve withdrawal: %w", err)
+	}
+	h.logger.Info().Str("id", command.TransactionID.String()).Str("actor", command.Actor).Msg("withdrawal approved after review")
+	return nil
+}
+
+type RejectRiskReviewCommandHandler struct {
+	logger logger.Logger
+	repo   repo.RiskRepo
+}
+
+func NewRejectRiskReviewCommandHandler(logger logger.Logger, repo repo.RiskRepo) *RejectRiskReviewCommandHandler {
+	return &RejectRiskReviewCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *RejectRiskReviewCommandHandler) Handle(ctx context.Context, command DecideRiskReviewCommand) error {
+	if err := command.Err(); err != nil {
+		return fmt.Errorf("input variables are not correct: %w", err)
+	}
+	if err := h.repo.RejectRiskReview(ctx, command.TransactionID, command.Actor); err != nil {
+		return fmt.Errorf("failed to reject withdrawal: %w", err)
+	}
+	h.logger.Info().Str("id", command.TransactionID.String()).Str("actor", command.Actor).Msg("withdrawal rejected after review")
+	return nil
+}
@@ -0,0 +1,269 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"sync/atomic"
+	"time"
+)
+
+type CreateScheduleCommand struct {
+	UserId        int64
+	Type          entity.TransactionType
+	Amount        int64
+	Frequency     entity.ScheduleFrequency
+	Interval      int
+	DestinationID *int64
+	StartAt       time.Time
+	EndAt         *time.Time
+}
+
+func (cc *CreateScheduleCommand) schedule() entity.Schedule {
+	return entity.Schedule{
+		UserID:        cc.UserId,
+		Type:          cc.Type,
+		Amount:        cc.Amount,
+		Frequency:     cc.Frequency,
+		Interval:      cc.Interval,
+		DestinationID: cc.DestinationID,
+		StartAt:       cc.StartAt,
+		EndAt:         cc.EndAt,
+	}
+}
+
+func (cc *CreateScheduleCommand) Err(now time.Time) error {
+	if err := cc.schedule().Validate(); err != nil {
+		return err
+	}
+	if cc.StartAt.Before(now.Add(-time.Minute)) {
+		return fmt.Errorf("%w: start time cannot be in the past", entity.ErrInvalidSchedule)
+	}
+	return nil
+}
+
+type CreateScheduleCommandHandler struct {
+	logger logger.Logger
+	repo   repo.ScheduleRepo
+	clock  clock.Clock
+}
+
+func NewCreateScheduleCommandHandler(logger logger.Logger, repo repo.ScheduleRepo, clock clock.Clock) *CreateScheduleCommandHandler {
+	return &CreateScheduleCommandHandler{
+		logger: logger,
+		repo:   repo,
+		clock:  clock,
+	}
+}
+
+func (h *CreateScheduleCommandHandler) Handle(ctx context.Context, command CreateScheduleCommand) (*entity.Schedule, error) {
+	if err := command.Err(h.clock.Now()); err != nil {
+		return nil, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	schedule := command.schedule()
+	created, err := h.repo.CreateSchedule(ctx, &schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schedule: %w", err)
+	}
+	return created, nil
+}
+
+type ChangeScheduleCommand struct {
+	UserId     int64
+	ScheduleID int64
+}
+
+type PauseScheduleCommandHandler struct {
+	logger logger.Logger
+	repo   repo.ScheduleRepo
+}
+
+func NewPauseScheduleCommandHandler(logger logger.Logger, repo repo.ScheduleRepo) *PauseScheduleCommandHandler {
+	return &PauseScheduleCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *PauseScheduleCommandHandler) Handle(ctx context.Context, command ChangeScheduleCommand) (*entity.Schedule, error) {
+	schedule, err := h.repo.PauseSchedule(ctx, command.UserId, command.ScheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pause schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+type ResumeScheduleCommandHandler struct {
+	logger logger.Logger
+	repo   repo.ScheduleRepo
+	clock  clock.Clock
+}
+
+func NewResumeScheduleCommandHandler(logger logger.Logger, repo repo.ScheduleRepo, clock clock.Clock) *ResumeScheduleCommandHandler {
+	return &ResumeScheduleCommandHandler{
+		logger: logger,
+		repo:   repo,
+		clock:  clock,
+	}
+}
+
+// Handle activates a paused schedule from its next occurrence, the occurrences missed while paused are skipped
+func (h *ResumeScheduleCommandHandler) Handle(ctx context.Context, command ChangeScheduleCommand) (*entity.Schedule, error) {
+	schedule, err := h.repo.GetSchedule(ctx, command.UserId, command.ScheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule: %w", err)
+	}
+	if schedule.Status != entity.SCHEDULE_PAUSED {
+		return nil, fmt.Errorf("failed to resume schedule: %w", entity.ErrScheduleStatus)
+	}
+
+	occurrence := schedule.OccurrenceFrom(h.clock.Now())
+	schedule, err = h.repo.ResumeSchedule(ctx, command.UserId, command.ScheduleID, occurrence, schedule.NextRun(occurrence))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+type DeleteScheduleCommandHandler struct {
+	logger logger.Logger
+	repo   repo.ScheduleRepo
+}
+
+func NewDeleteScheduleCommandHandler(logger logger.Logger, repo repo.ScheduleRepo) *DeleteScheduleCommandHandler {
+	return &DeleteScheduleCommandHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *DeleteScheduleCommandHandler) Handle(ctx context.Context, command ChangeScheduleCommand) error {
+	if err := h.repo.DeleteSchedule(ctx, command.UserId, command.ScheduleID); err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	return nil
+}
+
+type RunSchedulesCommand struct {
+	BatchSize int
+}
+
+// RunSchedulesCommandHandler turns the due occurrences of schedules into charges and payouts.
+// Occurrences missed while the scheduler was down are run in order, at most maxCatchUp of a schedule
+// per run, and every occurrence has its own idempotency so running it twice has no effect.
+type RunSchedulesCommandHandler struct {
+	logger      logger.Logger
+	repo        repo.ScheduleRepo
+	charge      *ChargeCommandHandler
+	debit       *DebitCommandHandler
+	maxCatchUp  atomic.Int64
+	payoutDelay time.Duration
+	claimFor    time.Duration
+	clock       clock.Clock
+}
+
+func NewRunSchedulesCommandHandler(logger logger.Logger, repo repo.ScheduleRepo, charge *ChargeCommandHandler,
+	debit *DebitCommandHandler, maxCatchUp int, payoutDelay time.Duration, claimFor time.Duration, clock clock.Clock) *RunSchedulesCommandHandler {
+	h := &RunSchedulesCommandHandler{
+		logger:      logger,
+		repo:        repo,
+		charge:      charge,
+		debit:       debit,
+		payoutDelay: payoutDelay,
+		claimFor:    claimFor,
+		clock:       clock,
+	}
+	h.maxCatchUp.Store(int64(maxCatchUp))
+	return h
+}
+
+// SetMaxCatchUp changes the number of missed occurrences of a schedule run at once from the next run on
+func (h *RunSchedulesCommandHandler) SetMaxCatchUp(maxCatchUp int) {
+	h.maxCatchUp.Store(int64(maxCatchUp))
+}
+
+// Handle runs the due occurrences of at most BatchSize schedules and returns the number of run occurrences
+func (h *RunSchedulesCommandHandler) Handle(ctx context.Context, command RunSchedulesCommand) (int, error) {
+	schedules, err := h.repo.ClaimDueSchedules(ctx, command.BatchSize, h.claimFor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim due schedules: %w", err)
+	}
+
+	total := 0
+	for _, schedule := range schedules {
+		count, err := h.runSchedule(ctx, schedule)
+		total += count
+		if err != nil {
+			h.logger.Error().Err(err).Int64("schedule_id", schedule.ID).Msg("couldn't run schedule")
+		}
+	}
+
+	if total > 0 {
+		h.logger.Info().Int("count", total).Msg("ran scheduled occurrences")
+	}
+	return total, nil
+}
+
+func (h *RunSchedulesCommandHandler) runSchedule(ctx context.Context, schedule entity.Schedule) (int, error) {
+	now := h.clock.Now()
+	occurrence := schedule.Occurrence
+	lastError := schedule.LastError
+	count := 0
+	maxCatchUp := int(h.maxCatchUp.Load())
+	var runErr error
+	for count < maxCatchUp {
+		next := schedule.NextRun(occurrence)
+		if next == nil || next.After(now) {
+			break
+		}
+		err := h.runOccurrence(ctx, schedule, occurrence)
+		if err != nil && !entity.IsRejection(err) {
+			// the occurrence is retried once the claim is over
+			runErr = err
+			break
+		}
+		lastError = ""
+		if err != nil {
+			lastError = err.Error()
+			h.logger.Warn().Err(err).Int64("schedule_id", schedule.ID).Int64("occurrence", occurrence).
+				Msg("scheduled occurrence rejected")
+		}
+		occurrence++
+		count++
+	}
+
+	if err := h.repo.AdvanceSchedule(ctx, schedule.ID, occurrence, schedule.NextRun(occurrence), lastError); err != nil {
+		return count, fmt.Errorf("failed to advance schedule: %w", err)
+	}
+	return count, runErr
+}
+
+func (h *RunSchedulesCommandHandler) runOccurrence(ctx context.Context, schedule entity.Schedule, occurrence int64) error {
+	idempotency := schedule.Idempotency(occurrence)
+	recorded, err := h.repo.OccurrenceRecorded(ctx, &idempotency)
+	if err != nil || recorded {
+		return err
+	}
+
+	if schedule.Type == entity.CREDIT {
+		_, err = h.charge.Handle(ctx, ChargeCommand{
+			UserId:      schedule.UserID,
+			Amount:      schedule.Amount,
+			Idempotency: &idempotency,
+		})
+		return err
+	}
+
+	releaseTime := h.clock.Now().Add(h.payoutDelay)
+	_, err = h.debit.Handle(ctx, DebitCommand{
+		UserId:        schedule.UserID,
+		Amount:        schedule.Amount,
+		Idempotency:   &idempotency,
+		ReleaseTime:   &releaseTime,
+		DestinationID: schedule.DestinationID,
+	})
+	return err
+}
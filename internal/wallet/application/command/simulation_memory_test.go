@@ -0,0 +1,15 @@
+//go:build !integration
+
+package command
+
+import (
+	infrastructure "github.com/MaisamV/wallet/internal/wallet/infrastructure/repo"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/clock"
+	"testing"
+)
+
+// newSimulationRepo returns an empty in-memory repo
+func newSimulationRepo(t *testing.T, clock clock.Clock) repo.WalletRepo {
+	return infrastructure.NewMemoryWalletRepo(clock)
+}
@@ -0,0 +1,38 @@
+//go:build integration
+
+package command
+
+import (
+	"context"
+	"fmt"
+	infrastructure "github.com/MaisamV/wallet/internal/wallet/infrastructure/repo"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/MaisamV/wallet/test/testdb"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"os"
+	"testing"
+)
+
+var db *pgxpool.Pool
+
+// TestMain connects to the test database, so the simulation checks the invariants of the Postgres repo
+// under concurrent debits, cancels, releases and withdrawals
+func TestMain(m *testing.M) {
+	var err error
+	db, err = testdb.Open(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	db.Close()
+	os.Exit(code)
+}
+
+// newSimulationRepo empties the test database and returns the Postgres repo on the clock of the simulation
+func newSimulationRepo(t *testing.T, clock clock.Clock) repo.WalletRepo {
+	testdb.Reset(t, db)
+	return infrastructure.NewPgxWalletRepo(logger.NewNoopLogger(), db, clock)
+}
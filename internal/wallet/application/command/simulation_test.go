@@ -0,0 +1,305 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The simulation runs random sequences of wallet operations against the in-memory repo and checks the money
+// invariants after every step. Time only moves when the simulation advances its clock, so a failing seed
+// replays the same way. The integration build runs the same simulation against Postgres, see newSimulationRepo.
+
+// simBank fails a call with failRate and keeps the amount paid out to every user
+type simBank struct {
+	mu       sync.Mutex
+	rnd      *rand.Rand
+	failRate float64
+	paid     map[int64]int64
+	payouts  map[uuid.UUID]struct{}
+}
+
+func (b *simBank) Withdraw(ctx context.Context, userId int64, idempotency *uuid.UUID, withdrawAmount int64, destination *entity.Destination) (*uuid.UUID, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rnd.Float64() < b.failRate {
+		return nil, errors.New("bank is unavailable")
+	}
+	if _, ok := b.payouts[*idempotency]; ok {
+		return nil, fmt.Errorf("withdrawal %s is paid out twice", idempotency)
+	}
+	b.payouts[*idempotency] = struct{}{}
+	b.paid[userId] += -withdrawAmount
+	id, err := uuid.NewV4()
+	return &id, err
+}
+
+func (b *simBank) setFailRate(rate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failRate = rate
+}
+
+type simulation struct {
+	clock    *clock.Fake
+	bank     *simBank
+	repo     repo.WalletRepo
+	release  *ReleaseCommandHandler
+	withdraw *WithdrawCommandHandler
+	users    int64
+
+	mu     sync.Mutex
+	debits map[int64][]uuid.UUID
+}
+
+func newSimulation(t *testing.T, seed int64, users int64) *simulation {
+	log := logger.NewNoopLogger()
+	fakeClock := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	bank := &simBank{rnd: rand.New(rand.NewSource(seed)), paid: make(map[int64]int64), payouts: make(map[uuid.UUID]struct{})}
+	walletRepo := newSimulationRepo(t, fakeClock)
+	return &simulation{
+		clock:    fakeClock,
+		bank:     bank,
+		repo:     walletRepo,
+		release:  NewReleaseCommandHandler(log, walletRepo),
+		withdraw: NewWithdrawCommandHandler(log, walletRepo, bank, 1),
+		users:    users,
+		debits:   make(map[int64][]uuid.UUID),
+	}
+}
+
+type simOperation struct {
+	name string
+	run  func(s *simulation, ctx context.Context, rnd *rand.Rand, userId int64) error
+}
+
+var simOperations = []simOperation{
+	{"charge", (*simulation).charge},
+	{"debit", (*simulation).debit},
+	{"cancel", (*simulation).cancel},
+	{"release", (*simulation).releaseDue},
+	{"withdraw success", func(s *simulation, ctx context.Context, rnd *rand.Rand, userId int64) error {
+		s.bank.setFailRate(0)
+		return s.payOut(ctx)
+	}},
+	{"withdraw failure", func(s *simulation, ctx context.Context, rnd *rand.Rand, userId int64) error {
+		s.bank.setFailRate(1)
+		return s.payOut(ctx)
+	}},
+}
+
+func (s *simulation) charge(ctx context.Context, rnd *rand.Rand, userId int64) error {
+	key := uuid.Must(uuid.NewV4())
+	var releaseTime *time.Time
+	if rnd.Intn(3) == 0 {
+		at := s.clock.Now().Add(time.Duration(1+rnd.Intn(60)) * time.Minute)
+		releaseTime = &at
+	}
+	_, err := s.repo.Charge(ctx, userId, &key, int64(1+rnd.Intn(1000)), releaseTime)
+	return err
+}
+
+func (s *simulation) debit(ctx context.Context, rnd *rand.Rand, userId int64) error {
+	key := uuid.Must(uuid.NewV4())
+	releaseTime := s.clock.Now().Add(time.Duration(1+rnd.Intn(60)) * time.Minute)
+	risk := repotest.Decide(entity.ALLOW)
+	if rnd.Intn(5) == 0 {
+		risk = repotest.Decide(entity.REVIEW, "simulation")
+	}
+	id, _, err := s.repo.Debit(ctx, userId, &key, int64(1+rnd.Intn(1500)), int64(rnd.Intn(20)), &releaseTime, nil, risk)
+	if errors.Is(err, entity.ErrInsufficientFunds) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.debits[userId] = append(s.debits[userId], *id)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *simulation) cancel(ctx context.Context, rnd *rand.Rand, userId int64) error {
+	s.mu.Lock()
+	debits := s.debits[userId]
+	if len(debits) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	id := debits[rnd.Intn(len(debits))]
+	s.mu.Unlock()
+
+	err := s.repo.CancelDebit(ctx, userId, &id)
+	if errors.Is(err, entity.ErrNotCancellable) {
+		return nil
+	}
+	return err
+}
+
+func (s *simulation) releaseDue(ctx context.Context, rnd *rand.Rand, userId int64) error {
+	s.clock.Advance(time.Duration(rnd.Intn(600)) * time.Second)
+	_, err := s.release.Handle(ctx, ReleaseCommand{BatchSize: 100})
+	return err
+}
+
+// payOut is a single run of the withdraw job, the clock is moved past the claim window first so failed
+// withdrawals are retried
+func (s *simulation) payOut(ctx context.Context) error {
+	s.clock.Advance(31 * time.Second)
+	pending, err := s.repo.GetPendingTransactions(ctx, 100)
+	if err != nil {
+		return err
+	}
+	for _, tx := range pending {
+		s.withdraw.process(ctx, &tx)
+	}
+	return nil
+}
+
+// checkInvariants compares every wallet with its transactions and with the bank. Credits count in the total
+// balance when they are made and in the available balance when they are released, debits the other way around,
+// and failed or cancelled debits do not count at all.
+func (s *simulation) checkInvariants(ctx context.Context) error {
+	for userId := int64(1); userId <= s.users; userId++ {
+		wallet, err := s.repo.GetBalance(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if wallet.AvailableBalance < 0 {
+			return fmt.Errorf("user %d: negative available balance %d", userId, wallet.AvailableBalance)
+		}
+		if wallet.AvailableBalance > wallet.TotalBalance {
+			return fmt.Errorf("user %d: available balance %d is more than the total balance %d", userId, wallet.AvailableBalance, wallet.TotalBalance)
+		}
+
+		var total, available, withdrawn int64
+		var cursor *uuid.UUID
+		for {
+			page, err := s.repo.GetTransactionList(ctx, userId, cursor, 30)
+			if err != nil {
+				return err
+			}
+			for _, t := range page.TransactionList {
+				if t.Status == entity.FAILED || t.Status == entity.CANCELLED {
+					continue
+				}
+				if t.Type == entity.CREDIT {
+					total += t.Amount
+					if t.Released {
+						available += t.Amount
+					}
+					continue
+				}
+				available += t.Amount
+				if t.Released {
+					total += t.Amount
+				}
+				if t.Source == entity.USER_SOURCE && t.Status == entity.SUCCESS {
+					withdrawn -= t.Amount
+				}
+			}
+			if page.Cursor == nil {
+				break
+			}
+			cursor = page.Cursor
+		}
+		if total != wallet.TotalBalance || available != wallet.AvailableBalance {
+			return fmt.Errorf("user %d: transactions sum up to %d/%d, the wallet has %d/%d",
+				userId, total, available, wallet.TotalBalance, wallet.AvailableBalance)
+		}
+
+		s.bank.mu.Lock()
+		paid := s.bank.paid[userId]
+		s.bank.mu.Unlock()
+		if paid != withdrawn {
+			return fmt.Errorf("user %d: the bank paid out %d for %d of successful withdrawals", userId, paid, withdrawn)
+		}
+	}
+	return nil
+}
+
+func TestSimulation_sequential(t *testing.T) {
+	steps := 1000
+	if testing.Short() {
+		steps = 200
+	}
+	ctx := context.Background()
+	for seed := int64(1); seed <= 5; seed++ {
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			s := newSimulation(t, seed, 5)
+			rnd := rand.New(rand.NewSource(seed))
+			for step := 0; step < steps; step++ {
+				op := simOperations[rnd.Intn(len(simOperations))]
+				userId := 1 + rnd.Int63n(s.users)
+				if err := op.run(s, ctx, rnd, userId); err != nil {
+					t.Fatalf("step %d, %s of user %d failed: %v", step, op.name, userId, err)
+				}
+				if err := s.checkInvariants(ctx); err != nil {
+					t.Fatalf("step %d, after %s of user %d: %v", step, op.name, userId, err)
+				}
+			}
+		})
+	}
+}
+
+// TestSimulation_concurrent runs every user in its own goroutine next to the release and withdraw jobs and
+// checks the invariants whenever a round of operations has finished
+func TestSimulation_concurrent(t *testing.T) {
+	rounds := 200
+	if testing.Short() {
+		rounds = 20
+	}
+	ctx := context.Background()
+	const seed = 42
+	s := newSimulation(t, seed, 8)
+	s.bank.setFailRate(0.3)
+	rnds := make([]*rand.Rand, s.users+1)
+	for i := range rnds {
+		rnds[i] = rand.New(rand.NewSource(seed + int64(i)))
+	}
+	userOperations := simOperations[:3]
+
+	for round := 0; round < rounds; round++ {
+		var wg sync.WaitGroup
+		errs := make(chan error, len(rnds))
+		for userId := int64(1); userId <= s.users; userId++ {
+			wg.Add(1)
+			go func(userId int64, rnd *rand.Rand) {
+				defer wg.Done()
+				op := userOperations[rnd.Intn(len(userOperations))]
+				if err := op.run(s, ctx, rnd, userId); err != nil {
+					errs <- fmt.Errorf("%s of user %d failed: %w", op.name, userId, err)
+				}
+			}(userId, rnds[userId])
+		}
+		wg.Add(1)
+		go func(rnd *rand.Rand) {
+			defer wg.Done()
+			if err := s.releaseDue(ctx, rnd, 0); err != nil {
+				errs <- fmt.Errorf("release failed: %w", err)
+				return
+			}
+			if err := s.payOut(ctx); err != nil {
+				errs <- fmt.Errorf("withdraw failed: %w", err)
+			}
+		}(rnds[0])
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("round %d: %v", round, err)
+		}
+		if err := s.checkInvariants(ctx); err != nil {
+			t.Fatalf("round %d: %v", round, err)
+		}
+	}
+}
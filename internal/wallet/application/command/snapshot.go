@@ -0,0 +1,66 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"time"
+)
+
+type SnapshotCommand struct {
+	// Date is the day whose closing balances are recorded, the previous UTC day of the clock when it is zero
+	Date      time.Time
+	BatchSize int
+}
+
+func (sc *SnapshotCommand) Err() error {
+	if sc.BatchSize <= 0 {
+		return errors.New("batch size must be positive")
+	}
+	return nil
+}
+
+type SnapshotCommandHandler struct {
+	logger logger.Logger
+	repo   repo.WalletWriter
+	clock  clock.Clock
+}
+
+func NewSnapshotCommandHandler(logger logger.Logger, repo repo.WalletWriter, clock clock.Clock) *SnapshotCommandHandler {
+	return &SnapshotCommandHandler{
+		logger: logger,
+		repo:   repo,
+		clock:  clock,
+	}
+}
+
+// Handle records a snapshot for every wallet that does not have one for the given date yet
+func (h *SnapshotCommandHandler) Handle(ctx context.Context, command SnapshotCommand) (int64, error) {
+	if err := command.Err(); err != nil {
+		return 0, fmt.Errorf("input variables are not correct: %w", err)
+	}
+	if command.Date.IsZero() {
+		command.Date = h.clock.Now().UTC().AddDate(0, 0, -1)
+	}
+	date := time.Date(command.Date.Year(), command.Date.Month(), command.Date.Day(), 0, 0, 0, 0, time.UTC)
+
+	var total int64
+	for {
+		count, err := h.repo.TakeBalanceSnapshots(ctx, date, command.BatchSize)
+		if err != nil {
+			return total, fmt.Errorf("failed to take balance snapshots: %w", err)
+		}
+		total += count
+		if count < int64(command.BatchSize) {
+			break
+		}
+	}
+
+	if total > 0 {
+		h.logger.Info().Int64("count", total).Str("date", date.Format(time.DateOnly)).Msg("balance snapshots taken")
+	}
+	return total, nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/MaisamV/wallet/internal/wallet/entity"
 	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
 	"github.com/MaisamV/wallet/internal/wallet/ports/service"
+	"github.com/MaisamV/wallet/platform/audit"
 	"github.com/MaisamV/wallet/platform/logger"
 )
 
@@ -49,29 +50,37 @@ func (h *WithdrawCommandHandler) Handle(ctx context.Context, command WithdrawCom
 }
 
 func (h *WithdrawCommandHandler) WorkerLoop() {
-	ctx := context.Background()
+	ctx := audit.NewContext(context.Background(), audit.Info{Actor: "withdraw_worker"})
 	for tx := range h.pendingCh {
-		bankTxUUID, err := h.bankService.Withdraw(ctx, tx.UserID, &tx.Idempotency, tx.Amount)
-		if err != nil {
-			h.logger.Error().Err(err).Msg("error happened while trying to call Bank API")
-			tx.RetryCount++
-			if tx.RetryCount >= 5 {
-				err := h.repo.UpdateTransactionStatus(ctx, &tx.ID, entity.FAILED, nil)
-				if err != nil {
-					h.logger.Error().Err(err).Msg("couldn't update transaction status to failed")
-				}
-			} else {
-				err := h.repo.IncreaseTransactionRetryCount(ctx, &tx.ID)
-				if err != nil {
-					h.logger.Error().Err(err).Msg("couldn't update transaction status to failed")
-				}
+		h.process(ctx, tx)
+	}
+}
+
+// process pays a claimed withdrawal out and records the bank outcome, a withdrawal failing for the fifth time is failed
+func (h *WithdrawCommandHandler) process(ctx context.Context, tx *entity.Transaction) {
+	bankTxUUID, err := h.bankService.Withdraw(ctx, tx.UserID, &tx.Idempotency, tx.Amount, tx.Destination)
+	if recordErr := h.repo.RecordWithdrawAttempt(ctx, &tx.ID, tx.RetryCount+1, bankTxUUID, err); recordErr != nil {
+		h.logger.Error().Err(recordErr).Msg("couldn't record withdraw attempt")
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Msg("error happened while trying to call Bank API")
+		tx.RetryCount++
+		if tx.RetryCount >= 5 {
+			err := h.repo.UpdateTransactionStatus(ctx, &tx.ID, entity.FAILED, nil)
+			if err != nil {
+				h.logger.Error().Err(err).Msg("couldn't update transaction status to failed")
 			}
 		} else {
-			err := h.repo.UpdateTransactionStatus(ctx, &tx.ID, entity.SUCCESS, bankTxUUID)
+			err := h.repo.IncreaseTransactionRetryCount(ctx, &tx.ID)
 			if err != nil {
-				h.logger.Error().Err(err).Msg("couldn't update transaction status to success")
+				h.logger.Error().Err(err).Msg("couldn't update transaction status to failed")
 			}
-			h.logger.Info().Str("id", tx.ID.String()).Msg("successfully withdraw")
 		}
+	} else {
+		err := h.repo.UpdateTransactionStatus(ctx, &tx.ID, entity.SUCCESS, bankTxUUID)
+		if err != nil {
+			h.logger.Error().Err(err).Msg("couldn't update transaction status to success")
+		}
+		h.logger.Info().Str("id", tx.ID.String()).Msg("successfully withdraw")
 	}
 }
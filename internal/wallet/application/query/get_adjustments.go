@@ -0,0 +1,34 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type GetAdjustmentsQuery struct {
+	Status entity.AdjustmentStatus
+	Limit  int
+}
+
+type GetAdjustmentsQueryHandler struct {
+	logger logger.Logger
+	repo   repo.WalletAdminRepo
+}
+
+func NewGetAdjustmentsQueryHandler(logger logger.Logger, repo repo.WalletAdminRepo) *GetAdjustmentsQueryHandler {
+	return &GetAdjustmentsQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetAdjustmentsQueryHandler) Handle(ctx context.Context, query GetAdjustmentsQuery) ([]entity.Adjustment, error) {
+	adjustments, err := h.repo.GetAdjustments(ctx, query.Status, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get adjustments: %w", err)
+	}
+	return adjustments, nil
+}
@@ -0,0 +1,41 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"time"
+)
+
+type GetBalanceAtQuery struct {
+	UserID int64
+	At     time.Time
+}
+
+type GetBalanceAtQueryHandler struct {
+	logger logger.Logger
+	repo   repo.WalletReader
+	clock  clock.Clock
+}
+
+func NewGetBalanceAtQueryHandler(logger logger.Logger, repo repo.WalletReader, clock clock.Clock) *GetBalanceAtQueryHandler {
+	return &GetBalanceAtQueryHandler{
+		logger: logger,
+		repo:   repo,
+		clock:  clock,
+	}
+}
+
+func (h *GetBalanceAtQueryHandler) Handle(ctx context.Context, query GetBalanceAtQuery) (*entity.Wallet, error) {
+	if query.At.After(h.clock.Now()) {
+		return nil, entity.ErrFutureBalance
+	}
+	wallet, err := h.repo.GetBalanceAt(ctx, query.UserID, query.At)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance at %s: %w", query.At.Format(time.RFC3339), err)
+	}
+	return wallet, nil
+}
@@ -0,0 +1,88 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type GetBatchesQuery struct {
+	Limit int
+}
+
+type GetBatchesQueryHandler struct {
+	logger logger.Logger
+	repo   repo.BatchRepo
+}
+
+func NewGetBatchesQueryHandler(logger logger.Logger, repo repo.BatchRepo) *GetBatchesQueryHandler {
+	return &GetBatchesQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetBatchesQueryHandler) Handle(ctx context.Context, query GetBatchesQuery) ([]entity.Batch, error) {
+	batches, err := h.repo.GetBatches(ctx, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batches: %w", err)
+	}
+	return batches, nil
+}
+
+type GetBatchQuery struct {
+	ID int64
+}
+
+type GetBatchQueryHandler struct {
+	logger logger.Logger
+	repo   repo.BatchRepo
+}
+
+func NewGetBatchQueryHandler(logger logger.Logger, repo repo.BatchRepo) *GetBatchQueryHandler {
+	return &GetBatchQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetBatchQueryHandler) Handle(ctx context.Context, query GetBatchQuery) (*entity.Batch, error) {
+	batch, err := h.repo.GetBatch(ctx, query.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	return batch, nil
+}
+
+// GetBatchRowsQuery pages through the per row results of a batch, an empty Status returns every row
+type GetBatchRowsQuery struct {
+	BatchID   int64
+	Status    entity.BatchRowStatus
+	AfterLine int
+	Limit     int
+}
+
+type GetBatchRowsQueryHandler struct {
+	logger logger.Logger
+	repo   repo.BatchRepo
+}
+
+func NewGetBatchRowsQueryHandler(logger logger.Logger, repo repo.BatchRepo) *GetBatchRowsQueryHandler {
+	return &GetBatchRowsQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetBatchRowsQueryHandler) Handle(ctx context.Context, query GetBatchRowsQuery) ([]entity.BatchRow, error) {
+	if _, err := h.repo.GetBatch(ctx, query.BatchID); err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	rows, err := h.repo.GetBatchRows(ctx, query.BatchID, query.Status, query.AfterLine, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch rows: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,33 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type GetDestinationsQuery struct {
+	UserID int64
+}
+
+type GetDestinationsQueryHandler struct {
+	logger logger.Logger
+	repo   repo.DestinationRepo
+}
+
+func NewGetDestinationsQueryHandler(logger logger.Logger, repo repo.DestinationRepo) *GetDestinationsQueryHandler {
+	return &GetDestinationsQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetDestinationsQueryHandler) Handle(ctx context.Context, query GetDestinationsQuery) ([]entity.Destination, error) {
+	destinations, err := h.repo.GetDestinations(ctx, query.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destinations: %w", err)
+	}
+	return destinations, nil
+}
@@ -0,0 +1,39 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+)
+
+type GetHoldQuery struct {
+	UserID int64
+	HoldID *uuid.UUID
+}
+
+type GetHoldQueryHandler struct {
+	logger logger.Logger
+	repo   repo.HoldRepo
+}
+
+func NewGetHoldQueryHandler(logger logger.Logger, repo repo.HoldRepo) *GetHoldQueryHandler {
+	return &GetHoldQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetHoldQueryHandler) Handle(ctx context.Context, query GetHoldQuery) (*entity.Hold, error) {
+	if query.HoldID == nil || query.HoldID.IsNil() {
+		return nil, errors.New("hold id cannot be empty")
+	}
+	hold, err := h.repo.GetHold(ctx, query.UserID, query.HoldID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+	return hold, nil
+}
@@ -0,0 +1,33 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type GetLimitsQuery struct {
+	UserID int64
+}
+
+type GetLimitsQueryHandler struct {
+	logger logger.Logger
+	repo   repo.LimitsRepo
+}
+
+func NewGetLimitsQueryHandler(logger logger.Logger, repo repo.LimitsRepo) *GetLimitsQueryHandler {
+	return &GetLimitsQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetLimitsQueryHandler) Handle(ctx context.Context, query GetLimitsQuery) (*entity.LimitSettings, error) {
+	settings, err := h.repo.GetLimits(ctx, query.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limits: %w", err)
+	}
+	return settings, nil
+}
@@ -0,0 +1,33 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type GetPromoGrantsQuery struct {
+	UserID int64
+}
+
+type GetPromoGrantsQueryHandler struct {
+	logger logger.Logger
+	repo   repo.PromoRepo
+}
+
+func NewGetPromoGrantsQueryHandler(logger logger.Logger, repo repo.PromoRepo) *GetPromoGrantsQueryHandler {
+	return &GetPromoGrantsQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetPromoGrantsQueryHandler) Handle(ctx context.Context, query GetPromoGrantsQuery) ([]entity.PromoGrant, error) {
+	grants, err := h.repo.GetPromoGrants(ctx, query.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promo grants: %w", err)
+	}
+	return grants, nil
+}
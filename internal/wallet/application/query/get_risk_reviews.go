@@ -0,0 +1,33 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type GetRiskReviewsQuery struct {
+	Limit int
+}
+
+type GetRiskReviewsQueryHandler struct {
+	logger logger.Logger
+	repo   repo.RiskRepo
+}
+
+func NewGetRiskReviewsQueryHandler(logger logger.Logger, repo repo.RiskRepo) *GetRiskReviewsQueryHandler {
+	return &GetRiskReviewsQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetRiskReviewsQueryHandler) Handle(ctx context.Context, query GetRiskReviewsQuery) ([]entity.RiskReview, error) {
+	reviews, err := h.repo.GetRiskReviews(ctx, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk reviews: %w", err)
+	}
+	return reviews, nil
+}
@@ -0,0 +1,33 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type GetSchedulesQuery struct {
+	UserID int64
+}
+
+type GetSchedulesQueryHandler struct {
+	logger logger.Logger
+	repo   repo.ScheduleRepo
+}
+
+func NewGetSchedulesQueryHandler(logger logger.Logger, repo repo.ScheduleRepo) *GetSchedulesQueryHandler {
+	return &GetSchedulesQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetSchedulesQueryHandler) Handle(ctx context.Context, query GetSchedulesQuery) ([]entity.Schedule, error) {
+	schedules, err := h.repo.GetSchedules(ctx, query.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+	return schedules, nil
+}
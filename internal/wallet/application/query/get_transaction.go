@@ -0,0 +1,34 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+)
+
+type GetTransactionQuery struct {
+	ID *uuid.UUID
+}
+
+type GetTransactionQueryHandler struct {
+	logger logger.Logger
+	repo   repo.WalletAdminRepo
+}
+
+func NewGetTransactionQueryHandler(logger logger.Logger, repo repo.WalletAdminRepo) *GetTransactionQueryHandler {
+	return &GetTransactionQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *GetTransactionQueryHandler) Handle(ctx context.Context, query GetTransactionQuery) (*entity.TransactionDetails, error) {
+	details, err := h.repo.GetTransaction(ctx, query.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return details, nil
+}
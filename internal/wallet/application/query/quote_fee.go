@@ -0,0 +1,38 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/service"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type QuoteFeeQuery struct {
+	UserID int64
+	Amount int64
+}
+
+type QuoteFeeQueryHandler struct {
+	logger logger.Logger
+	fees   service.FeeCalculator
+}
+
+func NewQuoteFeeQueryHandler(logger logger.Logger, fees service.FeeCalculator) *QuoteFeeQueryHandler {
+	return &QuoteFeeQueryHandler{
+		logger: logger,
+		fees:   fees,
+	}
+}
+
+func (h *QuoteFeeQueryHandler) Handle(ctx context.Context, query QuoteFeeQuery) (*entity.FeeQuote, error) {
+	if query.Amount <= 0 {
+		return nil, errors.New("amount cannot be negative or zero")
+	}
+	quote, err := h.fees.Quote(ctx, query.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote withdrawal fee: %w", err)
+	}
+	return quote, nil
+}
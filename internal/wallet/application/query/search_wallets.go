@@ -0,0 +1,36 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/logger"
+)
+
+type SearchWalletsQuery struct {
+	UserID  *int64
+	Status  entity.WalletStatus
+	AfterID int64
+	Limit   int
+}
+
+type SearchWalletsQueryHandler struct {
+	logger logger.Logger
+	repo   repo.WalletAdminRepo
+}
+
+func NewSearchWalletsQueryHandler(logger logger.Logger, repo repo.WalletAdminRepo) *SearchWalletsQueryHandler {
+	return &SearchWalletsQueryHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (h *SearchWalletsQueryHandler) Handle(ctx context.Context, query SearchWalletsQuery) ([]entity.WalletDetails, error) {
+	wallets, err := h.repo.SearchWallets(ctx, query.UserID, query.Status, query.AfterID, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search wallets: %w", err)
+	}
+	return wallets, nil
+}
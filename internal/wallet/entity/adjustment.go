@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+type ReasonCode = string
+
+const (
+	GOODWILL   ReasonCode = "goodwill"
+	CORRECTION            = "correction"
+	CHARGEBACK            = "chargeback"
+	FEE_REFUND            = "fee_refund"
+	OTHER                 = "other"
+)
+
+// IsValidReasonCode reports whether the code is one of the known adjustment reason codes
+func IsValidReasonCode(code ReasonCode) bool {
+	switch code {
+	case GOODWILL, CORRECTION, CHARGEBACK, FEE_REFUND, OTHER:
+		return true
+	}
+	return false
+}
+
+type AdjustmentStatus = string
+
+const (
+	ADJUSTMENT_PENDING  AdjustmentStatus = "pending"
+	ADJUSTMENT_APPROVED                  = "approved"
+	ADJUSTMENT_REJECTED                  = "rejected"
+)
+
+// Adjustment is a manual credit or debit requested by one operator and approved by another
+type Adjustment struct {
+	ID            int64            `json:"id"`
+	UserID        int64            `json:"user_id"`
+	Type          TransactionType  `json:"type"`
+	Amount        int64            `json:"amount"`
+	ReasonCode    ReasonCode       `json:"reason_code"`
+	Note          string           `json:"note,omitempty"`
+	Status        AdjustmentStatus `json:"status"`
+	Idempotency   uuid.UUID        `json:"idempotency"`
+	RequestedBy   string           `json:"requested_by"`
+	DecidedBy     *string          `json:"decided_by,omitempty"`
+	TransactionID *uuid.UUID       `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	DecidedAt     *time.Time       `json:"decided_at,omitempty"`
+}
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+// WalletDetails is the back office view of a wallet
+type WalletDetails struct {
+	ID               int64        `json:"id"`
+	UserID           int64        `json:"user_id"`
+	TotalBalance     int64        `json:"total_balance"`
+	AvailableBalance int64        `json:"available_balance"`
+	Status           WalletStatus `json:"status"`
+	Quarantined      bool         `json:"quarantined"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+// WithdrawAttempt is a single call to the bank for a withdrawal
+type WithdrawAttempt struct {
+	Attempt        int        `json:"attempt"`
+	Succeeded      bool       `json:"succeeded"`
+	BankResponseID *uuid.UUID `json:"bank_response_id,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// TransactionDetails is the back office view of a transaction including its bank call history
+type TransactionDetails struct {
+	ID             uuid.UUID         `json:"id"`
+	WalletID       int64             `json:"wallet_id"`
+	UserID         int64             `json:"user_id"`
+	Type           TransactionType   `json:"type"`
+	Status         Status            `json:"status"`
+	Source         Source            `json:"source"`
+	Amount         int64             `json:"amount"`
+	Idempotency    uuid.UUID         `json:"idempotency"`
+	ReleaseTime    *time.Time        `json:"release_time,omitempty"`
+	Released       bool              `json:"released"`
+	ReleasedAt     *time.Time        `json:"released_at,omitempty"`
+	RetryCount     int               `json:"retry_count"`
+	LastRetry      *time.Time        `json:"last_retry,omitempty"`
+	SentToBank     bool              `json:"sent_to_bank"`
+	BankResponseID *uuid.UUID        `json:"bank_response_id,omitempty"`
+	RelatedID      *uuid.UUID        `json:"related_transaction_id,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	Attempts       []WithdrawAttempt `json:"attempts"`
+}
@@ -0,0 +1,157 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid/v5"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidBatch    = errors.New("batch is not valid")
+	ErrBatchNotFound   = errors.New("batch not found")
+	ErrIdempotencyUsed = errors.New("idempotency is already used")
+)
+
+type BatchFormat = string
+
+const (
+	BATCH_CSV  BatchFormat = "csv"
+	BATCH_JSON             = "json"
+)
+
+// BatchFormatOf returns the format of a batch file by its extension
+func BatchFormatOf(fileName string) BatchFormat {
+	if strings.HasSuffix(strings.ToLower(fileName), ".json") {
+		return BATCH_JSON
+	}
+	return BATCH_CSV
+}
+
+type BatchMode = string
+
+const (
+	// BATCH_PARTIAL applies every row on its own, rejected rows do not stop the others
+	BATCH_PARTIAL BatchMode = "partial"
+	// BATCH_ALL_OR_NOTHING stages every row before any of them is released, a single rejected row rolls back the batch
+	BATCH_ALL_OR_NOTHING = "all_or_nothing"
+)
+
+type BatchStatus = string
+
+const (
+	BATCH_PENDING    BatchStatus = "pending"
+	BATCH_PROCESSING             = "processing"
+	BATCH_COMPLETED              = "completed"
+	BATCH_FAILED                 = "failed"
+)
+
+type BatchRowStatus = string
+
+const (
+	BATCH_ROW_PENDING BatchRowStatus = "pending"
+	// BATCH_ROW_STAGED is a row of an all or nothing batch waiting for the rest of the batch, a staged credit is in
+	// the total balance only and a staged debit reserves its amount and fee without a release time. It is released
+	// once every row of the batch is staged or rolled back when a row is rejected
+	BATCH_ROW_STAGED      = "staged"
+	BATCH_ROW_SUCCEEDED   = "succeeded"
+	BATCH_ROW_FAILED      = "failed"
+	BATCH_ROW_ROLLED_BACK = "rolled_back"
+)
+
+// Batch is a file of charges and debits uploaded by an operator and applied by the batch worker
+type Batch struct {
+	ID          int64       `json:"id"`
+	FileName    string      `json:"file_name"`
+	Mode        BatchMode   `json:"mode"`
+	Status      BatchStatus `json:"status"`
+	TotalRows   int         `json:"total_rows"`
+	Staged      int         `json:"staged"`
+	Succeeded   int         `json:"succeeded"`
+	Failed      int         `json:"failed"`
+	RolledBack  int         `json:"rolled_back"`
+	Pending     int         `json:"pending"`
+	CreatedBy   string      `json:"created_by"`
+	CreatedAt   time.Time   `json:"created_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+}
+
+// BatchRow is a single charge or debit of a batch, Line is its line in the uploaded file
+type BatchRow struct {
+	ID            int64           `json:"id"`
+	BatchID       int64           `json:"batch_id"`
+	Line          int             `json:"line"`
+	UserID        int64           `json:"user_id"`
+	Type          TransactionType `json:"type"`
+	Amount        int64           `json:"amount"`
+	Idempotency   uuid.UUID       `json:"idempotency"`
+	Status        BatchRowStatus  `json:"status"`
+	Error         string          `json:"error,omitempty"`
+	TransactionID *uuid.UUID      `json:"transaction_id,omitempty"`
+	ProcessedAt   *time.Time      `json:"processed_at,omitempty"`
+}
+
+func (r BatchRow) Validate() error {
+	if r.UserID <= 0 {
+		return errors.New("user id must be positive")
+	}
+	if r.Type != CREDIT && r.Type != DEBIT {
+		return fmt.Errorf("type must be %s or %s", CREDIT, DEBIT)
+	}
+	if r.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if r.Idempotency.IsNil() {
+		return errors.New("idempotency cannot be empty")
+	}
+	return nil
+}
+
+// BatchRowError tells why a line of a batch file is not valid
+type BatchRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// BatchValidationError lists every invalid line of a batch file
+type BatchValidationError struct {
+	Rows []BatchRowError
+}
+
+func (e *BatchValidationError) Error() string {
+	return fmt.Sprintf("%s: %d invalid rows, first on line %d: %s", ErrInvalidBatch, len(e.Rows), e.Rows[0].Line, e.Rows[0].Error)
+}
+
+func (e *BatchValidationError) Unwrap() error {
+	return ErrInvalidBatch
+}
+
+// ValidateBatchRows checks every row of a batch file, rows the reader could not parse carry their error already.
+// An idempotency may only be used once in a file.
+func ValidateBatchRows(rows []BatchRow) error {
+	if len(rows) == 0 {
+		return fmt.Errorf("%w: batch file has no rows", ErrInvalidBatch)
+	}
+	invalid := make([]BatchRowError, 0)
+	lines := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		if row.Error != "" {
+			invalid = append(invalid, BatchRowError{Line: row.Line, Error: row.Error})
+			continue
+		}
+		if err := row.Validate(); err != nil {
+			invalid = append(invalid, BatchRowError{Line: row.Line, Error: err.Error()})
+			continue
+		}
+		if line, ok := lines[row.Idempotency]; ok {
+			invalid = append(invalid, BatchRowError{Line: row.Line, Error: fmt.Sprintf("idempotency is already used on line %d", line)})
+			continue
+		}
+		lines[row.Idempotency] = row.Line
+	}
+	if len(invalid) > 0 {
+		return &BatchValidationError{Rows: invalid}
+	}
+	return nil
+}
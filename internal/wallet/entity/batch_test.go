@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"errors"
+	"github.com/gofrs/uuid/v5"
+	"testing"
+)
+
+func TestValidateBatchRows(t *testing.T) {
+	key := uuid.Must(uuid.NewV4())
+	valid := BatchRow{Line: 2, UserID: 1, Type: CREDIT, Amount: 1000, Idempotency: uuid.Must(uuid.NewV4())}
+	if err := ValidateBatchRows([]BatchRow{valid}); err != nil {
+		t.Fatalf("expected valid rows, got %v", err)
+	}
+
+	rows := []BatchRow{
+		valid,
+		{Line: 3, UserID: 2, Type: CREDIT, Amount: 500, Idempotency: key},
+		{Line: 4, UserID: 3, Type: CREDIT, Amount: 500, Idempotency: key},
+		{Line: 5, UserID: 0, Type: CREDIT, Amount: 500, Idempotency: uuid.Must(uuid.NewV4())},
+		{Line: 6, UserID: 4, Type: "transfer", Amount: 500, Idempotency: uuid.Must(uuid.NewV4())},
+		{Line: 7, UserID: 5, Type: CREDIT, Amount: 0, Idempotency: uuid.Must(uuid.NewV4())},
+		{Line: 8, Error: "amount is not a number"},
+		{Line: 9, UserID: 6, Type: DEBIT, Amount: 500, Idempotency: uuid.Must(uuid.NewV4())},
+	}
+	err := ValidateBatchRows(rows)
+	var invalid *BatchValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidBatch) {
+		t.Fatalf("expected a batch validation error, got %v", err)
+	}
+	lines := make([]int, 0, len(invalid.Rows))
+	for _, row := range invalid.Rows {
+		lines = append(lines, row.Line)
+	}
+	expected := []int{4, 5, 6, 7, 8}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected invalid lines %v, got %v", expected, lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Fatalf("expected invalid lines %v, got %v", expected, lines)
+		}
+	}
+
+	if err := ValidateBatchRows(nil); !errors.Is(err, ErrInvalidBatch) {
+		t.Fatalf("expected an empty batch to be invalid, got %v", err)
+	}
+}
+
+func TestBatchFormatOf(t *testing.T) {
+	if f := BatchFormatOf("payroll.JSON"); f != BATCH_JSON {
+		t.Fatalf("expected json, got %s", f)
+	}
+	if f := BatchFormatOf("cashback.csv"); f != BATCH_CSV {
+		t.Fatalf("expected csv, got %s", f)
+	}
+}
@@ -0,0 +1,106 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidDestination     = errors.New("payout destination is not valid")
+	ErrDestinationNotFound    = errors.New("payout destination not found")
+	ErrDestinationNotVerified = errors.New("payout destination is not verified")
+	ErrDestinationExists      = errors.New("payout destination is already registered")
+)
+
+type DestinationType = string
+
+const (
+	IBAN DestinationType = "iban"
+	CARD                 = "card"
+)
+
+// Destination is a bank account (Sheba) or card number a user withdraws to
+type Destination struct {
+	ID         int64           `json:"id"`
+	UserID     int64           `json:"user_id"`
+	Type       DestinationType `json:"type"`
+	Number     string          `json:"number"`
+	Verified   bool            `json:"verified"`
+	IsDefault  bool            `json:"is_default"`
+	CreatedAt  time.Time       `json:"created_at"`
+	VerifiedAt *time.Time      `json:"verified_at,omitempty"`
+	VerifiedBy *string         `json:"verified_by,omitempty"`
+}
+
+// NormalizeDestination removes the separators users type between digit groups and upper cases the country code
+func NormalizeDestination(number string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(number)))
+}
+
+// ValidateDestination checks the format and checksum of a normalized destination number
+func ValidateDestination(destinationType DestinationType, number string) error {
+	switch destinationType {
+	case IBAN:
+		return validateIranianIBAN(number)
+	case CARD:
+		return validateCardNumber(number)
+	default:
+		return fmt.Errorf("%w: type must be %s or %s", ErrInvalidDestination, IBAN, CARD)
+	}
+}
+
+// validateIranianIBAN validates a Sheba number: IR, two check digits and 22 digits, with the ISO 13616 mod 97 checksum
+func validateIranianIBAN(number string) error {
+	if len(number) != 26 || !strings.HasPrefix(number, "IR") || !isDigits(number[2:]) {
+		return fmt.Errorf("%w: sheba must be IR followed by 24 digits", ErrInvalidDestination)
+	}
+	// the country code moves to the end and its letters become numbers, I = 18 and R = 27
+	rearranged := number[4:] + "1827" + number[2:4]
+	n, _ := new(big.Int).SetString(rearranged, 10)
+	if new(big.Int).Mod(n, big.NewInt(97)).Int64() != 1 {
+		return fmt.Errorf("%w: sheba checksum does not match", ErrInvalidDestination)
+	}
+	return nil
+}
+
+// validateCardNumber validates a 16 digit card number with the Luhn checksum
+func validateCardNumber(number string) error {
+	if len(number) != 16 || !isDigits(number) {
+		return fmt.Errorf("%w: card number must be 16 digits", ErrInvalidDestination)
+	}
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if (len(number)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	if sum%10 != 0 {
+		return fmt.Errorf("%w: card number checksum does not match", ErrInvalidDestination)
+	}
+	return nil
+}
+
+// MaskDestination hides a card number but its last 4 digits, the full number is only read by the withdraw job
+func MaskDestination(destinationType DestinationType, number string) string {
+	if destinationType != CARD || len(number) <= 4 {
+		return number
+	}
+	return strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
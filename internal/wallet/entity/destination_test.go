@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateDestination(t *testing.T) {
+	cases := []struct {
+		name   string
+		typ    DestinationType
+		number string
+		valid  bool
+	}{
+		{name: "valid sheba", typ: IBAN, number: "IR110620000000123456789012", valid: true},
+		{name: "sheba with wrong check digits", typ: IBAN, number: "IR120620000000123456789012"},
+		{name: "sheba of another country", typ: IBAN, number: "DE110620000000123456789012"},
+		{name: "short sheba", typ: IBAN, number: "IR1106200000001234567890"},
+		{name: "valid card", typ: CARD, number: "6037991234567893", valid: true},
+		{name: "card with wrong check digit", typ: CARD, number: "6037991234567890"},
+		{name: "card with letters", typ: CARD, number: "603799123456789A"},
+		{name: "unknown type", typ: "wallet", number: "6037991234567893"},
+	}
+	for _, c := range cases {
+		err := ValidateDestination(c.typ, c.number)
+		if c.valid && err != nil {
+			t.Fatalf("%s: expected valid, got %v", c.name, err)
+		}
+		if !c.valid && !errors.Is(err, ErrInvalidDestination) {
+			t.Fatalf("%s: expected invalid destination, got %v", c.name, err)
+		}
+	}
+}
+
+func TestMaskDestination(t *testing.T) {
+	if n := MaskDestination(CARD, "6037991234567893"); n != "************7893" {
+		t.Fatalf("unexpected masked card %q", n)
+	}
+	if n := MaskDestination(IBAN, "IR110620000000123456789012"); n != "IR110620000000123456789012" {
+		t.Fatalf("expected a sheba not to be masked, got %q", n)
+	}
+}
+
+func TestNormalizeDestination(t *testing.T) {
+	if n := NormalizeDestination(" ir11 0620-0000 0012 3456 7890 12 "); n != "IR110620000000123456789012" {
+		t.Fatalf("unexpected normalized sheba %q", n)
+	}
+	if n := NormalizeDestination("6037-9912-3456-7893"); n != "6037991234567893" {
+		t.Fatalf("unexpected normalized card %q", n)
+	}
+}
@@ -0,0 +1,40 @@
+package entity
+
+import "errors"
+
+var (
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrWalletFrozen        = errors.New("wallet is frozen")
+	ErrWalletClosed        = errors.New("wallet is closed")
+	ErrWalletQuarantined   = errors.New("wallet is quarantined")
+	ErrWalletNotEmpty      = errors.New("wallet balance is not zero")
+	ErrPendingFunds        = errors.New("wallet has unreleased transactions")
+	ErrInsufficientFunds   = errors.New("insufficient available balance")
+	ErrInvalidTransition   = errors.New("wallet status transition is not allowed")
+	ErrInvalidStatusChange = errors.New("wallet status change is not valid")
+	ErrFutureBalance       = errors.New("point in time cannot be in the future")
+	ErrInvalidReleaseTime  = errors.New("release time is not valid")
+
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrNotCancellable      = errors.New("only pending debits which are neither released nor sent to the bank can be cancelled")
+	ErrNotPending          = errors.New("transaction is not a pending withdrawal sent to the bank")
+
+	ErrAdjustmentNotFound = errors.New("adjustment not found")
+	ErrAdjustmentDecided  = errors.New("adjustment is already approved or rejected")
+	ErrSelfApproval       = errors.New("adjustments must be approved by someone other than the requester")
+)
+
+// IsRejection tells whether the operation was refused by the state, limits or risk rules of the wallet,
+// running it again gives the same result
+func IsRejection(err error) bool {
+	return errors.Is(err, ErrWalletNotFound) ||
+		errors.Is(err, ErrInsufficientFunds) ||
+		errors.Is(err, ErrWalletFrozen) ||
+		errors.Is(err, ErrWalletClosed) ||
+		errors.Is(err, ErrWalletQuarantined) ||
+		errors.Is(err, ErrLimitExceeded) ||
+		errors.Is(err, ErrRiskDenied) ||
+		errors.Is(err, ErrDestinationNotFound) ||
+		errors.Is(err, ErrDestinationNotVerified) ||
+		errors.Is(err, ErrIdempotencyUsed)
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FeeTier applies to withdrawals up to UpTo, the last tier may leave UpTo zero to cover every larger amount
+type FeeTier struct {
+	UpTo    int64
+	Flat    int64
+	RateBps int64
+}
+
+// FeeSchedule computes the withdrawal fee of a currency. The fee is Flat plus RateBps basis points of the
+// amount, taken from the first tier covering the amount when tiers are set, and kept between Min and Max.
+// A zero Max does not cap the fee.
+type FeeSchedule struct {
+	Currency string
+	Flat     int64
+	RateBps  int64
+	Tiers    []FeeTier
+	Min      int64
+	Max      int64
+}
+
+func (s FeeSchedule) Validate() error {
+	if s.Currency == "" {
+		return errors.New("fee schedule currency cannot be empty")
+	}
+	if s.Flat < 0 || s.RateBps < 0 || s.Min < 0 || s.Max < 0 {
+		return fmt.Errorf("fee schedule %s: fees cannot be negative", s.Currency)
+	}
+	if s.Max > 0 && s.Min > s.Max {
+		return fmt.Errorf("fee schedule %s: min cannot be more than max", s.Currency)
+	}
+	var previous int64
+	for i, t := range s.Tiers {
+		if t.Flat < 0 || t.RateBps < 0 {
+			return fmt.Errorf("fee schedule %s: fees cannot be negative", s.Currency)
+		}
+		if t.UpTo == 0 && i != len(s.Tiers)-1 {
+			return fmt.Errorf("fee schedule %s: only the last tier can be unbounded", s.Currency)
+		}
+		if t.UpTo != 0 && t.UpTo <= previous {
+			return fmt.Errorf("fee schedule %s: tiers must be in ascending order", s.Currency)
+		}
+		previous = t.UpTo
+	}
+	return nil
+}
+
+// Compute returns the fee of withdrawing amount
+func (s FeeSchedule) Compute(amount int64) int64 {
+	flat, rate := s.Flat, s.RateBps
+	for _, t := range s.Tiers {
+		if t.UpTo == 0 || amount <= t.UpTo {
+			flat, rate = t.Flat, t.RateBps
+			break
+		}
+	}
+
+	fee := flat + percentage(amount, rate)
+	if fee < s.Min {
+		fee = s.Min
+	}
+	if s.Max > 0 && fee > s.Max {
+		fee = s.Max
+	}
+	return fee
+}
+
+// percentage returns bps basis points of amount rounded up, without overflowing for large amounts
+func percentage(amount int64, bps int64) int64 {
+	return amount/10000*bps + (amount%10000*bps+9999)/10000
+}
+
+// FeeQuote is what a withdrawal of Amount costs the wallet in total
+type FeeQuote struct {
+	Amount   int64  `json:"amount"`
+	Fee      int64  `json:"fee"`
+	Total    int64  `json:"total"`
+	Currency string `json:"currency"`
+}
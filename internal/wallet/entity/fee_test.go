@@ -0,0 +1,75 @@
+package entity
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFeeScheduleCompute(t *testing.T) {
+	cases := []struct {
+		name     string
+		schedule FeeSchedule
+		amount   int64
+		fee      int64
+	}{
+		{name: "free", schedule: FeeSchedule{Currency: "IRR"}, amount: 1000},
+		{name: "flat", schedule: FeeSchedule{Currency: "IRR", Flat: 500}, amount: 1000, fee: 500},
+		{name: "percentage", schedule: FeeSchedule{Currency: "IRR", RateBps: 150}, amount: 10000, fee: 150},
+		{name: "percentage rounds up", schedule: FeeSchedule{Currency: "IRR", RateBps: 1}, amount: 10001, fee: 2},
+		{name: "flat and percentage", schedule: FeeSchedule{Currency: "IRR", Flat: 100, RateBps: 100}, amount: 10000, fee: 200},
+		{name: "min", schedule: FeeSchedule{Currency: "IRR", RateBps: 10, Min: 50}, amount: 10000, fee: 50},
+		{name: "max", schedule: FeeSchedule{Currency: "IRR", RateBps: 100, Max: 50}, amount: 10000, fee: 50},
+		{name: "large amount", schedule: FeeSchedule{Currency: "IRR", RateBps: 10000}, amount: math.MaxInt64 / 2, fee: math.MaxInt64 / 2},
+	}
+	for _, c := range cases {
+		if fee := c.schedule.Compute(c.amount); fee != c.fee {
+			t.Fatalf("%s: expected fee %d, got %d", c.name, c.fee, fee)
+		}
+	}
+}
+
+func TestFeeScheduleTiers(t *testing.T) {
+	schedule := FeeSchedule{
+		Currency: "IRR",
+		Flat:     1,
+		Tiers: []FeeTier{
+			{UpTo: 1000, Flat: 10},
+			{UpTo: 100000, RateBps: 100},
+		},
+	}
+	if err := schedule.Validate(); err != nil {
+		t.Fatalf("expected valid schedule, got %v", err)
+	}
+	if fee := schedule.Compute(1000); fee != 10 {
+		t.Fatalf("expected first tier fee 10, got %d", fee)
+	}
+	if fee := schedule.Compute(50000); fee != 500 {
+		t.Fatalf("expected second tier fee 500, got %d", fee)
+	}
+	if fee := schedule.Compute(100001); fee != 1 {
+		t.Fatalf("expected base fee above the tiers, got %d", fee)
+	}
+
+	schedule.Tiers = append(schedule.Tiers, FeeTier{RateBps: 50})
+	if fee := schedule.Compute(200000); fee != 1000 {
+		t.Fatalf("expected unbounded tier fee 1000, got %d", fee)
+	}
+}
+
+func TestFeeScheduleValidate(t *testing.T) {
+	cases := []struct {
+		name     string
+		schedule FeeSchedule
+	}{
+		{name: "no currency", schedule: FeeSchedule{Flat: 1}},
+		{name: "negative rate", schedule: FeeSchedule{Currency: "IRR", RateBps: -1}},
+		{name: "min over max", schedule: FeeSchedule{Currency: "IRR", Min: 10, Max: 5}},
+		{name: "unbounded tier before the last", schedule: FeeSchedule{Currency: "IRR", Tiers: []FeeTier{{}, {UpTo: 10}}}},
+		{name: "tiers out of order", schedule: FeeSchedule{Currency: "IRR", Tiers: []FeeTier{{UpTo: 10}, {UpTo: 5}}}},
+	}
+	for _, c := range cases {
+		if err := c.schedule.Validate(); err == nil {
+			t.Fatalf("%s: expected error", c.name)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"errors"
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+var (
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotActive      = errors.New("hold is already captured, voided or expired")
+	ErrCaptureExceedsHold = errors.New("capture amount is more than the held amount")
+)
+
+type HoldStatus = string
+
+const (
+	HOLD_ACTIVE   HoldStatus = "active"
+	HOLD_CAPTURED            = "captured"
+	HOLD_VOIDED              = "voided"
+	HOLD_EXPIRED             = "expired"
+)
+
+// Hold reserves part of the available balance until it is captured, voided or expires.
+// Capturing debits the captured amount and gives the rest of the hold back to the available balance.
+type Hold struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         int64      `json:"user_id"`
+	Amount         int64      `json:"amount"`
+	CapturedAmount int64      `json:"captured_amount"`
+	Status         HoldStatus `json:"status"`
+	Reference      string     `json:"reference,omitempty"`
+	Idempotency    uuid.UUID  `json:"idempotency"`
+	// PromoAmount is the promotional credit reserved by the hold, it is spent before the reserved cash
+	PromoAmount int64      `json:"promo_amount"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	// TransactionID is the debit recording the captured amount
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty"`
+}
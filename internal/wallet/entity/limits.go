@@ -0,0 +1,114 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLimitExceeded is wrapped by every LimitExceededError
+var ErrLimitExceeded = errors.New("transaction limit exceeded")
+
+type LimitType = string
+
+const (
+	MAX_SINGLE_WITHDRAWAL    LimitType = "max_single_withdrawal"
+	DAILY_WITHDRAWAL_TOTAL             = "daily_withdrawal_total"
+	MONTHLY_WITHDRAWAL_TOTAL           = "monthly_withdrawal_total"
+	MAX_BALANCE                        = "max_balance"
+	HOURLY_WITHDRAWAL_COUNT            = "hourly_withdrawal_count"
+)
+
+// LimitExceededError tells which limit rejected an operation
+type LimitExceededError struct {
+	Limit     LimitType
+	Max       int64
+	Used      int64
+	Requested int64
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s limit exceeded: max %d, used %d, requested %d", e.Limit, e.Max, e.Used, e.Requested)
+}
+
+func (e *LimitExceededError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// Limits holds the regulatory limits of a wallet. A nil limit is not enforced.
+type Limits struct {
+	MaxSingleWithdrawal    *int64 `json:"max_single_withdrawal"`
+	DailyWithdrawalTotal   *int64 `json:"daily_withdrawal_total"`
+	MonthlyWithdrawalTotal *int64 `json:"monthly_withdrawal_total"`
+	MaxBalance             *int64 `json:"max_balance"`
+	HourlyWithdrawalCount  *int64 `json:"hourly_withdrawal_count"`
+}
+
+// HasVelocityLimits reports whether checking a debit needs the withdrawal history of the wallet
+func (l Limits) HasVelocityLimits() bool {
+	return l.DailyWithdrawalTotal != nil || l.MonthlyWithdrawalTotal != nil || l.HourlyWithdrawalCount != nil
+}
+
+// WithdrawalUsage is what a wallet has already withdrawn in the windows the velocity limits apply to
+type WithdrawalUsage struct {
+	Today         int64
+	ThisMonth     int64
+	LastHourCount int64
+}
+
+// CheckDebit validates a withdrawal of amount against the limits
+func (l Limits) CheckDebit(usage WithdrawalUsage, amount int64) error {
+	if l.MaxSingleWithdrawal != nil && amount > *l.MaxSingleWithdrawal {
+		return &LimitExceededError{Limit: MAX_SINGLE_WITHDRAWAL, Max: *l.MaxSingleWithdrawal, Requested: amount}
+	}
+	if l.DailyWithdrawalTotal != nil && usage.Today+amount > *l.DailyWithdrawalTotal {
+		return &LimitExceededError{Limit: DAILY_WITHDRAWAL_TOTAL, Max: *l.DailyWithdrawalTotal, Used: usage.Today, Requested: amount}
+	}
+	if l.MonthlyWithdrawalTotal != nil && usage.ThisMonth+amount > *l.MonthlyWithdrawalTotal {
+		return &LimitExceededError{Limit: MONTHLY_WITHDRAWAL_TOTAL, Max: *l.MonthlyWithdrawalTotal, Used: usage.ThisMonth, Requested: amount}
+	}
+	if l.HourlyWithdrawalCount != nil && usage.LastHourCount+1 > *l.HourlyWithdrawalCount {
+		return &LimitExceededError{Limit: HOURLY_WITHDRAWAL_COUNT, Max: *l.HourlyWithdrawalCount, Used: usage.LastHourCount, Requested: 1}
+	}
+	return nil
+}
+
+// CheckCharge validates a credit of amount to a wallet whose total balance is balance
+func (l Limits) CheckCharge(balance int64, amount int64) error {
+	if l.MaxBalance != nil && balance+amount > *l.MaxBalance {
+		return &LimitExceededError{Limit: MAX_BALANCE, Max: *l.MaxBalance, Used: balance, Requested: amount}
+	}
+	return nil
+}
+
+// LimitSettings shows how the effective limits of a user are built from the defaults and the user's override
+type LimitSettings struct {
+	UserID    int64   `json:"user_id"`
+	Defaults  Limits  `json:"defaults"`
+	Override  *Limits `json:"override,omitempty"`
+	Effective Limits  `json:"effective"`
+}
+
+// Merge returns the limits of override, falling back to l for the ones override does not set.
+// An override can tighten or loosen a default but can not remove it.
+func (l Limits) Merge(override *Limits) Limits {
+	if override == nil {
+		return l
+	}
+	merged := l
+	if override.MaxSingleWithdrawal != nil {
+		merged.MaxSingleWithdrawal = override.MaxSingleWithdrawal
+	}
+	if override.DailyWithdrawalTotal != nil {
+		merged.DailyWithdrawalTotal = override.DailyWithdrawalTotal
+	}
+	if override.MonthlyWithdrawalTotal != nil {
+		merged.MonthlyWithdrawalTotal = override.MonthlyWithdrawalTotal
+	}
+	if override.MaxBalance != nil {
+		merged.MaxBalance = override.MaxBalance
+	}
+	if override.HourlyWithdrawalCount != nil {
+		merged.HourlyWithdrawalCount = override.HourlyWithdrawalCount
+	}
+	return merged
+}
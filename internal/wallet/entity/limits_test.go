@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func limit(v int64) *int64 {
+	return &v
+}
+
+func TestLimitsCheckDebit(t *testing.T) {
+	limits := Limits{
+		MaxSingleWithdrawal:    limit(100),
+		DailyWithdrawalTotal:   limit(250),
+		MonthlyWithdrawalTotal: limit(1000),
+		HourlyWithdrawalCount:  limit(3),
+	}
+
+	cases := []struct {
+		name   string
+		usage  WithdrawalUsage
+		amount int64
+		limit  LimitType
+	}{
+		{name: "within limits", usage: WithdrawalUsage{Today: 150, ThisMonth: 900, LastHourCount: 2}, amount: 100},
+		{name: "single withdrawal", amount: 101, limit: MAX_SINGLE_WITHDRAWAL},
+		{name: "daily total", usage: WithdrawalUsage{Today: 200, ThisMonth: 200}, amount: 51, limit: DAILY_WITHDRAWAL_TOTAL},
+		{name: "monthly total", usage: WithdrawalUsage{ThisMonth: 950}, amount: 51, limit: MONTHLY_WITHDRAWAL_TOTAL},
+		{name: "hourly count", usage: WithdrawalUsage{LastHourCount: 3}, amount: 1, limit: HOURLY_WITHDRAWAL_COUNT},
+	}
+	for _, c := range cases {
+		err := limits.CheckDebit(c.usage, c.amount)
+		if c.limit == "" {
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", c.name, err)
+			}
+			continue
+		}
+		var limitErr *LimitExceededError
+		if !errors.As(err, &limitErr) || limitErr.Limit != c.limit {
+			t.Fatalf("%s: expected %s to be exceeded, got %v", c.name, c.limit, err)
+		}
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("%s: expected error to wrap ErrLimitExceeded", c.name)
+		}
+	}
+
+	if err := (Limits{}).CheckDebit(WithdrawalUsage{Today: 1 << 40}, 1<<40); err != nil {
+		t.Fatalf("expected unset limits not to be enforced, got %v", err)
+	}
+}
+
+func TestLimitsCheckCharge(t *testing.T) {
+	limits := Limits{MaxBalance: limit(500)}
+	if err := limits.CheckCharge(400, 100); err != nil {
+		t.Fatalf("expected charge up to the max balance to pass, got %v", err)
+	}
+	if err := limits.CheckCharge(400, 101); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected max balance to be exceeded, got %v", err)
+	}
+}
+
+func TestLimitsMerge(t *testing.T) {
+	defaults := Limits{MaxSingleWithdrawal: limit(100), MaxBalance: limit(1000)}
+	merged := defaults.Merge(&Limits{MaxSingleWithdrawal: limit(500), HourlyWithdrawalCount: limit(2)})
+
+	if *merged.MaxSingleWithdrawal != 500 {
+		t.Fatalf("expected override to replace the default, got %d", *merged.MaxSingleWithdrawal)
+	}
+	if *merged.MaxBalance != 1000 {
+		t.Fatalf("expected default to apply when not overridden, got %d", *merged.MaxBalance)
+	}
+	if *merged.HourlyWithdrawalCount != 2 {
+		t.Fatalf("expected override to add a limit, got %d", *merged.HourlyWithdrawalCount)
+	}
+	if merged.DailyWithdrawalTotal != nil {
+		t.Fatalf("expected limit set by neither to stay unset")
+	}
+	if defaults.Merge(nil) != defaults {
+		t.Fatalf("expected no override to keep the defaults")
+	}
+}
@@ -0,0 +1,86 @@
+package entity
+
+import (
+	"errors"
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+var ErrInvalidPromo = errors.New("promo grant is not valid")
+
+type PromoStatus = string
+
+const (
+	PROMO_ACTIVE PromoStatus = "active"
+	// PROMO_SPENT grants were used up by purchases before their expiry
+	PROMO_SPENT   = "spent"
+	PROMO_EXPIRED = "expired"
+)
+
+// PromoGrant is a bucket of promotional credit given by marketing. Promotional credit is spent before cash
+// by purchases, is never withdrawn and whatever is left of it is taken back once the grant expires.
+type PromoGrant struct {
+	ID        int64       `json:"id"`
+	UserID    int64       `json:"user_id"`
+	Amount    int64       `json:"amount"`
+	Remaining int64       `json:"remaining"`
+	Expired   int64       `json:"expired"`
+	Status    PromoStatus `json:"status"`
+	Reason    string      `json:"reason"`
+	CreatedBy string      `json:"created_by"`
+	// TransactionID is the credit which added the grant to the balance
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty"`
+	Idempotency   uuid.UUID  `json:"idempotency"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
+
+// PromoSpend is the part of a purchase paid by a single grant
+type PromoSpend struct {
+	GrantID int64
+	Amount  int64
+}
+
+// SpendPromo pays amount from the grants in the order they are given, which must be the soonest expiring first,
+// and returns what each grant pays. Whatever the grants can not pay is left to cash.
+func SpendPromo(grants []PromoGrant, amount int64) []PromoSpend {
+	spends := make([]PromoSpend, 0)
+	for _, grant := range grants {
+		if amount <= 0 {
+			break
+		}
+		spend := min(grant.Remaining, amount)
+		if spend <= 0 {
+			continue
+		}
+		spends = append(spends, PromoSpend{GrantID: grant.ID, Amount: spend})
+		amount -= spend
+	}
+	return spends
+}
+
+// BalanceBuckets splits the balance of a wallet into cash and promotional credit
+type BalanceBuckets struct {
+	Cash  int64 `json:"cash"`
+	Promo int64 `json:"promo"`
+	// Withdrawable is the available cash, promotional credit and funds reserved by holds are left out
+	Withdrawable int64        `json:"withdrawable"`
+	PromoGrants  []PromoGrant `json:"promo_grants"`
+}
+
+// NewBalanceBuckets splits the balances of a wallet, promoReserved is the promotional credit reserved by active holds
+func NewBalanceBuckets(totalBalance int64, availableBalance int64, promo int64, promoReserved int64, grants []PromoGrant) *BalanceBuckets {
+	return &BalanceBuckets{
+		Cash:         totalBalance - promo,
+		Promo:        promo,
+		Withdrawable: WithdrawableBalance(availableBalance, promo, promoReserved),
+		PromoGrants:  grants,
+	}
+}
+
+// WithdrawableBalance is the part of the available balance which is cash. Holds reserve promotional
+// credit before cash, so the promotional credit reserved by holds is not part of the available balance.
+func WithdrawableBalance(availableBalance int64, promo int64, promoReserved int64) int64 {
+	return max(availableBalance-(promo-promoReserved), 0)
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSpendPromo(t *testing.T) {
+	grants := []PromoGrant{
+		{ID: 1, Remaining: 30},
+		{ID: 2, Remaining: 0},
+		{ID: 3, Remaining: 50},
+	}
+	cases := []struct {
+		name   string
+		amount int64
+		spends []PromoSpend
+	}{
+		{name: "soonest expiring grant first", amount: 20, spends: []PromoSpend{{GrantID: 1, Amount: 20}}},
+		{name: "spans grants and skips empty ones", amount: 60, spends: []PromoSpend{{GrantID: 1, Amount: 30}, {GrantID: 3, Amount: 30}}},
+		{name: "rest is left to cash", amount: 100, spends: []PromoSpend{{GrantID: 1, Amount: 30}, {GrantID: 3, Amount: 50}}},
+		{name: "nothing to spend", amount: 0, spends: []PromoSpend{}},
+	}
+	for _, c := range cases {
+		if spends := SpendPromo(grants, c.amount); !reflect.DeepEqual(spends, c.spends) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.spends, spends)
+		}
+	}
+}
+
+func TestNewBalanceBuckets(t *testing.T) {
+	// 100 cash and 50 promo, a hold of 70 reserved the whole promo and 20 cash
+	buckets := NewBalanceBuckets(150, 80, 50, 50, nil)
+	if buckets.Cash != 100 || buckets.Promo != 50 || buckets.Withdrawable != 80 {
+		t.Fatalf("unexpected buckets %+v", buckets)
+	}
+
+	// nothing reserved, the promo is part of the available balance but can not be withdrawn
+	buckets = NewBalanceBuckets(150, 150, 50, 0, nil)
+	if buckets.Withdrawable != 100 {
+		t.Fatalf("expected 100 withdrawable, got %d", buckets.Withdrawable)
+	}
+
+	// pending credits are not available yet
+	if w := WithdrawableBalance(20, 50, 0); w != 0 {
+		t.Fatalf("expected nothing withdrawable, got %d", w)
+	}
+}
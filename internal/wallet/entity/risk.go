@@ -0,0 +1,146 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+var (
+	ErrRiskDenied  = errors.New("withdrawal was denied by risk screening")
+	ErrNotInReview = errors.New("transaction is not waiting for risk review")
+)
+
+type RiskDecision = string
+
+const (
+	ALLOW  RiskDecision = "allow"
+	REVIEW              = "review"
+	DENY                = "deny"
+)
+
+type RiskRuleType = string
+
+const (
+	// WITHDRAWAL_AFTER_LARGE_CHARGE matches withdrawals within Window of a charge of at least Amount
+	WITHDRAWAL_AFTER_LARGE_CHARGE RiskRuleType = "withdrawal_after_large_charge"
+	// NEW_DESTINATION matches withdrawals to a destination no withdrawal has succeeded to yet
+	NEW_DESTINATION = "new_destination"
+	// UNUSUAL_AMOUNT matches withdrawals of more than Multiplier times the average of the previous ones,
+	// once the wallet has at least MinHistory successful withdrawals
+	UNUSUAL_AMOUNT = "unusual_amount"
+)
+
+// RiskRule is a single screening rule. Only the fields used by its type are read.
+type RiskRule struct {
+	Name       string
+	Type       RiskRuleType
+	Outcome    RiskDecision
+	Amount     int64
+	Window     time.Duration
+	Multiplier float64
+	MinHistory int64
+}
+
+func (r RiskRule) Validate() error {
+	if r.Outcome != REVIEW && r.Outcome != DENY {
+		return fmt.Errorf("rule %s: outcome must be review or deny", r.Name)
+	}
+	switch r.Type {
+	case WITHDRAWAL_AFTER_LARGE_CHARGE:
+		if r.Amount <= 0 || r.Window <= 0 {
+			return fmt.Errorf("rule %s: amount and window must be positive", r.Name)
+		}
+	case NEW_DESTINATION:
+	case UNUSUAL_AMOUNT:
+		if r.Multiplier <= 1 {
+			return fmt.Errorf("rule %s: multiplier must be greater than 1", r.Name)
+		}
+	default:
+		return fmt.Errorf("rule %s: unknown type %q", r.Name, r.Type)
+	}
+	return nil
+}
+
+func (r RiskRule) matches(profile RiskProfile, amount int64, now time.Time) bool {
+	switch r.Type {
+	case WITHDRAWAL_AFTER_LARGE_CHARGE:
+		for _, charge := range profile.RecentCharges {
+			if charge.Amount >= r.Amount && now.Sub(charge.At) <= r.Window {
+				return true
+			}
+		}
+	case NEW_DESTINATION:
+		return profile.NewDestination
+	case UNUSUAL_AMOUNT:
+		return profile.WithdrawalCount >= r.MinHistory && profile.WithdrawalCount > 0 &&
+			float64(amount) > r.Multiplier*float64(profile.AverageWithdrawal)
+	}
+	return false
+}
+
+// RiskCharge is a credit the wallet received recently
+type RiskCharge struct {
+	Amount int64
+	At     time.Time
+}
+
+// RiskProfile is the history of a wallet the risk rules are evaluated against
+type RiskProfile struct {
+	RecentCharges     []RiskCharge
+	WithdrawalCount   int64
+	AverageWithdrawal int64
+	NewDestination    bool
+}
+
+type RiskAssessment struct {
+	Decision RiskDecision `json:"decision"`
+	Reasons  []string     `json:"reasons,omitempty"`
+}
+
+type RiskRules []RiskRule
+
+func (rs RiskRules) Validate() error {
+	for _, r := range rs {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookback is how far back the charges of a wallet are needed to evaluate the rules
+func (rs RiskRules) Lookback() time.Duration {
+	var lookback time.Duration
+	for _, r := range rs {
+		if r.Type == WITHDRAWAL_AFTER_LARGE_CHARGE && r.Window > lookback {
+			lookback = r.Window
+		}
+	}
+	return lookback
+}
+
+// Evaluate returns the most severe outcome of the matching rules along with their names
+func (rs RiskRules) Evaluate(profile RiskProfile, amount int64, now time.Time) RiskAssessment {
+	assessment := RiskAssessment{Decision: ALLOW}
+	for _, r := range rs {
+		if !r.matches(profile, amount, now) {
+			continue
+		}
+		assessment.Reasons = append(assessment.Reasons, r.Name)
+		if r.Outcome == DENY || assessment.Decision == ALLOW {
+			assessment.Decision = r.Outcome
+		}
+	}
+	return assessment
+}
+
+// RiskReview is a withdrawal held for an admin to approve or reject
+type RiskReview struct {
+	TransactionID uuid.UUID `json:"transaction_id"`
+	UserID        int64     `json:"user_id"`
+	Amount        int64     `json:"amount"`
+	Reasons       []string  `json:"reasons"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRiskRulesEvaluate(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	rules := RiskRules{
+		{Name: "large_charge", Type: WITHDRAWAL_AFTER_LARGE_CHARGE, Outcome: REVIEW, Amount: 1000, Window: time.Hour},
+		{Name: "new_destination", Type: NEW_DESTINATION, Outcome: REVIEW},
+		{Name: "unusual_amount", Type: UNUSUAL_AMOUNT, Outcome: DENY, Multiplier: 5, MinHistory: 3},
+	}
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			t.Fatalf("expected rule %s to be valid, got %v", r.Name, err)
+		}
+	}
+	if rules.Lookback() != time.Hour {
+		t.Fatalf("expected lookback of an hour, got %v", rules.Lookback())
+	}
+
+	known := RiskProfile{WithdrawalCount: 4, AverageWithdrawal: 100}
+	if a := rules.Evaluate(known, 400, now); a.Decision != ALLOW || len(a.Reasons) != 0 {
+		t.Fatalf("expected usual withdrawal to be allowed, got %+v", a)
+	}
+
+	oldCharge := known
+	oldCharge.RecentCharges = []RiskCharge{{Amount: 5000, At: now.Add(-2 * time.Hour)}}
+	if a := rules.Evaluate(oldCharge, 400, now); a.Decision != ALLOW {
+		t.Fatalf("expected charge outside the window to be ignored, got %+v", a)
+	}
+
+	recentCharge := known
+	recentCharge.RecentCharges = []RiskCharge{{Amount: 5000, At: now.Add(-10 * time.Minute)}}
+	if a := rules.Evaluate(recentCharge, 400, now); a.Decision != REVIEW || a.Reasons[0] != "large_charge" {
+		t.Fatalf("expected withdrawal after a large charge to be reviewed, got %+v", a)
+	}
+
+	if a := rules.Evaluate(RiskProfile{NewDestination: true}, 10000, now); a.Decision != REVIEW {
+		t.Fatalf("expected unusual amount rule to wait for enough history, got %+v", a)
+	}
+
+	recentCharge.NewDestination = true
+	a := rules.Evaluate(recentCharge, 501, now)
+	if a.Decision != DENY || len(a.Reasons) != 3 {
+		t.Fatalf("expected deny to win over review, got %+v", a)
+	}
+}
+
+func TestRiskRuleValidate(t *testing.T) {
+	invalid := []RiskRule{
+		{Name: "outcome", Type: NEW_DESTINATION, Outcome: ALLOW},
+		{Name: "type", Type: "velocity", Outcome: REVIEW},
+		{Name: "window", Type: WITHDRAWAL_AFTER_LARGE_CHARGE, Outcome: REVIEW, Amount: 10},
+		{Name: "multiplier", Type: UNUSUAL_AMOUNT, Outcome: REVIEW, Multiplier: 1},
+	}
+	for _, r := range invalid {
+		if r.Validate() == nil {
+			t.Fatalf("expected rule %s to be invalid", r.Name)
+		}
+	}
+}
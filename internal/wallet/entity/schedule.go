@@ -0,0 +1,123 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+var (
+	ErrInvalidSchedule  = errors.New("schedule is not valid")
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrScheduleStatus   = errors.New("schedule status does not allow this change")
+)
+
+type ScheduleFrequency = string
+
+const (
+	DAILY   ScheduleFrequency = "daily"
+	WEEKLY                    = "weekly"
+	MONTHLY                   = "monthly"
+)
+
+type ScheduleStatus = string
+
+const (
+	SCHEDULE_ACTIVE    ScheduleStatus = "active"
+	SCHEDULE_PAUSED                   = "paused"
+	SCHEDULE_COMPLETED                = "completed"
+	SCHEDULE_DELETED                  = "deleted"
+)
+
+// scheduleNamespace derives the idempotency keys of schedule occurrences
+var scheduleNamespace = uuid.Must(uuid.FromString("8f4c3b1e-6a57-4d1c-9a0e-3f2b7c5d9e41"))
+
+// Schedule repeats a charge or payout of Amount every Interval days, weeks or months from StartAt.
+// Occurrence is the number of the next occurrence to run, NextRunAt is nil once the schedule is over.
+type Schedule struct {
+	ID            int64             `json:"id"`
+	UserID        int64             `json:"user_id"`
+	Type          TransactionType   `json:"type"`
+	Amount        int64             `json:"amount"`
+	Frequency     ScheduleFrequency `json:"frequency"`
+	Interval      int               `json:"interval"`
+	DestinationID *int64            `json:"destination_id,omitempty"`
+	StartAt       time.Time         `json:"start_at"`
+	EndAt         *time.Time        `json:"end_at,omitempty"`
+	Occurrence    int64             `json:"occurrence"`
+	NextRunAt     *time.Time        `json:"next_run_at,omitempty"`
+	Status        ScheduleStatus    `json:"status"`
+	LastError     string            `json:"last_error,omitempty"`
+	LastRunAt     *time.Time        `json:"last_run_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+func (s Schedule) Validate() error {
+	if s.Type != CREDIT && s.Type != DEBIT {
+		return fmt.Errorf("%w: type must be %s or %s", ErrInvalidSchedule, CREDIT, DEBIT)
+	}
+	if s.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidSchedule)
+	}
+	if s.Frequency != DAILY && s.Frequency != WEEKLY && s.Frequency != MONTHLY {
+		return fmt.Errorf("%w: frequency must be %s, %s or %s", ErrInvalidSchedule, DAILY, WEEKLY, MONTHLY)
+	}
+	if s.Interval <= 0 || s.Interval > 365 {
+		return fmt.Errorf("%w: interval must be between 1 and 365", ErrInvalidSchedule)
+	}
+	if s.Type == CREDIT && s.DestinationID != nil {
+		return fmt.Errorf("%w: only payouts have a destination", ErrInvalidSchedule)
+	}
+	if s.StartAt.IsZero() {
+		return fmt.Errorf("%w: start time cannot be empty", ErrInvalidSchedule)
+	}
+	if s.EndAt != nil && s.EndAt.Before(s.StartAt) {
+		return fmt.Errorf("%w: end time cannot be before the start time", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// OccurrenceAt returns the time occurrence n is due. Monthly occurrences keep the day of StartAt
+// and fall on the last day of shorter months.
+func (s Schedule) OccurrenceAt(n int64) time.Time {
+	steps := int(n) * s.Interval
+	switch s.Frequency {
+	case DAILY:
+		return s.StartAt.AddDate(0, 0, steps)
+	case WEEKLY:
+		return s.StartAt.AddDate(0, 0, 7*steps)
+	default:
+		first := time.Date(s.StartAt.Year(), s.StartAt.Month()+time.Month(steps), 1,
+			s.StartAt.Hour(), s.StartAt.Minute(), s.StartAt.Second(), s.StartAt.Nanosecond(), s.StartAt.Location())
+		day := s.StartAt.Day()
+		if last := first.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+		return first.AddDate(0, 0, day-1)
+	}
+}
+
+// NextRun returns when occurrence n is due, or nil when it falls after EndAt
+func (s Schedule) NextRun(n int64) *time.Time {
+	at := s.OccurrenceAt(n)
+	if s.EndAt != nil && at.After(*s.EndAt) {
+		return nil
+	}
+	return &at
+}
+
+// OccurrenceFrom returns the first occurrence from the current one which is due at or after t,
+// resuming a schedule skips the occurrences missed while it was paused
+func (s Schedule) OccurrenceFrom(t time.Time) int64 {
+	n := s.Occurrence
+	for s.OccurrenceAt(n).Before(t) {
+		n++
+	}
+	return n
+}
+
+// Idempotency returns the idempotency key of occurrence n, running an occurrence twice is rejected
+func (s Schedule) Idempotency(n int64) uuid.UUID {
+	return uuid.NewV5(scheduleNamespace, fmt.Sprintf("schedule/%d/%d", s.ID, n))
+}
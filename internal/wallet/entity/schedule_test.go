@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduleOccurrenceAt(t *testing.T) {
+	start := time.Date(2025, time.January, 31, 9, 30, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		frequency ScheduleFrequency
+		interval  int
+		n         int64
+		at        time.Time
+	}{
+		{name: "first occurrence", frequency: DAILY, interval: 1, n: 0, at: start},
+		{name: "every other day", frequency: DAILY, interval: 2, n: 3, at: time.Date(2025, time.February, 6, 9, 30, 0, 0, time.UTC)},
+		{name: "weekly", frequency: WEEKLY, interval: 1, n: 2, at: time.Date(2025, time.February, 14, 9, 30, 0, 0, time.UTC)},
+		{name: "monthly falls on the last day of february", frequency: MONTHLY, interval: 1, n: 1, at: time.Date(2025, time.February, 28, 9, 30, 0, 0, time.UTC)},
+		{name: "monthly keeps the day after a short month", frequency: MONTHLY, interval: 1, n: 2, at: time.Date(2025, time.March, 31, 9, 30, 0, 0, time.UTC)},
+		{name: "quarterly over the year end", frequency: MONTHLY, interval: 3, n: 4, at: time.Date(2026, time.January, 31, 9, 30, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		s := Schedule{Frequency: c.frequency, Interval: c.interval, StartAt: start}
+		if at := s.OccurrenceAt(c.n); !at.Equal(c.at) {
+			t.Fatalf("%s: expected %s, got %s", c.name, c.at, at)
+		}
+	}
+}
+
+func TestScheduleNextRunAndOccurrenceFrom(t *testing.T) {
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+	s := Schedule{Frequency: DAILY, Interval: 1, StartAt: start, EndAt: &end, Occurrence: 2}
+
+	if n := s.OccurrenceFrom(time.Date(2025, time.March, 5, 12, 0, 0, 0, time.UTC)); n != 5 {
+		t.Fatalf("expected to resume from occurrence 5, got %d", n)
+	}
+	if n := s.OccurrenceFrom(start); n != 2 {
+		t.Fatalf("expected to keep occurrence 2, got %d", n)
+	}
+	if next := s.NextRun(9); next == nil || !next.Equal(end) {
+		t.Fatalf("expected the last run at the end time, got %v", next)
+	}
+	if next := s.NextRun(10); next != nil {
+		t.Fatalf("expected no run after the end time, got %v", next)
+	}
+}
+
+func TestScheduleIdempotency(t *testing.T) {
+	s := Schedule{ID: 7}
+	if s.Idempotency(1) != s.Idempotency(1) {
+		t.Fatal("expected the same key for the same occurrence")
+	}
+	if s.Idempotency(1) == s.Idempotency(2) {
+		t.Fatal("expected different keys for different occurrences")
+	}
+	if s.Idempotency(1) == (Schedule{ID: 8}).Idempotency(1) {
+		t.Fatal("expected different keys for different schedules")
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	start := time.Now()
+	before := start.Add(-time.Hour)
+	destination := int64(1)
+	valid := Schedule{Type: DEBIT, Amount: 100, Frequency: WEEKLY, Interval: 1, StartAt: start, DestinationID: &destination}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid schedule, got %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		modify func(s *Schedule)
+	}{
+		{name: "unknown type", modify: func(s *Schedule) { s.Type = "transfer" }},
+		{name: "zero amount", modify: func(s *Schedule) { s.Amount = 0 }},
+		{name: "unknown frequency", modify: func(s *Schedule) { s.Frequency = "hourly" }},
+		{name: "zero interval", modify: func(s *Schedule) { s.Interval = 0 }},
+		{name: "credit with destination", modify: func(s *Schedule) { s.Type = CREDIT }},
+		{name: "end before start", modify: func(s *Schedule) { s.EndAt = &before }},
+	}
+	for _, c := range cases {
+		s := valid
+		c.modify(&s)
+		if err := s.Validate(); !errors.Is(err, ErrInvalidSchedule) {
+			t.Fatalf("%s: expected invalid schedule, got %v", c.name, err)
+		}
+	}
+}
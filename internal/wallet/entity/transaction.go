@@ -15,9 +15,24 @@ const (
 type Status = string
 
 const (
-	PENDING Status = "pending"
-	FAILED         = "failed"
-	SUCCESS        = "success"
+	PENDING   Status = "pending"
+	FAILED           = "failed"
+	SUCCESS          = "success"
+	CANCELLED        = "cancelled"
+	IN_REVIEW        = "review"
+)
+
+type Source = string
+
+const (
+	USER_SOURCE       Source = "user"
+	ADJUSTMENT_SOURCE        = "adjustment"
+	FEE_SOURCE               = "fee"
+	REFUND_SOURCE            = "refund"
+	HOLD_SOURCE              = "hold"
+	BATCH_SOURCE             = "batch"
+	// PROMO_SOURCE credits add promotional credit, its debits take back expired or forfeited promotional credit
+	PROMO_SOURCE = "promo"
 )
 
 type Transaction struct {
@@ -26,13 +41,19 @@ type Transaction struct {
 	UserID      int64           `json:"-"`
 	Type        TransactionType `json:"type,omitempty"`
 	Status      Status          `json:"status,omitempty"`
+	Source      Source          `json:"source,omitempty"`
 	Amount      int64           `json:"amount,omitempty"`
 	Idempotency uuid.UUID       `json:"-"`
 	ReleaseTime *time.Time      `json:"release_time,omitempty"`
 	Released    bool            `json:"released"`
-	RetryCount  int
-	CreatedAt   time.Time `json:"created_at,omitempty"`
-	UpdatedAt   time.Time `json:"-"`
+	RetryCount  int             `json:"-"`
+	// DestinationID is where a debit is paid out to, Destination is only loaded for the withdraw job
+	DestinationID *int64       `json:"destination_id,omitempty"`
+	Destination   *Destination `json:"-"`
+	// RelatedTransactionID links a fee to its withdrawal and a refund to the transaction it gives back
+	RelatedTransactionID *uuid.UUID `json:"related_transaction_id,omitempty"`
+	CreatedAt            time.Time  `json:"created_at,omitempty"`
+	UpdatedAt            time.Time  `json:"-"`
 }
 
 type TransactionPage struct {
@@ -1,10 +1,23 @@
 package entity
 
+type WalletStatus = string
+
+const (
+	ACTIVE WalletStatus = "active"
+	// FROZEN_DEBIT wallets can still be charged but can not be debited
+	FROZEN_DEBIT = "frozen_debit"
+	FROZEN_ALL   = "frozen_all"
+	CLOSED       = "closed"
+)
+
 type Wallet struct {
 	id               int64
 	userId           int64
-	TotalBalance     int64 `json:"total_balance,omitempty"`
-	AvailableBalance int64 `json:"available_balance,omitempty"`
+	TotalBalance     int64        `json:"total_balance,omitempty"`
+	AvailableBalance int64        `json:"available_balance,omitempty"`
+	Status           WalletStatus `json:"status,omitempty"`
+	// Buckets is only filled for the current balance
+	Buckets *BalanceBuckets `json:"buckets,omitempty"`
 }
 
 func NewWallet(id int64, userId int64, totalBalance int64, availableBalance int64) *Wallet {
@@ -15,3 +28,13 @@ func NewWallet(id int64, userId int64, totalBalance int64, availableBalance int6
 		AvailableBalance: availableBalance,
 	}
 }
+
+// CanCharge reports whether credits are accepted by a wallet in the given status
+func CanCharge(status WalletStatus) bool {
+	return status == ACTIVE || status == FROZEN_DEBIT
+}
+
+// CanDebit reports whether debits are accepted by a wallet in the given status
+func CanDebit(status WalletStatus) bool {
+	return status == ACTIVE
+}
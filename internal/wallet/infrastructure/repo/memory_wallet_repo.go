@@ -0,0 +1,664 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/gofrs/uuid/v5"
+	"sort"
+	"sync"
+	"time"
+)
+
+// claimWindow is how long a pending withdrawal claimed by the withdraw job is kept away from other claims
+const claimWindow = 30 * time.Second
+
+type memoryWallet struct {
+	id               int64
+	userId           int64
+	totalBalance     int64
+	availableBalance int64
+	status           entity.WalletStatus
+	changes          []balanceChange
+}
+
+// balanceChange is a single movement of the wallet balances, replayed by GetBalanceAt
+type balanceChange struct {
+	at        time.Time
+	total     int64
+	available int64
+}
+
+type memoryTransaction struct {
+	entity.Transaction
+	releasedAt *time.Time
+	lastRetry  *time.Time
+	// sentToBank is set on the first claim and never reset
+	sentToBank bool
+	bankTxID   *uuid.UUID
+}
+
+type withdrawAttempt struct {
+	transactionID uuid.UUID
+	attempt       int
+	bankTxID      *uuid.UUID
+	err           string
+}
+
+type balanceSnapshot struct {
+	totalBalance     int64
+	availableBalance int64
+	takenAt          time.Time
+}
+
+// MemoryWalletRepo is an in-memory WalletRepo with the semantics of PgxWalletRepo. It keeps everything
+// behind a single lock, which serializes operations the way the wallet row locks do in Postgres.
+// Wallets are never quarantined and have no limits, promotional credit or payout destinations.
+type MemoryWalletRepo struct {
+	mu           sync.Mutex
+	lastWalletID int64
+	wallets      map[int64]*memoryWallet
+	// transactions are kept in insertion order
+	transactions []*memoryTransaction
+	keys         map[userKey]struct{}
+	attempts     []withdrawAttempt
+	snapshots    map[int64]map[string]balanceSnapshot
+	clock        clock.Clock
+}
+
+type userKey struct {
+	userId int64
+	key    uuid.UUID
+}
+
+func NewMemoryWalletRepo(clock clock.Clock) *MemoryWalletRepo {
+	return &MemoryWalletRepo{
+		wallets:   make(map[int64]*memoryWallet),
+		keys:      make(map[userKey]struct{}),
+		snapshots: make(map[int64]map[string]balanceSnapshot),
+		clock:     clock,
+	}
+}
+
+// Charge Adds credit to the user wallet
+func (dc *MemoryWalletRepo) Charge(ctx context.Context, userId int64, idempotency *uuid.UUID, chargeAmount int64, releaseTime *time.Time) (*uuid.UUID, error) {
+	if idempotency == nil {
+		return nil, errors.New("charge operations must have idempotency")
+	}
+
+	if chargeAmount <= 0 {
+		return nil, errors.New("negative or 0 is not acceptable amount for charge operation")
+	}
+
+	now := dc.clock.Now()
+	if releaseTime != nil && now.After(*releaseTime) {
+		return nil, fmt.Errorf("%w: release time can't be in the past", entity.ErrInvalidReleaseTime)
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	w, ok := dc.wallets[userId]
+	if ok && !entity.CanCharge(w.status) {
+		return nil, fmt.Errorf("database charge operation failed: %w", dc.rejectionReason(userId))
+	}
+	if dc.keyUsed(userId, idempotency) {
+		return nil, fmt.Errorf("database charge operation failed: %w", entity.ErrIdempotencyUsed)
+	}
+	if !ok {
+		w = dc.createWallet(userId)
+	}
+
+	txn, err := dc.insertTransaction(w, entity.CREDIT, entity.SUCCESS, entity.USER_SOURCE, chargeAmount, releaseTime, idempotency, now)
+	if err != nil {
+		return nil, fmt.Errorf("database charge operation failed: %w", err)
+	}
+	if releaseTime == nil {
+		txn.Released = true
+		txn.releasedAt = &now
+		w.apply(now, chargeAmount, chargeAmount)
+	} else {
+		w.apply(now, chargeAmount, 0)
+	}
+
+	return &txn.ID, nil
+}
+
+// Debit deducts from user's wallet balance. The withdrawal is screened by risk under the lock, a denied
+// withdrawal is rejected, a debit sent to review is held out of the withdraw job and a positive fee is
+// reserved together with the amount.
+func (dc *MemoryWalletRepo) Debit(ctx context.Context, userId int64, idempotency *uuid.UUID, debitAmount int64, fee int64, releaseTime *time.Time, destinationID *int64, screen *repo.DebitScreen) (*uuid.UUID, *entity.RiskAssessment, error) {
+	if idempotency == nil {
+		return nil, nil, errors.New("debits operations must have idempotency")
+	}
+
+	if debitAmount <= 0 {
+		return nil, nil, errors.New("negative or 0 is not acceptable amount for debit operation")
+	}
+
+	if fee < 0 {
+		return nil, nil, errors.New("negative fee is not acceptable for debit operation")
+	}
+
+	if releaseTime == nil {
+		return nil, nil, fmt.Errorf("%w: debits must have release time", entity.ErrInvalidReleaseTime)
+	}
+
+	now := dc.clock.Now()
+	if now.After(*releaseTime) {
+		return nil, nil, fmt.Errorf("%w: release time can't be in the past", entity.ErrInvalidReleaseTime)
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	assessment := &entity.RiskAssessment{Decision: entity.ALLOW}
+	if screen != nil {
+		*assessment = screen.Decide(dc.riskProfile(userId, destinationID, now.Add(-screen.Lookback)), now)
+	}
+	if assessment.Decision == entity.DENY {
+		return nil, assessment, entity.ErrRiskDenied
+	}
+	status := entity.PENDING
+	if assessment.Decision == entity.REVIEW {
+		status = entity.IN_REVIEW
+	}
+
+	w, ok := dc.wallets[userId]
+	if !ok || !entity.CanDebit(w.status) || w.availableBalance < debitAmount+fee {
+		return nil, nil, fmt.Errorf("database debit operation failed: %w", dc.rejectionReason(userId))
+	}
+	if dc.keyUsed(userId, idempotency) {
+		return nil, nil, fmt.Errorf("database debit operation failed: %w", entity.ErrIdempotencyUsed)
+	}
+
+	txn, err := dc.insertTransaction(w, entity.DEBIT, status, entity.USER_SOURCE, -debitAmount, releaseTime, idempotency, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("database debit operation failed: %w", err)
+	}
+	txn.DestinationID = destinationID
+	if fee > 0 {
+		key, err := uuid.NewV4()
+		if err != nil {
+			return nil, nil, fmt.Errorf("database debit operation failed: %w", err)
+		}
+		feeTxn, err := dc.insertTransaction(w, entity.DEBIT, entity.SUCCESS, entity.FEE_SOURCE, -fee, releaseTime, &key, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("database debit operation failed: %w", err)
+		}
+		feeTxn.RelatedTransactionID = &txn.ID
+	}
+	w.apply(now, 0, -(debitAmount + fee))
+
+	return &txn.ID, assessment, nil
+}
+
+// GetBalance return user's wallet balance
+func (dc *MemoryWalletRepo) GetBalance(ctx context.Context, userId int64) (*entity.Wallet, error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	var wallet *entity.Wallet
+	w, ok := dc.wallets[userId]
+	if ok {
+		wallet = entity.NewWallet(w.id, userId, w.totalBalance, w.availableBalance)
+		wallet.Status = w.status
+	} else {
+		wallet = entity.NewWallet(int64(0), userId, int64(0), int64(0))
+		wallet.Status = entity.ACTIVE
+	}
+	wallet.Buckets = entity.NewBalanceBuckets(wallet.TotalBalance, wallet.AvailableBalance, 0, 0, make([]entity.PromoGrant, 0))
+
+	return wallet, nil
+}
+
+// GetBalanceAt return user's wallet balance at the given point in time by replaying its balance changes
+func (dc *MemoryWalletRepo) GetBalanceAt(ctx context.Context, userId int64, at time.Time) (*entity.Wallet, error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	w, ok := dc.wallets[userId]
+	if !ok {
+		return entity.NewWallet(int64(0), userId, int64(0), int64(0)), nil
+	}
+	var totalBalance, availableBalance int64
+	for _, change := range w.changes {
+		if change.at.After(at) {
+			break
+		}
+		totalBalance += change.total
+		availableBalance += change.available
+	}
+
+	return entity.NewWallet(w.id, userId, totalBalance, availableBalance), nil
+}
+
+// GetTransactionList return a list of user transactions
+func (dc *MemoryWalletRepo) GetTransactionList(ctx context.Context, userId int64, cursor *uuid.UUID, limit int) (*entity.TransactionPage, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 30 {
+		limit = 30
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	// ids made within the same millisecond are not always in insertion order, pages are ordered by id like in Postgres
+	list := make([]entity.Transaction, 0, limit)
+	for _, t := range dc.transactions {
+		if t.UserID != userId || (cursor != nil && bytes.Compare(t.ID.Bytes(), cursor.Bytes()) >= 0) {
+			continue
+		}
+		list = append(list, t.Transaction)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].ID.Bytes(), list[j].ID.Bytes()) > 0
+	})
+	if len(list) > limit {
+		list = list[:limit]
+	}
+
+	page := entity.TransactionPage{
+		TransactionList: list,
+	}
+	size := len(list)
+	if size == limit {
+		page.Cursor = &list[size-1].ID
+	}
+
+	return &page, nil
+}
+
+// ReleaseDueTransactions return the list of released transactions
+func (dc *MemoryWalletRepo) ReleaseDueTransactions(ctx context.Context, batchSize int) ([]entity.Transaction, error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	now := dc.clock.Now()
+	due := make([]*memoryTransaction, 0, batchSize)
+	for _, t := range dc.transactions {
+		if t.Released || t.ReleaseTime == nil || t.ReleaseTime.After(now) ||
+			t.Status == entity.FAILED || t.Status == entity.CANCELLED || t.Status == entity.IN_REVIEW {
+			continue
+		}
+		// the fee of a debit in review waits for its debit
+		if t.Source == entity.FEE_SOURCE && dc.findTransaction(t.RelatedTransactionID).Status == entity.IN_REVIEW {
+			continue
+		}
+		due = append(due, t)
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].ReleaseTime.Before(*due[j].ReleaseTime)
+	})
+	if len(due) > batchSize {
+		due = due[:batchSize]
+	}
+
+	list := make([]entity.Transaction, 0, len(due))
+	for _, t := range due {
+		w := dc.wallets[t.UserID]
+		if t.Type == entity.CREDIT {
+			w.apply(now, 0, t.Amount)
+		} else {
+			w.apply(now, t.Amount, 0)
+		}
+		t.Released = true
+		t.releasedAt = &now
+		t.UpdatedAt = now
+		list = append(list, entity.Transaction{ID: t.ID, UserID: t.UserID, Type: t.Type, Amount: t.Amount})
+	}
+
+	return list, nil
+}
+
+// GetPendingTransactions claims the released pending withdrawals which are not claimed within the claim window
+func (dc *MemoryWalletRepo) GetPendingTransactions(ctx context.Context, limit int) ([]entity.Transaction, error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	now := dc.clock.Now()
+	list := make([]entity.Transaction, 0, limit)
+	for _, t := range dc.transactions {
+		if len(list) == limit {
+			break
+		}
+		if t.Status != entity.PENDING || !t.Released || t.ReleaseTime == nil || t.ReleaseTime.After(now) ||
+			(t.lastRetry != nil && t.lastRetry.After(now.Add(-claimWindow))) {
+			continue
+		}
+		t.lastRetry = &now
+		t.sentToBank = true
+		list = append(list, entity.Transaction{ID: t.ID, UserID: t.UserID, RetryCount: t.RetryCount, Amount: t.Amount,
+			Idempotency: t.Idempotency, DestinationID: t.DestinationID})
+	}
+
+	return list, nil
+}
+
+// UpdateTransactionStatus records the bank outcome of a withdrawal. A failed withdrawal gives the amount
+// back to the wallet and refunds its fee.
+func (dc *MemoryWalletRepo) UpdateTransactionStatus(ctx context.Context, id *uuid.UUID, txStatus entity.Status, bankTxID *uuid.UUID) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	t := dc.findTransaction(id)
+	if t == nil {
+		return nil
+	}
+	now := dc.clock.Now()
+	if txStatus != entity.FAILED {
+		t.Status = txStatus
+		t.bankTxID = bankTxID
+		t.UpdatedAt = now
+		return nil
+	}
+	if t.Status != entity.PENDING {
+		return nil
+	}
+	t.Status = entity.FAILED
+	t.bankTxID = bankTxID
+	t.UpdatedAt = now
+	w := dc.wallets[t.UserID]
+	if t.Released {
+		w.apply(now, -t.Amount, -t.Amount)
+	} else {
+		w.apply(now, 0, -t.Amount)
+	}
+
+	if err := dc.refundFee(id, now); err != nil {
+		return fmt.Errorf("something happened while trying to update failed transactions: %w", err)
+	}
+	return nil
+}
+
+func (dc *MemoryWalletRepo) IncreaseTransactionRetryCount(ctx context.Context, id *uuid.UUID) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if t := dc.findTransaction(id); t != nil {
+		t.RetryCount++
+		t.UpdatedAt = dc.clock.Now()
+	}
+
+	return nil
+}
+
+// RecordWithdrawAttempt keeps the outcome of a single bank call
+func (dc *MemoryWalletRepo) RecordWithdrawAttempt(ctx context.Context, id *uuid.UUID, attempt int, bankTxID *uuid.UUID, withdrawErr error) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if dc.findTransaction(id) == nil {
+		return fmt.Errorf("recording withdraw attempt failed: %w", entity.ErrTransactionNotFound)
+	}
+	errMsg := ""
+	if withdrawErr != nil {
+		errMsg = withdrawErr.Error()
+	}
+	dc.attempts = append(dc.attempts, withdrawAttempt{transactionID: *id, attempt: attempt, bankTxID: bankTxID, err: errMsg})
+
+	return nil
+}
+
+// CancelDebit cancels a pending withdrawal before its release, when it was never claimed by the withdraw job,
+// gives the amount back to the available balance and refunds its fee
+func (dc *MemoryWalletRepo) CancelDebit(ctx context.Context, userId int64, id *uuid.UUID) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	t := dc.findTransaction(id)
+	if t == nil || t.UserID != userId {
+		return entity.ErrTransactionNotFound
+	}
+	w := dc.wallets[userId]
+	if t.Type != entity.DEBIT || t.Source != entity.USER_SOURCE || t.Status != entity.PENDING ||
+		t.Released || t.sentToBank || w.status == entity.CLOSED {
+		return entity.ErrNotCancellable
+	}
+
+	now := dc.clock.Now()
+	t.Status = entity.CANCELLED
+	t.UpdatedAt = now
+	w.apply(now, 0, -t.Amount)
+	if err := dc.refundFee(id, now); err != nil {
+		return fmt.Errorf("cancel debit failed: %w", err)
+	}
+
+	return nil
+}
+
+// TakeBalanceSnapshots records the current balances of at most batchSize wallets
+// which do not have a snapshot for snapshotDate yet and returns the number of recorded snapshots
+func (dc *MemoryWalletRepo) TakeBalanceSnapshots(ctx context.Context, snapshotDate time.Time, batchSize int) (int64, error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	ids := make([]int64, 0, len(dc.wallets))
+	byID := make(map[int64]*memoryWallet, len(dc.wallets))
+	for _, w := range dc.wallets {
+		ids = append(ids, w.id)
+		byID[w.id] = w
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	date := snapshotDate.Format(time.DateOnly)
+	now := dc.clock.Now()
+	var taken int64
+	for _, id := range ids {
+		if taken == int64(batchSize) {
+			break
+		}
+		if _, ok := dc.snapshots[id][date]; ok {
+			continue
+		}
+		if dc.snapshots[id] == nil {
+			dc.snapshots[id] = make(map[string]balanceSnapshot)
+		}
+		w := byID[id]
+		dc.snapshots[id][date] = balanceSnapshot{totalBalance: w.totalBalance, availableBalance: w.availableBalance, takenAt: now}
+		taken++
+	}
+
+	return taken, nil
+}
+
+// UpdateWalletStatus changes the wallet status. Closed wallets can not be reopened and must be closed through CloseWallet.
+func (dc *MemoryWalletRepo) UpdateWalletStatus(ctx context.Context, userId int64, status entity.WalletStatus, reason string, actor string) (entity.WalletStatus, error) {
+	if status == entity.CLOSED {
+		return "", entity.ErrInvalidTransition
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	w, ok := dc.wallets[userId]
+	switch {
+	case !ok:
+		return "", fmt.Errorf("update wallet status failed: %w", entity.ErrWalletNotFound)
+	case w.status == entity.CLOSED:
+		return "", fmt.Errorf("update wallet status failed: %w", entity.ErrWalletClosed)
+	}
+	from := w.status
+	w.status = status
+
+	return from, nil
+}
+
+// CloseWallet closes an empty wallet. A wallet with balance is only closed when a payout idempotency
+// is given, in which case the whole balance is debited as a final payout released immediately.
+func (dc *MemoryWalletRepo) CloseWallet(ctx context.Context, userId int64, reason string, actor string, payoutIdempotency *uuid.UUID) (*uuid.UUID, error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	w, ok := dc.wallets[userId]
+	switch {
+	case !ok:
+		return nil, entity.ErrWalletNotFound
+	case w.status == entity.CLOSED:
+		return nil, entity.ErrWalletClosed
+	case w.totalBalance != w.availableBalance:
+		return nil, entity.ErrPendingFunds
+	case w.availableBalance != 0 && payoutIdempotency == nil:
+		return nil, entity.ErrWalletNotEmpty
+	}
+
+	var payoutID *uuid.UUID
+	if w.availableBalance != 0 {
+		if dc.keyUsed(userId, payoutIdempotency) {
+			return nil, fmt.Errorf("final payout failed: %w", entity.ErrIdempotencyUsed)
+		}
+		now := dc.clock.Now()
+		txn, err := dc.insertTransaction(w, entity.DEBIT, entity.PENDING, entity.USER_SOURCE, -w.availableBalance, &now, payoutIdempotency, now)
+		if err != nil {
+			return nil, fmt.Errorf("final payout failed: %w", err)
+		}
+		w.apply(now, 0, -w.availableBalance)
+		payoutID = &txn.ID
+	}
+	w.status = entity.CLOSED
+
+	return payoutID, nil
+}
+
+// rejectionReason explains why a wallet operation was refused
+func (dc *MemoryWalletRepo) rejectionReason(userId int64) error {
+	w, ok := dc.wallets[userId]
+	switch {
+	case !ok:
+		return entity.ErrInsufficientFunds
+	case w.status == entity.CLOSED:
+		return entity.ErrWalletClosed
+	case w.status == entity.FROZEN_ALL || w.status == entity.FROZEN_DEBIT:
+		return entity.ErrWalletFrozen
+	default:
+		return entity.ErrInsufficientFunds
+	}
+}
+
+// refundFee gives the fee of a withdrawal back with a refund transaction, at most once. An unreleased fee
+// is released at the same time so the total balance is not counted twice.
+// riskProfile mirrors the getWithdrawalStats and getRecentCharges queries
+func (dc *MemoryWalletRepo) riskProfile(userId int64, destinationID *int64, chargesSince time.Time) entity.RiskProfile {
+	profile := entity.RiskProfile{NewDestination: true}
+	var withdrawn int64
+	for i := len(dc.transactions) - 1; i >= 0; i-- {
+		t := dc.transactions[i]
+		if t.UserID != userId {
+			continue
+		}
+		if t.Type == entity.DEBIT && t.Source == entity.USER_SOURCE && t.Status == entity.SUCCESS {
+			profile.WithdrawalCount++
+			withdrawn -= t.Amount
+			if sameDestination(t.DestinationID, destinationID) {
+				profile.NewDestination = false
+			}
+		}
+		if t.Type == entity.CREDIT && t.Source != entity.REFUND_SOURCE && !t.CreatedAt.Before(chargesSince) &&
+			len(profile.RecentCharges) < 100 {
+			profile.RecentCharges = append(profile.RecentCharges, entity.RiskCharge{Amount: t.Amount, At: t.CreatedAt})
+		}
+	}
+	if profile.WithdrawalCount > 0 {
+		profile.AverageWithdrawal = withdrawn / profile.WithdrawalCount
+	}
+	return profile
+}
+
+func sameDestination(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (dc *MemoryWalletRepo) refundFee(id *uuid.UUID, now time.Time) error {
+	var fee *memoryTransaction
+	for _, t := range dc.transactions {
+		if t.Source == entity.FEE_SOURCE && t.RelatedTransactionID != nil && *t.RelatedTransactionID == *id {
+			fee = t
+			break
+		}
+	}
+	if fee == nil {
+		return nil
+	}
+	for _, t := range dc.transactions {
+		if t.Source == entity.REFUND_SOURCE && t.RelatedTransactionID != nil && *t.RelatedTransactionID == fee.ID {
+			return nil
+		}
+	}
+
+	key, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	w := dc.wallets[fee.UserID]
+	refund, err := dc.insertTransaction(w, entity.CREDIT, entity.SUCCESS, entity.REFUND_SOURCE, -fee.Amount, &now, &key, now)
+	if err != nil {
+		return err
+	}
+	refund.Released = true
+	refund.releasedAt = &now
+	refund.RelatedTransactionID = &fee.ID
+	if fee.Released {
+		w.apply(now, -fee.Amount, -fee.Amount)
+	} else {
+		fee.Released = true
+		fee.releasedAt = &now
+		fee.UpdatedAt = now
+		w.apply(now, 0, -fee.Amount)
+	}
+
+	return nil
+}
+
+func (dc *MemoryWalletRepo) createWallet(userId int64) *memoryWallet {
+	dc.lastWalletID++
+	w := &memoryWallet{id: dc.lastWalletID, userId: userId, status: entity.ACTIVE}
+	dc.wallets[userId] = w
+	return w
+}
+
+func (dc *MemoryWalletRepo) keyUsed(userId int64, idempotency *uuid.UUID) bool {
+	_, ok := dc.keys[userKey{userId: userId, key: *idempotency}]
+	return ok
+}
+
+func (dc *MemoryWalletRepo) insertTransaction(w *memoryWallet, txType entity.TransactionType, status entity.Status, source entity.Source,
+	amount int64, releaseTime *time.Time, idempotency *uuid.UUID, now time.Time) (*memoryTransaction, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	if releaseTime != nil {
+		at := *releaseTime
+		releaseTime = &at
+	}
+	t := &memoryTransaction{Transaction: entity.Transaction{ID: id, WalletID: w.id, UserID: w.userId, Type: txType, Status: status,
+		Source: source, Amount: amount, Idempotency: *idempotency, ReleaseTime: releaseTime, CreatedAt: now, UpdatedAt: now}}
+	dc.transactions = append(dc.transactions, t)
+	dc.keys[userKey{userId: w.userId, key: *idempotency}] = struct{}{}
+	return t, nil
+}
+
+func (dc *MemoryWalletRepo) findTransaction(id *uuid.UUID) *memoryTransaction {
+	for _, t := range dc.transactions {
+		if t.ID == *id {
+			return t
+		}
+	}
+	return nil
+}
+
+// apply moves the wallet balances and keeps the movement for GetBalanceAt
+func (w *memoryWallet) apply(at time.Time, total int64, available int64) {
+	w.totalBalance += total
+	w.availableBalance += available
+	w.changes = append(w.changes, balanceChange{at: at, total: total, available: available})
+}
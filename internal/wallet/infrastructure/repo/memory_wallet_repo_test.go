@@ -0,0 +1,52 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/gofrs/uuid/v5"
+	"testing"
+	"time"
+)
+
+func TestMemoryWalletRepo(t *testing.T) {
+	repotest.RunWalletRepoSuite(t, func(t *testing.T) repotest.Subject {
+		fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		return repotest.Subject{Repo: NewMemoryWalletRepo(fake), Clock: fake}
+	})
+}
+
+func TestMemoryWalletRepo_releaseFollowsClock(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := NewMemoryWalletRepo(fake)
+	key := uuid.Must(uuid.NewV4())
+	releaseTime := fake.Now().Add(time.Hour)
+	if _, err := r.Charge(ctx, 1, &key, 100, &releaseTime); err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+
+	fake.Advance(59 * time.Minute)
+	released, err := r.ReleaseDueTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if len(released) != 0 {
+		t.Fatalf("expected nothing to be released before the release time, got %d", len(released))
+	}
+
+	fake.Advance(time.Minute)
+	if released, err = r.ReleaseDueTransactions(ctx, 10); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if len(released) != 1 {
+		t.Fatalf("expected the charge to be released at the release time, got %d", len(released))
+	}
+	w, err := r.GetBalance(ctx, 1)
+	if err != nil {
+		t.Fatalf("get balance failed: %v", err)
+	}
+	if w.AvailableBalance != 100 {
+		t.Fatalf("expected an available balance of 100, got %d", w.AvailableBalance)
+	}
+}
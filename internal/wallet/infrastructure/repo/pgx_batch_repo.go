@@ -0,0 +1,632 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+)
+
+// UsedIdempotencies return the idempotencies of the rows which belong to another batch row
+// or to a transaction of the same user
+func (dc *PgxWalletRepo) UsedIdempotencies(ctx context.Context, rows []entity.BatchRow) ([]uuid.UUID, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	userIds := make([]int64, 0, len(rows))
+	keys := make([]string, 0, len(rows))
+	for _, row := range rows {
+		userIds = append(userIds, row.UserID)
+		keys = append(keys, row.Idempotency.String())
+	}
+	dbRows, err := dc.db.Query(opCtx, getUsedIdempotencies, userIds, keys)
+	if err != nil {
+		return nil, fmt.Errorf("get used idempotencies failed: %w", err)
+	}
+	defer dbRows.Close()
+	used := make([]uuid.UUID, 0)
+	for dbRows.Next() {
+		var key uuid.UUID
+		if err := dbRows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("error in reading idempotency row: %w", err)
+		}
+		used = append(used, key)
+	}
+	if dbRows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading used idempotencies: %w", dbRows.Err())
+	}
+
+	return used, nil
+}
+
+// CreateBatch stores a batch with all of its rows, the batch worker applies them later
+func (dc *PgxWalletRepo) CreateBatch(ctx context.Context, batch *entity.Batch, rows []entity.BatchRow) (*entity.Batch, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var batchID int64
+	err := audit.InTx(opCtx, dc.db, "create_batch", func(tx pgx.Tx) error {
+		err := tx.QueryRow(opCtx, insertBatch, batch.FileName, batch.Mode, len(rows), batch.CreatedBy, dc.clock.Now()).Scan(&batchID)
+		if err != nil {
+			return fmt.Errorf("insert batch failed: %w", err)
+		}
+		queue := &pgx.Batch{}
+		for _, row := range rows {
+			queue.Queue(insertBatchRow, batchID, row.Line, row.UserID, row.Type, row.Amount, row.Idempotency)
+		}
+		if err := tx.SendBatch(opCtx, queue).Close(); err != nil {
+			return fmt.Errorf("insert batch rows failed: %w", err)
+		}
+		return nil
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.ConstraintName == "batch_rows_idempotency_key_key" {
+		return nil, entity.ErrIdempotencyUsed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create batch failed: %w", err)
+	}
+
+	return dc.GetBatch(opCtx, batchID)
+}
+
+// GetBatches return the latest batches with the number of rows in each state
+func (dc *PgxWalletRepo) GetBatches(ctx context.Context, limit int) ([]entity.Batch, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := dc.db.Query(opCtx, getBatches, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get batches failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.Batch, 0)
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error in reading batch row: %w", err)
+		}
+		list = append(list, *b)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading batches: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+// GetBatch return the batch with the number of rows in each state
+func (dc *PgxWalletRepo) GetBatch(ctx context.Context, id int64) (*entity.Batch, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	b, err := scanBatch(dc.db.QueryRow(opCtx, getBatch, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get batch failed: %w", err)
+	}
+
+	return b, nil
+}
+
+// GetBatchRows return the rows of a batch after the given line in the order of the file,
+// an empty status returns the rows in every state
+func (dc *PgxWalletRepo) GetBatchRows(ctx context.Context, batchID int64, status entity.BatchRowStatus, afterLine int, limit int) ([]entity.BatchRow, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	return readBatchRows(opCtx, dc.db, getBatchRows, batchID, status, afterLine, limit)
+}
+
+// ClaimBatch picks the oldest batch which is not finished and keeps it away from other batch workers
+// for claimFor. A batch whose worker stopped is picked again once its claim is over.
+func (dc *PgxWalletRepo) ClaimBatch(ctx context.Context, claimFor time.Duration) (*entity.Batch, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var batchID int64
+	err := audit.InTx(opCtx, dc.db, "claim_batch", func(tx pgx.Tx) error {
+		return tx.QueryRow(opCtx, claimBatch, claimFor, dc.clock.Now()).Scan(&batchID)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim batch failed: %w", err)
+	}
+
+	return dc.GetBatch(opCtx, batchID)
+}
+
+// ApplyBatchRow applies a single row of a partial batch and marks it succeeded in the same database
+// transaction, so a row is never applied twice even when its worker stops halfway. A debit row is due
+// at once, a denied debit row is failed in the same transaction so its denial is kept.
+func (dc *PgxWalletRepo) ApplyBatchRow(ctx context.Context, row entity.BatchRow, debits repo.BatchDebits) (*uuid.UUID, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var transactionID *uuid.UUID
+	err := audit.InTx(opCtx, dc.db, "apply_batch_row", func(tx pgx.Tx) error {
+		var err error
+		transactionID, err = dc.applyBatchRow(opCtx, tx, row, false, debits)
+		return err
+	})
+	if err == nil && transactionID == nil {
+		return nil, entity.ErrRiskDenied
+	}
+	if entity.IsRejection(err) {
+		// the reason is kept on the row as it is
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("apply batch row failed: %w", err)
+	}
+
+	return transactionID, nil
+}
+
+// FailBatchRow records why a pending row was rejected
+func (dc *PgxWalletRepo) FailBatchRow(ctx context.Context, rowID int64, reason string) error {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	if _, err := dc.db.Exec(opCtx, failBatchRow, rowID, reason, dc.clock.Now()); err != nil {
+		return fmt.Errorf("fail batch row failed: %w", err)
+	}
+
+	return nil
+}
+
+// StageBatchRows stages at most limit pending rows of an all or nothing batch in one database transaction and
+// returns the number of rows it went through. A staged credit is in the total balance only, a staged debit reserves
+// its amount and fee without a release time. A rejected row is failed with its reason and the rows after it are
+// still staged, so every rejected row of the batch is found.
+func (dc *PgxWalletRepo) StageBatchRows(ctx context.Context, batchID int64, limit int, debits repo.BatchDebits) (int, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var processed int
+	err := audit.InTx(opCtx, dc.db, "stage_batch_rows", func(tx pgx.Tx) error {
+		rows, err := readBatchRows(opCtx, tx, getBatchRowsForUpdate, batchID, limit)
+		if err != nil {
+			return err
+		}
+		processed = len(rows)
+		for _, row := range rows {
+			savepoint, err := tx.Begin(opCtx)
+			if err != nil {
+				return fmt.Errorf("could not begin savepoint: %w", err)
+			}
+			_, err = dc.applyBatchRow(opCtx, savepoint, row, true, debits)
+			if err == nil {
+				if err := savepoint.Commit(opCtx); err != nil {
+					return fmt.Errorf("could not release savepoint: %w", err)
+				}
+				continue
+			}
+			if rollbackErr := savepoint.Rollback(opCtx); rollbackErr != nil {
+				return fmt.Errorf("could not roll back savepoint: %w", rollbackErr)
+			}
+			if !entity.IsRejection(err) {
+				return err
+			}
+			if _, err := tx.Exec(opCtx, failBatchRow, row.ID, err.Error(), dc.clock.Now()); err != nil {
+				return fmt.Errorf("could not update batch row: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("stage batch rows failed: %w", err)
+	}
+
+	return processed, nil
+}
+
+// ReleaseBatchRows makes the credits of at most limit staged rows available, gives their debits a release time of now
+// so the release job takes them to the bank and returns the number of released rows
+func (dc *PgxWalletRepo) ReleaseBatchRows(ctx context.Context, batchID int64, limit int) (int, error) {
+	return dc.finishStagedRows(ctx, "release_batch_rows", releaseStagedRows, batchID, limit)
+}
+
+// RollBackBatchRows takes the credits of at most limit staged rows back out of the total balance, cancels their debits
+// with the fees and returns the number of rolled back rows
+func (dc *PgxWalletRepo) RollBackBatchRows(ctx context.Context, batchID int64, limit int) (int, error) {
+	return dc.finishStagedRows(ctx, "roll_back_batch_rows", rollBackStagedRows, batchID, limit)
+}
+
+func (dc *PgxWalletRepo) finishStagedRows(ctx context.Context, action string, query string, batchID int64, limit int) (int, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var finished int
+	err := audit.InTx(opCtx, dc.db, action, func(tx pgx.Tx) error {
+		return tx.QueryRow(opCtx, query, batchID, limit, dc.clock.Now()).Scan(&finished)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s failed: %w", action, err)
+	}
+
+	return finished, nil
+}
+
+// CompleteBatch finishes a batch without pending or staged rows and releases its claim
+func (dc *PgxWalletRepo) CompleteBatch(ctx context.Context, batchID int64) (*entity.Batch, error) {
+	return dc.finishBatch(ctx, "complete_batch", completeBatch, batchID)
+}
+
+// FailBatch finishes an all or nothing batch whose staged rows were rolled back and releases its claim
+func (dc *PgxWalletRepo) FailBatch(ctx context.Context, batchID int64) (*entity.Batch, error) {
+	return dc.finishBatch(ctx, "fail_batch", failBatch, batchID)
+}
+
+func (dc *PgxWalletRepo) finishBatch(ctx context.Context, action string, query string, batchID int64) (*entity.Batch, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	err := audit.InTx(opCtx, dc.db, action, func(tx pgx.Tx) error {
+		_, err := tx.Exec(opCtx, query, batchID, dc.clock.Now())
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", action, err)
+	}
+
+	return dc.GetBatch(opCtx, batchID)
+}
+
+// applyBatchRow credits or debits the wallet of the row, a staged row is left unreleased for the rest of its batch.
+// A denied debit fails its row and returns no transaction.
+func (dc *PgxWalletRepo) applyBatchRow(ctx context.Context, tx pgx.Tx, row entity.BatchRow, staged bool, debits repo.BatchDebits) (*uuid.UUID, error) {
+	var transactionID *uuid.UUID
+	var err error
+	if row.Type == entity.DEBIT {
+		transactionID, err = dc.applyBatchDebit(ctx, tx, row, staged, debits)
+	} else {
+		transactionID, err = dc.applyBatchCredit(ctx, tx, row, staged)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = dc.rejectionReason(ctx, row.UserID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := dc.clock.Now()
+	if transactionID == nil {
+		_, err := tx.Exec(ctx, failBatchRow, row.ID, entity.ErrRiskDenied.Error(), now)
+		if err != nil {
+			return nil, fmt.Errorf("could not update batch row: %w", err)
+		}
+		return nil, nil
+	}
+	status := entity.BATCH_ROW_SUCCEEDED
+	if staged {
+		status = entity.BATCH_ROW_STAGED
+	}
+	tag, err := tx.Exec(ctx, applyBatchRowQuery, row.ID, *transactionID, status, now)
+	if err != nil {
+		return nil, fmt.Errorf("could not update batch row: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, fmt.Errorf("batch row %d is already processed", row.ID)
+	}
+	return transactionID, nil
+}
+
+func (dc *PgxWalletRepo) applyBatchCredit(ctx context.Context, tx pgx.Tx, row entity.BatchRow, staged bool) (*uuid.UUID, error) {
+	if err := dc.checkChargeLimits(ctx, tx, row.UserID, row.Amount); err != nil {
+		return nil, err
+	}
+
+	query := batchCreditQuery
+	if staged {
+		query = stageBatchCreditQuery
+	}
+	var transactionID uuid.UUID
+	err := idempotencyError(tx.QueryRow(ctx, query, row.UserID, row.Amount, row.Idempotency, dc.clock.Now()).Scan(&transactionID))
+	if err != nil {
+		return nil, err
+	}
+	return &transactionID, nil
+}
+
+// applyBatchDebit takes a debit row through the checks of the debit API and pays it out to the default destination
+// of the user. A debit of a partial batch is due at once, a staged debit has no release time until its batch is released.
+func (dc *PgxWalletRepo) applyBatchDebit(ctx context.Context, tx pgx.Tx, row entity.BatchRow, staged bool, debits repo.BatchDebits) (*uuid.UUID, error) {
+	if debits.Quote == nil {
+		return nil, fmt.Errorf("batch row %d is a debit and the batch has no fee quote", row.ID)
+	}
+	destination, err := scanDestination(tx.QueryRow(ctx, getDefaultDestination, row.UserID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.ErrDestinationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get destination failed: %w", err)
+	}
+	if !destination.Verified {
+		return nil, entity.ErrDestinationNotVerified
+	}
+	fee, err := debits.Quote(ctx, row.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("could not quote withdrawal fee: %w", err)
+	}
+	var screen *repo.DebitScreen
+	if debits.Screen != nil {
+		screen = debits.Screen(row.Amount)
+	}
+
+	var releaseTime *time.Time
+	if !staged {
+		now := dc.clock.Now()
+		releaseTime = &now
+	}
+	transactionID, _, err := dc.debit(ctx, tx, row.UserID, &row.Idempotency, row.Amount, fee, releaseTime, &destination.ID, screen)
+	return transactionID, err
+}
+
+// querier is implemented by both the pool and a transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func readBatchRows(ctx context.Context, db querier, query string, args ...any) ([]entity.BatchRow, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get batch rows failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.BatchRow, 0)
+	for rows.Next() {
+		r := entity.BatchRow{}
+		err := rows.Scan(&r.ID, &r.BatchID, &r.Line, &r.UserID, &r.Type, &r.Amount, &r.Idempotency, &r.Status,
+			&r.Error, &r.TransactionID, &r.ProcessedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error in reading batch row: %w", err)
+		}
+		list = append(list, r)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading batch rows: %w", rows.Err())
+	}
+	return list, nil
+}
+
+func scanBatch(row pgx.Row) (*entity.Batch, error) {
+	b := entity.Batch{}
+	err := row.Scan(&b.ID, &b.FileName, &b.Mode, &b.Status, &b.TotalRows, &b.Staged, &b.Succeeded, &b.Failed, &b.RolledBack,
+		&b.Pending, &b.CreatedBy, &b.CreatedAt, &b.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+const (
+	batchColumns = `b.id, b.file_name, b.mode, b.status, b.total_rows,
+    COUNT(r.id) FILTER (WHERE r.status = 'staged'),
+    COUNT(r.id) FILTER (WHERE r.status = 'succeeded'),
+    COUNT(r.id) FILTER (WHERE r.status = 'failed'),
+    COUNT(r.id) FILTER (WHERE r.status = 'rolled_back'),
+    COUNT(r.id) FILTER (WHERE r.status = 'pending'),
+    b.created_by, b.created_at, b.completed_at`
+	batchRowColumns      = `id, batch_id, line, user_id, type, amount, idempotency_key, status, error, transaction_id, processed_at`
+	getUsedIdempotencies = `
+SELECT k.key
+FROM unnest($1::bigint[], $2::uuid[]) AS k(user_id, key)
+WHERE EXISTS (SELECT 1 FROM batch_rows r WHERE r.idempotency_key = k.key)
+   OR EXISTS (SELECT 1 FROM transactions t WHERE t.user_id = k.user_id AND t.idempotency_key = k.key)
+`
+	insertBatch = `
+INSERT INTO batches (file_name, mode, total_rows, created_by, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5::timestamptz, $5::timestamptz)
+RETURNING id
+`
+	insertBatchRow = `
+INSERT INTO batch_rows (batch_id, line, user_id, type, amount, idempotency_key)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+	getBatches = `
+SELECT ` + batchColumns + `
+FROM (SELECT * FROM batches ORDER BY id DESC LIMIT $1) b
+LEFT JOIN batch_rows r ON r.batch_id = b.id
+GROUP BY b.id, b.file_name, b.mode, b.status, b.total_rows, b.created_by, b.created_at, b.completed_at
+ORDER BY b.id DESC
+`
+	getBatch = `
+SELECT ` + batchColumns + `
+FROM batches b
+LEFT JOIN batch_rows r ON r.batch_id = b.id
+WHERE b.id = $1
+GROUP BY b.id
+`
+	getBatchRows = `
+SELECT ` + batchRowColumns + `
+FROM batch_rows
+WHERE batch_id = $1 AND ($2::text = '' OR status = $2) AND line > $3
+ORDER BY line
+LIMIT $4
+`
+	getBatchRowsForUpdate = `
+SELECT ` + batchRowColumns + `
+FROM batch_rows
+WHERE batch_id = $1 AND status = 'pending'
+ORDER BY line
+LIMIT $2
+FOR UPDATE
+`
+	claimBatch = `
+WITH next_batch AS (
+    SELECT id AS claimed_id
+    FROM batches
+    WHERE status IN ('pending', 'processing')
+      AND (claimed_until IS NULL OR claimed_until <= $2::timestamptz)
+    ORDER BY id
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE batches b
+SET status = 'processing', claimed_until = $2::timestamptz + $1::interval, updated_at = $2::timestamptz
+FROM next_batch n
+WHERE b.id = n.claimed_id
+RETURNING b.id
+`
+	batchCreditQuery = `
+WITH upserted_wallet AS (
+    INSERT INTO wallets (user_id, total_balance, available_balance, created_at, updated_at)
+    VALUES ($1, $2, $2, $4::timestamptz, $4::timestamptz)
+    ON CONFLICT (user_id) DO UPDATE
+    SET total_balance = wallets.total_balance + EXCLUDED.total_balance,
+        available_balance = wallets.available_balance + EXCLUDED.total_balance,
+        updated_at = $4::timestamptz
+    WHERE wallets.status IN ('active', 'frozen_debit')
+    RETURNING id AS wallet_id, user_id
+)
+INSERT INTO transactions
+    (wallet_id, user_id, type, status, source, amount, released, released_at, idempotency_key, created_at)
+SELECT wallet_id, user_id, 'credit', 'success', 'batch', $2, TRUE, $4::timestamptz, $3, $4::timestamptz
+FROM upserted_wallet
+RETURNING id;
+`
+	// stageBatchCreditQuery leaves the credit without release time, so only its batch releases it
+	stageBatchCreditQuery = `
+WITH upserted_wallet AS (
+    INSERT INTO wallets (user_id, total_balance, available_balance, created_at, updated_at)
+    VALUES ($1, $2, 0, $4::timestamptz, $4::timestamptz)
+    ON CONFLICT (user_id) DO UPDATE
+    SET total_balance = wallets.total_balance + EXCLUDED.total_balance,
+        updated_at = $4::timestamptz
+    WHERE wallets.status IN ('active', 'frozen_debit')
+    RETURNING id AS wallet_id, user_id
+)
+INSERT INTO transactions
+    (wallet_id, user_id, type, status, source, amount, released, idempotency_key, created_at)
+SELECT wallet_id, user_id, 'credit', 'success', 'batch', $2, FALSE, $3, $4::timestamptz
+FROM upserted_wallet
+RETURNING id;
+`
+	applyBatchRowQuery = `
+UPDATE batch_rows
+SET status = $3, transaction_id = $2, error = '', processed_at = $4::timestamptz
+WHERE id = $1 AND status = 'pending'
+`
+	failBatchRow = `
+UPDATE batch_rows
+SET status = 'failed', error = $2, processed_at = $3::timestamptz
+WHERE id = $1 AND status = 'pending'
+`
+	releaseStagedRows = `
+WITH staged AS (
+    SELECT id, transaction_id
+    FROM batch_rows
+    WHERE batch_id = $1 AND status = 'staged'
+    ORDER BY line
+    LIMIT $2
+    FOR UPDATE
+),
+succeeded AS (
+    UPDATE batch_rows r
+    SET status = 'succeeded', processed_at = $3::timestamptz
+    FROM staged s
+    WHERE r.id = s.id
+),
+released_txn AS (
+    UPDATE transactions t
+    SET released = TRUE, released_at = $3::timestamptz, updated_at = $3::timestamptz
+    FROM staged s
+    WHERE t.id = s.transaction_id AND t.type = 'credit' AND NOT t.released
+    RETURNING t.wallet_id, t.amount
+),
+-- a staged debit and its fee are left to the release job like any due debit
+due_debits AS (
+    UPDATE transactions t
+    SET release_time = $3::timestamptz, updated_at = $3::timestamptz
+    FROM staged s
+    WHERE (t.id = s.transaction_id OR t.related_transaction_id = s.transaction_id)
+      AND t.type = 'debit' AND t.release_time IS NULL
+),
+per_wallet AS (
+    SELECT wallet_id, SUM(amount) AS amount
+    FROM released_txn
+    GROUP BY wallet_id
+),
+updated_wallets AS (
+    UPDATE wallets w
+    SET available_balance = w.available_balance + p.amount,
+        updated_at = $3::timestamptz
+    FROM per_wallet p
+    WHERE w.id = p.wallet_id
+)
+SELECT COUNT(*) FROM staged;
+`
+	rollBackStagedRows = `
+WITH staged AS (
+    SELECT id, transaction_id
+    FROM batch_rows
+    WHERE batch_id = $1 AND status = 'staged'
+    ORDER BY line
+    LIMIT $2
+    FOR UPDATE
+),
+rolled_back AS (
+    UPDATE batch_rows r
+    SET status = 'rolled_back', processed_at = $3::timestamptz
+    FROM staged s
+    WHERE r.id = s.id
+),
+cancelled_credits AS (
+    UPDATE transactions t
+    SET status = 'cancelled', updated_at = $3::timestamptz
+    FROM staged s
+    WHERE t.id = s.transaction_id AND t.type = 'credit' AND NOT t.released AND t.status = 'success'
+    RETURNING t.wallet_id, t.amount
+),
+-- a staged debit is cancelled with its fee whether it waits for review or not, both go back to the available balance
+cancelled_debits AS (
+    UPDATE transactions t
+    SET status = 'cancelled', updated_at = $3::timestamptz
+    FROM staged s
+    WHERE (t.id = s.transaction_id OR t.related_transaction_id = s.transaction_id)
+      AND t.type = 'debit' AND NOT t.released AND t.status IN ('pending', 'review', 'success')
+    RETURNING t.wallet_id, t.amount
+),
+per_wallet AS (
+    SELECT wallet_id, SUM(total) AS total, SUM(available) AS available
+    FROM (
+        SELECT wallet_id, amount AS total, 0 AS available FROM cancelled_credits
+        UNION ALL
+        SELECT wallet_id, 0 AS total, amount AS available FROM cancelled_debits
+    ) c
+    GROUP BY wallet_id
+),
+updated_wallets AS (
+    UPDATE wallets w
+    SET total_balance = w.total_balance - p.total,
+        available_balance = w.available_balance - p.available,
+        updated_at = $3::timestamptz
+    FROM per_wallet p
+    WHERE w.id = p.wallet_id
+)
+SELECT COUNT(*) FROM staged;
+`
+	completeBatch = `
+UPDATE batches
+SET status = 'completed', claimed_until = NULL, completed_at = $2::timestamptz, updated_at = $2::timestamptz
+WHERE id = $1 AND status = 'processing'
+  AND NOT EXISTS (SELECT 1 FROM batch_rows WHERE batch_id = $1 AND status IN ('pending', 'staged'))
+`
+	failBatch = `
+UPDATE batches
+SET status = 'failed', claimed_until = NULL, completed_at = $2::timestamptz, updated_at = $2::timestamptz
+WHERE id = $1 AND status = 'processing'
+  AND NOT EXISTS (SELECT 1 FROM batch_rows WHERE batch_id = $1 AND status IN ('pending', 'staged'))
+`
+)
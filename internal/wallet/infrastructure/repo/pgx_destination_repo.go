@@ -0,0 +1,199 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+)
+
+// AddDestination registers an unverified destination, the first destination of a user becomes the default.
+// The wallet row of the user is locked first, so two first destinations added at once do not both become the default.
+func (dc *PgxWalletRepo) AddDestination(ctx context.Context, destination *entity.Destination) (*entity.Destination, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var added *entity.Destination
+	err := audit.InTx(opCtx, dc.db, "add_destination", func(tx pgx.Tx) error {
+		if _, err := tx.Exec(opCtx, ensureWallet, destination.UserID); err != nil {
+			return fmt.Errorf("could not create wallet: %w", err)
+		}
+		if _, err := tx.Exec(opCtx, lockDestinationOwner, destination.UserID); err != nil {
+			return fmt.Errorf("could not lock wallet: %w", err)
+		}
+		var err error
+		added, err = scanDestination(tx.QueryRow(opCtx, insertDestination, destination.UserID, destination.Type, destination.Number,
+			dc.clock.Now()))
+		return err
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.ConstraintName == "payout_destinations_user_id_number_key" {
+		return nil, entity.ErrDestinationExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("add destination failed: %w", err)
+	}
+
+	return added, nil
+}
+
+// GetDestinations return the destinations of the user, the default one first
+func (dc *PgxWalletRepo) GetDestinations(ctx context.Context, userId int64) ([]entity.Destination, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	rows, err := dc.db.Query(opCtx, getDestinations, userId)
+	if err != nil {
+		return nil, fmt.Errorf("get destinations failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.Destination, 0)
+	for rows.Next() {
+		d, err := scanDestination(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error in reading destination row: %w", err)
+		}
+		list = append(list, *d)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading destinations: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+// GetDestination return the destination of the user with the given id, or the default destination when id is nil
+func (dc *PgxWalletRepo) GetDestination(ctx context.Context, userId int64, id *int64) (*entity.Destination, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var row pgx.Row
+	if id == nil {
+		row = dc.db.QueryRow(opCtx, getDefaultDestination, userId)
+	} else {
+		row = dc.db.QueryRow(opCtx, getDestination, userId, *id)
+	}
+	d, err := scanDestination(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.ErrDestinationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get destination failed: %w", err)
+	}
+
+	return d, nil
+}
+
+// SetDefaultDestination makes the destination the one used by withdrawals which do not select a destination
+func (dc *PgxWalletRepo) SetDefaultDestination(ctx context.Context, userId int64, id int64) error {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	now := dc.clock.Now()
+	err := audit.InTx(opCtx, dc.db, "set_default_destination", func(tx pgx.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(opCtx, lockDestinations, userId, id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return entity.ErrDestinationNotFound
+		}
+		if _, err := tx.Exec(opCtx, clearDefaultDestination, userId, id, now); err != nil {
+			return err
+		}
+		_, err := tx.Exec(opCtx, setDefaultDestination, userId, id, now)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("set default destination failed: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyDestination marks a destination as confirmed to belong to its user
+func (dc *PgxWalletRepo) VerifyDestination(ctx context.Context, id int64, actor string) (*entity.Destination, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var verified *entity.Destination
+	err := audit.InTx(opCtx, dc.db, "verify_destination", func(tx pgx.Tx) error {
+		var err error
+		verified, err = scanDestination(tx.QueryRow(opCtx, verifyDestination, id, actor, dc.clock.Now()))
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.ErrDestinationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("verify destination failed: %w", err)
+	}
+
+	return verified, nil
+}
+
+// scanDestination reads a destination with its card number masked
+func scanDestination(row pgx.Row) (*entity.Destination, error) {
+	d := entity.Destination{}
+	err := row.Scan(&d.ID, &d.UserID, &d.Type, &d.Number, &d.Verified, &d.IsDefault, &d.CreatedAt, &d.VerifiedAt, &d.VerifiedBy)
+	if err != nil {
+		return nil, err
+	}
+	d.Number = entity.MaskDestination(d.Type, d.Number)
+	return &d, nil
+}
+
+const (
+	destinationColumns   = `id, user_id, type, number, verified, is_default, created_at, verified_at, verified_by`
+	lockDestinationOwner = `
+SELECT 1 FROM wallets WHERE user_id = $1 FOR UPDATE
+`
+	insertDestination = `
+INSERT INTO payout_destinations (user_id, type, number, is_default, created_at, updated_at)
+VALUES ($1, $2, $3, NOT EXISTS (SELECT 1 FROM payout_destinations WHERE user_id = $1 AND is_default), $4::timestamptz, $4::timestamptz)
+RETURNING ` + destinationColumns
+	getDestinations = `
+SELECT ` + destinationColumns + `
+FROM payout_destinations
+WHERE user_id = $1
+ORDER BY is_default DESC, id
+`
+	getDestination = `
+SELECT ` + destinationColumns + `
+FROM payout_destinations
+WHERE user_id = $1 AND id = $2
+`
+	getDefaultDestination = `
+SELECT ` + destinationColumns + `
+FROM payout_destinations
+WHERE user_id = $1 AND is_default
+`
+	lockDestinations = `
+SELECT COALESCE(bool_or(id = $2), FALSE)
+FROM (
+    SELECT id FROM payout_destinations WHERE user_id = $1 FOR UPDATE
+) d
+`
+	clearDefaultDestination = `
+UPDATE payout_destinations
+SET is_default = FALSE, updated_at = $3::timestamptz
+WHERE user_id = $1 AND is_default AND id <> $2
+`
+	setDefaultDestination = `
+UPDATE payout_destinations
+SET is_default = TRUE, updated_at = $3::timestamptz
+WHERE user_id = $1 AND id = $2 AND NOT is_default
+`
+	verifyDestination = `
+UPDATE payout_destinations
+SET verified = TRUE,
+    verified_by = COALESCE(verified_by, $2),
+    verified_at = COALESCE(verified_at, $3::timestamptz),
+    updated_at = $3::timestamptz
+WHERE id = $1
+RETURNING ` + destinationColumns
+)
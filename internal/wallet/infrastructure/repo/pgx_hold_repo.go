@@ -0,0 +1,282 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// PlaceHold reserves amount of the available balance until expiresAt. Placing a hold again with the same
+// idempotency returns the hold placed the first time.
+func (dc *PgxWalletRepo) PlaceHold(ctx context.Context, userId int64, idempotency *uuid.UUID, amount int64, reference string, expiresAt time.Time) (*entity.Hold, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	if idempotency == nil {
+		return nil, errors.New("hold operations must have idempotency")
+	}
+
+	if amount <= 0 {
+		return nil, errors.New("negative or 0 is not acceptable amount for hold operation")
+	}
+
+	now := dc.clock.Now()
+	if now.After(expiresAt) {
+		return nil, errors.New("hold expiry can't be in the past")
+	}
+
+	var hold *entity.Hold
+	err := audit.InTx(opCtx, dc.db, "place_hold", func(tx pgx.Tx) error {
+		var err error
+		hold, err = scanHold(tx.QueryRow(opCtx, getHoldByIdempotency, userId, idempotency))
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		hold, err = scanHold(tx.QueryRow(opCtx, placeHoldQuery, userId, amount, reference, idempotency, expiresAt, now))
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = dc.rejectionReason(opCtx, userId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("place hold failed: %w", err)
+	}
+
+	return hold, nil
+}
+
+// CaptureHold debits amount of an active hold and gives the rest of the hold back to the available balance.
+// The promotional credit reserved by the hold is spent first, from the soonest expiring grant on.
+func (dc *PgxWalletRepo) CaptureHold(ctx context.Context, userId int64, id *uuid.UUID, amount int64) (*entity.Hold, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	if amount <= 0 {
+		return nil, errors.New("negative or 0 is not acceptable amount for capture operation")
+	}
+
+	now := dc.clock.Now()
+	var captured *entity.Hold
+	err := audit.InTx(opCtx, dc.db, "capture_hold", func(tx pgx.Tx) error {
+		var walletID int64
+		hold := entity.Hold{}
+		err := tx.QueryRow(opCtx, lockHold, userId, id).Scan(&walletID, &hold.Amount, &hold.PromoAmount, &hold.Status,
+			&hold.Idempotency, &hold.ExpiresAt)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return entity.ErrHoldNotFound
+		case err != nil:
+			return err
+		case hold.Status != entity.HOLD_ACTIVE || !hold.ExpiresAt.After(now):
+			return entity.ErrHoldNotActive
+		case amount > hold.Amount:
+			return entity.ErrCaptureExceedsHold
+		}
+
+		promoSpent := min(amount, hold.PromoAmount)
+		if err := spendPromo(opCtx, tx, walletID, promoSpent, now); err != nil {
+			return err
+		}
+
+		// the capture debit is derived from the hold so it is recorded only once
+		captureIdempotency := uuid.NewV5(hold.Idempotency, "capture")
+		captured, err = scanHold(tx.QueryRow(opCtx, captureHoldQuery, id, walletID, userId, amount, hold.Amount,
+			captureIdempotency, hold.PromoAmount, promoSpent, now))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("capture hold failed: %w", err)
+	}
+
+	return captured, nil
+}
+
+// VoidHold cancels an active hold and gives its amount back to the available balance
+func (dc *PgxWalletRepo) VoidHold(ctx context.Context, userId int64, id *uuid.UUID) (*entity.Hold, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var voided *entity.Hold
+	err := audit.InTx(opCtx, dc.db, "void_hold", func(tx pgx.Tx) error {
+		var err error
+		voided, err = scanHold(tx.QueryRow(opCtx, voidHoldQuery, userId, id, dc.clock.Now()))
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := dc.GetHold(opCtx, userId, id); err != nil {
+			return nil, err
+		}
+		return nil, entity.ErrHoldNotActive
+	}
+	if err != nil {
+		return nil, fmt.Errorf("void hold failed: %w", err)
+	}
+
+	return voided, nil
+}
+
+// GetHold return the hold of the user with the given id
+func (dc *PgxWalletRepo) GetHold(ctx context.Context, userId int64, id *uuid.UUID) (*entity.Hold, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	hold, err := scanHold(dc.db.QueryRow(opCtx, getHold, userId, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get hold failed: %w", err)
+	}
+
+	return hold, nil
+}
+
+// ExpireHolds gives the amount of at most batchSize overdue holds back to the available balance
+// and returns the number of expired holds
+func (dc *PgxWalletRepo) ExpireHolds(ctx context.Context, batchSize int) (int64, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var count int64
+	err := audit.InTx(opCtx, dc.db, "expire_holds", func(tx pgx.Tx) error {
+		return tx.QueryRow(opCtx, expireHoldsQuery, batchSize, dc.clock.Now()).Scan(&count)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("expire holds failed: %w", err)
+	}
+
+	return count, nil
+}
+
+func scanHold(row pgx.Row) (*entity.Hold, error) {
+	h := entity.Hold{}
+	err := row.Scan(&h.ID, &h.UserID, &h.Amount, &h.CapturedAmount, &h.PromoAmount, &h.Status, &h.Reference, &h.Idempotency,
+		&h.ExpiresAt, &h.CreatedAt, &h.ResolvedAt, &h.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+const (
+	holdColumns          = `id, user_id, amount, captured_amount, promo_amount, status, reference, idempotency_key, expires_at, created_at, resolved_at, transaction_id`
+	getHoldByIdempotency = `
+SELECT ` + holdColumns + `
+FROM holds
+WHERE user_id = $1 AND idempotency_key = $2
+`
+	getHold = `
+SELECT ` + holdColumns + `
+FROM holds
+WHERE user_id = $1 AND id = $2
+`
+	// placeHoldQuery reserves the unreserved promotional credit before cash
+	placeHoldQuery = `
+WITH current_wallet AS (
+    SELECT id, LEAST($2::bigint, promo_balance - promo_reserved) AS promo_amount
+    FROM wallets
+    WHERE user_id = $1
+    FOR UPDATE
+),
+updated_wallet AS (
+    UPDATE wallets w
+    SET available_balance = w.available_balance - $2,
+        promo_reserved = w.promo_reserved + c.promo_amount,
+        updated_at = $6::timestamptz
+    FROM current_wallet c
+    WHERE w.id = c.id AND w.available_balance >= $2
+      AND w.status = 'active'
+      AND NOT EXISTS (
+          SELECT 1 FROM wallet_quarantines q
+          WHERE q.wallet_id = w.id AND q.released_at IS NULL
+      )
+    RETURNING w.id, w.user_id, c.promo_amount
+)
+INSERT INTO holds (wallet_id, user_id, amount, promo_amount, reference, idempotency_key, expires_at, created_at, updated_at)
+SELECT id, user_id, $2, promo_amount, $3, $4, $5, $6::timestamptz, $6::timestamptz
+FROM updated_wallet
+RETURNING ` + holdColumns
+	lockHold = `
+SELECT wallet_id, amount, promo_amount, status, idempotency_key, expires_at
+FROM holds
+WHERE user_id = $1 AND id = $2
+FOR UPDATE
+`
+	captureHoldQuery = `
+WITH capture_txn AS (
+    INSERT INTO transactions
+        (wallet_id, user_id, type, status, source, amount, release_time, released, released_at, idempotency_key, created_at)
+    VALUES ($2, $3, 'debit', 'success', 'hold', ($4::bigint * -1), $9::timestamptz, TRUE, $9::timestamptz, $6, $9::timestamptz)
+    RETURNING id
+),
+updated_wallet AS (
+    UPDATE wallets
+    SET total_balance = total_balance - $4::bigint,
+        available_balance = available_balance + ($5::bigint - $4::bigint),
+        promo_balance = promo_balance - $8::bigint,
+        promo_reserved = promo_reserved - $7::bigint,
+        updated_at = $9::timestamptz
+    WHERE id = $2
+)
+UPDATE holds
+SET status = 'captured',
+    captured_amount = $4,
+    transaction_id = (SELECT id FROM capture_txn),
+    resolved_at = $9::timestamptz,
+    updated_at = $9::timestamptz
+WHERE id = $1
+RETURNING ` + holdColumns
+	voidHoldQuery = `
+WITH voided_hold AS (
+    UPDATE holds
+    SET status = 'voided', resolved_at = $3::timestamptz, updated_at = $3::timestamptz
+    WHERE user_id = $1 AND id = $2 AND status = 'active'
+    RETURNING wallet_id, ` + holdColumns + `
+),
+restored_wallet AS (
+    UPDATE wallets w
+    SET available_balance = w.available_balance + v.amount,
+        promo_reserved = w.promo_reserved - v.promo_amount,
+        updated_at = $3::timestamptz
+    FROM voided_hold v
+    WHERE w.id = v.wallet_id
+)
+SELECT ` + holdColumns + ` FROM voided_hold
+`
+	expireHoldsQuery = `
+WITH due_hold AS (
+    SELECT id
+    FROM holds
+    WHERE status = 'active'
+      AND expires_at <= $2::timestamptz
+    ORDER BY expires_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+),
+expired_hold AS (
+    UPDATE holds h
+    SET status = 'expired', resolved_at = $2::timestamptz, updated_at = $2::timestamptz
+    FROM due_hold d
+    WHERE h.id = d.id
+    RETURNING h.wallet_id, h.amount, h.promo_amount
+),
+restored_wallet AS (
+    UPDATE wallets w
+    SET available_balance = w.available_balance + e.amount,
+        promo_reserved = w.promo_reserved - e.promo_amount,
+        updated_at = $2::timestamptz
+    FROM (
+        SELECT wallet_id, SUM(amount) AS amount, SUM(promo_amount) AS promo_amount
+        FROM expired_hold
+        GROUP BY wallet_id
+    ) e
+    WHERE w.id = e.wallet_id
+)
+SELECT COUNT(*) FROM expired_hold
+`
+)
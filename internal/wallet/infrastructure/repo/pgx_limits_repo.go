@@ -0,0 +1,202 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// checkDebitLimits locks the wallet and validates a withdrawal against its limits. The withdrawal history is read
+// after the lock is taken, so concurrent debits of the same wallet are evaluated one after the other.
+func (dc *PgxWalletRepo) checkDebitLimits(ctx context.Context, tx pgx.Tx, userId int64, amount int64) error {
+	_, locked, err := lockForLimits(ctx, tx, userId)
+	if err != nil || !locked {
+		// a missing wallet is rejected by the debit itself
+		return err
+	}
+	settings, err := readLimits(ctx, tx, userId)
+	if err != nil {
+		return err
+	}
+
+	usage := entity.WithdrawalUsage{}
+	if settings.Effective.HasVelocityLimits() {
+		err = tx.QueryRow(ctx, getWithdrawalUsage, userId, dc.clock.Now()).Scan(&usage.Today, &usage.ThisMonth, &usage.LastHourCount)
+		if err != nil {
+			return fmt.Errorf("could not read withdrawal usage: %w", err)
+		}
+	}
+	return settings.Effective.CheckDebit(usage, amount)
+}
+
+// checkChargeLimits validates a credit against the maximum balance of the wallet. The wallet row is created
+// first when missing so even the first charges of a user are serialized by the wallet lock.
+func (dc *PgxWalletRepo) checkChargeLimits(ctx context.Context, tx pgx.Tx, userId int64, amount int64) error {
+	if _, err := tx.Exec(ctx, ensureWallet, userId, dc.clock.Now()); err != nil {
+		return fmt.Errorf("could not create wallet: %w", err)
+	}
+	totalBalance, _, err := lockForLimits(ctx, tx, userId)
+	if err != nil {
+		return err
+	}
+	settings, err := readLimits(ctx, tx, userId)
+	if err != nil {
+		return err
+	}
+	return settings.Effective.CheckCharge(totalBalance, amount)
+}
+
+// GetLimits return the default limits, the override of the user and the limits in effect for the user
+func (dc *PgxWalletRepo) GetLimits(ctx context.Context, userId int64) (*entity.LimitSettings, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	return readLimits(opCtx, dc.db, userId)
+}
+
+// SetDefaultLimits replaces the limits of every wallet without an override
+func (dc *PgxWalletRepo) SetDefaultLimits(ctx context.Context, limits entity.Limits) error {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	err := audit.InTx(opCtx, dc.db, "set_default_limits", func(tx pgx.Tx) error {
+		_, err := tx.Exec(opCtx, setDefaultLimits, limits.MaxSingleWithdrawal, limits.DailyWithdrawalTotal,
+			limits.MonthlyWithdrawalTotal, limits.MaxBalance, limits.HourlyWithdrawalCount, dc.clock.Now())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("set default limits failed: %w", err)
+	}
+	return nil
+}
+
+// SetUserLimits replaces the limit override of the user
+func (dc *PgxWalletRepo) SetUserLimits(ctx context.Context, userId int64, limits entity.Limits) error {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	err := audit.InTx(opCtx, dc.db, "set_user_limits", func(tx pgx.Tx) error {
+		_, err := tx.Exec(opCtx, setUserLimits, userId, limits.MaxSingleWithdrawal, limits.DailyWithdrawalTotal,
+			limits.MonthlyWithdrawalTotal, limits.MaxBalance, limits.HourlyWithdrawalCount, dc.clock.Now())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("set user limits failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserLimits removes the limit override of the user so the defaults apply again
+func (dc *PgxWalletRepo) DeleteUserLimits(ctx context.Context, userId int64) error {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	err := audit.InTx(opCtx, dc.db, "delete_user_limits", func(tx pgx.Tx) error {
+		_, err := tx.Exec(opCtx, deleteUserLimits, userId)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("delete user limits failed: %w", err)
+	}
+	return nil
+}
+
+func lockForLimits(ctx context.Context, tx pgx.Tx, userId int64) (int64, bool, error) {
+	var walletID, totalBalance, availableBalance, promoBalance int64
+	var status entity.WalletStatus
+	err := tx.QueryRow(ctx, lockWallet, userId).Scan(&walletID, &totalBalance, &availableBalance, &status, &promoBalance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("could not lock wallet: %w", err)
+	}
+	return totalBalance, true, nil
+}
+
+// rowQuerier is implemented by both the pool and a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func readLimits(ctx context.Context, q rowQuerier, userId int64) (*entity.LimitSettings, error) {
+	settings := &entity.LimitSettings{UserID: userId}
+	err := scanLimits(q.QueryRow(ctx, getDefaultLimits), &settings.Defaults)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("could not read default limits: %w", err)
+	}
+	override := &entity.Limits{}
+	err = scanLimits(q.QueryRow(ctx, getUserLimits, userId), override)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return nil, fmt.Errorf("could not read user limits: %w", err)
+	default:
+		settings.Override = override
+	}
+	settings.Effective = settings.Defaults.Merge(settings.Override)
+	return settings, nil
+}
+
+func scanLimits(row pgx.Row, l *entity.Limits) error {
+	return row.Scan(&l.MaxSingleWithdrawal, &l.DailyWithdrawalTotal, &l.MonthlyWithdrawalTotal, &l.MaxBalance, &l.HourlyWithdrawalCount)
+}
+
+const (
+	ensureWallet = `
+INSERT INTO wallets (user_id, total_balance, available_balance, created_at, updated_at)
+VALUES ($1, 0, 0, $2::timestamptz, $2::timestamptz)
+ON CONFLICT (user_id) DO NOTHING
+`
+	getDefaultLimits = `
+SELECT max_single_withdrawal, daily_withdrawal_total, monthly_withdrawal_total, max_balance, hourly_withdrawal_count
+FROM limit_defaults
+`
+	getUserLimits = `
+SELECT max_single_withdrawal, daily_withdrawal_total, monthly_withdrawal_total, max_balance, hourly_withdrawal_count
+FROM user_limits
+WHERE user_id = $1
+`
+	// Days and months are calendar periods in UTC, the withdrawal count is over a sliding hour.
+	getWithdrawalUsage = `
+SELECT
+    COALESCE(SUM(-amount) FILTER (WHERE created_at >= date_trunc('day', $2::timestamptz, 'UTC')), 0),
+    COALESCE(SUM(-amount) FILTER (WHERE created_at >= date_trunc('month', $2::timestamptz, 'UTC')), 0),
+    COUNT(*) FILTER (WHERE created_at > $2::timestamptz - INTERVAL '1 hour')
+FROM transactions
+WHERE user_id = $1
+  AND type = 'debit'
+  AND source = 'user'
+  AND status NOT IN ('failed', 'cancelled')
+  AND created_at >= LEAST(date_trunc('month', $2::timestamptz, 'UTC'), $2::timestamptz - INTERVAL '1 hour')
+`
+	setDefaultLimits = `
+UPDATE limit_defaults
+SET max_single_withdrawal = $1,
+    daily_withdrawal_total = $2,
+    monthly_withdrawal_total = $3,
+    max_balance = $4,
+    hourly_withdrawal_count = $5,
+    updated_at = $6::timestamptz
+`
+	setUserLimits = `
+INSERT INTO user_limits
+    (user_id, max_single_withdrawal, daily_withdrawal_total, monthly_withdrawal_total, max_balance, hourly_withdrawal_count, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7::timestamptz)
+ON CONFLICT (user_id) DO UPDATE
+SET max_single_withdrawal = EXCLUDED.max_single_withdrawal,
+    daily_withdrawal_total = EXCLUDED.daily_withdrawal_total,
+    monthly_withdrawal_total = EXCLUDED.monthly_withdrawal_total,
+    max_balance = EXCLUDED.max_balance,
+    hourly_withdrawal_count = EXCLUDED.hourly_withdrawal_count,
+    updated_at = EXCLUDED.updated_at
+`
+	deleteUserLimits = `
+DELETE FROM user_limits
+WHERE user_id = $1
+`
+)
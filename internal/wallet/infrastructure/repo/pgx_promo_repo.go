@@ -0,0 +1,319 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+)
+
+// GrantPromo adds a promo grant to the balance of the user. Granting again with the same idempotency
+// returns the grant added the first time.
+func (dc *PgxWalletRepo) GrantPromo(ctx context.Context, grant *entity.PromoGrant) (*entity.PromoGrant, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	if grant.Amount <= 0 {
+		return nil, errors.New("negative or 0 is not acceptable amount for promo grant")
+	}
+
+	now := dc.clock.Now()
+	if now.After(grant.ExpiresAt) {
+		return nil, errors.New("promo expiry can't be in the past")
+	}
+
+	var granted *entity.PromoGrant
+	err := audit.InTx(opCtx, dc.db, "grant_promo", func(tx pgx.Tx) error {
+		var err error
+		granted, err = scanPromoGrant(tx.QueryRow(opCtx, getPromoGrantByIdempotency, grant.UserID, grant.Idempotency))
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		granted, err = scanPromoGrant(tx.QueryRow(opCtx, grantPromoQuery, grant.UserID, grant.Amount, grant.Idempotency,
+			grant.Reason, grant.CreatedBy, grant.ExpiresAt, now))
+		return err
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.ConstraintName == "idx_txn_user_key" || pgErr.ConstraintName == "promo_grants_idempotency_key_key") {
+		return nil, entity.ErrIdempotencyUsed
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = dc.rejectionReason(opCtx, grant.UserID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("grant promo failed: %w", err)
+	}
+
+	return granted, nil
+}
+
+// GetPromoGrants return all promo grants of the user, the latest first
+func (dc *PgxWalletRepo) GetPromoGrants(ctx context.Context, userId int64) ([]entity.PromoGrant, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	grants, err := dc.getPromoGrants(opCtx, getPromoGrants, userId)
+	if err != nil {
+		return nil, fmt.Errorf("get promo grants failed: %w", err)
+	}
+	return grants, nil
+}
+
+// ExpirePromoGrants takes back what is left of at most batchSize overdue promo grants and returns the number
+// of grants taken back from. Promotional credit reserved by a hold is only taken back once the hold is voided
+// or expires, a capture spends it before any other grant.
+func (dc *PgxWalletRepo) ExpirePromoGrants(ctx context.Context, batchSize int) (int64, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	now := dc.clock.Now()
+	var count int64
+	err := audit.InTx(opCtx, dc.db, "expire_promo_grants", func(tx pgx.Tx) error {
+		rows, err := tx.Query(opCtx, claimDuePromoGrants, batchSize, now)
+		if err != nil {
+			return fmt.Errorf("could not claim due promo grants: %w", err)
+		}
+		type dueGrant struct {
+			id, walletID, remaining, unreserved int64
+		}
+		due := make([]dueGrant, 0, batchSize)
+		for rows.Next() {
+			g := dueGrant{}
+			if err := rows.Scan(&g.id, &g.walletID, &g.remaining, &g.unreserved); err != nil {
+				rows.Close()
+				return fmt.Errorf("error in reading due promo grant row: %w", err)
+			}
+			due = append(due, g)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			return fmt.Errorf("something went wrong reading due promo grants: %w", rows.Err())
+		}
+
+		// grants of the same wallet share what is not reserved by its holds
+		taken := make(map[int64]int64)
+		for _, g := range due {
+			amount := min(g.remaining, g.unreserved-taken[g.walletID])
+			if amount <= 0 {
+				continue
+			}
+			if _, err := tx.Exec(opCtx, expirePromoGrantQuery, g.id, amount, now); err != nil {
+				return fmt.Errorf("could not expire promo grant %d: %w", g.id, err)
+			}
+			taken[g.walletID] += amount
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("expire promo grants failed: %w", err)
+	}
+
+	return count, nil
+}
+
+func (dc *PgxWalletRepo) getPromoGrants(ctx context.Context, query string, userId int64) ([]entity.PromoGrant, error) {
+	rows, err := dc.db.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]entity.PromoGrant, 0)
+	for rows.Next() {
+		g, err := scanPromoGrant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error in reading promo grant row: %w", err)
+		}
+		list = append(list, *g)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading promo grants: %w", rows.Err())
+	}
+	return list, nil
+}
+
+// spendPromo takes amount from the active promo grants of the wallet, soonest expiring first
+func spendPromo(ctx context.Context, tx pgx.Tx, walletID int64, amount int64, now time.Time) error {
+	if amount <= 0 {
+		return nil
+	}
+	rows, err := tx.Query(ctx, lockPromoGrants, walletID)
+	if err != nil {
+		return fmt.Errorf("could not lock promo grants: %w", err)
+	}
+	grants := make([]entity.PromoGrant, 0)
+	for rows.Next() {
+		g := entity.PromoGrant{}
+		if err := rows.Scan(&g.ID, &g.Remaining); err != nil {
+			rows.Close()
+			return fmt.Errorf("error in reading promo grant row: %w", err)
+		}
+		grants = append(grants, g)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return fmt.Errorf("something went wrong reading promo grants: %w", rows.Err())
+	}
+
+	spends := entity.SpendPromo(grants, amount)
+	ids := make([]int64, len(spends))
+	amounts := make([]int64, len(spends))
+	for i, spend := range spends {
+		ids[i] = spend.GrantID
+		amounts[i] = spend.Amount
+	}
+	if _, err := tx.Exec(ctx, spendPromoQuery, ids, amounts, now); err != nil {
+		return fmt.Errorf("could not spend promo grants: %w", err)
+	}
+	return nil
+}
+
+func scanPromoGrant(row pgx.Row) (*entity.PromoGrant, error) {
+	g := entity.PromoGrant{}
+	err := row.Scan(&g.ID, &g.UserID, &g.Amount, &g.Remaining, &g.Expired, &g.Status, &g.Reason, &g.CreatedBy,
+		&g.TransactionID, &g.Idempotency, &g.ExpiresAt, &g.CreatedAt, &g.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+const (
+	promoColumns = `id, user_id, amount, remaining, expired_amount, status, reason, created_by, transaction_id,
+    idempotency_key, expires_at, created_at, resolved_at`
+	getPromoGrantByIdempotency = `
+SELECT ` + promoColumns + `
+FROM promo_grants
+WHERE user_id = $1 AND idempotency_key = $2
+`
+	getPromoGrants = `
+SELECT ` + promoColumns + `
+FROM promo_grants
+WHERE user_id = $1
+ORDER BY id DESC
+`
+	getActivePromoGrants = `
+SELECT ` + promoColumns + `
+FROM promo_grants
+WHERE user_id = $1 AND status = 'active'
+ORDER BY expires_at, id
+`
+	grantPromoQuery = `
+WITH upserted_wallet AS (
+    INSERT INTO wallets (user_id, total_balance, available_balance, promo_balance, created_at, updated_at)
+    VALUES ($1, $2, $2, $2, $7::timestamptz, $7::timestamptz)
+    ON CONFLICT (user_id) DO UPDATE
+    SET total_balance = wallets.total_balance + EXCLUDED.total_balance,
+        available_balance = wallets.available_balance + EXCLUDED.total_balance,
+        promo_balance = wallets.promo_balance + EXCLUDED.total_balance,
+        updated_at = $7::timestamptz
+    WHERE wallets.status IN ('active', 'frozen_debit')
+    RETURNING id AS wallet_id, user_id
+),
+grant_txn AS (
+    INSERT INTO transactions
+        (wallet_id, user_id, type, status, source, amount, released, released_at, idempotency_key, created_at)
+    SELECT wallet_id, user_id, 'credit', 'success', 'promo', $2, TRUE, $7::timestamptz, $3, $7::timestamptz
+    FROM upserted_wallet
+    RETURNING id, wallet_id, user_id
+)
+INSERT INTO promo_grants
+    (wallet_id, user_id, amount, remaining, reason, created_by, idempotency_key, transaction_id, expires_at, created_at, updated_at)
+SELECT wallet_id, user_id, $2, $2, $4, $5, $3, id, $6, $7::timestamptz, $7::timestamptz
+FROM grant_txn
+RETURNING ` + promoColumns
+	lockPromoGrants = `
+SELECT id, remaining
+FROM promo_grants
+WHERE wallet_id = $1 AND status = 'active'
+ORDER BY expires_at, id
+FOR UPDATE
+`
+	// spendPromoQuery closes the grants it uses up, a grant partly taken back by its expiry stays expired
+	spendPromoQuery = `
+UPDATE promo_grants g
+SET remaining = g.remaining - s.amount,
+    status = CASE
+        WHEN g.remaining > s.amount THEN g.status
+        WHEN g.expired_amount > 0 THEN 'expired'
+        ELSE 'spent'
+    END,
+    resolved_at = CASE WHEN g.remaining > s.amount THEN g.resolved_at ELSE $3::timestamptz END,
+    updated_at = $3::timestamptz
+FROM unnest($1::bigint[], $2::bigint[]) AS s(id, amount)
+WHERE g.id = s.id
+`
+	// claimDuePromoGrants skips the wallets whose whole promotional credit is reserved by holds
+	claimDuePromoGrants = `
+SELECT g.id, g.wallet_id, g.remaining, w.promo_balance - w.promo_reserved
+FROM promo_grants g
+JOIN wallets w ON w.id = g.wallet_id
+WHERE g.status = 'active'
+  AND g.expires_at <= $2::timestamptz
+  AND w.promo_balance > w.promo_reserved
+ORDER BY g.expires_at, g.id
+LIMIT $1
+FOR UPDATE OF g, w SKIP LOCKED
+`
+	expirePromoGrantQuery = `
+WITH expired_grant AS (
+    UPDATE promo_grants
+    SET remaining = remaining - $2,
+        expired_amount = expired_amount + $2,
+        status = CASE WHEN remaining = $2 THEN 'expired' ELSE status END,
+        resolved_at = CASE WHEN remaining = $2 THEN $3::timestamptz ELSE resolved_at END,
+        updated_at = $3::timestamptz
+    WHERE id = $1
+    RETURNING wallet_id, user_id, transaction_id
+),
+expiry_txn AS (
+    INSERT INTO transactions
+        (wallet_id, user_id, type, status, source, amount, released, released_at, idempotency_key, related_transaction_id, created_at)
+    SELECT wallet_id, user_id, 'debit', 'success', 'promo', ($2::bigint * -1), TRUE, $3::timestamptz, gen_random_uuid(), transaction_id, $3::timestamptz
+    FROM expired_grant
+)
+UPDATE wallets w
+SET total_balance = w.total_balance - $2,
+    available_balance = w.available_balance - $2,
+    promo_balance = w.promo_balance - $2,
+    updated_at = $3::timestamptz
+FROM expired_grant e
+WHERE w.id = e.wallet_id
+`
+	// forfeitPromoQuery takes back all promotional credit of a wallet being closed, none of it may be reserved
+	forfeitPromoQuery = `
+WITH active_grant AS (
+    SELECT id, wallet_id, user_id, remaining, transaction_id
+    FROM promo_grants
+    WHERE wallet_id = $1 AND status = 'active'
+    FOR UPDATE
+),
+forfeited_grant AS (
+    UPDATE promo_grants g
+    SET remaining = 0,
+        expired_amount = g.expired_amount + a.remaining,
+        status = 'expired',
+        resolved_at = $2::timestamptz,
+        updated_at = $2::timestamptz
+    FROM active_grant a
+    WHERE g.id = a.id
+),
+forfeit_txn AS (
+    INSERT INTO transactions
+        (wallet_id, user_id, type, status, source, amount, released, released_at, idempotency_key, related_transaction_id, created_at)
+    SELECT wallet_id, user_id, 'debit', 'success', 'promo', -remaining, TRUE, $2::timestamptz, gen_random_uuid(), transaction_id, $2::timestamptz
+    FROM active_grant
+    WHERE remaining > 0
+)
+UPDATE wallets
+SET total_balance = total_balance - promo_balance,
+    available_balance = available_balance - promo_balance,
+    promo_balance = 0,
+    updated_at = $2::timestamptz
+WHERE id = $1
+`
+)
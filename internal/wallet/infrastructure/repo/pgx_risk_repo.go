@@ -0,0 +1,198 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// GetRiskProfile return the withdrawal history of the user and the charges received since chargesSince.
+// The destination is new when no withdrawal to it has succeeded yet.
+func (dc *PgxWalletRepo) GetRiskProfile(ctx context.Context, userId int64, destinationID *int64, chargesSince time.Time) (*entity.RiskProfile, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	return readRiskProfile(opCtx, dc.db, userId, destinationID, chargesSince)
+}
+
+// profileQuerier is implemented by both the pool and a transaction, the debit reads the profile
+// after it locks the wallet
+type profileQuerier interface {
+	rowQuerier
+	querier
+}
+
+func readRiskProfile(ctx context.Context, q profileQuerier, userId int64, destinationID *int64, chargesSince time.Time) (*entity.RiskProfile, error) {
+	profile := entity.RiskProfile{}
+	var destinationCount int64
+	err := q.QueryRow(ctx, getWithdrawalStats, userId, destinationID).Scan(&profile.WithdrawalCount, &profile.AverageWithdrawal, &destinationCount)
+	if err != nil {
+		return nil, fmt.Errorf("could not read withdrawal stats: %w", err)
+	}
+	profile.NewDestination = destinationCount == 0
+
+	rows, err := q.Query(ctx, getRecentCharges, userId, chargesSince)
+	if err != nil {
+		return nil, fmt.Errorf("could not read recent charges: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c := entity.RiskCharge{}
+		if err := rows.Scan(&c.Amount, &c.At); err != nil {
+			return nil, fmt.Errorf("error in reading charge row: %w", err)
+		}
+		profile.RecentCharges = append(profile.RecentCharges, c)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading recent charges: %w", rows.Err())
+	}
+
+	return &profile, nil
+}
+
+// GetRiskReviews return the oldest withdrawals waiting for review
+func (dc *PgxWalletRepo) GetRiskReviews(ctx context.Context, limit int) ([]entity.RiskReview, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	rows, err := dc.db.Query(opCtx, getRiskReviews, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get risk reviews failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.RiskReview, 0, limit)
+	for rows.Next() {
+		r := entity.RiskReview{}
+		if err := rows.Scan(&r.TransactionID, &r.UserID, &r.Amount, &r.Reasons, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error in reading risk review row: %w", err)
+		}
+		list = append(list, r)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading risk reviews: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+// ApproveRiskReview hands a withdrawal in review over to the release and withdraw jobs
+func (dc *PgxWalletRepo) ApproveRiskReview(ctx context.Context, id *uuid.UUID, actor string) error {
+	return dc.decideRiskReview(ctx, "approve_risk_review", approveRiskReview, id, actor, false)
+}
+
+// RejectRiskReview cancels a withdrawal in review, gives the amount back to the available balance
+// and refunds its fee
+func (dc *PgxWalletRepo) RejectRiskReview(ctx context.Context, id *uuid.UUID, actor string) error {
+	return dc.decideRiskReview(ctx, "reject_risk_review", rejectRiskReview, id, actor, true)
+}
+
+func (dc *PgxWalletRepo) decideRiskReview(ctx context.Context, action string, query string, id *uuid.UUID, actor string, refundFee bool) error {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	now := dc.clock.Now()
+	var decidedID uuid.UUID
+	err := audit.InTx(opCtx, dc.db, action, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(opCtx, query, id, actor, now).Scan(&decidedID); err != nil {
+			return err
+		}
+		if !refundFee {
+			return nil
+		}
+		_, err := tx.Exec(opCtx, refundFeeQuery, id, now)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := dc.db.QueryRow(opCtx, transactionExistsByID, id).Scan(&exists); err != nil {
+			return fmt.Errorf("could not read transaction: %w", err)
+		}
+		if !exists {
+			return entity.ErrTransactionNotFound
+		}
+		return entity.ErrNotInReview
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", action, err)
+	}
+
+	return nil
+}
+
+const (
+	getWithdrawalStats = `
+SELECT COUNT(*), COALESCE(AVG(-amount), 0)::bigint, COUNT(*) FILTER (WHERE destination_id IS NOT DISTINCT FROM $2)
+FROM transactions
+WHERE user_id = $1
+  AND type = 'debit'
+  AND source = 'user'
+  AND status = 'success'
+`
+	getRecentCharges = `
+SELECT amount, created_at
+FROM transactions
+WHERE user_id = $1
+  AND type = 'credit'
+  AND source <> 'refund'
+  AND created_at >= $2
+ORDER BY created_at DESC
+LIMIT 100
+`
+	insertRiskAssessment = `
+INSERT INTO risk_assessments (user_id, transaction_id, idempotency_key, amount, decision, reasons, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7::timestamptz)
+`
+	getRiskReviews = `
+SELECT t.id, t.user_id, -t.amount, COALESCE(r.reasons, '{}'), t.created_at
+FROM transactions t
+LEFT JOIN risk_assessments r ON r.transaction_id = t.id
+WHERE t.status = 'review'
+ORDER BY t.id
+LIMIT $1
+`
+	approveRiskReview = `
+WITH approved_txn AS (
+    UPDATE transactions
+    SET status = 'pending', updated_at = $3::timestamptz
+    WHERE id = $1 AND status = 'review'
+    RETURNING id
+),
+decided AS (
+    UPDATE risk_assessments r
+    SET decided_by = $2, decided_at = $3::timestamptz
+    FROM approved_txn a
+    WHERE r.transaction_id = a.id
+)
+SELECT id FROM approved_txn;
+`
+	rejectRiskReview = `
+WITH rejected_txn AS (
+    UPDATE transactions
+    SET status = 'cancelled', updated_at = $3::timestamptz
+    WHERE id = $1 AND status = 'review'
+    RETURNING id, wallet_id, amount
+),
+restored_wallet AS (
+    UPDATE wallets w
+    SET available_balance = w.available_balance - r.amount,
+        updated_at = $3::timestamptz
+    FROM rejected_txn r
+    WHERE w.id = r.wallet_id
+),
+decided AS (
+    UPDATE risk_assessments a
+    SET decided_by = $2, decided_at = $3::timestamptz
+    FROM rejected_txn r
+    WHERE a.transaction_id = r.id
+)
+SELECT id FROM rejected_txn;
+`
+	transactionExistsByID = `
+SELECT EXISTS (SELECT 1 FROM transactions WHERE id = $1)
+`
+)
@@ -0,0 +1,253 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// CreateSchedule stores a schedule whose first occurrence is due at its start time
+func (dc *PgxWalletRepo) CreateSchedule(ctx context.Context, schedule *entity.Schedule) (*entity.Schedule, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var created *entity.Schedule
+	err := audit.InTx(opCtx, dc.db, "create_schedule", func(tx pgx.Tx) error {
+		var err error
+		created, err = scanSchedule(tx.QueryRow(opCtx, insertSchedule, schedule.UserID, schedule.Type, schedule.Amount,
+			schedule.Frequency, schedule.Interval, schedule.DestinationID, schedule.StartAt, schedule.EndAt, dc.clock.Now()))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create schedule failed: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetSchedules return the schedules of the user which are not deleted
+func (dc *PgxWalletRepo) GetSchedules(ctx context.Context, userId int64) ([]entity.Schedule, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	rows, err := dc.db.Query(opCtx, getSchedules, userId)
+	if err != nil {
+		return nil, fmt.Errorf("get schedules failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.Schedule, 0)
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error in reading schedule row: %w", err)
+		}
+		list = append(list, *s)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading schedules: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+// GetSchedule return the schedule of the user with the given id unless it is deleted
+func (dc *PgxWalletRepo) GetSchedule(ctx context.Context, userId int64, id int64) (*entity.Schedule, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	s, err := scanSchedule(dc.db.QueryRow(opCtx, getSchedule, userId, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get schedule failed: %w", err)
+	}
+
+	return s, nil
+}
+
+// PauseSchedule stops an active schedule from running until it is resumed
+func (dc *PgxWalletRepo) PauseSchedule(ctx context.Context, userId int64, id int64) (*entity.Schedule, error) {
+	return dc.changeSchedule(ctx, "pause_schedule", pauseSchedule, userId, id)
+}
+
+// ResumeSchedule activates a paused schedule again from the given occurrence
+func (dc *PgxWalletRepo) ResumeSchedule(ctx context.Context, userId int64, id int64, occurrence int64, nextRunAt *time.Time) (*entity.Schedule, error) {
+	return dc.changeSchedule(ctx, "resume_schedule", resumeSchedule, userId, id, occurrence, nextRunAt)
+}
+
+// DeleteSchedule stops a schedule for good, the row is kept for the audit trail
+func (dc *PgxWalletRepo) DeleteSchedule(ctx context.Context, userId int64, id int64) error {
+	_, err := dc.changeSchedule(ctx, "delete_schedule", deleteSchedule, userId, id)
+	return err
+}
+
+func (dc *PgxWalletRepo) changeSchedule(ctx context.Context, action string, query string, userId int64, id int64, args ...any) (*entity.Schedule, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var changed *entity.Schedule
+	err := audit.InTx(opCtx, dc.db, action, func(tx pgx.Tx) error {
+		var err error
+		changed, err = scanSchedule(tx.QueryRow(opCtx, query, append([]any{userId, id, dc.clock.Now()}, args...)...))
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := dc.GetSchedule(opCtx, userId, id); err != nil {
+			return nil, err
+		}
+		return nil, entity.ErrScheduleStatus
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", action, err)
+	}
+
+	return changed, nil
+}
+
+// ClaimDueSchedules return at most limit active schedules with a due occurrence and keeps
+// them away from other scheduler workers for claimFor
+func (dc *PgxWalletRepo) ClaimDueSchedules(ctx context.Context, limit int, claimFor time.Duration) ([]entity.Schedule, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	list := make([]entity.Schedule, 0, limit)
+	err := audit.InTx(opCtx, dc.db, "claim_schedules", func(tx pgx.Tx) error {
+		rows, err := tx.Query(opCtx, claimDueSchedules, limit, claimFor, dc.clock.Now())
+		if err != nil {
+			return fmt.Errorf("claim due schedules failed: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			s, err := scanSchedule(rows)
+			if err != nil {
+				return fmt.Errorf("error in reading schedule row: %w", err)
+			}
+			list = append(list, *s)
+		}
+		if rows.Err() != nil {
+			return fmt.Errorf("something went wrong reading due schedules: %w", rows.Err())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// AdvanceSchedule records the occurrences run by the scheduler and releases its claim.
+// A schedule without a next run is completed.
+func (dc *PgxWalletRepo) AdvanceSchedule(ctx context.Context, id int64, occurrence int64, nextRunAt *time.Time, lastError string) error {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	err := audit.InTx(opCtx, dc.db, "advance_schedule", func(tx pgx.Tx) error {
+		_, err := tx.Exec(opCtx, advanceSchedule, id, occurrence, nextRunAt, lastError, dc.clock.Now())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("advance schedule failed: %w", err)
+	}
+
+	return nil
+}
+
+// OccurrenceRecorded tells whether an operation with the idempotency was already accepted or denied
+func (dc *PgxWalletRepo) OccurrenceRecorded(ctx context.Context, idempotency *uuid.UUID) (bool, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var recorded bool
+	if err := dc.db.QueryRow(opCtx, idempotencyRecorded, idempotency).Scan(&recorded); err != nil {
+		return false, fmt.Errorf("could not read idempotency: %w", err)
+	}
+
+	return recorded, nil
+}
+
+func scanSchedule(row pgx.Row) (*entity.Schedule, error) {
+	s := entity.Schedule{}
+	err := row.Scan(&s.ID, &s.UserID, &s.Type, &s.Amount, &s.Frequency, &s.Interval, &s.DestinationID, &s.StartAt,
+		&s.EndAt, &s.Occurrence, &s.NextRunAt, &s.Status, &s.LastError, &s.LastRunAt, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+const (
+	scheduleColumns = `id, user_id, type, amount, frequency, interval_count, destination_id, start_at, end_at, occurrence,
+    next_run_at, status, last_error, last_run_at, created_at`
+	insertSchedule = `
+INSERT INTO schedules
+    (user_id, type, amount, frequency, interval_count, destination_id, start_at, end_at, next_run_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $7, $9::timestamptz, $9::timestamptz)
+RETURNING ` + scheduleColumns
+	getSchedules = `
+SELECT ` + scheduleColumns + `
+FROM schedules
+WHERE user_id = $1 AND status <> 'deleted'
+ORDER BY id
+`
+	getSchedule = `
+SELECT ` + scheduleColumns + `
+FROM schedules
+WHERE user_id = $1 AND id = $2 AND status <> 'deleted'
+`
+	pauseSchedule = `
+UPDATE schedules
+SET status = 'paused', updated_at = $3::timestamptz
+WHERE user_id = $1 AND id = $2 AND status = 'active'
+RETURNING ` + scheduleColumns
+	resumeSchedule = `
+UPDATE schedules
+SET status = CASE WHEN $5::timestamptz IS NULL THEN 'completed' ELSE 'active' END,
+    occurrence = $4,
+    next_run_at = $5,
+    updated_at = $3::timestamptz
+WHERE user_id = $1 AND id = $2 AND status = 'paused'
+RETURNING ` + scheduleColumns
+	deleteSchedule = `
+UPDATE schedules
+SET status = 'deleted', next_run_at = NULL, updated_at = $3::timestamptz
+WHERE user_id = $1 AND id = $2 AND status <> 'deleted'
+RETURNING ` + scheduleColumns
+	claimDueSchedules = `
+WITH due_schedule AS (
+    SELECT id AS due_id
+    FROM schedules
+    WHERE status = 'active'
+      AND next_run_at <= $3::timestamptz
+      AND (claimed_until IS NULL OR claimed_until <= $3::timestamptz)
+    ORDER BY next_run_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE schedules s
+SET claimed_until = $3::timestamptz + $2::interval
+FROM due_schedule d
+WHERE s.id = d.due_id
+RETURNING ` + scheduleColumns
+	advanceSchedule = `
+UPDATE schedules
+SET occurrence = $2,
+    next_run_at = CASE WHEN status = 'deleted' THEN NULL ELSE $3 END,
+    status = CASE WHEN $3::timestamptz IS NULL AND status = 'active' THEN 'completed' ELSE status END,
+    last_error = $4,
+    last_run_at = $5::timestamptz,
+    claimed_until = NULL,
+    updated_at = $5::timestamptz
+WHERE id = $1
+`
+	idempotencyRecorded = `
+SELECT EXISTS (SELECT 1 FROM transactions WHERE idempotency_key = $1)
+    OR EXISTS (SELECT 1 FROM risk_assessments WHERE idempotency_key = $1 AND transaction_id IS NULL)
+`
+)
@@ -0,0 +1,330 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// SearchWallets return wallets ordered by id, optionally filtered by user and status
+func (dc *PgxWalletRepo) SearchWallets(ctx context.Context, userId *int64, status entity.WalletStatus, afterId int64, limit int) ([]entity.WalletDetails, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := dc.db.Query(opCtx, searchWallets, userId, status, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search wallets failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.WalletDetails, 0, limit)
+	for rows.Next() {
+		w := entity.WalletDetails{}
+		if err := rows.Scan(&w.ID, &w.UserID, &w.TotalBalance, &w.AvailableBalance, &w.Status, &w.Quarantined, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error in reading wallet row: %w", err)
+		}
+		list = append(list, w)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading wallet list: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+// GetTransaction return all details of a transaction together with its bank call attempts
+func (dc *PgxWalletRepo) GetTransaction(ctx context.Context, id *uuid.UUID) (*entity.TransactionDetails, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	t := entity.TransactionDetails{Attempts: make([]entity.WithdrawAttempt, 0)}
+	err := dc.db.QueryRow(opCtx, getTransactionDetails, id).Scan(&t.ID, &t.WalletID, &t.UserID, &t.Type, &t.Status, &t.Source,
+		&t.Amount, &t.Idempotency, &t.ReleaseTime, &t.Released, &t.ReleasedAt, &t.RetryCount, &t.LastRetry, &t.SentToBank, &t.BankResponseID,
+		&t.RelatedID, &t.CreatedAt, &t.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get transaction failed: %w", err)
+	}
+
+	rows, err := dc.db.Query(opCtx, getTransactionAttempts, id)
+	if err != nil {
+		return nil, fmt.Errorf("get transaction attempts failed: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := entity.WithdrawAttempt{}
+		if err := rows.Scan(&a.Attempt, &a.Succeeded, &a.BankResponseID, &a.Error, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error in reading transaction attempt row: %w", err)
+		}
+		t.Attempts = append(t.Attempts, a)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading transaction attempts: %w", rows.Err())
+	}
+
+	return &t, nil
+}
+
+// ReinquireTransaction makes a pending withdrawal already sent to the bank claimable by the withdraw job right away.
+// The withdrawal stays marked as sent, so it can not be cancelled while the bank may still pay it out.
+func (dc *PgxWalletRepo) ReinquireTransaction(ctx context.Context, id *uuid.UUID) error {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var affected int64
+	err := audit.InTx(opCtx, dc.db, "reinquire", func(tx pgx.Tx) error {
+		tag, err := tx.Exec(opCtx, reinquireTransaction, id, dc.clock.Now())
+		affected = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("reinquire transaction failed: %w", err)
+	}
+	if affected == 0 {
+		return entity.ErrNotPending
+	}
+
+	return nil
+}
+
+// CreateAdjustment stores an adjustment request waiting for approval
+func (dc *PgxWalletRepo) CreateAdjustment(ctx context.Context, adjustment *entity.Adjustment) (int64, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var id int64
+	err := audit.InTx(opCtx, dc.db, "request_adjustment", func(tx pgx.Tx) error {
+		return tx.QueryRow(opCtx, insertAdjustment, adjustment.UserID, adjustment.Type, adjustment.Amount, adjustment.ReasonCode,
+			adjustment.Note, adjustment.Idempotency, adjustment.RequestedBy, dc.clock.Now()).Scan(&id, &adjustment.CreatedAt)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("create adjustment failed: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetAdjustments return the latest adjustments in the given status
+func (dc *PgxWalletRepo) GetAdjustments(ctx context.Context, status entity.AdjustmentStatus, limit int) ([]entity.Adjustment, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := dc.db.Query(opCtx, getAdjustments, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get adjustments failed: %w", err)
+	}
+	defer rows.Close()
+	list := make([]entity.Adjustment, 0, limit)
+	for rows.Next() {
+		a := entity.Adjustment{}
+		if err := scanAdjustment(rows, &a); err != nil {
+			return nil, fmt.Errorf("error in reading adjustment row: %w", err)
+		}
+		list = append(list, a)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("something went wrong reading adjustment list: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+// ApproveAdjustment applies a pending adjustment to the wallet. Adjustments move both balances at once
+// and are never sent to the bank. The approver must be someone other than the requester.
+func (dc *PgxWalletRepo) ApproveAdjustment(ctx context.Context, id int64, approver string) (*entity.Adjustment, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := dc.db.Begin(opCtx)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback(opCtx)
+	if err := audit.Tag(opCtx, tx, "approve_adjustment"); err != nil {
+		return nil, err
+	}
+
+	a := entity.Adjustment{}
+	if err := dc.lockPendingAdjustment(opCtx, tx, id, &a); err != nil {
+		return nil, err
+	}
+	if a.RequestedBy == approver {
+		return nil, entity.ErrSelfApproval
+	}
+
+	query := adjustmentCreditQuery
+	if a.Type == entity.DEBIT {
+		query = adjustmentDebitQuery
+	}
+	now := dc.clock.Now()
+	var txnID uuid.UUID
+	err = tx.QueryRow(opCtx, query, a.UserID, a.Amount, a.Idempotency, now).Scan(&txnID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = dc.rejectionReason(opCtx, a.UserID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("applying adjustment failed: %w", err)
+	}
+
+	err = tx.QueryRow(opCtx, decideAdjustment, id, entity.ADJUSTMENT_APPROVED, approver, txnID, now).Scan(&a.DecidedAt)
+	if err != nil {
+		return nil, fmt.Errorf("approve adjustment failed: %w", err)
+	}
+	if err := tx.Commit(opCtx); err != nil {
+		return nil, fmt.Errorf("could not commit adjustment: %w", err)
+	}
+
+	a.Status = entity.ADJUSTMENT_APPROVED
+	a.DecidedBy = &approver
+	a.TransactionID = &txnID
+	return &a, nil
+}
+
+// RejectAdjustment rejects a pending adjustment without touching the wallet
+func (dc *PgxWalletRepo) RejectAdjustment(ctx context.Context, id int64, actor string) (*entity.Adjustment, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	tx, err := dc.db.Begin(opCtx)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback(opCtx)
+	if err := audit.Tag(opCtx, tx, "reject_adjustment"); err != nil {
+		return nil, err
+	}
+
+	a := entity.Adjustment{}
+	if err := dc.lockPendingAdjustment(opCtx, tx, id, &a); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(opCtx, decideAdjustment, id, entity.ADJUSTMENT_REJECTED, actor, nil, dc.clock.Now()).Scan(&a.DecidedAt)
+	if err != nil {
+		return nil, fmt.Errorf("reject adjustment failed: %w", err)
+	}
+	if err := tx.Commit(opCtx); err != nil {
+		return nil, fmt.Errorf("could not commit adjustment: %w", err)
+	}
+
+	a.Status = entity.ADJUSTMENT_REJECTED
+	a.DecidedBy = &actor
+	return &a, nil
+}
+
+func (dc *PgxWalletRepo) lockPendingAdjustment(ctx context.Context, tx pgx.Tx, id int64, a *entity.Adjustment) error {
+	err := scanAdjustment(tx.QueryRow(ctx, lockAdjustment, id), a)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.ErrAdjustmentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("could not lock adjustment: %w", err)
+	}
+	if a.Status != entity.ADJUSTMENT_PENDING {
+		return entity.ErrAdjustmentDecided
+	}
+	return nil
+}
+
+func scanAdjustment(row pgx.Row, a *entity.Adjustment) error {
+	return row.Scan(&a.ID, &a.UserID, &a.Type, &a.Amount, &a.ReasonCode, &a.Note, &a.Status, &a.Idempotency,
+		&a.RequestedBy, &a.DecidedBy, &a.TransactionID, &a.CreatedAt, &a.DecidedAt)
+}
+
+const (
+	searchWallets = `
+SELECT w.id, w.user_id, w.total_balance, w.available_balance, w.status,
+       EXISTS (
+           SELECT 1 FROM wallet_quarantines q
+           WHERE q.wallet_id = w.id AND q.released_at IS NULL
+       ) AS quarantined,
+       w.created_at, w.updated_at
+FROM wallets w
+WHERE ($1::bigint IS NULL OR w.user_id = $1)
+  AND ($2 = '' OR w.status = $2)
+  AND w.id > $3
+ORDER BY w.id
+LIMIT $4
+`
+	getTransactionDetails = `
+SELECT id, wallet_id, user_id, type, status, source, amount, idempotency_key, release_time, released, released_at,
+       retry_count, last_retry, sent_to_bank, bank_response_id, related_transaction_id, created_at, updated_at
+FROM transactions
+WHERE id = $1
+`
+	getTransactionAttempts = `
+SELECT attempt, succeeded, bank_response_id, error, created_at
+FROM transaction_attempts
+WHERE transaction_id = $1
+ORDER BY id
+`
+	reinquireTransaction = `
+UPDATE transactions
+SET last_retry = NULL, updated_at = $2::timestamptz
+WHERE id = $1 AND status = 'pending' AND type = 'debit' AND source = 'user' AND sent_to_bank
+`
+	insertAdjustment = `
+INSERT INTO adjustments (user_id, type, amount, reason_code, note, idempotency_key, requested_by, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8::timestamptz)
+RETURNING id, created_at
+`
+	getAdjustments = `
+SELECT id, user_id, type, amount, reason_code, note, status, idempotency_key, requested_by, decided_by,
+       transaction_id, created_at, decided_at
+FROM adjustments
+WHERE status = $1
+ORDER BY id DESC
+LIMIT $2
+`
+	lockAdjustment = `
+SELECT id, user_id, type, amount, reason_code, note, status, idempotency_key, requested_by, decided_by,
+       transaction_id, created_at, decided_at
+FROM adjustments
+WHERE id = $1
+FOR UPDATE
+`
+	decideAdjustment = `
+UPDATE adjustments
+SET status = $2, decided_by = $3, transaction_id = $4, decided_at = $5::timestamptz
+WHERE id = $1
+RETURNING decided_at
+`
+	adjustmentCreditQuery = `
+WITH upserted_wallet AS (
+    INSERT INTO wallets (user_id, total_balance, available_balance, created_at, updated_at)
+    VALUES ($1, $2, $2, $4::timestamptz, $4::timestamptz)
+    ON CONFLICT (user_id) DO UPDATE
+    SET total_balance = wallets.total_balance + EXCLUDED.total_balance,
+        available_balance = wallets.available_balance + EXCLUDED.total_balance,
+        updated_at = $4::timestamptz
+    WHERE wallets.status <> 'closed'
+    RETURNING id AS wallet_id, user_id
+)
+INSERT INTO transactions
+    (wallet_id, user_id, type, status, source, amount, released, released_at, idempotency_key, created_at)
+SELECT wallet_id, user_id, 'credit', 'success', 'adjustment', $2, TRUE, $4::timestamptz, $3, $4::timestamptz
+FROM upserted_wallet
+RETURNING id;
+`
+	// adjustmentDebitQuery takes cash only, promotional credit leaves the wallet when it expires
+	adjustmentDebitQuery = `
+WITH updated_wallet AS (
+    UPDATE wallets
+    SET total_balance = total_balance - $2,
+        available_balance = available_balance - $2,
+        updated_at = $4::timestamptz
+    WHERE user_id = $1 AND available_balance - promo_balance + promo_reserved >= $2 AND status <> 'closed'
+    RETURNING id AS wallet_id, user_id
+)
+INSERT INTO transactions
+    (wallet_id, user_id, type, status, source, amount, released, released_at, idempotency_key, created_at)
+SELECT wallet_id, user_id, 'debit', 'success', 'adjustment', ($2 * -1), TRUE, $4::timestamptz, $3, $4::timestamptz
+FROM updated_wallet
+RETURNING id;
+`
+)
@@ -5,9 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/MaisamV/wallet/platform/clock"
 	"github.com/MaisamV/wallet/platform/logger"
 	"github.com/gofrs/uuid/v5"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"time"
 )
@@ -15,12 +19,15 @@ import (
 type PgxWalletRepo struct {
 	logger logger.Logger
 	db     *pgxpool.Pool
+	// clock is passed to the queries instead of NOW(), so release times and claim windows follow it
+	clock clock.Clock
 }
 
-func NewPgxWalletRepo(logger logger.Logger, db *pgxpool.Pool) *PgxWalletRepo {
+func NewPgxWalletRepo(logger logger.Logger, db *pgxpool.Pool, clock clock.Clock) *PgxWalletRepo {
 	return &PgxWalletRepo{
 		logger: logger,
 		db:     db,
+		clock:  clock,
 	}
 }
 
@@ -37,8 +44,9 @@ func (dc *PgxWalletRepo) Charge(ctx context.Context, userId int64, idempotency *
 		return nil, errors.New("negative or 0 is not acceptable amount for charge operation")
 	}
 
-	if releaseTime != nil && time.Now().After(*releaseTime) {
-		return nil, errors.New("release time can't be in the past")
+	now := dc.clock.Now()
+	if releaseTime != nil && now.After(*releaseTime) {
+		return nil, fmt.Errorf("%w: release time can't be in the past", entity.ErrInvalidReleaseTime)
 	}
 
 	var query string
@@ -48,7 +56,15 @@ func (dc *PgxWalletRepo) Charge(ctx context.Context, userId int64, idempotency *
 		query = chargeWithReleaseQuery
 	}
 	var transactionID uuid.UUID
-	err := dc.db.QueryRow(opCtx, query, userId, chargeAmount, releaseTime, idempotency).Scan(&transactionID)
+	err := audit.InTx(opCtx, dc.db, "charge", func(tx pgx.Tx) error {
+		if err := dc.checkChargeLimits(opCtx, tx, userId, chargeAmount); err != nil {
+			return err
+		}
+		return idempotencyError(tx.QueryRow(opCtx, query, userId, chargeAmount, releaseTime, idempotency, now).Scan(&transactionID))
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = dc.rejectionReason(opCtx, userId)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("database charge operation failed: %w", err)
 	}
@@ -56,34 +72,93 @@ func (dc *PgxWalletRepo) Charge(ctx context.Context, userId int64, idempotency *
 	return &transactionID, nil
 }
 
-// Debit deducts from user's wallet balance
-func (dc *PgxWalletRepo) Debit(ctx context.Context, userId int64, idempotency *uuid.UUID, debitAmount int64, releaseTime *time.Time) (*uuid.UUID, error) {
+// Debit deducts from user's wallet balance and records the destination the amount is paid out to.
+// The withdrawal is screened once the wallet is locked, a nil screen allows every withdrawal.
+// A denied withdrawal is only recorded, a debit sent to review is held out of the withdraw job until
+// an admin approves it. A positive fee is reserved together with the amount and kept as a fee transaction
+// linked to the debit.
+func (dc *PgxWalletRepo) Debit(ctx context.Context, userId int64, idempotency *uuid.UUID, debitAmount int64, fee int64, releaseTime *time.Time, destinationID *int64, screen *repo.DebitScreen) (*uuid.UUID, *entity.RiskAssessment, error) {
 	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 
 	if idempotency == nil {
-		return nil, errors.New("debits operations must have idempotency")
+		return nil, nil, errors.New("debits operations must have idempotency")
 	}
 
 	if debitAmount <= 0 {
-		return nil, errors.New("negative or 0 is not acceptable amount for debit operation")
+		return nil, nil, errors.New("negative or 0 is not acceptable amount for debit operation")
+	}
+
+	if fee < 0 {
+		return nil, nil, errors.New("negative fee is not acceptable for debit operation")
 	}
 
 	if releaseTime == nil {
-		return nil, errors.New("debits must have release time")
+		return nil, nil, fmt.Errorf("%w: debits must have release time", entity.ErrInvalidReleaseTime)
 	}
 
-	if time.Now().After(*releaseTime) {
-		return nil, errors.New("release time can't be in the past")
+	now := dc.clock.Now()
+	if now.After(*releaseTime) {
+		return nil, nil, fmt.Errorf("%w: release time can't be in the past", entity.ErrInvalidReleaseTime)
 	}
 
-	var transactionID uuid.UUID
-	err := dc.db.QueryRow(opCtx, debitWithReleaseQuery, userId, debitAmount, releaseTime, idempotency).Scan(&transactionID)
+	var transactionID *uuid.UUID
+	var assessment *entity.RiskAssessment
+	err := audit.InTx(opCtx, dc.db, "debit", func(tx pgx.Tx) error {
+		var err error
+		transactionID, assessment, err = dc.debit(opCtx, tx, userId, idempotency, debitAmount, fee, releaseTime, destinationID, screen)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = dc.rejectionReason(opCtx, userId)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("database debit operation failed: %w", err)
+		return nil, nil, fmt.Errorf("database debit operation failed: %w", err)
+	}
+	if assessment.Decision == entity.DENY {
+		return nil, assessment, entity.ErrRiskDenied
 	}
 
-	return &transactionID, nil
+	return transactionID, assessment, nil
+}
+
+// debit runs a withdrawal in tx, it is shared by the debit API and the debit rows of a batch. A denied
+// withdrawal only records its assessment and returns no transaction, a withdrawal the wallet cannot pay
+// returns pgx.ErrNoRows. A nil release time leaves the debit and its fee to be released by their batch.
+func (dc *PgxWalletRepo) debit(ctx context.Context, tx pgx.Tx, userId int64, idempotency *uuid.UUID, debitAmount int64, fee int64, releaseTime *time.Time, destinationID *int64, screen *repo.DebitScreen) (*uuid.UUID, *entity.RiskAssessment, error) {
+	now := dc.clock.Now()
+	assessment := &entity.RiskAssessment{Decision: entity.ALLOW}
+	if err := dc.checkDebitLimits(ctx, tx, userId, debitAmount); err != nil {
+		return nil, nil, err
+	}
+	if screen != nil {
+		profile, err := readRiskProfile(ctx, tx, userId, destinationID, now.Add(-screen.Lookback))
+		if err != nil {
+			return nil, nil, err
+		}
+		*assessment = screen.Decide(*profile, now)
+	}
+	if assessment.Decision == entity.DENY {
+		// the denial is kept even though no transaction is created for it
+		_, err := tx.Exec(ctx, insertRiskAssessment, userId, nil, idempotency, debitAmount, assessment.Decision, assessment.Reasons, now)
+		return nil, assessment, err
+	}
+	status := entity.PENDING
+	if assessment.Decision == entity.REVIEW {
+		status = entity.IN_REVIEW
+	}
+	var transactionID uuid.UUID
+	err := idempotencyError(tx.QueryRow(ctx, debitWithReleaseQuery, userId, debitAmount, releaseTime, idempotency, status, destinationID, fee, now).Scan(&transactionID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if status == entity.IN_REVIEW {
+		_, err = tx.Exec(ctx, insertRiskAssessment, userId, transactionID, idempotency, debitAmount, assessment.Decision, assessment.Reasons, now)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return &transactionID, assessment, nil
 }
 
 // GetBalance return user's wallet balance
@@ -95,19 +170,52 @@ func (dc *PgxWalletRepo) GetBalance(ctx context.Context, userId int64) (*entity.
 	var id int64
 	var totalBalance int64
 	var availableBalance int64
-	err := dc.db.QueryRow(opCtx, getBalance, userId).Scan(&id, &userId, &totalBalance, &availableBalance)
+	var promoBalance int64
+	var promoReserved int64
+	var status entity.WalletStatus
+	err := dc.db.QueryRow(opCtx, getBalance, userId).Scan(&id, &userId, &totalBalance, &availableBalance, &status,
+		&promoBalance, &promoReserved)
 	switch err {
 	case pgx.ErrNoRows:
 		w = entity.NewWallet(int64(0), userId, int64(0), int64(0))
+		w.Status = entity.ACTIVE
 	case nil:
 		w = entity.NewWallet(id, userId, totalBalance, availableBalance)
+		w.Status = status
 	default:
 		return nil, fmt.Errorf("get balance operation failed: %w", err)
 	}
 
+	grants := make([]entity.PromoGrant, 0)
+	if promoBalance != 0 {
+		grants, err = dc.getPromoGrants(opCtx, getActivePromoGrants, userId)
+		if err != nil {
+			return nil, fmt.Errorf("get balance operation failed: %w", err)
+		}
+	}
+	w.Buckets = entity.NewBalanceBuckets(totalBalance, availableBalance, promoBalance, promoReserved, grants)
+
 	return w, nil
 }
 
+// GetBalanceAt return user's wallet balance at the given point in time.
+// It starts from the latest snapshot taken before that time and replays the
+// transactions and holds that affected the balances after the snapshot.
+func (dc *PgxWalletRepo) GetBalanceAt(ctx context.Context, userId int64, at time.Time) (*entity.Wallet, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var id int64
+	var totalBalance int64
+	var availableBalance int64
+	err := dc.db.QueryRow(opCtx, getBalanceAt, userId, at).Scan(&id, &totalBalance, &availableBalance)
+	if err != nil {
+		return nil, fmt.Errorf("get balance at operation failed: %w", err)
+	}
+
+	return entity.NewWallet(id, userId, totalBalance, availableBalance), nil
+}
+
 // GetTransactionList return a list of user transactions
 func (dc *PgxWalletRepo) GetTransactionList(ctx context.Context, userId int64, cursor *uuid.UUID, limit int) (*entity.TransactionPage, error) {
 	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
@@ -134,7 +242,8 @@ func (dc *PgxWalletRepo) GetTransactionList(ctx context.Context, userId int64, c
 	list := make([]entity.Transaction, 0, limit)
 	for rows.Next() {
 		t := entity.Transaction{}
-		if err := rows.Scan(&t.ID, &t.UserID, &t.Type, &t.Status, &t.Amount, &t.CreatedAt, &t.Released, &t.ReleaseTime, &t.Idempotency, &t.RetryCount); err != nil {
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Type, &t.Status, &t.Source, &t.Amount, &t.CreatedAt, &t.Released, &t.ReleaseTime, &t.Idempotency, &t.RetryCount,
+			&t.DestinationID, &t.RelatedTransactionID); err != nil {
 			return nil, fmt.Errorf("error in reading transaction row: %w", err)
 		}
 		list = append(list, t)
@@ -159,55 +268,82 @@ func (dc *PgxWalletRepo) ReleaseDueTransactions(ctx context.Context, batchSize i
 	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	rows, err := dc.db.Query(opCtx, releaseQuery, batchSize)
-	if err != nil {
-		return nil, fmt.Errorf("release due transactions failed: %w", err)
-	}
-	defer rows.Close()
 	list := make([]entity.Transaction, 0, batchSize)
-	for rows.Next() {
-		t := entity.Transaction{}
-		if err := rows.Scan(&t.ID, &t.UserID, &t.Type, &t.Amount); err != nil {
-			return nil, fmt.Errorf("error in reading due transaction row: %w", err)
+	err := audit.InTx(opCtx, dc.db, "release", func(tx pgx.Tx) error {
+		rows, err := tx.Query(opCtx, releaseQuery, batchSize, dc.clock.Now())
+		if err != nil {
+			return fmt.Errorf("release due transactions failed: %w", err)
 		}
-		list = append(list, t)
-	}
-	if rows.Err() != nil {
-		return nil, fmt.Errorf("something went wrong reading due transaction list: %w", rows.Err())
+		defer rows.Close()
+		for rows.Next() {
+			t := entity.Transaction{}
+			if err := rows.Scan(&t.ID, &t.UserID, &t.Type, &t.Amount); err != nil {
+				return fmt.Errorf("error in reading due transaction row: %w", err)
+			}
+			list = append(list, t)
+		}
+		if rows.Err() != nil {
+			return fmt.Errorf("something went wrong reading due transaction list: %w", rows.Err())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return list, nil
 }
 
+// GetPendingTransactions claims the released pending withdrawals which are not claimed within the claim window
 func (dc *PgxWalletRepo) GetPendingTransactions(ctx context.Context, limit int) ([]entity.Transaction, error) {
 	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	rows, err := dc.db.Query(opCtx, getPendingTransactions, limit)
-	if err != nil {
-		return nil, fmt.Errorf("get pending transactions failed: %w", err)
-	}
-	defer rows.Close()
 	list := make([]entity.Transaction, 0, limit)
-	for rows.Next() {
-		t := entity.Transaction{}
-		if err := rows.Scan(&t.ID, &t.UserID, &t.RetryCount, &t.Amount, &t.Idempotency); err != nil {
-			return nil, fmt.Errorf("error in reading due transaction row: %w", err)
+	err := audit.InTx(opCtx, dc.db, "claim_withdrawal", func(tx pgx.Tx) error {
+		rows, err := tx.Query(opCtx, getPendingTransactions, limit, dc.clock.Now())
+		if err != nil {
+			return fmt.Errorf("get pending transactions failed: %w", err)
 		}
-		list = append(list, t)
-	}
-	if rows.Err() != nil {
-		return nil, fmt.Errorf("something went wrong reading due transaction list: %w", rows.Err())
+		defer rows.Close()
+		for rows.Next() {
+			t := entity.Transaction{}
+			var destinationType, destinationNumber *string
+			if err := rows.Scan(&t.ID, &t.UserID, &t.RetryCount, &t.Amount, &t.Idempotency, &t.DestinationID, &destinationType, &destinationNumber); err != nil {
+				return fmt.Errorf("error in reading due transaction row: %w", err)
+			}
+			if t.DestinationID != nil {
+				t.Destination = &entity.Destination{ID: *t.DestinationID, UserID: t.UserID, Type: *destinationType, Number: *destinationNumber, Verified: true}
+			}
+			list = append(list, t)
+		}
+		if rows.Err() != nil {
+			return fmt.Errorf("something went wrong reading due transaction list: %w", rows.Err())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return list, nil
 }
 
+// UpdateTransactionStatus records the bank outcome of a withdrawal. A failed withdrawal gives the amount
+// back to the wallet and refunds its fee.
 func (dc *PgxWalletRepo) UpdateTransactionStatus(ctx context.Context, id *uuid.UUID, txStatus entity.Status, bankTxID *uuid.UUID) error {
 	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 
-	_, err := dc.db.Exec(opCtx, updateTransactionStatus, id, txStatus, bankTxID)
+	now := dc.clock.Now()
+	err := audit.InTx(opCtx, dc.db, "update_transaction_status", func(tx pgx.Tx) error {
+		if txStatus != entity.FAILED {
+			_, err := tx.Exec(opCtx, updateTransactionStatus, id, txStatus, bankTxID, now)
+			return err
+		}
+		_, err := tx.Exec(opCtx, failWithdrawalQuery, id, bankTxID, now)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("something happened while trying to update failed transactions: %w", err)
 	}
@@ -219,7 +355,10 @@ func (dc *PgxWalletRepo) IncreaseTransactionRetryCount(ctx context.Context, id *
 	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 
-	_, err := dc.db.Exec(opCtx, increaseRetryCount, id)
+	err := audit.InTx(opCtx, dc.db, "increase_retry_count", func(tx pgx.Tx) error {
+		_, err := tx.Exec(opCtx, increaseRetryCount, id, dc.clock.Now())
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("increasing transaction retry count failed: %w", err)
 	}
@@ -227,6 +366,187 @@ func (dc *PgxWalletRepo) IncreaseTransactionRetryCount(ctx context.Context, id *
 	return nil
 }
 
+// RecordWithdrawAttempt keeps the outcome of a single bank call so support can see the full history of a withdrawal
+func (dc *PgxWalletRepo) RecordWithdrawAttempt(ctx context.Context, id *uuid.UUID, attempt int, bankTxID *uuid.UUID, withdrawErr error) error {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	errMsg := ""
+	if withdrawErr != nil {
+		errMsg = withdrawErr.Error()
+	}
+	_, err := dc.db.Exec(opCtx, insertWithdrawAttempt, id, attempt, withdrawErr == nil, bankTxID, errMsg, dc.clock.Now())
+	if err != nil {
+		return fmt.Errorf("recording withdraw attempt failed: %w", err)
+	}
+
+	return nil
+}
+
+// CancelDebit cancels a pending withdrawal before its release, when it was never claimed by the withdraw job,
+// gives the amount back to the available balance and refunds its fee
+func (dc *PgxWalletRepo) CancelDebit(ctx context.Context, userId int64, id *uuid.UUID) error {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	now := dc.clock.Now()
+	var walletID int64
+	err := audit.InTx(opCtx, dc.db, "cancel_debit", func(tx pgx.Tx) error {
+		if err := tx.QueryRow(opCtx, cancelDebitQuery, userId, id, now).Scan(&walletID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(opCtx, refundFeeQuery, id, now)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := dc.db.QueryRow(opCtx, transactionExists, userId, id).Scan(&exists); err != nil {
+			return fmt.Errorf("could not read transaction: %w", err)
+		}
+		if !exists {
+			return entity.ErrTransactionNotFound
+		}
+		return entity.ErrNotCancellable
+	}
+	if err != nil {
+		return fmt.Errorf("cancel debit failed: %w", err)
+	}
+
+	return nil
+}
+
+// TakeBalanceSnapshots records the current balances of at most batchSize wallets
+// which do not have a snapshot for snapshotDate yet and returns the number of recorded snapshots
+func (dc *PgxWalletRepo) TakeBalanceSnapshots(ctx context.Context, snapshotDate time.Time, batchSize int) (int64, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tag, err := dc.db.Exec(opCtx, takeBalanceSnapshots, snapshotDate, batchSize, dc.clock.Now())
+	if err != nil {
+		return 0, fmt.Errorf("taking balance snapshots failed: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// UpdateWalletStatus changes the wallet status and records the change with its reason and actor.
+// Closed wallets can not be reopened and must be closed through CloseWallet.
+func (dc *PgxWalletRepo) UpdateWalletStatus(ctx context.Context, userId int64, status entity.WalletStatus, reason string, actor string) (entity.WalletStatus, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	if status == entity.CLOSED {
+		return "", entity.ErrInvalidTransition
+	}
+
+	var from entity.WalletStatus
+	err := audit.InTx(opCtx, dc.db, "update_wallet_status", func(tx pgx.Tx) error {
+		return tx.QueryRow(opCtx, updateWalletStatus, userId, status, reason, actor, dc.clock.Now()).Scan(&from)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = dc.rejectionReason(opCtx, userId)
+		if errors.Is(err, entity.ErrInsufficientFunds) {
+			err = entity.ErrWalletNotFound
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("update wallet status failed: %w", err)
+	}
+
+	return from, nil
+}
+
+// CloseWallet closes an empty wallet. A wallet with balance is only closed when a payout idempotency
+// is given, in which case the whole balance is debited as a final payout released immediately
+// to the default destination of the user when it is verified. Promotional credit is forfeited, not paid out.
+func (dc *PgxWalletRepo) CloseWallet(ctx context.Context, userId int64, reason string, actor string, payoutIdempotency *uuid.UUID) (*uuid.UUID, error) {
+	opCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := dc.db.Begin(opCtx)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback(opCtx)
+	if err := audit.Tag(opCtx, tx, "close_wallet"); err != nil {
+		return nil, err
+	}
+
+	var walletID, totalBalance, availableBalance, promoBalance int64
+	var status entity.WalletStatus
+	err = tx.QueryRow(opCtx, lockWallet, userId).Scan(&walletID, &totalBalance, &availableBalance, &status, &promoBalance)
+	cashBalance := availableBalance - promoBalance
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, entity.ErrWalletNotFound
+	case err != nil:
+		return nil, fmt.Errorf("could not lock wallet: %w", err)
+	case status == entity.CLOSED:
+		return nil, entity.ErrWalletClosed
+	case totalBalance != availableBalance:
+		return nil, entity.ErrPendingFunds
+	case cashBalance != 0 && payoutIdempotency == nil:
+		return nil, entity.ErrWalletNotEmpty
+	}
+
+	now := dc.clock.Now()
+	if promoBalance != 0 {
+		if _, err := tx.Exec(opCtx, forfeitPromoQuery, walletID, now); err != nil {
+			return nil, fmt.Errorf("forfeit promo failed: %w", err)
+		}
+	}
+
+	var payoutID *uuid.UUID
+	if cashBalance != 0 {
+		var id uuid.UUID
+		err = idempotencyError(tx.QueryRow(opCtx, finalPayoutQuery, walletID, userId, cashBalance, payoutIdempotency, now).Scan(&id))
+		if err != nil {
+			return nil, fmt.Errorf("final payout failed: %w", err)
+		}
+		payoutID = &id
+	}
+
+	_, err = tx.Exec(opCtx, closeWalletQuery, walletID, userId, status, reason, actor, payoutID, now)
+	if err != nil {
+		return nil, fmt.Errorf("close wallet failed: %w", err)
+	}
+	if err := tx.Commit(opCtx); err != nil {
+		return nil, fmt.Errorf("could not commit wallet closure: %w", err)
+	}
+
+	return payoutID, nil
+}
+
+// rejectionReason explains why a wallet operation did not affect any row
+func (dc *PgxWalletRepo) rejectionReason(ctx context.Context, userId int64) error {
+	var status entity.WalletStatus
+	var quarantined bool
+	err := dc.db.QueryRow(ctx, getWalletState, userId).Scan(&status, &quarantined)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return entity.ErrInsufficientFunds
+	case err != nil:
+		return fmt.Errorf("could not read wallet state: %w", err)
+	case status == entity.CLOSED:
+		return entity.ErrWalletClosed
+	case status == entity.FROZEN_ALL || status == entity.FROZEN_DEBIT:
+		return entity.ErrWalletFrozen
+	case quarantined:
+		return entity.ErrWalletQuarantined
+	default:
+		return entity.ErrInsufficientFunds
+	}
+}
+
+// idempotencyError reports a unique violation of idx_txn_user_key as a used idempotency
+func idempotencyError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.ConstraintName == "idx_txn_user_key" {
+		return entity.ErrIdempotencyUsed
+	}
+	return err
+}
+
 // Close gracefully close all database pool connections
 func (dc *PgxWalletRepo) Close() {
 	dc.db.Close()
@@ -235,18 +555,19 @@ func (dc *PgxWalletRepo) Close() {
 const (
 	chargeQuery = `
 WITH upserted_wallet AS (
-    INSERT INTO wallets (user_id, total_balance, available_balance)
-    VALUES ($1, $2, $2)
+    INSERT INTO wallets (user_id, total_balance, available_balance, created_at, updated_at)
+    VALUES ($1, $2, $2, $5::timestamptz, $5::timestamptz)
     ON CONFLICT (user_id) DO UPDATE
     SET total_balance = wallets.total_balance + EXCLUDED.total_balance,
         available_balance = wallets.available_balance + EXCLUDED.total_balance,
-        updated_at = NOW()
+        updated_at = $5::timestamptz
+    WHERE wallets.status IN ('active', 'frozen_debit')
     RETURNING id AS wallet_id, user_id
 ),
 inserted_txn AS (
     INSERT INTO transactions 
-        (wallet_id, user_id, type, status, amount, release_time, released, idempotency_key)
-    SELECT wallet_id, user_id, 'credit' AS type, 'success' AS status, $2 AS amount, $3 AS release_time, TRUE, $4 AS idempotency_key
+        (wallet_id, user_id, type, status, amount, release_time, released, released_at, idempotency_key, created_at)
+    SELECT wallet_id, user_id, 'credit' AS type, 'success' AS status, $2 AS amount, $3 AS release_time, TRUE, $5::timestamptz, $4 AS idempotency_key, $5::timestamptz
     FROM upserted_wallet 
     RETURNING id AS txn_id
 )
@@ -254,17 +575,18 @@ SELECT txn_id FROM inserted_txn;
 `
 	chargeWithReleaseQuery = `
 WITH upserted_wallet AS (
-    INSERT INTO wallets (user_id, total_balance, available_balance)
-    VALUES ($1, $2, 0)
+    INSERT INTO wallets (user_id, total_balance, available_balance, created_at, updated_at)
+    VALUES ($1, $2, 0, $5::timestamptz, $5::timestamptz)
     ON CONFLICT (user_id) DO UPDATE
     SET total_balance = wallets.total_balance + EXCLUDED.total_balance,
-        updated_at = NOW()
+        updated_at = $5::timestamptz
+    WHERE wallets.status IN ('active', 'frozen_debit')
     RETURNING id AS wallet_id, user_id
 ),
 inserted_txn AS (
     INSERT INTO transactions 
-        (wallet_id, user_id, type, status, amount, release_time, released, idempotency_key)
-    SELECT wallet_id, user_id, 'credit' AS type, 'success' AS status, $2 AS amount, $3 AS release_time, FALSE, $4 AS idempotency_key
+        (wallet_id, user_id, type, status, amount, release_time, released, idempotency_key, created_at)
+    SELECT wallet_id, user_id, 'credit' AS type, 'success' AS status, $2 AS amount, $3 AS release_time, FALSE, $4 AS idempotency_key, $5::timestamptz
     FROM upserted_wallet 
     RETURNING id AS txn_id
 )
@@ -274,27 +596,46 @@ SELECT txn_id FROM inserted_txn;
 WITH updated_wallet AS (
     UPDATE wallets
     SET
-        available_balance = available_balance - $2,
-        updated_at = NOW()
-    WHERE user_id = $1 AND available_balance >= $2
+        available_balance = available_balance - ($2::bigint + $7::bigint),
+        updated_at = $8::timestamptz
+    -- withdrawals are paid from cash only, the promotional credit reserved by holds is not available anyway
+    WHERE user_id = $1 AND available_balance - promo_balance + promo_reserved >= $2::bigint + $7::bigint
+      AND status = 'active'
+      AND NOT EXISTS (
+          SELECT 1 FROM wallet_quarantines q
+          WHERE q.wallet_id = wallets.id AND q.released_at IS NULL
+      )
     RETURNING id AS wallet_id, user_id
 ),
 inserted_txn AS (
     INSERT INTO transactions 
-        (wallet_id, user_id, type, status, amount, release_time, released, idempotency_key)
-    SELECT wallet_id, user_id, 'debit' AS type, 'pending' AS status, ($2 * -1) AS amount, $3 AS release_time, FALSE, $4 AS idempotency_key
+        (wallet_id, user_id, type, status, amount, release_time, released, idempotency_key, destination_id, created_at)
+    SELECT wallet_id, user_id, 'debit' AS type, $5 AS status, ($2::bigint * -1) AS amount, $3 AS release_time, FALSE, $4 AS idempotency_key, $6 AS destination_id, $8::timestamptz
     FROM updated_wallet
-    RETURNING id AS txn_id
+    RETURNING id AS txn_id, wallet_id, user_id
+),
+fee_txn AS (
+    INSERT INTO transactions
+        (wallet_id, user_id, type, status, source, amount, release_time, released, idempotency_key, related_transaction_id, created_at)
+    SELECT wallet_id, user_id, 'debit', 'success', 'fee', ($7::bigint * -1), $3, FALSE, gen_random_uuid(), txn_id, $8::timestamptz
+    FROM inserted_txn
+    WHERE $7::bigint > 0
 )
 SELECT txn_id FROM inserted_txn;
 `
+	// releaseQuery keeps the fee of a debit in review with its debit, a rejected review refunds it unreleased
 	releaseQuery = `
 WITH due_tx AS (
     SELECT id, wallet_id, user_id, type, amount
-    FROM transactions
+    FROM transactions t
     WHERE released = FALSE
+      AND status NOT IN ('failed', 'cancelled', 'review')
       AND release_time IS NOT NULL
-      AND release_time <= NOW()
+      AND release_time <= $2::timestamptz
+      AND NOT EXISTS (
+          SELECT 1 FROM transactions d
+          WHERE d.id = t.related_transaction_id AND t.source = 'fee' AND d.status = 'review'
+      )
     ORDER BY release_time ASC
     LIMIT $1                     -- batch size
     FOR UPDATE SKIP LOCKED
@@ -312,7 +653,7 @@ updated_wallets AS (
                 WHEN tx.type = 'debit' THEN tx.amount
                 ELSE 0
             END,
-        updated_at = NOW()
+        updated_at = $2::timestamptz
     FROM due_tx tx
     WHERE w.id = tx.wallet_id
     RETURNING w.id AS wallet_id
@@ -320,7 +661,8 @@ updated_wallets AS (
 updated_tx AS (
     UPDATE transactions t
     SET released = TRUE,
-        updated_at = NOW()
+        released_at = $2::timestamptz,
+        updated_at = $2::timestamptz
     FROM due_tx tx
     WHERE t.id = tx.id
     RETURNING t.id, t.user_id, t.type, t.amount
@@ -328,19 +670,137 @@ updated_tx AS (
 SELECT * FROM updated_tx;
 `
 	getBalance = `
-SELECT id, user_id, total_balance, available_balance 
+SELECT id, user_id, total_balance, available_balance, status, promo_balance, promo_reserved
 FROM wallets
 WHERE user_id = $1
+`
+	getBalanceAt = `
+WITH snapshot AS (
+    SELECT total_balance, available_balance, taken_at
+    FROM balance_snapshots
+    WHERE user_id = $1
+      AND taken_at <= $2
+    ORDER BY taken_at DESC
+    LIMIT 1
+),
+base AS (
+    SELECT COALESCE((SELECT total_balance FROM snapshot), 0) AS total_balance,
+           COALESCE((SELECT available_balance FROM snapshot), 0) AS available_balance,
+           COALESCE((SELECT taken_at FROM snapshot), '-infinity'::timestamptz) AS since
+)
+SELECT
+    COALESCE((SELECT id FROM wallets WHERE user_id = $1), 0) AS wallet_id,
+    b.total_balance + COALESCE(SUM(t.amount) FILTER (
+        WHERE (t.type = 'credit' AND t.created_at > b.since AND t.created_at <= $2)
+           OR (t.type = 'debit' AND t.released_at > b.since AND t.released_at <= $2)
+    ), 0) - COALESCE(SUM(t.amount) FILTER (
+        WHERE ((t.type = 'debit' AND t.status = 'failed' AND t.released) OR (t.type = 'credit' AND t.status = 'cancelled'))
+          AND t.updated_at > b.since AND t.updated_at <= $2
+    ), 0) AS total_balance,
+    b.available_balance + COALESCE(SUM(t.amount) FILTER (
+        WHERE (t.type = 'credit' AND t.released_at > b.since AND t.released_at <= $2)
+           OR (t.type = 'debit' AND t.created_at > b.since AND t.created_at <= $2)
+    ), 0) - COALESCE(SUM(t.amount) FILTER (
+        WHERE t.type = 'debit' AND t.status IN ('failed', 'cancelled') AND t.updated_at > b.since AND t.updated_at <= $2
+    ), 0) - COALESCE((
+        SELECT SUM(h.amount) FROM holds h
+        WHERE h.user_id = $1 AND h.created_at > b.since AND h.created_at <= $2
+    ), 0) + COALESCE((
+        SELECT SUM(h.amount) FROM holds h
+        WHERE h.user_id = $1 AND h.resolved_at > b.since AND h.resolved_at <= $2
+    ), 0) AS available_balance
+FROM base b
+LEFT JOIN transactions t
+    ON t.user_id = $1
+   AND (t.created_at > b.since OR t.released_at > b.since OR (t.status IN ('failed', 'cancelled') AND t.updated_at > b.since))
+GROUP BY b.total_balance, b.available_balance, b.since
+`
+	takeBalanceSnapshots = `
+INSERT INTO balance_snapshots (wallet_id, user_id, snapshot_date, total_balance, available_balance, taken_at)
+SELECT w.id, w.user_id, $1, w.total_balance, w.available_balance, $3::timestamptz
+FROM wallets w
+WHERE NOT EXISTS (
+    SELECT 1 FROM balance_snapshots s
+    WHERE s.wallet_id = w.id AND s.snapshot_date = $1
+)
+ORDER BY w.id
+LIMIT $2
+ON CONFLICT (wallet_id, snapshot_date) DO NOTHING
+`
+	getWalletState = `
+SELECT w.status, EXISTS (
+    SELECT 1 FROM wallet_quarantines q
+    WHERE q.wallet_id = w.id AND q.released_at IS NULL
+) AS quarantined
+FROM wallets w
+WHERE w.user_id = $1
+`
+	updateWalletStatus = `
+WITH current_wallet AS (
+    SELECT id, user_id, status
+    FROM wallets
+    WHERE user_id = $1
+    FOR UPDATE
+),
+updated_wallet AS (
+    UPDATE wallets w
+    SET status = $2,
+        updated_at = $5::timestamptz
+    FROM current_wallet c
+    WHERE w.id = c.id AND c.status <> 'closed'
+    RETURNING w.id, w.user_id, c.status AS from_status
+),
+history AS (
+    INSERT INTO wallet_status_history (wallet_id, user_id, from_status, to_status, reason, actor, created_at)
+    SELECT id, user_id, from_status, $2, $3, $4, $5::timestamptz
+    FROM updated_wallet
+)
+SELECT from_status FROM updated_wallet;
+`
+	lockWallet = `
+SELECT id, total_balance, available_balance, status, promo_balance
+FROM wallets
+WHERE user_id = $1
+FOR UPDATE
+`
+	finalPayoutQuery = `
+WITH updated_wallet AS (
+    UPDATE wallets
+    SET available_balance = available_balance - $3,
+        updated_at = $5::timestamptz
+    WHERE id = $1
+    RETURNING id
+)
+INSERT INTO transactions
+    (wallet_id, user_id, type, status, amount, release_time, released, idempotency_key, destination_id, created_at)
+SELECT id, $2, 'debit', 'pending', ($3 * -1), $5::timestamptz, FALSE, $4,
+    (SELECT d.id FROM payout_destinations d WHERE d.user_id = $2 AND d.is_default AND d.verified), $5::timestamptz
+FROM updated_wallet
+RETURNING id;
+`
+	closeWalletQuery = `
+WITH closed_wallet AS (
+    UPDATE wallets
+    SET status = 'closed',
+        updated_at = $7::timestamptz
+    WHERE id = $1
+    RETURNING id
+)
+INSERT INTO wallet_status_history (wallet_id, user_id, from_status, to_status, reason, actor, payout_transaction_id, created_at)
+SELECT id, $2, $3, 'closed', $4, $5, $6, $7::timestamptz
+FROM closed_wallet;
 `
 	getTransactionsFirstPage = `
-SELECT id, user_id, type, status, amount, created_at, released, release_time, idempotency_key, retry_count
+SELECT id, user_id, type, status, source, amount, created_at, released, release_time, idempotency_key, retry_count, destination_id,
+       related_transaction_id
 FROM transactions
 WHERE user_id = $1
 ORDER BY ID DESC
 LIMIT $2
 `
 	getTransactionsNextPage = `
-SELECT id, user_id, type, status, amount, created_at, released, release_time, idempotency_key, retry_count
+SELECT id, user_id, type, status, source, amount, created_at, released, release_time, idempotency_key, retry_count, destination_id,
+       related_transaction_id
 FROM transactions
 WHERE user_id = $1
 AND id < $3
@@ -352,24 +812,138 @@ WITH claimed AS (
     SELECT id
     FROM transactions
     WHERE status = 'pending'
-      AND (last_retry IS NULL OR last_retry <= NOW() - INTERVAL '30 seconds')
+      -- a debit is cancellable until its release time, the bank only sees it after that
+      AND released
+      AND release_time <= $2::timestamptz
+      AND (last_retry IS NULL OR last_retry <= $2::timestamptz - INTERVAL '30 seconds')
     ORDER BY id
     LIMIT $1
+    FOR UPDATE SKIP LOCKED
 )
+-- a claimed debit may reach the bank, sent_to_bank keeps it away from cancellation for good
 UPDATE transactions t
-SET last_retry = NOW()
+SET last_retry = $2::timestamptz, sent_to_bank = TRUE
 FROM claimed c
 WHERE t.id = c.id
-RETURNING t.id, t.user_id, t.retry_count, t.amount, t.idempotency_key;
+RETURNING t.id, t.user_id, t.retry_count, t.amount, t.idempotency_key, t.destination_id,
+    (SELECT d.type FROM payout_destinations d WHERE d.id = t.destination_id),
+    (SELECT d.number FROM payout_destinations d WHERE d.id = t.destination_id);
 `
 	updateTransactionStatus = `
 UPDATE transactions
-SET status = $2, bank_response_id = $3, updated_at = NOW()
+SET status = $2, bank_response_id = $3, updated_at = $4::timestamptz
 WHERE id = $1
+`
+	// failWithdrawalQuery fails a pending withdrawal and gives its principal and its fee back in a single
+	// statement, the wallet is updated once with both amounts so neither can be refunded without the other.
+	failWithdrawalQuery = `
+WITH failed_txn AS (
+    UPDATE transactions
+    SET status = 'failed', bank_response_id = $2, updated_at = $3::timestamptz
+    WHERE id = $1 AND status = 'pending'
+    RETURNING id, wallet_id, amount, released
+),
+fee_txn AS (
+    SELECT t.id, t.wallet_id, t.user_id, t.amount, t.released
+    FROM transactions t
+    JOIN failed_txn f ON t.related_transaction_id = f.id
+    WHERE t.source = 'fee'
+    FOR UPDATE OF t
+),
+refund_txn AS (
+    INSERT INTO transactions
+        (wallet_id, user_id, type, status, source, amount, release_time, released, released_at, idempotency_key, related_transaction_id, created_at)
+    SELECT wallet_id, user_id, 'credit', 'success', 'refund', -amount, $3::timestamptz, TRUE, $3::timestamptz, gen_random_uuid(), id, $3::timestamptz
+    FROM fee_txn
+    ON CONFLICT DO NOTHING
+    RETURNING related_transaction_id
+),
+refunded_fee AS (
+    SELECT f.id, f.wallet_id, f.amount, f.released
+    FROM fee_txn f
+    JOIN refund_txn r ON r.related_transaction_id = f.id
+),
+released_fee AS (
+    UPDATE transactions t
+    SET released = TRUE, released_at = $3::timestamptz, updated_at = $3::timestamptz
+    FROM refunded_fee f
+    WHERE t.id = f.id AND NOT f.released
+)
+UPDATE wallets w
+SET available_balance = w.available_balance - f.amount - COALESCE(r.amount, 0),
+    total_balance = w.total_balance
+        - CASE WHEN f.released THEN f.amount ELSE 0 END
+        - CASE WHEN r.released THEN r.amount ELSE 0 END,
+    updated_at = $3::timestamptz
+FROM failed_txn f
+LEFT JOIN refunded_fee r ON r.wallet_id = f.wallet_id
+WHERE w.id = f.wallet_id
+`
+	// refundFeeQuery gives the fee of a withdrawal back with a refund transaction. An unreleased fee is
+	// released at the same time so the total balance is not counted twice, and a fee is refunded at most once.
+	refundFeeQuery = `
+WITH fee_txn AS (
+    SELECT id, wallet_id, user_id, amount, released
+    FROM transactions
+    WHERE related_transaction_id = $1 AND source = 'fee'
+    FOR UPDATE
+),
+refund_txn AS (
+    INSERT INTO transactions
+        (wallet_id, user_id, type, status, source, amount, release_time, released, released_at, idempotency_key, related_transaction_id, created_at)
+    SELECT wallet_id, user_id, 'credit', 'success', 'refund', -amount, $2::timestamptz, TRUE, $2::timestamptz, gen_random_uuid(), id, $2::timestamptz
+    FROM fee_txn
+    ON CONFLICT DO NOTHING
+    RETURNING related_transaction_id
+),
+released_fee AS (
+    UPDATE transactions t
+    SET released = TRUE, released_at = $2::timestamptz, updated_at = $2::timestamptz
+    FROM fee_txn f
+    JOIN refund_txn r ON r.related_transaction_id = f.id
+    WHERE t.id = f.id AND NOT f.released
+)
+UPDATE wallets w
+SET available_balance = w.available_balance - f.amount,
+    total_balance = w.total_balance - CASE WHEN f.released THEN f.amount ELSE 0 END,
+    updated_at = $2::timestamptz
+FROM fee_txn f
+JOIN refund_txn r ON r.related_transaction_id = f.id
+WHERE w.id = f.wallet_id
 `
 	increaseRetryCount = `
 UPDATE transactions
-SET retry_count = retry_count + 1, updated_at = NOW()
+SET retry_count = retry_count + 1, updated_at = $2::timestamptz
 WHERE id = $1
+`
+	insertWithdrawAttempt = `
+INSERT INTO transaction_attempts (transaction_id, attempt, succeeded, bank_response_id, error, created_at)
+VALUES ($1, $2, $3, $4, $5, $6::timestamptz)
+`
+	cancelDebitQuery = `
+WITH cancelled_txn AS (
+    UPDATE transactions
+    SET status = 'cancelled', updated_at = $3::timestamptz
+    WHERE id = $2
+      AND user_id = $1
+      AND type = 'debit'
+      AND source = 'user'
+      -- a debit in review is decided by an admin through the risk review
+      AND status = 'pending'
+      AND released = FALSE
+      AND NOT sent_to_bank
+      -- the final payout of a closed wallet has nowhere to go back to
+      AND NOT EXISTS (SELECT 1 FROM wallets w WHERE w.id = wallet_id AND w.status = 'closed')
+    RETURNING wallet_id, amount
+)
+UPDATE wallets w
+SET available_balance = w.available_balance - c.amount,
+    updated_at = $3::timestamptz
+FROM cancelled_txn c
+WHERE w.id = c.wallet_id
+RETURNING w.id;
+`
+	transactionExists = `
+SELECT EXISTS (SELECT 1 FROM transactions WHERE user_id = $1 AND id = $2)
 `
 )
@@ -3,6 +3,7 @@ package infrastructure
 import (
 	"context"
 	"fmt"
+	"github.com/MaisamV/wallet/platform/clock"
 	"github.com/MaisamV/wallet/platform/config"
 	"github.com/MaisamV/wallet/platform/database"
 	"github.com/MaisamV/wallet/platform/logger"
@@ -193,7 +194,7 @@ func BenchmarkDebit_singleUsersConcurrent(b *testing.B) {
 
 				for job := range jobs {
 					// Charging only user 0 wallet
-					_, err = repo.Debit(context.Background(), 0, &job.UUID, 1000, &releaseTime)
+					_, _, err = repo.Debit(context.Background(), 0, &job.UUID, 1000, 0, &releaseTime, nil, nil)
 					if err != nil {
 						failedCount++
 						fmt.Printf("Failed: %v", err)
@@ -270,7 +271,7 @@ func BenchmarkDebit_multipleUsersConcurrent(b *testing.B) {
 
 				for job := range jobs {
 					// Charging only user 0 wallet
-					_, err = repo.Debit(context.Background(), job.ID, &job.UUID, 1000, &releaseTime)
+					_, _, err = repo.Debit(context.Background(), job.ID, &job.UUID, 1000, 0, &releaseTime, nil, nil)
 					if err != nil {
 						failedCount++
 						fmt.Printf("Failed: %v", err)
@@ -530,6 +531,6 @@ func Init() *PgxWalletRepo {
 	if err != nil {
 		panic(err)
 	}
-	repo := NewPgxWalletRepo(noopLogger, pool)
+	repo := NewPgxWalletRepo(noopLogger, pool, clock.NewSystemClock())
 	return repo
 }
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// batchColumns are the header names of the columns of a CSV batch file, their order is free
+var batchColumns = []string{"user_id", "type", "amount", "idempotency"}
+
+// BatchFileReader reads CSV batch files with a header line and JSON batch files holding an array of rows
+type BatchFileReader struct {
+	logger logger.Logger
+}
+
+func NewBatchFileReader(logger logger.Logger) *BatchFileReader {
+	return &BatchFileReader{
+		logger: logger,
+	}
+}
+
+type batchFileRow struct {
+	UserID      int64  `json:"user_id"`
+	Type        string `json:"type"`
+	Amount      int64  `json:"amount"`
+	Idempotency string `json:"idempotency"`
+}
+
+// Read parses the whole batch file. A malformed file fails, a malformed row is returned with its error
+// so every invalid row of the file can be reported at once.
+func (r *BatchFileReader) Read(ctx context.Context, format entity.BatchFormat, in io.Reader) ([]entity.BatchRow, error) {
+	var rows []entity.BatchRow
+	var err error
+	switch format {
+	case entity.BATCH_CSV:
+		rows, err = r.readCSV(ctx, in)
+	case entity.BATCH_JSON:
+		rows, err = r.readJSON(ctx, in)
+	default:
+		return nil, fmt.Errorf("unknown batch file format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Debug().Int("rows", len(rows)).Str("format", format).Msg("batch file parsed")
+	return rows, nil
+}
+
+func (r *BatchFileReader) readCSV(ctx context.Context, in io.Reader) ([]entity.BatchRow, error) {
+	reader := csv.NewReader(in)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read batch file header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, column := range batchColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("batch file header does not contain the %s column", column)
+		}
+	}
+
+	rows := make([]entity.BatchRow, 0)
+	for line := 2; ; line++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read batch file line %d: %w", line, err)
+		}
+
+		field := func(column string) string {
+			if i := index[column]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := batchFileRow{Type: strings.ToLower(field("type")), Idempotency: field("idempotency")}
+		var parseErr error
+		if row.UserID, err = strconv.ParseInt(field("user_id"), 10, 64); err != nil {
+			parseErr = errors.New("user id is not a number")
+		} else if row.Amount, err = strconv.ParseInt(field("amount"), 10, 64); err != nil {
+			parseErr = errors.New("amount is not a number")
+		}
+		rows = append(rows, toBatchRow(line, row, parseErr))
+	}
+	return rows, nil
+}
+
+func (r *BatchFileReader) readJSON(ctx context.Context, in io.Reader) ([]entity.BatchRow, error) {
+	var records []json.RawMessage
+	if err := json.NewDecoder(in).Decode(&records); err != nil {
+		return nil, fmt.Errorf("batch file is not a json array: %w", err)
+	}
+
+	rows := make([]entity.BatchRow, 0, len(records))
+	for i, record := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		row := batchFileRow{}
+		parseErr := json.Unmarshal(record, &row)
+		row.Type = strings.ToLower(strings.TrimSpace(row.Type))
+		rows = append(rows, toBatchRow(i+1, row, parseErr))
+	}
+	return rows, nil
+}
+
+func toBatchRow(line int, row batchFileRow, parseErr error) entity.BatchRow {
+	batchRow := entity.BatchRow{
+		Line:   line,
+		UserID: row.UserID,
+		Type:   row.Type,
+		Amount: row.Amount,
+		Status: entity.BATCH_ROW_PENDING,
+	}
+	if parseErr != nil {
+		batchRow.Error = parseErr.Error()
+		return batchRow
+	}
+	idempotency, err := uuid.FromString(row.Idempotency)
+	if err != nil {
+		batchRow.Error = "idempotency is not a uuid"
+		return batchRow
+	}
+	batchRow.Idempotency = idempotency
+	return batchRow
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/service"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type BankOutcome = string
+
+const (
+	BANK_SUCCESS                = "success"
+	BANK_TIMEOUT                = "timeout"
+	BANK_DECLINED               = "declined"
+	BANK_INSUFFICIENT_LIQUIDITY = "insufficient_liquidity"
+	BANK_DUPLICATE              = "duplicate"
+)
+
+type LatencyDistribution = string
+
+const (
+	// LATENCY_FIXED waits Mean on every call
+	LATENCY_FIXED LatencyDistribution = "fixed"
+	// LATENCY_UNIFORM waits between Min and Max
+	LATENCY_UNIFORM = "uniform"
+	// LATENCY_NORMAL waits around Mean with StdDev, negative samples do not wait
+	LATENCY_NORMAL = "normal"
+)
+
+// Latency is the time a bank call takes before its outcome, no distribution answers at once
+type Latency struct {
+	Distribution LatencyDistribution
+	Min          time.Duration
+	Max          time.Duration
+	Mean         time.Duration
+	StdDev       time.Duration
+}
+
+// BankErrorRates are the chances of a call failing with each error class
+type BankErrorRates struct {
+	Timeout               float64
+	Declined              float64
+	InsufficientLiquidity float64
+	Duplicate             float64
+}
+
+// BankScript gives every call of UserID with an amount between MinAmount and MaxAmount the same outcome.
+// Zero user ids and bounds match everything, amounts are compared by their absolute value.
+type BankScript struct {
+	UserID    int64
+	MinAmount int64
+	MaxAmount int64
+	Outcome   BankOutcome
+}
+
+// BankOutage fails Rate of the calls with Outcome from After the bank is created for the duration For,
+// a rate below one is a partial outage
+type BankOutage struct {
+	After   time.Duration
+	For     time.Duration
+	Rate    float64
+	Outcome BankOutcome
+}
+
+// FaultBankOptions configures the outcomes of a FaultBankService. Scripts are matched first, then the
+// outages, and the error rates decide the calls neither matched. Timeout is how long a timed out call waits.
+type FaultBankOptions struct {
+	Seed       int64
+	Timeout    time.Duration
+	Latency    Latency
+	ErrorRates BankErrorRates
+	Scripts    []BankScript
+	Outages    []BankOutage
+	Record     bool
+}
+
+// BankCall is a call made to a FaultBankService in recording mode
+type BankCall struct {
+	UserID        int64
+	Idempotency   uuid.UUID
+	Amount        int64
+	DestinationID *int64
+	Outcome       BankOutcome
+	Reference     *uuid.UUID
+	At            time.Time
+}
+
+// FaultBankService is a fake bank for chaos and integration testing, it fails calls the way
+// FaultBankOptions tell it to
+type FaultBankService struct {
+	logger  logger.Logger
+	clock   clock.Clock
+	options FaultBankOptions
+	started time.Time
+
+	mu    sync.Mutex
+	rnd   *rand.Rand
+	calls []BankCall
+}
+
+func NewFaultBankService(logger logger.Logger, clock clock.Clock, options FaultBankOptions) (*FaultBankService, error) {
+	if err := options.validate(); err != nil {
+		return nil, fmt.Errorf("invalid fault bank options: %w", err)
+	}
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &FaultBankService{
+		logger:  logger,
+		clock:   clock,
+		options: options,
+		started: clock.Now(),
+		rnd:     rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+func (o FaultBankOptions) validate() error {
+	if o.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	switch o.Latency.Distribution {
+	case "", LATENCY_FIXED:
+	case LATENCY_UNIFORM:
+		if o.Latency.Max < o.Latency.Min {
+			return fmt.Errorf("latency max must not be less than min")
+		}
+	case LATENCY_NORMAL:
+		if o.Latency.StdDev < 0 {
+			return fmt.Errorf("latency standard deviation must not be negative")
+		}
+	default:
+		return fmt.Errorf("unknown latency distribution %q", o.Latency.Distribution)
+	}
+	r := o.ErrorRates
+	for _, rate := range []float64{r.Timeout, r.Declined, r.InsufficientLiquidity, r.Duplicate} {
+		if rate < 0 {
+			return fmt.Errorf("error rates must not be negative")
+		}
+	}
+	if r.Timeout+r.Declined+r.InsufficientLiquidity+r.Duplicate > 1 {
+		return fmt.Errorf("error rates must not add up to more than 1")
+	}
+	for i, s := range o.Scripts {
+		if err := validateOutcome(s.Outcome); err != nil {
+			return fmt.Errorf("script %d: %w", i, err)
+		}
+		if s.MaxAmount != 0 && s.MaxAmount < s.MinAmount {
+			return fmt.Errorf("script %d: max amount must not be less than min amount", i)
+		}
+	}
+	for i, outage := range o.Outages {
+		if err := validateOutcome(outage.Outcome); err != nil {
+			return fmt.Errorf("outage %d: %w", i, err)
+		}
+		if outage.For <= 0 || outage.Rate <= 0 || outage.Rate > 1 {
+			return fmt.Errorf("outage %d: duration must be positive and rate between 0 and 1", i)
+		}
+	}
+	return nil
+}
+
+func validateOutcome(outcome BankOutcome) error {
+	switch outcome {
+	case BANK_SUCCESS, BANK_TIMEOUT, BANK_DECLINED, BANK_INSUFFICIENT_LIQUIDITY, BANK_DUPLICATE:
+		return nil
+	}
+	return fmt.Errorf("unknown outcome %q", outcome)
+}
+
+func (s *FaultBankService) Withdraw(ctx context.Context, userId int64, idempotency *uuid.UUID, withdrawAmount int64, destination *entity.Destination) (*uuid.UUID, error) {
+	outcome, latency := s.decide(userId, withdrawAmount)
+	if outcome == BANK_TIMEOUT {
+		latency = s.options.Timeout
+	}
+
+	var reference *uuid.UUID
+	err := wait(ctx, latency)
+	switch {
+	case err != nil:
+		outcome = BANK_TIMEOUT
+		err = fmt.Errorf("%w: %w", service.ErrBankTimeout, err)
+	case outcome == BANK_SUCCESS:
+		id, uuidErr := uuid.NewV7()
+		if uuidErr != nil {
+			return nil, uuidErr
+		}
+		reference = &id
+	default:
+		err = outcomeError(outcome)
+	}
+
+	if outcome != BANK_SUCCESS {
+		s.logger.Debug().Int64("user_id", userId).Str("outcome", outcome).Msg("fault bank failed a payout")
+	}
+	if s.options.Record {
+		s.record(userId, idempotency, withdrawAmount, destination, outcome, reference)
+	}
+	return reference, err
+}
+
+// Calls returns the calls made so far, it is empty unless recording is on
+func (s *FaultBankService) Calls() []BankCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := make([]BankCall, len(s.calls))
+	copy(calls, s.calls)
+	return calls
+}
+
+func (s *FaultBankService) record(userId int64, idempotency *uuid.UUID, amount int64, destination *entity.Destination,
+	outcome BankOutcome, reference *uuid.UUID) {
+	call := BankCall{
+		UserID:    userId,
+		Amount:    amount,
+		Outcome:   outcome,
+		Reference: reference,
+		At:        s.clock.Now(),
+	}
+	if idempotency != nil {
+		call.Idempotency = *idempotency
+	}
+	if destination != nil {
+		id := destination.ID
+		call.DestinationID = &id
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, call)
+}
+
+// decide picks the outcome of a call and how long it takes
+func (s *FaultBankService) decide(userId int64, amount int64) (BankOutcome, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	latency := s.latency()
+	if amount < 0 {
+		amount = -amount
+	}
+	for _, script := range s.options.Scripts {
+		if script.matches(userId, amount) {
+			return script.Outcome, latency
+		}
+	}
+	elapsed := s.clock.Now().Sub(s.started)
+	for _, outage := range s.options.Outages {
+		if elapsed >= outage.After && elapsed < outage.After+outage.For && s.rnd.Float64() < outage.Rate {
+			return outage.Outcome, latency
+		}
+	}
+
+	r := s.options.ErrorRates
+	roll := s.rnd.Float64()
+	for _, class := range []struct {
+		rate    float64
+		outcome BankOutcome
+	}{
+		{r.Timeout, BANK_TIMEOUT},
+		{r.Declined, BANK_DECLINED},
+		{r.InsufficientLiquidity, BANK_INSUFFICIENT_LIQUIDITY},
+		{r.Duplicate, BANK_DUPLICATE},
+	} {
+		if roll < class.rate {
+			return class.outcome, latency
+		}
+		roll -= class.rate
+	}
+	return BANK_SUCCESS, latency
+}
+
+func (s *FaultBankService) latency() time.Duration {
+	l := s.options.Latency
+	switch l.Distribution {
+	case LATENCY_FIXED:
+		return l.Mean
+	case LATENCY_UNIFORM:
+		if l.Max == l.Min {
+			return l.Min
+		}
+		return l.Min + time.Duration(s.rnd.Int63n(int64(l.Max-l.Min)))
+	case LATENCY_NORMAL:
+		return max(0, l.Mean+time.Duration(s.rnd.NormFloat64()*float64(l.StdDev)))
+	}
+	return 0
+}
+
+func (s BankScript) matches(userId int64, amount int64) bool {
+	return (s.UserID == 0 || s.UserID == userId) &&
+		amount >= s.MinAmount &&
+		(s.MaxAmount == 0 || amount <= s.MaxAmount)
+}
+
+func outcomeError(outcome BankOutcome) error {
+	switch outcome {
+	case BANK_DECLINED:
+		return service.ErrBankDeclined
+	case BANK_INSUFFICIENT_LIQUIDITY:
+		return service.ErrBankInsufficientLiquidity
+	case BANK_DUPLICATE:
+		return service.ErrBankDuplicate
+	}
+	return service.ErrBankTimeout
+}
+
+// wait sleeps for d or until ctx is done
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/service"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofrs/uuid/v5"
+	"testing"
+	"time"
+)
+
+func newFaultBank(t *testing.T, clk clock.Clock, options FaultBankOptions) *FaultBankService {
+	t.Helper()
+	if options.Timeout == 0 {
+		options.Timeout = time.Millisecond
+	}
+	bank, err := NewFaultBankService(logger.NewNoopLogger(), clk, options)
+	if err != nil {
+		t.Fatalf("could not create fault bank: %v", err)
+	}
+	return bank
+}
+
+func withdraw(bank *FaultBankService, userId int64, amount int64) error {
+	key := uuid.Must(uuid.NewV4())
+	_, err := bank.Withdraw(context.Background(), userId, &key, amount, nil)
+	return err
+}
+
+func TestFaultBankService_scripts(t *testing.T) {
+	bank := newFaultBank(t, clock.NewSystemClock(), FaultBankOptions{
+		Seed: 1,
+		Scripts: []BankScript{
+			{UserID: 7, Outcome: BANK_DECLINED},
+			{MinAmount: 1000, MaxAmount: 2000, Outcome: BANK_INSUFFICIENT_LIQUIDITY},
+			{UserID: 8, Outcome: BANK_TIMEOUT},
+		},
+	})
+	tests := []struct {
+		name    string
+		userId  int64
+		amount  int64
+		wantErr error
+	}{
+		{"scripted user", 7, -10, service.ErrBankDeclined},
+		{"scripted amount", 1, -1500, service.ErrBankInsufficientLiquidity},
+		{"outside the amount range", 1, -2500, nil},
+		{"first script wins", 7, -1500, service.ErrBankDeclined},
+		{"timeout", 8, -10, service.ErrBankTimeout},
+		{"unscripted", 2, -10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := withdraw(bank, tt.userId, tt.amount); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFaultBankService_outage(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	bank := newFaultBank(t, fake, FaultBankOptions{
+		Seed:    1,
+		Outages: []BankOutage{{After: time.Minute, For: time.Minute, Rate: 1, Outcome: BANK_DECLINED}},
+	})
+	if err := withdraw(bank, 1, -10); err != nil {
+		t.Fatalf("expected no error before the outage, got %v", err)
+	}
+	fake.Advance(90 * time.Second)
+	if err := withdraw(bank, 1, -10); !errors.Is(err, service.ErrBankDeclined) {
+		t.Fatalf("expected a declined payout during the outage, got %v", err)
+	}
+	fake.Advance(time.Minute)
+	if err := withdraw(bank, 1, -10); err != nil {
+		t.Fatalf("expected no error after the outage, got %v", err)
+	}
+}
+
+func TestFaultBankService_errorRates(t *testing.T) {
+	bank := newFaultBank(t, clock.NewSystemClock(), FaultBankOptions{
+		Seed:       1,
+		ErrorRates: BankErrorRates{Declined: 0.3, Duplicate: 0.2},
+	})
+	counts := make(map[error]int)
+	for i := 0; i < 2000; i++ {
+		counts[withdraw(bank, 1, -10)]++
+	}
+	within := func(n int, want float64) bool {
+		return float64(n) > want*2000*0.8 && float64(n) < want*2000*1.2
+	}
+	if !within(counts[service.ErrBankDeclined], 0.3) || !within(counts[service.ErrBankDuplicate], 0.2) || !within(counts[nil], 0.5) {
+		t.Fatalf("outcomes do not follow the error rates: %v", counts)
+	}
+}
+
+func TestFaultBankService_record(t *testing.T) {
+	bank := newFaultBank(t, clock.NewSystemClock(), FaultBankOptions{
+		Record:  true,
+		Scripts: []BankScript{{UserID: 2, Outcome: BANK_DUPLICATE}},
+	})
+	key := uuid.Must(uuid.NewV4())
+	reference, err := bank.Withdraw(context.Background(), 1, &key, -10, &entity.Destination{ID: 3})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := withdraw(bank, 2, -20); !errors.Is(err, service.ErrBankDuplicate) {
+		t.Fatalf("expected a duplicate, got %v", err)
+	}
+
+	calls := bank.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	first := calls[0]
+	if first.UserID != 1 || first.Idempotency != key || first.Amount != -10 || first.Outcome != BANK_SUCCESS ||
+		first.DestinationID == nil || *first.DestinationID != 3 || first.Reference == nil || *first.Reference != *reference {
+		t.Fatalf("first call is not recorded correctly: %+v", first)
+	}
+	if calls[1].UserID != 2 || calls[1].Outcome != BANK_DUPLICATE || calls[1].Reference != nil {
+		t.Fatalf("second call is not recorded correctly: %+v", calls[1])
+	}
+}
+
+func TestFaultBankService_contextTimeout(t *testing.T) {
+	bank := newFaultBank(t, clock.NewSystemClock(), FaultBankOptions{
+		Latency: Latency{Distribution: LATENCY_FIXED, Mean: time.Second},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	key := uuid.Must(uuid.NewV4())
+	_, err := bank.Withdraw(ctx, 1, &key, -10, nil)
+	if !errors.Is(err, service.ErrBankTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}
+
+func TestNewFaultBankService_invalid(t *testing.T) {
+	tests := map[string]FaultBankOptions{
+		"no timeout":           {},
+		"unknown distribution": {Timeout: time.Second, Latency: Latency{Distribution: "poisson"}},
+		"rates over one":       {Timeout: time.Second, ErrorRates: BankErrorRates{Timeout: 0.6, Declined: 0.6}},
+		"unknown outcome":      {Timeout: time.Second, Scripts: []BankScript{{Outcome: "lost"}}},
+		"outage without rate":  {Timeout: time.Second, Outages: []BankOutage{{For: time.Minute, Outcome: BANK_TIMEOUT}}},
+	}
+	for name, options := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewFaultBankService(logger.NewNoopLogger(), clock.NewSystemClock(), options); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
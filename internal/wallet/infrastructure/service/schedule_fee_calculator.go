@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+)
+
+// ScheduleFeeCalculator prices withdrawals with the fee schedule configured for the wallet currency
+type ScheduleFeeCalculator struct {
+	schedule entity.FeeSchedule
+}
+
+// NewScheduleFeeCalculator picks the schedule of currency, withdrawals are free when there is none
+func NewScheduleFeeCalculator(currency string, schedules []entity.FeeSchedule) (*ScheduleFeeCalculator, error) {
+	calculator := &ScheduleFeeCalculator{schedule: entity.FeeSchedule{Currency: currency}}
+	for _, s := range schedules {
+		if err := s.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid fee schedule: %w", err)
+		}
+		if s.Currency == currency {
+			calculator.schedule = s
+		}
+	}
+	return calculator, nil
+}
+
+func (c *ScheduleFeeCalculator) Quote(ctx context.Context, withdrawAmount int64) (*entity.FeeQuote, error) {
+	if withdrawAmount <= 0 {
+		return nil, errors.New("amount cannot be negative or zero")
+	}
+	fee := c.schedule.Compute(withdrawAmount)
+	return &entity.FeeQuote{
+		Amount:   withdrawAmount,
+		Fee:      fee,
+		Total:    withdrawAmount + fee,
+		Currency: c.schedule.Currency,
+	}, nil
+}
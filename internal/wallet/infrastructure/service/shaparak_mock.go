@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/service"
 	"github.com/MaisamV/wallet/platform/logger"
 	"github.com/gofrs/uuid/v5"
 	"math/rand"
@@ -18,10 +21,13 @@ func NewShaparakMockService(logger logger.Logger) *ShaparakMockService {
 	}
 }
 
-func (s *ShaparakMockService) Withdraw(ctx context.Context, userId int64, idempotency *uuid.UUID, withdrawAmount int64) (*uuid.UUID, error) {
+func (s *ShaparakMockService) Withdraw(ctx context.Context, userId int64, idempotency *uuid.UUID, withdrawAmount int64, destination *entity.Destination) (*uuid.UUID, error) {
 	opCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	if destination != nil {
+		s.logger.Debug().Int64("destination_id", destination.ID).Str("destination_type", destination.Type).Msg("paying out to destination")
+	}
 	return mockHttpCall(opCtx, userId, idempotency, withdrawAmount)
 }
 
@@ -42,7 +48,7 @@ func mockHttpCall(ctx context.Context, id int64, idempotency *uuid.UUID, amount
 	}()
 	select {
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		return nil, fmt.Errorf("%w: %w", service.ErrBankTimeout, ctx.Err())
 	case result := <-resultCh:
 		return &result, nil
 	}
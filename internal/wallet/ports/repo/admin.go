@@ -0,0 +1,18 @@
+package repo
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/gofrs/uuid/v5"
+)
+
+// WalletAdminRepo holds the back office operations used by support staff
+type WalletAdminRepo interface {
+	SearchWallets(ctx context.Context, userId *int64, status entity.WalletStatus, afterId int64, limit int) ([]entity.WalletDetails, error)
+	GetTransaction(ctx context.Context, id *uuid.UUID) (*entity.TransactionDetails, error)
+	ReinquireTransaction(ctx context.Context, id *uuid.UUID) error
+	CreateAdjustment(ctx context.Context, adjustment *entity.Adjustment) (int64, error)
+	GetAdjustments(ctx context.Context, status entity.AdjustmentStatus, limit int) ([]entity.Adjustment, error)
+	ApproveAdjustment(ctx context.Context, id int64, approver string) (*entity.Adjustment, error)
+	RejectAdjustment(ctx context.Context, id int64, actor string) (*entity.Adjustment, error)
+}
@@ -0,0 +1,31 @@
+package repo
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+type BatchRepo interface {
+	UsedIdempotencies(ctx context.Context, rows []entity.BatchRow) ([]uuid.UUID, error)
+	CreateBatch(ctx context.Context, batch *entity.Batch, rows []entity.BatchRow) (*entity.Batch, error)
+	GetBatches(ctx context.Context, limit int) ([]entity.Batch, error)
+	GetBatch(ctx context.Context, id int64) (*entity.Batch, error)
+	GetBatchRows(ctx context.Context, batchID int64, status entity.BatchRowStatus, afterLine int, limit int) ([]entity.BatchRow, error)
+	ClaimBatch(ctx context.Context, claimFor time.Duration) (*entity.Batch, error)
+	ApplyBatchRow(ctx context.Context, row entity.BatchRow, debits BatchDebits) (*uuid.UUID, error)
+	FailBatchRow(ctx context.Context, rowID int64, reason string) error
+	StageBatchRows(ctx context.Context, batchID int64, limit int, debits BatchDebits) (int, error)
+	ReleaseBatchRows(ctx context.Context, batchID int64, limit int) (int, error)
+	RollBackBatchRows(ctx context.Context, batchID int64, limit int) (int, error)
+	CompleteBatch(ctx context.Context, batchID int64) (*entity.Batch, error)
+	FailBatch(ctx context.Context, batchID int64) (*entity.Batch, error)
+}
+
+// BatchDebits gives the debit rows of a batch the fee and the risk screen the debit API gives a withdrawal.
+// The row is paid out to the default destination of its user, which must be verified.
+type BatchDebits struct {
+	Quote  func(ctx context.Context, amount int64) (fee int64, err error)
+	Screen func(amount int64) *DebitScreen
+}
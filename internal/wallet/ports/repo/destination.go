@@ -0,0 +1,14 @@
+package repo
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+)
+
+type DestinationRepo interface {
+	AddDestination(ctx context.Context, destination *entity.Destination) (*entity.Destination, error)
+	GetDestinations(ctx context.Context, userId int64) ([]entity.Destination, error)
+	GetDestination(ctx context.Context, userId int64, id *int64) (*entity.Destination, error)
+	SetDefaultDestination(ctx context.Context, userId int64, id int64) error
+	VerifyDestination(ctx context.Context, id int64, actor string) (*entity.Destination, error)
+}
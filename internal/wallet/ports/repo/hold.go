@@ -0,0 +1,16 @@
+package repo
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+type HoldRepo interface {
+	PlaceHold(ctx context.Context, userId int64, idempotency *uuid.UUID, amount int64, reference string, expiresAt time.Time) (*entity.Hold, error)
+	CaptureHold(ctx context.Context, userId int64, id *uuid.UUID, amount int64) (*entity.Hold, error)
+	VoidHold(ctx context.Context, userId int64, id *uuid.UUID) (*entity.Hold, error)
+	GetHold(ctx context.Context, userId int64, id *uuid.UUID) (*entity.Hold, error)
+	ExpireHolds(ctx context.Context, batchSize int) (int64, error)
+}
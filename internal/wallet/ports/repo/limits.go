@@ -0,0 +1,14 @@
+package repo
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+)
+
+// LimitsRepo manages the default limits and the per user overrides
+type LimitsRepo interface {
+	GetLimits(ctx context.Context, userId int64) (*entity.LimitSettings, error)
+	SetDefaultLimits(ctx context.Context, limits entity.Limits) error
+	SetUserLimits(ctx context.Context, userId int64, limits entity.Limits) error
+	DeleteUserLimits(ctx context.Context, userId int64) error
+}
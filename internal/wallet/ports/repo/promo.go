@@ -0,0 +1,12 @@
+package repo
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+)
+
+type PromoRepo interface {
+	GrantPromo(ctx context.Context, grant *entity.PromoGrant) (*entity.PromoGrant, error)
+	GetPromoGrants(ctx context.Context, userId int64) ([]entity.PromoGrant, error)
+	ExpirePromoGrants(ctx context.Context, batchSize int) (int64, error)
+}
@@ -0,0 +1,1005 @@
+// Package repotest holds the conformance suite every repo.WalletRepo implementation must pass,
+// so the in-memory repo used by unit tests keeps the semantics of the Postgres one.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/internal/wallet/ports/service"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/gofrs/uuid/v5"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Subject is a wallet repo under test
+type Subject struct {
+	Repo repo.WalletRepo
+	// Clock is the clock the repo was built with, the suite advances it to make transactions due
+	// instead of waiting for them
+	Clock *clock.Fake
+}
+
+// RunWalletRepoSuite runs the conformance suite, newSubject must return a repo without any wallet
+// built with the clock of the subject
+func RunWalletRepoSuite(t *testing.T, newSubject func(t *testing.T) Subject) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s Subject)
+	}{
+		{"Charge", testCharge},
+		{"Charge_validation", testChargeValidation},
+		{"Charge_idempotency", testChargeIdempotency},
+		{"Charge_withReleaseTime", testChargeWithReleaseTime},
+		{"Debit", testDebit},
+		{"Debit_validation", testDebitValidation},
+		{"Debit_idempotency", testDebitIdempotency},
+		{"Debit_insufficientFunds", testDebitInsufficientFunds},
+		{"Debit_concurrentOverdraw", testDebitConcurrentOverdraw},
+		{"Debit_review", testDebitReview},
+		{"Debit_riskProfile", testDebitRiskProfile},
+		{"Debit_concurrentScreening", testDebitConcurrentScreening},
+		{"ReleaseDueTransactions_order", testReleaseOrder},
+		{"ReleaseDueTransactions_skipsFinished", testReleaseSkipsFinished},
+		{"GetPendingTransactions", testPendingClaim},
+		{"UpdateTransactionStatus_success", testWithdrawSuccess},
+		{"UpdateTransactionStatus_failedBeforeRelease", testWithdrawFailedBeforeRelease},
+		{"UpdateTransactionStatus_failedAfterRelease", testWithdrawFailedAfterRelease},
+		{"UpdateTransactionStatus_concurrentFailures", testWithdrawConcurrentFailures},
+		{"IncreaseTransactionRetryCount", testRetryCount},
+		{"CancelDebit", testCancelDebit},
+		{"GetTransactionList", testTransactionList},
+		{"GetBalanceAt", testBalanceAt},
+		{"GetBalanceAt_missingAndClosedWallet", testBalanceAtMissingAndClosedWallet},
+		{"TakeBalanceSnapshots", testBalanceSnapshots},
+		{"UpdateWalletStatus", testUpdateWalletStatus},
+		{"CloseWallet", testCloseWallet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newSubject(t))
+		})
+	}
+}
+
+// NewKey returns a new idempotency key
+func NewKey(t *testing.T) *uuid.UUID {
+	t.Helper()
+	key, err := uuid.NewV7()
+	if err != nil {
+		t.Fatalf("failed to generate UUID: %v", err)
+	}
+	return &key
+}
+
+// delay is how far ahead of the clock Later schedules a release
+const delay = time.Hour
+
+// Later returns a release time an hour ahead of the clock of the subject
+func Later(s Subject) *time.Time {
+	at := s.Clock.Now().Add(delay)
+	return &at
+}
+
+// Charge adds amount to the available balance of the user right away
+func Charge(t *testing.T, s Subject, userId int64, amount int64) *uuid.UUID {
+	t.Helper()
+	id, err := s.Repo.Charge(context.Background(), userId, NewKey(t), amount, nil)
+	if err != nil {
+		t.Fatalf("charge of %d failed: %v", amount, err)
+	}
+	return id
+}
+
+// Debit withdraws amount with the given fee, released an hour from now
+func Debit(t *testing.T, s Subject, userId int64, amount int64, fee int64) *uuid.UUID {
+	t.Helper()
+	id, _, err := s.Repo.Debit(context.Background(), userId, NewKey(t), amount, fee, Later(s), nil, nil)
+	if err != nil {
+		t.Fatalf("debit of %d failed: %v", amount, err)
+	}
+	return id
+}
+
+// Decide returns a screen which decides every withdrawal with the given outcome and reasons
+func Decide(outcome entity.RiskDecision, reasons ...string) *repo.DebitScreen {
+	return &repo.DebitScreen{Decide: func(profile entity.RiskProfile, now time.Time) entity.RiskAssessment {
+		return entity.RiskAssessment{Decision: outcome, Reasons: reasons}
+	}}
+}
+
+// Screen returns a screen which runs the risk evaluator on a withdrawal of amount
+func Screen(risk service.RiskEvaluator, amount int64) *repo.DebitScreen {
+	return &repo.DebitScreen{Lookback: risk.Lookback(), Decide: func(profile entity.RiskProfile, now time.Time) entity.RiskAssessment {
+		return risk.Evaluate(profile, amount, now)
+	}}
+}
+
+// BatchDebits charges the debit rows of a batch a flat fee and screens them with screen, which may be nil
+func BatchDebits(fee int64, screen *repo.DebitScreen) repo.BatchDebits {
+	return repo.BatchDebits{
+		Quote: func(ctx context.Context, amount int64) (int64, error) {
+			return fee, nil
+		},
+		Screen: func(amount int64) *repo.DebitScreen {
+			return screen
+		},
+	}
+}
+
+// MakeDue advances the clock past every release time Later has handed out so far
+func MakeDue(s Subject) {
+	s.Clock.Advance(delay + time.Second)
+}
+
+// Release runs the release job until nothing is due and returns the number of released transactions
+func Release(t *testing.T, s Subject) int {
+	t.Helper()
+	released := 0
+	for {
+		list, err := s.Repo.ReleaseDueTransactions(context.Background(), 100)
+		if err != nil {
+			t.Fatalf("release due transactions failed: %v", err)
+		}
+		if len(list) == 0 {
+			return released
+		}
+		released += len(list)
+	}
+}
+
+// ExpectBalance fails the test unless the user has the given balances
+func ExpectBalance(t *testing.T, s Subject, userId int64, total int64, available int64) {
+	t.Helper()
+	w, err := s.Repo.GetBalance(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("get balance failed: %v", err)
+	}
+	if w.TotalBalance != total || w.AvailableBalance != available {
+		t.Fatalf("expected total %d and available %d, got total %d and available %d",
+			total, available, w.TotalBalance, w.AvailableBalance)
+	}
+}
+
+// releaseAll advances the clock past the scheduled releases and releases everything that is due
+func releaseAll(t *testing.T, s Subject) int {
+	t.Helper()
+	MakeDue(s)
+	return Release(t, s)
+}
+
+func findTransaction(t *testing.T, s Subject, userId int64, id *uuid.UUID) entity.Transaction {
+	t.Helper()
+	var cursor *uuid.UUID
+	for {
+		page, err := s.Repo.GetTransactionList(context.Background(), userId, cursor, 30)
+		if err != nil {
+			t.Fatalf("get transaction list failed: %v", err)
+		}
+		for _, txn := range page.TransactionList {
+			if txn.ID == *id {
+				return txn
+			}
+		}
+		if page.Cursor == nil {
+			t.Fatalf("transaction %s not found", id)
+		}
+		cursor = page.Cursor
+	}
+}
+
+func testCharge(t *testing.T, s Subject) {
+	ctx := context.Background()
+	w, err := s.Repo.GetBalance(ctx, 1)
+	if err != nil {
+		t.Fatalf("get balance failed: %v", err)
+	}
+	if w.TotalBalance != 0 || w.AvailableBalance != 0 || w.Status != entity.ACTIVE {
+		t.Fatalf("expected an empty active wallet, got %+v", w)
+	}
+
+	id := Charge(t, s, 1, 100)
+	Charge(t, s, 1, 50)
+	ExpectBalance(t, s, 1, 150, 150)
+	ExpectBalance(t, s, 2, 0, 0)
+
+	txn := findTransaction(t, s, 1, id)
+	if txn.Type != entity.CREDIT || txn.Status != entity.SUCCESS || txn.Source != entity.USER_SOURCE || txn.Amount != 100 || !txn.Released {
+		t.Fatalf("unexpected charge transaction %+v", txn)
+	}
+}
+
+func testChargeValidation(t *testing.T, s Subject) {
+	ctx := context.Background()
+	past := s.Clock.Now().Add(-time.Minute)
+	if _, err := s.Repo.Charge(ctx, 1, nil, 100, nil); err == nil {
+		t.Fatalf("expected a charge without idempotency to fail")
+	}
+	if _, err := s.Repo.Charge(ctx, 1, NewKey(t), 0, nil); err == nil {
+		t.Fatalf("expected a charge of 0 to fail")
+	}
+	if _, err := s.Repo.Charge(ctx, 1, NewKey(t), -100, nil); err == nil {
+		t.Fatalf("expected a negative charge to fail")
+	}
+	if _, err := s.Repo.Charge(ctx, 1, NewKey(t), 100, &past); !errors.Is(err, entity.ErrInvalidReleaseTime) {
+		t.Fatalf("expected ErrInvalidReleaseTime for a charge released in the past, got %v", err)
+	}
+	ExpectBalance(t, s, 1, 0, 0)
+}
+
+func testChargeIdempotency(t *testing.T, s Subject) {
+	ctx := context.Background()
+	key := NewKey(t)
+	if _, err := s.Repo.Charge(ctx, 1, key, 100, nil); err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+	if _, err := s.Repo.Charge(ctx, 1, key, 100, nil); !errors.Is(err, entity.ErrIdempotencyUsed) {
+		t.Fatalf("expected ErrIdempotencyUsed, got %v", err)
+	}
+	ExpectBalance(t, s, 1, 100, 100)
+
+	// idempotency is unique per user
+	if _, err := s.Repo.Charge(ctx, 2, key, 100, nil); err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+	ExpectBalance(t, s, 2, 100, 100)
+}
+
+func testChargeWithReleaseTime(t *testing.T, s Subject) {
+	ctx := context.Background()
+	id, err := s.Repo.Charge(ctx, 1, NewKey(t), 100, Later(s))
+	if err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+	// an unreleased credit is part of the total balance only
+	ExpectBalance(t, s, 1, 100, 0)
+	if _, _, err := s.Repo.Debit(ctx, 1, NewKey(t), 50, 0, Later(s), nil, nil); !errors.Is(err, entity.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	if released := releaseAll(t, s); released != 1 {
+		t.Fatalf("expected 1 released transaction, got %d", released)
+	}
+	ExpectBalance(t, s, 1, 100, 100)
+	if txn := findTransaction(t, s, 1, id); !txn.Released {
+		t.Fatalf("expected the credit to be released")
+	}
+}
+
+func testDebit(t *testing.T, s Subject) {
+	Charge(t, s, 1, 1000)
+	id := Debit(t, s, 1, 300, 20)
+	// the amount and the fee leave the available balance now and the total balance on release
+	ExpectBalance(t, s, 1, 1000, 680)
+
+	txn := findTransaction(t, s, 1, id)
+	if txn.Type != entity.DEBIT || txn.Status != entity.PENDING || txn.Amount != -300 || txn.Released {
+		t.Fatalf("unexpected debit transaction %+v", txn)
+	}
+	page, err := s.Repo.GetTransactionList(context.Background(), 1, nil, 10)
+	if err != nil {
+		t.Fatalf("get transaction list failed: %v", err)
+	}
+	fees := 0
+	for _, txn := range page.TransactionList {
+		if txn.Source == entity.FEE_SOURCE {
+			fees++
+			if txn.Amount != -20 || txn.RelatedTransactionID == nil || *txn.RelatedTransactionID != *id {
+				t.Fatalf("unexpected fee transaction %+v", txn)
+			}
+		}
+	}
+	if fees != 1 {
+		t.Fatalf("expected 1 fee transaction, got %d", fees)
+	}
+
+	if released := releaseAll(t, s); released != 2 {
+		t.Fatalf("expected the debit and its fee to be released, got %d", released)
+	}
+	ExpectBalance(t, s, 1, 680, 680)
+}
+
+func testDebitValidation(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	past := s.Clock.Now().Add(-time.Minute)
+	if _, _, err := s.Repo.Debit(ctx, 1, nil, 100, 0, Later(s), nil, nil); err == nil {
+		t.Fatalf("expected a debit without idempotency to fail")
+	}
+	if _, _, err := s.Repo.Debit(ctx, 1, NewKey(t), 0, 0, Later(s), nil, nil); err == nil {
+		t.Fatalf("expected a debit of 0 to fail")
+	}
+	if _, _, err := s.Repo.Debit(ctx, 1, NewKey(t), -1, 0, Later(s), nil, nil); err == nil {
+		t.Fatalf("expected a negative debit to fail")
+	}
+	if _, _, err := s.Repo.Debit(ctx, 1, NewKey(t), 100, -1, Later(s), nil, nil); err == nil {
+		t.Fatalf("expected a negative fee to fail")
+	}
+	if _, _, err := s.Repo.Debit(ctx, 1, NewKey(t), 100, 0, nil, nil, nil); !errors.Is(err, entity.ErrInvalidReleaseTime) {
+		t.Fatalf("expected ErrInvalidReleaseTime for a debit without release time, got %v", err)
+	}
+	if _, _, err := s.Repo.Debit(ctx, 1, NewKey(t), 100, 0, &past, nil, nil); !errors.Is(err, entity.ErrInvalidReleaseTime) {
+		t.Fatalf("expected ErrInvalidReleaseTime for a debit released in the past, got %v", err)
+	}
+	if _, _, err := s.Repo.Debit(ctx, 1, NewKey(t), 100, 0, Later(s), nil, Decide(entity.DENY, "velocity")); !errors.Is(err, entity.ErrRiskDenied) {
+		t.Fatalf("expected ErrRiskDenied, got %v", err)
+	}
+	ExpectBalance(t, s, 1, 1000, 1000)
+}
+
+func testDebitIdempotency(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	key := NewKey(t)
+	if _, _, err := s.Repo.Debit(ctx, 1, key, 100, 0, Later(s), nil, nil); err != nil {
+		t.Fatalf("debit failed: %v", err)
+	}
+	if _, _, err := s.Repo.Debit(ctx, 1, key, 100, 0, Later(s), nil, nil); !errors.Is(err, entity.ErrIdempotencyUsed) {
+		t.Fatalf("expected ErrIdempotencyUsed, got %v", err)
+	}
+	ExpectBalance(t, s, 1, 1000, 900)
+}
+
+func testDebitInsufficientFunds(t *testing.T, s Subject) {
+	ctx := context.Background()
+	if _, _, err := s.Repo.Debit(ctx, 1, NewKey(t), 100, 0, Later(s), nil, nil); !errors.Is(err, entity.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds for a missing wallet, got %v", err)
+	}
+	Charge(t, s, 1, 100)
+	// the fee must be covered too
+	if _, _, err := s.Repo.Debit(ctx, 1, NewKey(t), 100, 1, Later(s), nil, nil); !errors.Is(err, entity.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	Debit(t, s, 1, 100, 0)
+	ExpectBalance(t, s, 1, 100, 0)
+}
+
+func testDebitConcurrentOverdraw(t *testing.T, s Subject) {
+	Charge(t, s, 1, 1000)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, rejected := 0, 0
+	start := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, _ := uuid.NewV7()
+			<-start
+			_, _, err := s.Repo.Debit(context.Background(), 1, &key, 100, 0, Later(s), nil, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, entity.ErrInsufficientFunds):
+				rejected++
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if succeeded != 10 || rejected != 10 {
+		t.Fatalf("expected 10 debits to succeed and 10 to be rejected, got %d and %d", succeeded, rejected)
+	}
+	ExpectBalance(t, s, 1, 1000, 0)
+}
+
+func testDebitReview(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	id, assessment, err := s.Repo.Debit(ctx, 1, NewKey(t), 300, 20, Later(s), nil, Decide(entity.REVIEW, "large withdrawal"))
+	if err != nil {
+		t.Fatalf("debit failed: %v", err)
+	}
+	if assessment.Decision != entity.REVIEW || len(assessment.Reasons) != 1 {
+		t.Fatalf("unexpected risk assessment %+v", assessment)
+	}
+	ExpectBalance(t, s, 1, 1000, 680)
+	if txn := findTransaction(t, s, 1, id); txn.Status != entity.IN_REVIEW {
+		t.Fatalf("expected the debit to wait for review, got %s", txn.Status)
+	}
+
+	// a debit in review is neither claimed by the withdraw job nor released
+	claimed, err := s.Repo.GetPendingTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Fatalf("expected no claimable debit, got %d", len(claimed))
+	}
+	// its fee waits for the review as well
+	if released := releaseAll(t, s); released != 0 {
+		t.Fatalf("expected nothing to release, got %d", released)
+	}
+	// only an admin decides a debit in review
+	if err := s.Repo.CancelDebit(ctx, 1, id); !errors.Is(err, entity.ErrNotCancellable) {
+		t.Fatalf("expected ErrNotCancellable for a debit in review, got %v", err)
+	}
+	ExpectBalance(t, s, 1, 1000, 680)
+}
+
+func testDebitRiskProfile(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	rules := entity.RiskRules{{Name: "large_charge", Type: entity.WITHDRAWAL_AFTER_LARGE_CHARGE, Outcome: entity.DENY, Amount: 500, Window: time.Hour}}
+
+	// the charge read by the screen denies the withdrawal and moves no money
+	_, assessment, err := s.Repo.Debit(ctx, 1, NewKey(t), 100, 0, Later(s), nil, Screen(rules, 100))
+	if !errors.Is(err, entity.ErrRiskDenied) {
+		t.Fatalf("expected ErrRiskDenied, got %v", err)
+	}
+	if assessment == nil || len(assessment.Reasons) != 1 || assessment.Reasons[0] != "large_charge" {
+		t.Fatalf("unexpected risk assessment %+v", assessment)
+	}
+	ExpectBalance(t, s, 1, 1000, 1000)
+
+	// another wallet has no such charge
+	Charge(t, s, 2, 100)
+	if _, assessment, err := s.Repo.Debit(ctx, 2, NewKey(t), 100, 0, Later(s), nil, Screen(rules, 100)); err != nil || assessment.Decision != entity.ALLOW {
+		t.Fatalf("expected the debit to be allowed, got %+v and %v", assessment, err)
+	}
+}
+
+// countingScreen allows every withdrawal and counts how many of its evaluations overlap
+type countingScreen struct {
+	mu              sync.Mutex
+	running, maxRun int
+}
+
+func (c *countingScreen) Lookback() time.Duration {
+	return time.Hour
+}
+
+func (c *countingScreen) Evaluate(profile entity.RiskProfile, amount int64, now time.Time) entity.RiskAssessment {
+	c.mu.Lock()
+	c.running++
+	c.maxRun = max(c.maxRun, c.running)
+	c.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	return entity.RiskAssessment{Decision: entity.ALLOW}
+}
+
+func testDebitConcurrentScreening(t *testing.T, s Subject) {
+	Charge(t, s, 1, 1000)
+	screen := &countingScreen{}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, _ := uuid.NewV7()
+			<-start
+			if _, _, err := s.Repo.Debit(context.Background(), 1, &key, 10, 0, Later(s), nil, Screen(screen, 10)); err != nil {
+				t.Errorf("debit failed: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// the wallet lock is held while screening, so withdrawals of a wallet are screened one at a time
+	if screen.maxRun != 1 {
+		t.Fatalf("expected the screenings not to overlap, %d ran at once", screen.maxRun)
+	}
+	ExpectBalance(t, s, 1, 1000, 900)
+}
+
+func testReleaseOrder(t *testing.T, s Subject) {
+	ctx := context.Background()
+	ids := make([]*uuid.UUID, 3)
+	for i, after := range []time.Duration{3 * time.Minute, time.Minute, 2 * time.Minute} {
+		releaseTime := s.Clock.Now().Add(after)
+		id, err := s.Repo.Charge(ctx, int64(i+1), NewKey(t), 100, &releaseTime)
+		if err != nil {
+			t.Fatalf("charge failed: %v", err)
+		}
+		ids[i] = id
+	}
+	notDue, err := s.Repo.Charge(ctx, 4, NewKey(t), 100, Later(s))
+	if err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+	s.Clock.Advance(3 * time.Minute)
+
+	// the oldest release time comes first
+	for _, expected := range []*uuid.UUID{ids[1], ids[2], ids[0]} {
+		list, err := s.Repo.ReleaseDueTransactions(ctx, 1)
+		if err != nil {
+			t.Fatalf("release due transactions failed: %v", err)
+		}
+		if len(list) != 1 || list[0].ID != *expected || list[0].Type != entity.CREDIT || list[0].Amount != 100 {
+			t.Fatalf("expected %s to be released, got %+v", expected, list)
+		}
+	}
+	list, err := s.Repo.ReleaseDueTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("release due transactions failed: %v", err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("expected nothing left to release, got %+v", list)
+	}
+	if txn := findTransaction(t, s, 4, notDue); txn.Released {
+		t.Fatalf("expected a transaction which is not due to stay unreleased")
+	}
+	ExpectBalance(t, s, 4, 100, 0)
+}
+
+func testReleaseSkipsFinished(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	failed := Debit(t, s, 1, 100, 0)
+	if err := s.Repo.UpdateTransactionStatus(ctx, failed, entity.FAILED, nil); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	cancelled := Debit(t, s, 1, 100, 0)
+	if err := s.Repo.CancelDebit(ctx, 1, cancelled); err != nil {
+		t.Fatalf("cancel debit failed: %v", err)
+	}
+	if released := releaseAll(t, s); released != 0 {
+		t.Fatalf("expected failed and cancelled debits not to be released, got %d", released)
+	}
+	ExpectBalance(t, s, 1, 1000, 1000)
+}
+
+func testPendingClaim(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	Charge(t, s, 2, 1000)
+	first := Debit(t, s, 1, 100, 0)
+	second := Debit(t, s, 2, 200, 0)
+
+	// a debit can be cancelled until its release time, the withdraw job leaves it alone until then
+	claimed, err := s.Repo.GetPendingTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Fatalf("expected unreleased debits not to be claimed, got %+v", claimed)
+	}
+	if released := releaseAll(t, s); released != 2 {
+		t.Fatalf("expected both debits to be released, got %d", released)
+	}
+
+	claimed, err = s.Repo.GetPendingTransactions(ctx, 1)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != *first || claimed[0].UserID != 1 || claimed[0].Amount != -100 {
+		t.Fatalf("expected the oldest debit to be claimed, got %+v", claimed)
+	}
+	claimed, err = s.Repo.GetPendingTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	// claimed debits are kept away from other withdraw workers and credits are never claimed
+	if len(claimed) != 1 || claimed[0].ID != *second {
+		t.Fatalf("expected only the unclaimed debit to be claimed, got %+v", claimed)
+	}
+	claimed, err = s.Repo.GetPendingTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Fatalf("expected nothing left to claim, got %+v", claimed)
+	}
+
+	// a claimed debit may already be at the bank
+	if err := s.Repo.CancelDebit(ctx, 1, first); !errors.Is(err, entity.ErrNotCancellable) {
+		t.Fatalf("expected ErrNotCancellable, got %v", err)
+	}
+}
+
+func testWithdrawSuccess(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	id := Debit(t, s, 1, 300, 10)
+	if err := s.Repo.UpdateTransactionStatus(ctx, id, entity.SUCCESS, NewKey(t)); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	ExpectBalance(t, s, 1, 1000, 690)
+	if released := releaseAll(t, s); released != 2 {
+		t.Fatalf("expected the debit and its fee to be released, got %d", released)
+	}
+	ExpectBalance(t, s, 1, 690, 690)
+
+	// only pending withdrawals can fail
+	if err := s.Repo.UpdateTransactionStatus(ctx, id, entity.FAILED, nil); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	ExpectBalance(t, s, 1, 690, 690)
+	if txn := findTransaction(t, s, 1, id); txn.Status != entity.SUCCESS {
+		t.Fatalf("expected the debit to stay successful, got %s", txn.Status)
+	}
+}
+
+func testWithdrawFailedBeforeRelease(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	id := Debit(t, s, 1, 300, 10)
+	if err := s.Repo.UpdateTransactionStatus(ctx, id, entity.FAILED, NewKey(t)); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	// the amount comes back and the fee is refunded
+	ExpectBalance(t, s, 1, 1000, 1000)
+	if txn := findTransaction(t, s, 1, id); txn.Status != entity.FAILED {
+		t.Fatalf("expected a failed debit, got %s", txn.Status)
+	}
+
+	// failing again neither gives the amount back nor refunds the fee twice
+	if err := s.Repo.UpdateTransactionStatus(ctx, id, entity.FAILED, NewKey(t)); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	if released := releaseAll(t, s); released != 0 {
+		t.Fatalf("expected nothing to release, got %d", released)
+	}
+	ExpectBalance(t, s, 1, 1000, 1000)
+}
+
+func testWithdrawFailedAfterRelease(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	id := Debit(t, s, 1, 300, 10)
+	if released := releaseAll(t, s); released != 2 {
+		t.Fatalf("expected the debit and its fee to be released, got %d", released)
+	}
+	ExpectBalance(t, s, 1, 690, 690)
+
+	if err := s.Repo.UpdateTransactionStatus(ctx, id, entity.FAILED, nil); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	ExpectBalance(t, s, 1, 1000, 1000)
+
+	// a failure reported twice gives nothing back the second time
+	if err := s.Repo.UpdateTransactionStatus(ctx, id, entity.FAILED, nil); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	ExpectBalance(t, s, 1, 1000, 1000)
+	page, err := s.Repo.GetTransactionList(ctx, 1, nil, 10)
+	if err != nil {
+		t.Fatalf("get transaction list failed: %v", err)
+	}
+	refunds := 0
+	for _, txn := range page.TransactionList {
+		if txn.Source == entity.REFUND_SOURCE {
+			refunds++
+			if txn.Amount != 10 || txn.RelatedTransactionID == nil {
+				t.Fatalf("unexpected refund %+v", txn)
+			}
+		}
+	}
+	if refunds != 1 {
+		t.Fatalf("expected a single fee refund, got %d", refunds)
+	}
+}
+
+func testWithdrawConcurrentFailures(t *testing.T, s Subject) {
+	Charge(t, s, 1, 1000)
+	id := Debit(t, s, 1, 300, 10)
+	if released := releaseAll(t, s); released != 2 {
+		t.Fatalf("expected the debit and its fee to be released, got %d", released)
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if err := s.Repo.UpdateTransactionStatus(context.Background(), id, entity.FAILED, nil); err != nil {
+				t.Errorf("update transaction status failed: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// the principal and the fee come back exactly once, whichever failure won
+	ExpectBalance(t, s, 1, 1000, 1000)
+}
+
+func testRetryCount(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	id := Debit(t, s, 1, 100, 0)
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err := s.Repo.IncreaseTransactionRetryCount(ctx, id); err != nil {
+			t.Fatalf("increase transaction retry count failed: %v", err)
+		}
+		if err := s.Repo.RecordWithdrawAttempt(ctx, id, attempt, nil, errors.New("bank is down")); err != nil {
+			t.Fatalf("record withdraw attempt failed: %v", err)
+		}
+	}
+	if txn := findTransaction(t, s, 1, id); txn.RetryCount != 2 {
+		t.Fatalf("expected 2 retries, got %d", txn.RetryCount)
+	}
+}
+
+func testCancelDebit(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 1000)
+	id := Debit(t, s, 1, 300, 10)
+
+	if err := s.Repo.CancelDebit(ctx, 2, id); !errors.Is(err, entity.ErrTransactionNotFound) {
+		t.Fatalf("expected ErrTransactionNotFound for the debit of another user, got %v", err)
+	}
+	if err := s.Repo.CancelDebit(ctx, 1, NewKey(t)); !errors.Is(err, entity.ErrTransactionNotFound) {
+		t.Fatalf("expected ErrTransactionNotFound, got %v", err)
+	}
+	if err := s.Repo.CancelDebit(ctx, 1, id); err != nil {
+		t.Fatalf("cancel debit failed: %v", err)
+	}
+	ExpectBalance(t, s, 1, 1000, 1000)
+	if txn := findTransaction(t, s, 1, id); txn.Status != entity.CANCELLED {
+		t.Fatalf("expected a cancelled debit, got %s", txn.Status)
+	}
+	if err := s.Repo.CancelDebit(ctx, 1, id); !errors.Is(err, entity.ErrNotCancellable) {
+		t.Fatalf("expected ErrNotCancellable, got %v", err)
+	}
+
+	// credits and released debits can not be cancelled
+	credit := Charge(t, s, 1, 100)
+	if err := s.Repo.CancelDebit(ctx, 1, credit); !errors.Is(err, entity.ErrNotCancellable) {
+		t.Fatalf("expected ErrNotCancellable for a credit, got %v", err)
+	}
+	released := Debit(t, s, 1, 100, 0)
+	releaseAll(t, s)
+	if err := s.Repo.CancelDebit(ctx, 1, released); !errors.Is(err, entity.ErrNotCancellable) {
+		t.Fatalf("expected ErrNotCancellable for a released debit, got %v", err)
+	}
+	ExpectBalance(t, s, 1, 1000, 1000)
+}
+
+func testTransactionList(t *testing.T, s Subject) {
+	ctx := context.Background()
+	ids := make([]uuid.UUID, 12)
+	for i := range ids {
+		ids[i] = *Charge(t, s, 1, int64(i+1))
+	}
+	Charge(t, s, 2, 100)
+
+	// the newest transactions come first
+	page, err := s.Repo.GetTransactionList(ctx, 1, nil, 5)
+	if err != nil {
+		t.Fatalf("get transaction list failed: %v", err)
+	}
+	if len(page.TransactionList) != 5 || page.TransactionList[0].ID != ids[11] || page.Cursor == nil || *page.Cursor != ids[7] {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	page, err = s.Repo.GetTransactionList(ctx, 1, page.Cursor, 5)
+	if err != nil {
+		t.Fatalf("get transaction list failed: %v", err)
+	}
+	if len(page.TransactionList) != 5 || page.TransactionList[0].ID != ids[6] {
+		t.Fatalf("unexpected second page %+v", page)
+	}
+	page, err = s.Repo.GetTransactionList(ctx, 1, page.Cursor, 5)
+	if err != nil {
+		t.Fatalf("get transaction list failed: %v", err)
+	}
+	if len(page.TransactionList) != 2 || page.Cursor != nil {
+		t.Fatalf("expected a last page of 2 without cursor, got %+v", page)
+	}
+
+	// the page size is kept between 10 and 30
+	page, err = s.Repo.GetTransactionList(ctx, 1, nil, 0)
+	if err != nil {
+		t.Fatalf("get transaction list failed: %v", err)
+	}
+	if len(page.TransactionList) != 10 || page.Cursor == nil {
+		t.Fatalf("expected the default page size of 10, got %d", len(page.TransactionList))
+	}
+	page, err = s.Repo.GetTransactionList(ctx, 1, nil, 100)
+	if err != nil {
+		t.Fatalf("get transaction list failed: %v", err)
+	}
+	if len(page.TransactionList) != 12 || page.Cursor != nil {
+		t.Fatalf("expected all 12 transactions, got %d", len(page.TransactionList))
+	}
+}
+
+func testBalanceAt(t *testing.T, s Subject) {
+	ctx := context.Background()
+	before := s.Clock.Now()
+	s.Clock.Advance(time.Second)
+	Charge(t, s, 1, 1000)
+	Debit(t, s, 1, 300, 0)
+
+	w, err := s.Repo.GetBalanceAt(ctx, 1, before)
+	if err != nil {
+		t.Fatalf("get balance at failed: %v", err)
+	}
+	if w.TotalBalance != 0 || w.AvailableBalance != 0 {
+		t.Fatalf("expected no balance before the first charge, got %+v", w)
+	}
+	w, err = s.Repo.GetBalanceAt(ctx, 1, s.Clock.Now())
+	if err != nil {
+		t.Fatalf("get balance at failed: %v", err)
+	}
+	if w.TotalBalance != 1000 || w.AvailableBalance != 700 {
+		t.Fatalf("expected the current balance, got %+v", w)
+	}
+}
+
+func testBalanceAtMissingAndClosedWallet(t *testing.T, s Subject) {
+	ctx := context.Background()
+	w, err := s.Repo.GetBalanceAt(ctx, 1, s.Clock.Now())
+	if err != nil {
+		t.Fatalf("get balance at of a missing wallet failed: %v", err)
+	}
+	if w.TotalBalance != 0 || w.AvailableBalance != 0 {
+		t.Fatalf("expected no balance for a missing wallet, got %+v", w)
+	}
+
+	// a closed wallet keeps the balances it had before its closure
+	Charge(t, s, 1, 1000)
+	beforeClosure := s.Clock.Now()
+	s.Clock.Advance(time.Second)
+	if _, err := s.Repo.CloseWallet(ctx, 1, "request", "admin", NewKey(t)); err != nil {
+		t.Fatalf("close wallet failed: %v", err)
+	}
+	w, err = s.Repo.GetBalanceAt(ctx, 1, beforeClosure)
+	if err != nil {
+		t.Fatalf("get balance at of a closed wallet failed: %v", err)
+	}
+	if w.TotalBalance != 1000 || w.AvailableBalance != 1000 {
+		t.Fatalf("expected the balance before the closure, got %+v", w)
+	}
+	w, err = s.Repo.GetBalanceAt(ctx, 1, s.Clock.Now())
+	if err != nil {
+		t.Fatalf("get balance at of a closed wallet failed: %v", err)
+	}
+	if w.TotalBalance != 1000 || w.AvailableBalance != 0 {
+		t.Fatalf("expected the final payout to be reserved, got %+v", w)
+	}
+}
+
+func testBalanceSnapshots(t *testing.T, s Subject) {
+	ctx := context.Background()
+	Charge(t, s, 1, 100)
+	Charge(t, s, 2, 100)
+	today := s.Clock.Now().Truncate(24 * time.Hour)
+
+	for _, expected := range []int64{1, 1, 0} {
+		taken, err := s.Repo.TakeBalanceSnapshots(ctx, today, 1)
+		if err != nil {
+			t.Fatalf("take balance snapshots failed: %v", err)
+		}
+		if taken != expected {
+			t.Fatalf("expected %d snapshots, got %d", expected, taken)
+		}
+	}
+	taken, err := s.Repo.TakeBalanceSnapshots(ctx, today.AddDate(0, 0, 1), 10)
+	if err != nil {
+		t.Fatalf("take balance snapshots failed: %v", err)
+	}
+	if taken != 2 {
+		t.Fatalf("expected a snapshot of both wallets for the next day, got %d", taken)
+	}
+
+	// the balance after a snapshot replays the transactions made since
+	s.Clock.Advance(time.Second)
+	Charge(t, s, 1, 25)
+	Debit(t, s, 1, 70, 0)
+	w, err := s.Repo.GetBalanceAt(ctx, 1, s.Clock.Now())
+	if err != nil {
+		t.Fatalf("get balance at failed: %v", err)
+	}
+	if w.TotalBalance != 125 || w.AvailableBalance != 55 {
+		t.Fatalf("expected total 125 and available 55, got %+v", w)
+	}
+	releaseAll(t, s)
+	w, err = s.Repo.GetBalanceAt(ctx, 1, s.Clock.Now())
+	if err != nil {
+		t.Fatalf("get balance at failed: %v", err)
+	}
+	if w.TotalBalance != 55 || w.AvailableBalance != 55 {
+		t.Fatalf("expected 55 after the release, got %+v", w)
+	}
+}
+
+func testUpdateWalletStatus(t *testing.T, s Subject) {
+	ctx := context.Background()
+	if _, err := s.Repo.UpdateWalletStatus(ctx, 1, entity.FROZEN_ALL, "fraud", "admin"); !errors.Is(err, entity.ErrWalletNotFound) {
+		t.Fatalf("expected ErrWalletNotFound, got %v", err)
+	}
+	Charge(t, s, 1, 1000)
+	if _, err := s.Repo.UpdateWalletStatus(ctx, 1, entity.CLOSED, "request", "admin"); !errors.Is(err, entity.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	from, err := s.Repo.UpdateWalletStatus(ctx, 1, entity.FROZEN_DEBIT, "dispute", "admin")
+	if err != nil {
+		t.Fatalf("update wallet status failed: %v", err)
+	}
+	if from != entity.ACTIVE {
+		t.Fatalf("expected the wallet to come from active, got %s", from)
+	}
+	// a wallet frozen for debits can still be charged
+	Charge(t, s, 1, 100)
+	if _, _, err := s.Repo.Debit(ctx, 1, NewKey(t), 100, 0, Later(s), nil, nil); !errors.Is(err, entity.ErrWalletFrozen) {
+		t.Fatalf("expected ErrWalletFrozen, got %v", err)
+	}
+
+	if _, err := s.Repo.UpdateWalletStatus(ctx, 1, entity.FROZEN_ALL, "fraud", "admin"); err != nil {
+		t.Fatalf("update wallet status failed: %v", err)
+	}
+	if _, err := s.Repo.Charge(ctx, 1, NewKey(t), 100, nil); !errors.Is(err, entity.ErrWalletFrozen) {
+		t.Fatalf("expected ErrWalletFrozen, got %v", err)
+	}
+
+	if _, err := s.Repo.UpdateWalletStatus(ctx, 1, entity.ACTIVE, "resolved", "admin"); err != nil {
+		t.Fatalf("update wallet status failed: %v", err)
+	}
+	Debit(t, s, 1, 100, 0)
+	ExpectBalance(t, s, 1, 1100, 1000)
+}
+
+func testCloseWallet(t *testing.T, s Subject) {
+	ctx := context.Background()
+	if _, err := s.Repo.CloseWallet(ctx, 1, "request", "admin", nil); !errors.Is(err, entity.ErrWalletNotFound) {
+		t.Fatalf("expected ErrWalletNotFound, got %v", err)
+	}
+	Charge(t, s, 1, 1000)
+	pending := Debit(t, s, 1, 100, 0)
+	if _, err := s.Repo.CloseWallet(ctx, 1, "request", "admin", NewKey(t)); !errors.Is(err, entity.ErrPendingFunds) {
+		t.Fatalf("expected ErrPendingFunds, got %v", err)
+	}
+	if err := s.Repo.UpdateTransactionStatus(ctx, pending, entity.SUCCESS, NewKey(t)); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	releaseAll(t, s)
+	if _, err := s.Repo.CloseWallet(ctx, 1, "request", "admin", nil); !errors.Is(err, entity.ErrWalletNotEmpty) {
+		t.Fatalf("expected ErrWalletNotEmpty, got %v", err)
+	}
+
+	payoutID, err := s.Repo.CloseWallet(ctx, 1, "request", "admin", NewKey(t))
+	if err != nil {
+		t.Fatalf("close wallet failed: %v", err)
+	}
+	if payoutID == nil {
+		t.Fatalf("expected a final payout")
+	}
+	payout := findTransaction(t, s, 1, payoutID)
+	if payout.Type != entity.DEBIT || payout.Status != entity.PENDING || payout.Amount != -900 {
+		t.Fatalf("unexpected final payout %+v", payout)
+	}
+	ExpectBalance(t, s, 1, 900, 0)
+	// the final payout has nowhere to go back to
+	if err := s.Repo.CancelDebit(ctx, 1, payoutID); !errors.Is(err, entity.ErrNotCancellable) {
+		t.Fatalf("expected ErrNotCancellable, got %v", err)
+	}
+
+	if _, err := s.Repo.Charge(ctx, 1, NewKey(t), 100, nil); !errors.Is(err, entity.ErrWalletClosed) {
+		t.Fatalf("expected ErrWalletClosed, got %v", err)
+	}
+	if _, err := s.Repo.UpdateWalletStatus(ctx, 1, entity.ACTIVE, "reopen", "admin"); !errors.Is(err, entity.ErrWalletClosed) {
+		t.Fatalf("expected ErrWalletClosed, got %v", err)
+	}
+	if _, err := s.Repo.CloseWallet(ctx, 1, "request", "admin", nil); !errors.Is(err, entity.ErrWalletClosed) {
+		t.Fatalf("expected ErrWalletClosed, got %v", err)
+	}
+
+	// an empty wallet is closed without a payout
+	Charge(t, s, 2, 100)
+	Debit(t, s, 2, 100, 0)
+	releaseAll(t, s)
+	payoutID, err = s.Repo.CloseWallet(ctx, 2, "request", "admin", nil)
+	if err != nil {
+		t.Fatalf("close wallet failed: %v", err)
+	}
+	if payoutID != nil {
+		t.Fatalf("expected no payout for an empty wallet")
+	}
+}
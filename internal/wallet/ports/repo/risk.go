@@ -0,0 +1,13 @@
+package repo
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/gofrs/uuid/v5"
+)
+
+type RiskRepo interface {
+	GetRiskReviews(ctx context.Context, limit int) ([]entity.RiskReview, error)
+	ApproveRiskReview(ctx context.Context, id *uuid.UUID, actor string) error
+	RejectRiskReview(ctx context.Context, id *uuid.UUID, actor string) error
+}
@@ -0,0 +1,20 @@
+package repo
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/gofrs/uuid/v5"
+	"time"
+)
+
+type ScheduleRepo interface {
+	CreateSchedule(ctx context.Context, schedule *entity.Schedule) (*entity.Schedule, error)
+	GetSchedules(ctx context.Context, userId int64) ([]entity.Schedule, error)
+	GetSchedule(ctx context.Context, userId int64, id int64) (*entity.Schedule, error)
+	PauseSchedule(ctx context.Context, userId int64, id int64) (*entity.Schedule, error)
+	ResumeSchedule(ctx context.Context, userId int64, id int64, occurrence int64, nextRunAt *time.Time) (*entity.Schedule, error)
+	DeleteSchedule(ctx context.Context, userId int64, id int64) error
+	ClaimDueSchedules(ctx context.Context, limit int, claimFor time.Duration) ([]entity.Schedule, error)
+	AdvanceSchedule(ctx context.Context, id int64, occurrence int64, nextRunAt *time.Time, lastError string) error
+	OccurrenceRecorded(ctx context.Context, idempotency *uuid.UUID) (bool, error)
+}
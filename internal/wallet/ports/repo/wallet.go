@@ -14,14 +14,27 @@ type WalletRepo interface {
 
 type WalletWriter interface {
 	Charge(ctx context.Context, userId int64, idempotency *uuid.UUID, chargeAmount int64, releaseTime *time.Time) (txnId *uuid.UUID, err error)
-	Debit(ctx context.Context, userId int64, idempotency *uuid.UUID, debitAmount int64, releaseTime *time.Time) (txnId *uuid.UUID, err error)
+	Debit(ctx context.Context, userId int64, idempotency *uuid.UUID, debitAmount int64, fee int64, releaseTime *time.Time, destinationID *int64, screen *DebitScreen) (txnId *uuid.UUID, assessment *entity.RiskAssessment, err error)
 	ReleaseDueTransactions(ctx context.Context, batchSize int) ([]entity.Transaction, error)
 	UpdateTransactionStatus(ctx context.Context, id *uuid.UUID, txStatus entity.Status, bankTxID *uuid.UUID) error
 	IncreaseTransactionRetryCount(ctx context.Context, id *uuid.UUID) error
+	RecordWithdrawAttempt(ctx context.Context, id *uuid.UUID, attempt int, bankTxID *uuid.UUID, withdrawErr error) error
+	CancelDebit(ctx context.Context, userId int64, id *uuid.UUID) error
+	TakeBalanceSnapshots(ctx context.Context, snapshotDate time.Time, batchSize int) (int64, error)
+	UpdateWalletStatus(ctx context.Context, userId int64, status entity.WalletStatus, reason string, actor string) (from entity.WalletStatus, err error)
+	CloseWallet(ctx context.Context, userId int64, reason string, actor string, payoutIdempotency *uuid.UUID) (payoutTxnId *uuid.UUID, err error)
+}
+
+// DebitScreen decides a withdrawal once its wallet is locked, so the withdrawals of a wallet are decided one
+// after the other. Decide is given the risk profile of the wallet reaching Lookback back from now.
+type DebitScreen struct {
+	Lookback time.Duration
+	Decide   func(profile entity.RiskProfile, now time.Time) entity.RiskAssessment
 }
 
 type WalletReader interface {
 	GetBalance(ctx context.Context, userId int64) (*entity.Wallet, error)
+	GetBalanceAt(ctx context.Context, userId int64, at time.Time) (*entity.Wallet, error)
 	GetTransactionList(ctx context.Context, userId int64, cursor *uuid.UUID, limit int) (*entity.TransactionPage, error)
 	GetPendingTransactions(ctx context.Context, limit int) ([]entity.Transaction, error)
 }
@@ -2,9 +2,20 @@ package service
 
 import (
 	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
 	"github.com/gofrs/uuid/v5"
 )
 
+// Error classes a bank call fails with, a failed call is retried by the withdraw worker whatever its class
+var (
+	ErrBankTimeout               = errors.New("bank did not answer in time")
+	ErrBankDeclined              = errors.New("bank declined the payout")
+	ErrBankInsufficientLiquidity = errors.New("bank has insufficient liquidity")
+	ErrBankDuplicate             = errors.New("bank rejected the payout as a duplicate")
+)
+
 type BankService interface {
-	Withdraw(ctx context.Context, userId int64, idempotency *uuid.UUID, withdrawAmount int64) (*uuid.UUID, error)
+	// Withdraw pays withdrawAmount out to destination, which is nil for withdrawals made before destinations existed
+	Withdraw(ctx context.Context, userId int64, idempotency *uuid.UUID, withdrawAmount int64, destination *entity.Destination) (*uuid.UUID, error)
 }
@@ -0,0 +1,12 @@
+package service
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"io"
+)
+
+// BatchReader parses a batch file, a row which cannot be parsed is returned with its Error set
+type BatchReader interface {
+	Read(ctx context.Context, format entity.BatchFormat, r io.Reader) ([]entity.BatchRow, error)
+}
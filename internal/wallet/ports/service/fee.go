@@ -0,0 +1,10 @@
+package service
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+)
+
+type FeeCalculator interface {
+	Quote(ctx context.Context, withdrawAmount int64) (*entity.FeeQuote, error)
+}
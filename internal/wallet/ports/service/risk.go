@@ -0,0 +1,14 @@
+package service
+
+import (
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"time"
+)
+
+// RiskEvaluator decides on a withdrawal from the risk profile of its wallet. The debit runs it after the wallet
+// is locked, so concurrent withdrawals of a wallet are evaluated one after the other.
+type RiskEvaluator interface {
+	// Lookback is how far back the charges of the wallet are read into the profile
+	Lookback() time.Duration
+	Evaluate(profile entity.RiskProfile, withdrawAmount int64, now time.Time) entity.RiskAssessment
+}
@@ -0,0 +1,38 @@
+package dto
+
+import "time"
+
+type WalletStatusChange struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+type CloseWallet struct {
+	Reason            string `json:"reason"`
+	PayoutIdempotency string `json:"payout_idempotency,omitempty"`
+}
+
+type Adjustment struct {
+	UserID      int64  `json:"user_id"`
+	Type        string `json:"type"`
+	Amount      int64  `json:"amount"`
+	ReasonCode  string `json:"reason_code"`
+	Note        string `json:"note,omitempty"`
+	Idempotency string `json:"idempotency"`
+}
+
+// Limits leaves a limit out when it is not enforced
+type Limits struct {
+	MaxSingleWithdrawal    *int64 `json:"max_single_withdrawal,omitempty"`
+	DailyWithdrawalTotal   *int64 `json:"daily_withdrawal_total,omitempty"`
+	MonthlyWithdrawalTotal *int64 `json:"monthly_withdrawal_total,omitempty"`
+	MaxBalance             *int64 `json:"max_balance,omitempty"`
+	HourlyWithdrawalCount  *int64 `json:"hourly_withdrawal_count,omitempty"`
+}
+
+type PromoGrant struct {
+	Amount      int64     `json:"amount"`
+	Reason      string    `json:"reason"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Idempotency string    `json:"idempotency"`
+}
@@ -4,6 +4,7 @@ type BaseResponse[T any] struct {
 	Result  *T      `json:"result,omitempty"`
 	Message *string `json:"message,omitempty"`
 	Error   *string `json:"error,omitempty"`
+	Code    *string `json:"code,omitempty"`
 }
 
 func ToResponse[T any](result T) *BaseResponse[T] {
@@ -36,3 +37,19 @@ func ToErrorWithMessage(err error, message string) *BaseResponse[any] {
 		Error:   &e,
 	}
 }
+
+func ToErrorWithCode(err error, message string, code string) *BaseResponse[any] {
+	response := ToErrorWithMessage(err, message)
+	response.Code = &code
+	return response
+}
+
+// ToErrorWithResult returns an error which carries details in its result, e.g. the invalid rows of a file
+func ToErrorWithResult[T any](err error, message string, result T) *BaseResponse[T] {
+	e := err.Error()
+	return &BaseResponse[T]{
+		Result:  &result,
+		Message: &message,
+		Error:   &e,
+	}
+}
@@ -6,4 +6,33 @@ type Transaction struct {
 	Amount      int64
 	Idempotency string
 	ReleaseTime *time.Time `json:"release_time,omitempty"`
+	// DestinationID is only read by withdrawals, nil pays out to the default destination
+	DestinationID *int64 `json:"destination_id,omitempty"`
+}
+
+type Destination struct {
+	Type   string `json:"type"`
+	Number string `json:"number"`
+}
+
+type Hold struct {
+	Amount      int64     `json:"amount"`
+	Idempotency string    `json:"idempotency"`
+	Reference   string    `json:"reference"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type Capture struct {
+	Amount int64 `json:"amount"`
+}
+
+type Schedule struct {
+	Type      string `json:"type"`
+	Amount    int64  `json:"amount"`
+	Frequency string `json:"frequency"`
+	Interval  int    `json:"interval"`
+	// StartAt is the time of the first occurrence, empty starts the schedule now
+	StartAt       *time.Time `json:"start_at,omitempty"`
+	EndAt         *time.Time `json:"end_at,omitempty"`
+	DestinationID *int64     `json:"destination_id,omitempty"`
 }
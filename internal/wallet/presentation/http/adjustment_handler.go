@@ -0,0 +1,131 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
+	platformHttp "github.com/MaisamV/wallet/platform/http"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid/v5"
+	"net/http"
+	"strconv"
+)
+
+type AdjustmentHandler struct {
+	logger          logger.Logger
+	requestHandler  *command.RequestAdjustmentCommandHandler
+	approveHandler  *command.ApproveAdjustmentCommandHandler
+	rejectHandler   *command.RejectAdjustmentCommandHandler
+	adjustmentsList *query.GetAdjustmentsQueryHandler
+}
+
+func NewAdjustmentHandler(logger logger.Logger, requestHandler *command.RequestAdjustmentCommandHandler,
+	approveHandler *command.ApproveAdjustmentCommandHandler, rejectHandler *command.RejectAdjustmentCommandHandler,
+	adjustmentsList *query.GetAdjustmentsQueryHandler) *AdjustmentHandler {
+	return &AdjustmentHandler{
+		logger:          logger,
+		requestHandler:  requestHandler,
+		approveHandler:  approveHandler,
+		rejectHandler:   rejectHandler,
+		adjustmentsList: adjustmentsList,
+	}
+}
+
+// RegisterRoutes registers the manual adjustment routes on the authenticated admin router
+func (h *AdjustmentHandler) RegisterRoutes(admin fiber.Router) {
+	group := admin.Group("/adjustments")
+	h.logger.Info().Msg("Registering adjustment routes")
+	group.Get("/", h.GetAdjustments)
+	group.Post("/", h.Request)
+	group.Post("/:id/approve", h.Approve)
+	group.Post("/:id/reject", h.Reject)
+	h.logger.Info().Msg("adjustment routes registered successfully")
+}
+
+func (h *AdjustmentHandler) GetAdjustments(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "limit should be between 1 and 500")
+	}
+
+	q := query.GetAdjustmentsQuery{
+		Status: c.Query("status", entity.ADJUSTMENT_PENDING),
+		Limit:  limit,
+	}
+	adjustments, err := h.adjustmentsList.Handle(ctx, q)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusInternalServerError, err, "Could not fetch adjustments")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(adjustments))
+}
+
+func (h *AdjustmentHandler) Request(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	request := dto.Adjustment{}
+	if err := c.BodyParser(&request); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+	idempotency, err := uuid.FromString(request.Idempotency)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse idempotency")
+	}
+
+	cmd := command.RequestAdjustmentCommand{
+		UserId:      request.UserID,
+		Type:        request.Type,
+		Amount:      request.Amount,
+		ReasonCode:  request.ReasonCode,
+		Note:        request.Note,
+		Idempotency: &idempotency,
+		Actor:       platformHttp.AdminUser(c),
+	}
+	if err := cmd.Err(); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Invalid adjustment")
+	}
+	adjustment, err := h.requestHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not request adjustment")
+	}
+
+	return c.Status(http.StatusCreated).JSON(dto.ToResponse(adjustment))
+}
+
+func (h *AdjustmentHandler) Approve(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse adjustment id")
+	}
+
+	cmd := command.DecideAdjustmentCommand{ID: id, Actor: platformHttp.AdminUser(c)}
+	adjustment, err := h.approveHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not approve adjustment")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(adjustment))
+}
+
+func (h *AdjustmentHandler) Reject(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse adjustment id")
+	}
+
+	cmd := command.DecideAdjustmentCommand{ID: id, Actor: platformHttp.AdminUser(c)}
+	adjustment, err := h.rejectHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not reject adjustment")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(adjustment))
+}
@@ -0,0 +1,230 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
+	platformHttp "github.com/MaisamV/wallet/platform/http"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid/v5"
+	"net/http"
+	"strconv"
+)
+
+type WalletAdminHandler struct {
+	logger             logger.Logger
+	freezeHandler      *command.FreezeWalletCommandHandler
+	unfreezeHandler    *command.UnfreezeWalletCommandHandler
+	closeHandler       *command.CloseWalletCommandHandler
+	cancelDebitHandler *command.CancelDebitCommandHandler
+	reinquireHandler   *command.ReinquireCommandHandler
+	searchHandler      *query.SearchWalletsQueryHandler
+	transactionHandler *query.GetTransactionQueryHandler
+}
+
+func NewWalletAdminHandler(logger logger.Logger, freezeHandler *command.FreezeWalletCommandHandler,
+	unfreezeHandler *command.UnfreezeWalletCommandHandler, closeHandler *command.CloseWalletCommandHandler,
+	cancelDebitHandler *command.CancelDebitCommandHandler, reinquireHandler *command.ReinquireCommandHandler,
+	searchHandler *query.SearchWalletsQueryHandler, transactionHandler *query.GetTransactionQueryHandler) *WalletAdminHandler {
+	return &WalletAdminHandler{
+		logger:             logger,
+		freezeHandler:      freezeHandler,
+		unfreezeHandler:    unfreezeHandler,
+		closeHandler:       closeHandler,
+		cancelDebitHandler: cancelDebitHandler,
+		reinquireHandler:   reinquireHandler,
+		searchHandler:      searchHandler,
+		transactionHandler: transactionHandler,
+	}
+}
+
+// RegisterRoutes registers the wallet administration routes on the authenticated admin router
+func (h *WalletAdminHandler) RegisterRoutes(admin fiber.Router) {
+	h.logger.Info().Msg("Registering wallet admin routes")
+	admin.Get("/wallets", h.SearchWallets)
+	admin.Get("/transactions/:id", h.GetTransaction)
+	admin.Post("/transactions/:id/reinquire", h.Reinquire)
+	group := admin.Group("/wallet")
+	group.Post("/:userid/freeze", h.Freeze)
+	group.Post("/:userid/unfreeze", h.Unfreeze)
+	group.Post("/:userid/close", h.Close)
+	group.Post("/:userid/transactions/:id/cancel", h.CancelDebit)
+	h.logger.Info().Msg("wallet admin routes registered successfully")
+}
+
+func (h *WalletAdminHandler) SearchWallets(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	q := query.SearchWalletsQuery{Status: c.Query("status")}
+	if userIDString := c.Query("user_id"); userIDString != "" {
+		userID, err := strconv.ParseInt(userIDString, 10, 64)
+		if err != nil {
+			return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse user_id")
+		}
+		q.UserID = &userID
+	}
+	afterID, err := strconv.ParseInt(c.Query("after", "0"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse after")
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "limit should be between 1 and 500")
+	}
+	q.AfterID = afterID
+	q.Limit = limit
+
+	wallets, err := h.searchHandler.Handle(ctx, q)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusInternalServerError, err, "Could not search wallets")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(wallets))
+}
+
+func (h *WalletAdminHandler) GetTransaction(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := uuid.FromString(c.Params("id"))
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse transaction id")
+	}
+
+	details, err := h.transactionHandler.Handle(ctx, query.GetTransactionQuery{ID: &id})
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not fetch transaction")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(details))
+}
+
+func (h *WalletAdminHandler) Reinquire(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := uuid.FromString(c.Params("id"))
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse transaction id")
+	}
+
+	cmd := command.ReinquireCommand{
+		TransactionID: &id,
+		Actor:         platformHttp.AdminUser(c),
+	}
+	if err := h.reinquireHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not reinquire transaction")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(id.String()))
+}
+
+func (h *WalletAdminHandler) CancelDebit(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	id, err := uuid.FromString(c.Params("id"))
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse transaction id")
+	}
+
+	cmd := command.CancelDebitCommand{
+		UserId:        userID,
+		TransactionID: &id,
+		Actor:         platformHttp.AdminUser(c),
+	}
+	if err := h.cancelDebitHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not cancel debit")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(id.String()))
+}
+
+func (h *WalletAdminHandler) Freeze(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	change := dto.WalletStatusChange{}
+	if err := c.BodyParser(&change); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+
+	cmd := command.FreezeWalletCommand{
+		UserId: userID,
+		Status: change.Status,
+		Reason: change.Reason,
+		Actor:  platformHttp.AdminUser(c),
+	}
+	if err := h.freezeHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not freeze wallet")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(cmd.Status))
+}
+
+func (h *WalletAdminHandler) Unfreeze(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	change := dto.WalletStatusChange{}
+	if err := c.BodyParser(&change); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+
+	cmd := command.UnfreezeWalletCommand{
+		UserId: userID,
+		Reason: change.Reason,
+		Actor:  platformHttp.AdminUser(c),
+	}
+	if err := h.unfreezeHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not unfreeze wallet")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse("active"))
+}
+
+func (h *WalletAdminHandler) Close(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	closure := dto.CloseWallet{}
+	if err := c.BodyParser(&closure); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+
+	var payoutIdempotency *uuid.UUID
+	if closure.PayoutIdempotency != "" {
+		idempotency, err := uuid.FromString(closure.PayoutIdempotency)
+		if err != nil {
+			return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse payout idempotency")
+		}
+		payoutIdempotency = &idempotency
+	}
+
+	cmd := command.CloseWalletCommand{
+		UserId:            userID,
+		Reason:            closure.Reason,
+		Actor:             platformHttp.AdminUser(c),
+		PayoutIdempotency: payoutIdempotency,
+	}
+	payoutID, err := h.closeHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not close wallet")
+	}
+
+	if payoutID == nil {
+		return c.Status(http.StatusOK).JSON(dto.ToResponseWithMessage("closed", "wallet closed"))
+	}
+	return c.Status(http.StatusOK).JSON(dto.ToResponseWithMessage(payoutID.String(), "wallet closed with a final payout"))
+}
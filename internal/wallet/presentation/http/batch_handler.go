@@ -0,0 +1,146 @@
+package http
+
+import (
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
+	platformHttp "github.com/MaisamV/wallet/platform/http"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"strconv"
+)
+
+type BatchHandler struct {
+	logger        logger.Logger
+	createHandler *command.CreateBatchCommandHandler
+	batchesList   *query.GetBatchesQueryHandler
+	batchHandler  *query.GetBatchQueryHandler
+	rowsHandler   *query.GetBatchRowsQueryHandler
+}
+
+func NewBatchHandler(logger logger.Logger, createHandler *command.CreateBatchCommandHandler,
+	batchesList *query.GetBatchesQueryHandler, batchHandler *query.GetBatchQueryHandler,
+	rowsHandler *query.GetBatchRowsQueryHandler) *BatchHandler {
+	return &BatchHandler{
+		logger:        logger,
+		createHandler: createHandler,
+		batchesList:   batchesList,
+		batchHandler:  batchHandler,
+		rowsHandler:   rowsHandler,
+	}
+}
+
+// RegisterRoutes registers the bulk charge and debit routes on the authenticated admin router
+func (h *BatchHandler) RegisterRoutes(admin fiber.Router) {
+	group := admin.Group("/batches")
+	h.logger.Info().Msg("Registering batch routes")
+	group.Get("/", h.GetBatches)
+	group.Post("/", h.CreateBatch)
+	group.Get("/:id", h.GetBatch)
+	group.Get("/:id/rows", h.GetBatchRows)
+	h.logger.Info().Msg("batch routes registered successfully")
+}
+
+// CreateBatch stores a batch file uploaded as the multipart field "file", the format is taken from the
+// "format" field or else from the file extension
+func (h *BatchHandler) CreateBatch(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not read batch file")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not open batch file")
+	}
+	defer file.Close()
+
+	cmd := command.CreateBatchCommand{
+		FileName: header.Filename,
+		Format:   c.FormValue("format", entity.BatchFormatOf(header.Filename)),
+		File:     file,
+		Mode:     c.FormValue("mode", entity.BATCH_PARTIAL),
+		Actor:    platformHttp.AdminUser(c),
+	}
+	if err := cmd.Err(); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Invalid batch")
+	}
+	batch, err := h.createHandler.Handle(ctx, cmd)
+	var invalid *entity.BatchValidationError
+	if errors.As(err, &invalid) {
+		h.logger.Error().Err(err).Msg("Invalid batch file")
+		return c.Status(http.StatusBadRequest).JSON(dto.ToErrorWithResult(err, "Invalid batch file", invalid.Rows))
+	}
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not create batch")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(batch))
+}
+
+func (h *BatchHandler) GetBatches(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit <= 0 || limit > 500 {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "limit should be between 1 and 500")
+	}
+
+	batches, err := h.batchesList.Handle(ctx, query.GetBatchesQuery{Limit: limit})
+	if err != nil {
+		return respondError(h.logger, c, http.StatusInternalServerError, err, "Could not fetch batches")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(batches))
+}
+
+func (h *BatchHandler) GetBatch(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse id")
+	}
+
+	batch, err := h.batchHandler.Handle(ctx, query.GetBatchQuery{ID: id})
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not fetch batch")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(batch))
+}
+
+// GetBatchRows pages through the per row results of a batch with the line of the last row as the cursor
+func (h *BatchHandler) GetBatchRows(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse id")
+	}
+	afterLine, err := strconv.Atoi(c.Query("after", "0"))
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse after")
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "limit should be between 1 and 1000")
+	}
+
+	q := query.GetBatchRowsQuery{
+		BatchID:   id,
+		Status:    c.Query("status"),
+		AfterLine: afterLine,
+		Limit:     limit,
+	}
+	rows, err := h.rowsHandler.Handle(ctx, q)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not fetch batch rows")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(rows))
+}
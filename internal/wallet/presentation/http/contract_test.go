@@ -0,0 +1,282 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	infrastructure "github.com/MaisamV/wallet/internal/wallet/infrastructure/repo"
+	"github.com/MaisamV/wallet/internal/wallet/infrastructure/service"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/config"
+	platformHttp "github.com/MaisamV/wallet/platform/http"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/MaisamV/wallet/platform/openapi"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid/v5"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const specFile = "../../../../resources/openapi.yaml"
+
+// contractDestinations pays every withdrawal out to the verified default destination 1
+type contractDestinations struct {
+	repo.DestinationRepo
+}
+
+func (contractDestinations) GetDestination(ctx context.Context, userId int64, id *int64) (*entity.Destination, error) {
+	if id != nil && *id != 1 {
+		return nil, entity.ErrDestinationNotFound
+	}
+	return &entity.Destination{ID: 1, UserID: userId, Type: entity.IBAN, Number: "IR000000000000000000000001", IsDefault: true, Verified: true}, nil
+}
+
+// contractRisk reviews withdrawals from 5000 and denies them from 9000
+type contractRisk struct{}
+
+func (contractRisk) Lookback() time.Duration {
+	return 0
+}
+
+func (contractRisk) Evaluate(profile entity.RiskProfile, amount int64, now time.Time) entity.RiskAssessment {
+	switch {
+	case amount >= 9000:
+		return entity.RiskAssessment{Decision: entity.DENY, Reasons: []string{"amount"}}
+	case amount >= 5000:
+		return entity.RiskAssessment{Decision: entity.REVIEW, Reasons: []string{"amount"}}
+	default:
+		return entity.RiskAssessment{Decision: entity.ALLOW}
+	}
+}
+
+func newContractApp(t *testing.T) *fiber.App {
+	t.Helper()
+	log := logger.NewNoopLogger()
+	clk := clock.NewSystemClock()
+	walletRepo := infrastructure.NewMemoryWalletRepo(clk)
+	fees, err := service.NewScheduleFeeCalculator("IRR", []entity.FeeSchedule{{Currency: "IRR", Flat: 10}})
+	if err != nil {
+		t.Fatalf("could not create fee calculator: %v", err)
+	}
+
+	handler := NewWalletHandler(log,
+		command.NewDebitCommandHandler(log, walletRepo, contractDestinations{}, contractRisk{}, fees, clk),
+		command.NewChargeCommandHandler(log, walletRepo, clk),
+		query.NewGetBalanceQueryHandler(log, walletRepo),
+		query.NewGetBalanceAtQueryHandler(log, walletRepo, clk),
+		query.NewGetTransactionPageQueryHandler(log, walletRepo),
+		query.NewQuoteFeeQueryHandler(log, fees),
+		command.NewCancelDebitCommandHandler(log, walletRepo))
+	app := fiber.New()
+	handler.RegisterRoutes(app)
+	return app
+}
+
+// contractAPIKey authenticates the requests of the admin contract
+const contractAPIKey = "contract-key"
+
+// newAdminContractApp serves the wallet admin routes behind the admin authentication, the handlers
+// the contract does not reach are left nil
+func newAdminContractApp(t *testing.T) (*fiber.App, *infrastructure.MemoryWalletRepo) {
+	t.Helper()
+	log := logger.NewNoopLogger()
+	walletRepo := infrastructure.NewMemoryWalletRepo(clock.NewSystemClock())
+
+	server := platformHttp.NewServer(config.ServerConfig{}, config.AdminConfig{
+		Users: []config.AdminUser{{Name: "analyst", APIKey: contractAPIKey}},
+	}, log)
+	handler := NewWalletAdminHandler(log,
+		command.NewFreezeWalletCommandHandler(log, walletRepo),
+		command.NewUnfreezeWalletCommandHandler(log, walletRepo),
+		command.NewCloseWalletCommandHandler(log, walletRepo),
+		command.NewCancelDebitCommandHandler(log, walletRepo),
+		nil, nil, nil)
+	handler.RegisterRoutes(server.GetAdminRouter())
+	return server.GetApp(), walletRepo
+}
+
+// contractCase is a request and the status it must get. The request of a case with invalid set
+// breaks the contract on purpose and only its response is checked.
+type contractCase struct {
+	name    string
+	method  string
+	path    string
+	body    string
+	status  int
+	invalid bool
+}
+
+func runContract(t *testing.T, spec *openapi.Spec, app *fiber.App, tc contractCase) []byte {
+	t.Helper()
+	req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+	req.Header.Set(platformHttp.APIKeyHeader, contractAPIKey)
+	if tc.body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	if !tc.invalid {
+		if err := spec.ValidateRequest(req, []byte(tc.body)); err != nil {
+			t.Fatalf("%s: request does not match the contract: %v", tc.name, err)
+		}
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s: request failed: %v", tc.name, err)
+	}
+	if err := spec.ValidateResponse(req, resp); err != nil {
+		t.Fatalf("%s: response does not match the contract: %v", tc.name, err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != tc.status {
+		t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, resp.StatusCode, body)
+	}
+	return body
+}
+
+func TestWalletHandler_contract(t *testing.T) {
+	spec, err := openapi.Load(specFile)
+	if err != nil {
+		t.Fatalf("could not load openapi document: %v", err)
+	}
+	app := newContractApp(t)
+
+	key := func() string {
+		k, _ := uuid.NewV7()
+		return k.String()
+	}
+	release := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	used, withdrawn := key(), key()
+	now := time.Now().UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	cases := []contractCase{
+		{name: "balance of a new wallet", method: http.MethodGet, path: "/api/v1/wallet/1", status: http.StatusOK},
+		{name: "balance with invalid user", method: http.MethodGet, path: "/api/v1/wallet/abc", status: http.StatusBadRequest, invalid: true},
+		{name: "charge", method: http.MethodPost, path: "/api/v1/wallet/1/charge",
+			body: fmt.Sprintf(`{"amount": 10000, "idempotency": %q}`, used), status: http.StatusOK},
+		{name: "charge with release time", method: http.MethodPost, path: "/api/v1/wallet/1/charge",
+			body: fmt.Sprintf(`{"amount": 500, "idempotency": %q, "release_time": %q}`, key(), release), status: http.StatusOK},
+		{name: "charge with used idempotency", method: http.MethodPost, path: "/api/v1/wallet/1/charge",
+			body: fmt.Sprintf(`{"amount": 10000, "idempotency": %q}`, used), status: http.StatusConflict},
+		{name: "charge released in the past", method: http.MethodPost, path: "/api/v1/wallet/1/charge",
+			body: fmt.Sprintf(`{"amount": 100, "idempotency": %q, "release_time": %q}`, key(), past), status: http.StatusBadRequest},
+		{name: "charge with invalid idempotency", method: http.MethodPost, path: "/api/v1/wallet/1/charge",
+			body: `{"amount": 100, "idempotency": "abc"}`, status: http.StatusBadRequest, invalid: true},
+		{name: "charge with invalid json", method: http.MethodPost, path: "/api/v1/wallet/1/charge",
+			body: `{"amount":`, status: http.StatusBadRequest, invalid: true},
+		{name: "balance", method: http.MethodGet, path: "/api/v1/wallet/1", status: http.StatusOK},
+		{name: "balance at", method: http.MethodGet, path: "/api/v1/wallet/1/balance?at=" + now, status: http.StatusOK},
+		{name: "balance at without time", method: http.MethodGet, path: "/api/v1/wallet/1/balance", status: http.StatusBadRequest, invalid: true},
+		{name: "balance at with invalid time", method: http.MethodGet, path: "/api/v1/wallet/1/balance?at=yesterday", status: http.StatusBadRequest, invalid: true},
+		{name: "balance at in the future", method: http.MethodGet, path: "/api/v1/wallet/1/balance?at=" + release, status: http.StatusBadRequest},
+		{name: "balance at of a missing wallet", method: http.MethodGet, path: "/api/v1/wallet/3/balance?at=" + now, status: http.StatusOK},
+		{name: "fee quote", method: http.MethodGet, path: "/api/v1/wallet/1/withdraw/fee?amount=1000", status: http.StatusOK},
+		{name: "fee quote of zero", method: http.MethodGet, path: "/api/v1/wallet/1/withdraw/fee?amount=0", status: http.StatusBadRequest, invalid: true},
+		{name: "withdraw", method: http.MethodPost, path: "/api/v1/wallet/1/withdraw",
+			body: fmt.Sprintf(`{"amount": 1000, "idempotency": %q, "release_time": %q}`, withdrawn, release), status: http.StatusOK},
+		{name: "withdraw held for review", method: http.MethodPost, path: "/api/v1/wallet/1/withdraw",
+			body: fmt.Sprintf(`{"amount": 5000, "idempotency": %q, "release_time": %q, "destination_id": 1}`, key(), release), status: http.StatusOK},
+		{name: "withdraw denied", method: http.MethodPost, path: "/api/v1/wallet/1/withdraw",
+			body: fmt.Sprintf(`{"amount": 9000, "idempotency": %q, "release_time": %q}`, key(), release), status: http.StatusUnprocessableEntity},
+		{name: "withdraw over balance", method: http.MethodPost, path: "/api/v1/wallet/1/withdraw",
+			body: fmt.Sprintf(`{"amount": 4500, "idempotency": %q, "release_time": %q}`, key(), release), status: http.StatusUnprocessableEntity},
+		{name: "withdraw to unknown destination", method: http.MethodPost, path: "/api/v1/wallet/1/withdraw",
+			body: fmt.Sprintf(`{"amount": 100, "idempotency": %q, "release_time": %q, "destination_id": 2}`, key(), release), status: http.StatusNotFound},
+		{name: "withdraw released in the past", method: http.MethodPost, path: "/api/v1/wallet/1/withdraw",
+			body: fmt.Sprintf(`{"amount": 100, "idempotency": %q, "release_time": %q}`, key(), past), status: http.StatusBadRequest},
+		{name: "withdraw without release time", method: http.MethodPost, path: "/api/v1/wallet/1/withdraw",
+			body: fmt.Sprintf(`{"amount": 100, "idempotency": %q}`, key()), status: http.StatusBadRequest},
+		{name: "withdraw with used idempotency", method: http.MethodPost, path: "/api/v1/wallet/1/withdraw",
+			body: fmt.Sprintf(`{"amount": 100, "idempotency": %q, "release_time": %q}`, withdrawn, release), status: http.StatusConflict},
+		{name: "transactions", method: http.MethodGet, path: "/api/v1/wallet/1/transactions", status: http.StatusOK},
+		{name: "transactions with cursor", method: http.MethodGet, path: "/api/v1/wallet/1/transactions?cursor=" + used, status: http.StatusOK},
+		{name: "transactions with invalid limit", method: http.MethodGet, path: "/api/v1/wallet/1/transactions?limit=abc", status: http.StatusBadRequest, invalid: true},
+		{name: "transactions with invalid cursor", method: http.MethodGet, path: "/api/v1/wallet/1/transactions?cursor=abc", status: http.StatusBadRequest, invalid: true},
+	}
+	bodies := make(map[string][]byte)
+	for _, tc := range cases {
+		bodies[tc.name] = runContract(t, spec, app, tc)
+	}
+
+	var withdrawal struct {
+		Result string `json:"result"`
+	}
+	if err := json.Unmarshal(bodies["withdraw"], &withdrawal); err != nil {
+		t.Fatalf("could not read withdrawal: %v", err)
+	}
+	cancel := "/api/v1/wallet/1/transactions/" + withdrawal.Result + "/cancel"
+	cases = []contractCase{
+		{name: "cancel withdrawal", method: http.MethodPost, path: cancel, status: http.StatusOK},
+		{name: "cancel cancelled withdrawal", method: http.MethodPost, path: cancel, status: http.StatusConflict},
+		{name: "cancel withdrawal of another user", method: http.MethodPost, path: "/api/v1/wallet/2/transactions/" + withdrawal.Result + "/cancel", status: http.StatusNotFound},
+		{name: "cancel with invalid id", method: http.MethodPost, path: "/api/v1/wallet/1/transactions/abc/cancel", status: http.StatusBadRequest, invalid: true},
+	}
+	for _, tc := range cases {
+		runContract(t, spec, app, tc)
+	}
+
+	// the page size comes from the limit query parameter
+	body := runContract(t, spec, app, contractCase{name: "transactions page", method: http.MethodGet,
+		path: "/api/v1/wallet/1/transactions?limit=2", status: http.StatusOK})
+	if !bytes.Contains(body, []byte(`"cursor"`)) {
+		t.Fatalf("expected a full page of 2 to have a cursor: %s", body)
+	}
+}
+
+func TestWalletAdminHandler_contract(t *testing.T) {
+	spec, err := openapi.Load(specFile)
+	if err != nil {
+		t.Fatalf("could not load openapi document: %v", err)
+	}
+	app, walletRepo := newAdminContractApp(t)
+	key, _ := uuid.NewV7()
+	if _, err := walletRepo.Charge(context.Background(), 1, &key, 1000, nil); err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+	payout, _ := uuid.NewV7()
+
+	cases := []contractCase{
+		{name: "freeze a missing wallet", method: http.MethodPost, path: "/api/v1/admin/wallet/2/freeze",
+			body: `{"status": "frozen_all", "reason": "fraud"}`, status: http.StatusNotFound},
+		{name: "freeze with an unknown status", method: http.MethodPost, path: "/api/v1/admin/wallet/1/freeze",
+			body: `{"status": "closed", "reason": "fraud"}`, status: http.StatusBadRequest, invalid: true},
+		{name: "freeze without reason", method: http.MethodPost, path: "/api/v1/admin/wallet/1/freeze",
+			body: `{"status": "frozen_all", "reason": " "}`, status: http.StatusBadRequest},
+		{name: "freeze with invalid json", method: http.MethodPost, path: "/api/v1/admin/wallet/1/freeze",
+			body: `{"status":`, status: http.StatusBadRequest, invalid: true},
+		{name: "freeze", method: http.MethodPost, path: "/api/v1/admin/wallet/1/freeze",
+			body: `{"status": "frozen_debit", "reason": "dispute"}`, status: http.StatusOK},
+		{name: "unfreeze a missing wallet", method: http.MethodPost, path: "/api/v1/admin/wallet/2/unfreeze",
+			body: `{"reason": "resolved"}`, status: http.StatusNotFound},
+		{name: "unfreeze without reason", method: http.MethodPost, path: "/api/v1/admin/wallet/1/unfreeze",
+			body: `{"reason": ""}`, status: http.StatusBadRequest},
+		{name: "unfreeze", method: http.MethodPost, path: "/api/v1/admin/wallet/1/unfreeze",
+			body: `{"reason": "resolved"}`, status: http.StatusOK},
+		{name: "close a missing wallet", method: http.MethodPost, path: "/api/v1/admin/wallet/2/close",
+			body: `{"reason": "request"}`, status: http.StatusNotFound},
+		{name: "close without reason", method: http.MethodPost, path: "/api/v1/admin/wallet/1/close",
+			body: `{"reason": ""}`, status: http.StatusBadRequest},
+		{name: "close with invalid payout idempotency", method: http.MethodPost, path: "/api/v1/admin/wallet/1/close",
+			body: `{"reason": "request", "payout_idempotency": "abc"}`, status: http.StatusBadRequest, invalid: true},
+		{name: "close a wallet with balance", method: http.MethodPost, path: "/api/v1/admin/wallet/1/close",
+			body: `{"reason": "request"}`, status: http.StatusConflict},
+		{name: "close with a final payout", method: http.MethodPost, path: "/api/v1/admin/wallet/1/close",
+			body: fmt.Sprintf(`{"reason": "request", "payout_idempotency": %q}`, payout), status: http.StatusOK},
+		{name: "freeze a closed wallet", method: http.MethodPost, path: "/api/v1/admin/wallet/1/freeze",
+			body: `{"status": "frozen_all", "reason": "fraud"}`, status: http.StatusConflict},
+		{name: "unfreeze a closed wallet", method: http.MethodPost, path: "/api/v1/admin/wallet/1/unfreeze",
+			body: `{"reason": "resolved"}`, status: http.StatusConflict},
+		{name: "close a closed wallet", method: http.MethodPost, path: "/api/v1/admin/wallet/1/close",
+			body: `{"reason": "request"}`, status: http.StatusConflict},
+	}
+	for _, tc := range cases {
+		runContract(t, spec, app, tc)
+	}
+}
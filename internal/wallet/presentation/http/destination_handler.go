@@ -0,0 +1,124 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
+	platformHttp "github.com/MaisamV/wallet/platform/http"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"strconv"
+)
+
+type DestinationHandler struct {
+	logger            logger.Logger
+	addHandler        *command.AddDestinationCommandHandler
+	setDefaultHandler *command.SetDefaultDestinationCommandHandler
+	verifyHandler     *command.VerifyDestinationCommandHandler
+	destinationsList  *query.GetDestinationsQueryHandler
+}
+
+func NewDestinationHandler(logger logger.Logger, addHandler *command.AddDestinationCommandHandler,
+	setDefaultHandler *command.SetDefaultDestinationCommandHandler, verifyHandler *command.VerifyDestinationCommandHandler,
+	destinationsList *query.GetDestinationsQueryHandler) *DestinationHandler {
+	return &DestinationHandler{
+		logger:            logger,
+		addHandler:        addHandler,
+		setDefaultHandler: setDefaultHandler,
+		verifyHandler:     verifyHandler,
+		destinationsList:  destinationsList,
+	}
+}
+
+// RegisterRoutes registers the payout destination routes of the users
+func (h *DestinationHandler) RegisterRoutes(app *fiber.App) {
+	group := app.Group("/api/v1/wallet/:userid/destinations")
+	h.logger.Info().Msg("Registering destination routes")
+	group.Get("/", h.GetDestinations)
+	group.Post("/", h.AddDestination)
+	group.Post("/:id/default", h.SetDefault)
+	h.logger.Info().Msg("destination routes registered successfully")
+}
+
+// RegisterAdminRoutes registers the destination verification route on the authenticated admin router
+func (h *DestinationHandler) RegisterAdminRoutes(admin fiber.Router) {
+	admin.Post("/destinations/:id/verify", h.Verify)
+}
+
+func (h *DestinationHandler) GetDestinations(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+
+	destinations, err := h.destinationsList.Handle(ctx, query.GetDestinationsQuery{UserID: userID})
+	if err != nil {
+		return respondError(h.logger, c, http.StatusInternalServerError, err, "Could not fetch destinations")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(destinations))
+}
+
+func (h *DestinationHandler) AddDestination(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	request := dto.Destination{}
+	if err := c.BodyParser(&request); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+
+	cmd := command.AddDestinationCommand{UserId: userID, Type: request.Type, Number: request.Number}
+	if err := cmd.Err(); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Invalid destination")
+	}
+	destination, err := h.addHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not add destination")
+	}
+
+	return c.Status(http.StatusCreated).JSON(dto.ToResponse(destination))
+}
+
+func (h *DestinationHandler) SetDefault(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse destination id")
+	}
+
+	cmd := command.SetDefaultDestinationCommand{UserId: userID, DestinationID: id}
+	if err := h.setDefaultHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not set default destination")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponseWithMessage(id, "default destination changed"))
+}
+
+func (h *DestinationHandler) Verify(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse destination id")
+	}
+
+	cmd := command.VerifyDestinationCommand{DestinationID: id, Actor: platformHttp.AdminUser(c)}
+	destination, err := h.verifyHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not verify destination")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(destination))
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+)
+
+// errorStatus maps domain errors to HTTP status codes, anything else is an internal error
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrWalletNotFound),
+		errors.Is(err, entity.ErrTransactionNotFound),
+		errors.Is(err, entity.ErrAdjustmentNotFound),
+		errors.Is(err, entity.ErrDestinationNotFound),
+		errors.Is(err, entity.ErrHoldNotFound),
+		errors.Is(err, entity.ErrScheduleNotFound),
+		errors.Is(err, entity.ErrBatchNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrWalletFrozen),
+		errors.Is(err, entity.ErrWalletClosed),
+		errors.Is(err, entity.ErrWalletQuarantined),
+		errors.Is(err, entity.ErrWalletNotEmpty),
+		errors.Is(err, entity.ErrPendingFunds),
+		errors.Is(err, entity.ErrInvalidTransition),
+		errors.Is(err, entity.ErrNotCancellable),
+		errors.Is(err, entity.ErrNotPending),
+		errors.Is(err, entity.ErrNotInReview),
+		errors.Is(err, entity.ErrAdjustmentDecided),
+		errors.Is(err, entity.ErrSelfApproval),
+		errors.Is(err, entity.ErrDestinationExists),
+		errors.Is(err, entity.ErrHoldNotActive),
+		errors.Is(err, entity.ErrScheduleStatus),
+		errors.Is(err, entity.ErrIdempotencyUsed):
+		return http.StatusConflict
+	case errors.Is(err, entity.ErrInsufficientFunds),
+		errors.Is(err, entity.ErrLimitExceeded),
+		errors.Is(err, entity.ErrRiskDenied),
+		errors.Is(err, entity.ErrDestinationNotVerified),
+		errors.Is(err, entity.ErrCaptureExceedsHold):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, entity.ErrInvalidBatch),
+		errors.Is(err, entity.ErrInvalidPromo),
+		errors.Is(err, entity.ErrFutureBalance),
+		errors.Is(err, entity.ErrInvalidReleaseTime),
+		errors.Is(err, entity.ErrInvalidStatusChange):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+const (
+	// LimitExceededCode is returned in the code field of responses rejected by a transaction limit
+	LimitExceededCode = "limit_exceeded"
+	// RiskDeniedCode is returned in the code field of withdrawals denied by risk screening
+	RiskDeniedCode = "risk_denied"
+)
+
+// errorCode gives clients a stable code for the errors they are expected to handle
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, entity.ErrLimitExceeded):
+		return LimitExceededCode
+	case errors.Is(err, entity.ErrRiskDenied):
+		return RiskDeniedCode
+	default:
+		return ""
+	}
+}
+
+func respondError(logger logger.Logger, c *fiber.Ctx, status int, err error, message string) error {
+	logger.Error().Err(err).Msg(message)
+	if code := errorCode(err); code != "" {
+		return c.Status(status).JSON(dto.ToErrorWithCode(err, message, code))
+	}
+	return c.Status(status).JSON(dto.ToErrorWithMessage(err, message))
+}
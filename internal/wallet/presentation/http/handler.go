@@ -3,12 +3,14 @@ package http
 import (
 	"github.com/MaisamV/wallet/internal/wallet/application/command"
 	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
 	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
 	"github.com/MaisamV/wallet/platform/logger"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofrs/uuid/v5"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type WalletHandler struct {
@@ -16,18 +18,26 @@ type WalletHandler struct {
 	debitHandler           *command.DebitCommandHandler
 	chargeHandler          *command.ChargeCommandHandler
 	balanceHandler         *query.GetBalanceQueryHandler
+	balanceAtHandler       *query.GetBalanceAtQueryHandler
 	transactionPageHandler *query.GetTransactionPageQueryHandler
+	quoteFeeHandler        *query.QuoteFeeQueryHandler
+	cancelDebitHandler     *command.CancelDebitCommandHandler
 }
 
 func NewWalletHandler(logger logger.Logger, debitHandler *command.DebitCommandHandler,
 	chargeHandler *command.ChargeCommandHandler, balanceHandler *query.GetBalanceQueryHandler,
-	transactionPageHandler *query.GetTransactionPageQueryHandler) *WalletHandler {
+	balanceAtHandler *query.GetBalanceAtQueryHandler,
+	transactionPageHandler *query.GetTransactionPageQueryHandler, quoteFeeHandler *query.QuoteFeeQueryHandler,
+	cancelDebitHandler *command.CancelDebitCommandHandler) *WalletHandler {
 	return &WalletHandler{
 		logger:                 logger,
 		debitHandler:           debitHandler,
 		chargeHandler:          chargeHandler,
 		balanceHandler:         balanceHandler,
+		balanceAtHandler:       balanceAtHandler,
 		transactionPageHandler: transactionPageHandler,
+		quoteFeeHandler:        quoteFeeHandler,
+		cancelDebitHandler:     cancelDebitHandler,
 	}
 }
 
@@ -36,8 +46,11 @@ func (h *WalletHandler) RegisterRoutes(app *fiber.App) {
 	group := app.Group("/api/v1/wallet")
 	h.logger.Info().Msg("Registering wallet routes")
 	group.Get("/:userid", h.GetBalance)
+	group.Get("/:userid/balance", h.GetBalanceAt)
 	group.Get("/:userid/transactions", h.GetTransactions)
+	group.Post("/:userid/transactions/:id/cancel", h.CancelDebit)
 	group.Post("/:userid/withdraw", h.Withdraw)
+	group.Get("/:userid/withdraw/fee", h.QuoteFee)
 	group.Post("/:userid/charge", h.Charge)
 	h.logger.Info().Msg("wallet routes registered successfully")
 }
@@ -59,6 +72,27 @@ func (h *WalletHandler) GetBalance(c *fiber.Ctx) error {
 	return c.Status(http.StatusOK).JSON(dto.ToResponse(balance))
 }
 
+func (h *WalletHandler) GetBalanceAt(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not parse at")
+	}
+
+	q := query.GetBalanceAtQuery{UserID: userID, At: at}
+	balance, err := h.balanceAtHandler.Handle(ctx, q)
+	if err != nil {
+		return h.respondError(c, errorStatus(err), err, "Could not fetch user's wallet balance")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(balance))
+}
+
 func (h *WalletHandler) GetTransactions(c *fiber.Ctx) error {
 	ctx := c.Context()
 
@@ -66,7 +100,7 @@ func (h *WalletHandler) GetTransactions(c *fiber.Ctx) error {
 	if err != nil {
 		return h.respondError(c, http.StatusBadRequest, err, "Could not parse userid")
 	}
-	limit, err := strconv.ParseInt(c.Params("limit", "10"), 10, 64)
+	limit, err := strconv.ParseInt(c.Query("limit", "10"), 10, 64)
 	if err != nil {
 		return h.respondError(c, http.StatusBadRequest, err, "Could not parse limit")
 	}
@@ -113,17 +147,68 @@ func (h *WalletHandler) Withdraw(c *fiber.Ctx) error {
 	}
 
 	cmd := command.DebitCommand{
-		UserId:      userID,
-		Amount:      withdraw.Amount,
-		Idempotency: &idempotency,
-		ReleaseTime: withdraw.ReleaseTime,
+		UserId:        userID,
+		Amount:        withdraw.Amount,
+		Idempotency:   &idempotency,
+		ReleaseTime:   withdraw.ReleaseTime,
+		DestinationID: withdraw.DestinationID,
 	}
-	transactionID, err := h.debitHandler.Handle(ctx, cmd)
+	result, err := h.debitHandler.Handle(ctx, cmd)
 	if err != nil {
-		return h.respondError(c, http.StatusInternalServerError, err, "Could not withdraw")
+		return h.respondError(c, errorStatus(err), err, "Could not withdraw")
+	}
+	if result.Status == entity.IN_REVIEW {
+		return c.Status(http.StatusOK).JSON(dto.ToResponseWithMessage(result.TransactionID.String(), "withdrawal is held for review"))
 	}
 
-	return c.Status(http.StatusOK).JSON(dto.ToResponse(transactionID.String()))
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(result.TransactionID.String()))
+}
+
+// CancelDebit lets users take back a pending withdrawal until its release time. Withdrawals in review are left to the admins.
+func (h *WalletHandler) CancelDebit(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	id, err := uuid.FromString(c.Params("id"))
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not parse transaction id")
+	}
+
+	cmd := command.CancelDebitCommand{
+		UserId:        userID,
+		TransactionID: &id,
+		Actor:         "user",
+	}
+	if err := h.cancelDebitHandler.Handle(ctx, cmd); err != nil {
+		return h.respondError(c, errorStatus(err), err, "Could not cancel debit")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(id.String()))
+}
+
+// QuoteFee tells the fee of a withdrawal before it is submitted
+func (h *WalletHandler) QuoteFee(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	amount, err := strconv.ParseInt(c.Query("amount"), 10, 64)
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not parse amount")
+	}
+
+	q := query.QuoteFeeQuery{UserID: userID, Amount: amount}
+	quote, err := h.quoteFeeHandler.Handle(ctx, q)
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err, "Could not quote withdrawal fee")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(quote))
 }
 
 func (h *WalletHandler) Charge(c *fiber.Ctx) error {
@@ -152,13 +237,12 @@ func (h *WalletHandler) Charge(c *fiber.Ctx) error {
 	}
 	transactionID, err := h.chargeHandler.Handle(ctx, cmd)
 	if err != nil {
-		return h.respondError(c, http.StatusInternalServerError, err, "Could not charge")
+		return h.respondError(c, errorStatus(err), err, "Could not charge")
 	}
 
 	return c.Status(http.StatusOK).JSON(dto.ToResponse(transactionID.String()))
 }
 
 func (h *WalletHandler) respondError(c *fiber.Ctx, status int, err error, message string) error {
-	h.logger.Error().Err(err).Msg(message)
-	return c.Status(status).JSON(dto.ToErrorWithMessage(err, message))
+	return respondError(h.logger, c, status, err, message)
 }
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid/v5"
+	"net/http"
+	"strconv"
+)
+
+type HoldHandler struct {
+	logger         logger.Logger
+	placeHandler   *command.PlaceHoldCommandHandler
+	captureHandler *command.CaptureHoldCommandHandler
+	voidHandler    *command.VoidHoldCommandHandler
+	holdHandler    *query.GetHoldQueryHandler
+	clock          clock.Clock
+}
+
+func NewHoldHandler(logger logger.Logger, placeHandler *command.PlaceHoldCommandHandler,
+	captureHandler *command.CaptureHoldCommandHandler, voidHandler *command.VoidHoldCommandHandler,
+	holdHandler *query.GetHoldQueryHandler, clock clock.Clock) *HoldHandler {
+	return &HoldHandler{
+		logger:         logger,
+		placeHandler:   placeHandler,
+		captureHandler: captureHandler,
+		voidHandler:    voidHandler,
+		holdHandler:    holdHandler,
+		clock:          clock,
+	}
+}
+
+// RegisterRoutes registers the hold routes
+func (h *HoldHandler) RegisterRoutes(app *fiber.App) {
+	group := app.Group("/api/v1/wallet/:userid/holds")
+	h.logger.Info().Msg("Registering hold routes")
+	group.Post("/", h.PlaceHold)
+	group.Get("/:id", h.GetHold)
+	group.Post("/:id/capture", h.CaptureHold)
+	group.Post("/:id/void", h.VoidHold)
+	h.logger.Info().Msg("hold routes registered successfully")
+}
+
+func (h *HoldHandler) PlaceHold(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	request := dto.Hold{}
+	if err := c.BodyParser(&request); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+	idempotency, err := uuid.FromString(request.Idempotency)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse idempotency")
+	}
+
+	cmd := command.PlaceHoldCommand{
+		UserId:      userID,
+		Amount:      request.Amount,
+		Idempotency: &idempotency,
+		Reference:   request.Reference,
+		ExpiresAt:   request.ExpiresAt,
+	}
+	if err := cmd.Err(h.clock.Now()); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Invalid hold")
+	}
+	hold, err := h.placeHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not place hold")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(hold))
+}
+
+func (h *HoldHandler) GetHold(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, id, err := parseHoldParams(c)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid or hold id")
+	}
+
+	hold, err := h.holdHandler.Handle(ctx, query.GetHoldQuery{UserID: userID, HoldID: id})
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not fetch hold")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(hold))
+}
+
+func (h *HoldHandler) CaptureHold(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, id, err := parseHoldParams(c)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid or hold id")
+	}
+	request := dto.Capture{}
+	if err := c.BodyParser(&request); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+
+	cmd := command.CaptureHoldCommand{UserId: userID, HoldID: id, Amount: request.Amount}
+	if err := cmd.Err(); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Invalid capture")
+	}
+	hold, err := h.captureHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not capture hold")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(hold))
+}
+
+func (h *HoldHandler) VoidHold(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, id, err := parseHoldParams(c)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid or hold id")
+	}
+
+	hold, err := h.voidHandler.Handle(ctx, command.VoidHoldCommand{UserId: userID, HoldID: id})
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not void hold")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(hold))
+}
+
+func parseHoldParams(c *fiber.Ctx) (int64, *uuid.UUID, error) {
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return 0, nil, err
+	}
+	id, err := uuid.FromString(c.Params("id"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return userID, &id, nil
+}
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
+	platformHttp "github.com/MaisamV/wallet/platform/http"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"strconv"
+)
+
+type LimitsHandler struct {
+	logger             logger.Logger
+	limitsHandler      *query.GetLimitsQueryHandler
+	setDefaultsHandler *command.SetDefaultLimitsCommandHandler
+	setUserHandler     *command.SetUserLimitsCommandHandler
+	deleteUserHandler  *command.DeleteUserLimitsCommandHandler
+}
+
+func NewLimitsHandler(logger logger.Logger, limitsHandler *query.GetLimitsQueryHandler,
+	setDefaultsHandler *command.SetDefaultLimitsCommandHandler, setUserHandler *command.SetUserLimitsCommandHandler,
+	deleteUserHandler *command.DeleteUserLimitsCommandHandler) *LimitsHandler {
+	return &LimitsHandler{
+		logger:             logger,
+		limitsHandler:      limitsHandler,
+		setDefaultsHandler: setDefaultsHandler,
+		setUserHandler:     setUserHandler,
+		deleteUserHandler:  deleteUserHandler,
+	}
+}
+
+// RegisterRoutes registers the limit management routes on the authenticated admin router
+func (h *LimitsHandler) RegisterRoutes(admin fiber.Router) {
+	h.logger.Info().Msg("Registering limits routes")
+	admin.Put("/limits/defaults", h.SetDefaults)
+	admin.Get("/wallet/:userid/limits", h.GetLimits)
+	admin.Put("/wallet/:userid/limits", h.SetUserLimits)
+	admin.Delete("/wallet/:userid/limits", h.DeleteUserLimits)
+	h.logger.Info().Msg("limits routes registered successfully")
+}
+
+func (h *LimitsHandler) GetLimits(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+
+	settings, err := h.limitsHandler.Handle(ctx, query.GetLimitsQuery{UserID: userID})
+	if err != nil {
+		return respondError(h.logger, c, http.StatusInternalServerError, err, "Could not fetch limits")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(settings))
+}
+
+func (h *LimitsHandler) SetDefaults(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	limits := dto.Limits{}
+	if err := c.BodyParser(&limits); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+
+	cmd := command.SetDefaultLimitsCommand{Limits: toLimits(limits), Actor: platformHttp.AdminUser(c)}
+	if err := cmd.Err(); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Invalid limits")
+	}
+	if err := h.setDefaultsHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, http.StatusInternalServerError, err, "Could not set default limits")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(cmd.Limits))
+}
+
+func (h *LimitsHandler) SetUserLimits(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	limits := dto.Limits{}
+	if err := c.BodyParser(&limits); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+
+	cmd := command.SetUserLimitsCommand{UserId: userID, Limits: toLimits(limits), Actor: platformHttp.AdminUser(c)}
+	if err := cmd.Err(); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Invalid limits")
+	}
+	if err := h.setUserHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, http.StatusInternalServerError, err, "Could not set user limits")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(cmd.Limits))
+}
+
+func (h *LimitsHandler) DeleteUserLimits(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+
+	cmd := command.DeleteUserLimitsCommand{UserId: userID, Actor: platformHttp.AdminUser(c)}
+	if err := h.deleteUserHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, http.StatusInternalServerError, err, "Could not delete user limits")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponseWithMessage("deleted", "default limits apply to the user"))
+}
+
+func toLimits(l dto.Limits) entity.Limits {
+	return entity.Limits{
+		MaxSingleWithdrawal:    l.MaxSingleWithdrawal,
+		DailyWithdrawalTotal:   l.DailyWithdrawalTotal,
+		MonthlyWithdrawalTotal: l.MonthlyWithdrawalTotal,
+		MaxBalance:             l.MaxBalance,
+		HourlyWithdrawalCount:  l.HourlyWithdrawalCount,
+	}
+}
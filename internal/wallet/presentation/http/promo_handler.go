@@ -0,0 +1,91 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
+	"github.com/MaisamV/wallet/platform/clock"
+	platformHttp "github.com/MaisamV/wallet/platform/http"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid/v5"
+	"net/http"
+	"strconv"
+)
+
+type PromoHandler struct {
+	logger       logger.Logger
+	grantHandler *command.GrantPromoCommandHandler
+	grantsList   *query.GetPromoGrantsQueryHandler
+	clock        clock.Clock
+}
+
+func NewPromoHandler(logger logger.Logger, grantHandler *command.GrantPromoCommandHandler,
+	grantsList *query.GetPromoGrantsQueryHandler, clock clock.Clock) *PromoHandler {
+	return &PromoHandler{
+		logger:       logger,
+		grantHandler: grantHandler,
+		grantsList:   grantsList,
+		clock:        clock,
+	}
+}
+
+// RegisterRoutes registers the promotional credit routes on the authenticated admin router
+func (h *PromoHandler) RegisterRoutes(admin fiber.Router) {
+	group := admin.Group("/wallet/:userid/promos")
+	h.logger.Info().Msg("Registering promo routes")
+	group.Get("/", h.GetPromoGrants)
+	group.Post("/", h.GrantPromo)
+	h.logger.Info().Msg("promo routes registered successfully")
+}
+
+func (h *PromoHandler) GetPromoGrants(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+
+	grants, err := h.grantsList.Handle(ctx, query.GetPromoGrantsQuery{UserID: userID})
+	if err != nil {
+		return respondError(h.logger, c, http.StatusInternalServerError, err, "Could not fetch promo grants")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(grants))
+}
+
+func (h *PromoHandler) GrantPromo(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	request := dto.PromoGrant{}
+	if err := c.BodyParser(&request); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+	idempotency, err := uuid.FromString(request.Idempotency)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse idempotency")
+	}
+
+	cmd := command.GrantPromoCommand{
+		UserId:      userID,
+		Amount:      request.Amount,
+		Reason:      request.Reason,
+		ExpiresAt:   request.ExpiresAt,
+		Idempotency: &idempotency,
+		Actor:       platformHttp.AdminUser(c),
+	}
+	if err := cmd.Err(h.clock.Now()); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Invalid promo grant")
+	}
+	grant, err := h.grantHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not grant promo")
+	}
+
+	return c.Status(http.StatusCreated).JSON(dto.ToResponse(grant))
+}
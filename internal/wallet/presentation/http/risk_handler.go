@@ -0,0 +1,88 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
+	platformHttp "github.com/MaisamV/wallet/platform/http"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid/v5"
+	"net/http"
+	"strconv"
+)
+
+type RiskReviewHandler struct {
+	logger         logger.Logger
+	reviewsList    *query.GetRiskReviewsQueryHandler
+	approveHandler *command.ApproveRiskReviewCommandHandler
+	rejectHandler  *command.RejectRiskReviewCommandHandler
+}
+
+func NewRiskReviewHandler(logger logger.Logger, reviewsList *query.GetRiskReviewsQueryHandler,
+	approveHandler *command.ApproveRiskReviewCommandHandler, rejectHandler *command.RejectRiskReviewCommandHandler) *RiskReviewHandler {
+	return &RiskReviewHandler{
+		logger:         logger,
+		reviewsList:    reviewsList,
+		approveHandler: approveHandler,
+		rejectHandler:  rejectHandler,
+	}
+}
+
+// RegisterRoutes registers the risk review routes on the authenticated admin router
+func (h *RiskReviewHandler) RegisterRoutes(admin fiber.Router) {
+	group := admin.Group("/risk/reviews")
+	h.logger.Info().Msg("Registering risk review routes")
+	group.Get("/", h.GetReviews)
+	group.Post("/:id/approve", h.Approve)
+	group.Post("/:id/reject", h.Reject)
+	h.logger.Info().Msg("risk review routes registered successfully")
+}
+
+func (h *RiskReviewHandler) GetReviews(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "limit should be between 1 and 500")
+	}
+
+	reviews, err := h.reviewsList.Handle(ctx, query.GetRiskReviewsQuery{Limit: limit})
+	if err != nil {
+		return respondError(h.logger, c, http.StatusInternalServerError, err, "Could not fetch risk reviews")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(reviews))
+}
+
+func (h *RiskReviewHandler) Approve(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := uuid.FromString(c.Params("id"))
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse transaction id")
+	}
+
+	cmd := command.DecideRiskReviewCommand{TransactionID: &id, Actor: platformHttp.AdminUser(c)}
+	if err := h.approveHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not approve withdrawal")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponseWithMessage(id.String(), "withdrawal approved"))
+}
+
+func (h *RiskReviewHandler) Reject(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := uuid.FromString(c.Params("id"))
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse transaction id")
+	}
+
+	cmd := command.DecideRiskReviewCommand{TransactionID: &id, Actor: platformHttp.AdminUser(c)}
+	if err := h.rejectHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not reject withdrawal")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponseWithMessage(id.String(), "withdrawal rejected and amount returned to the wallet"))
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/internal/wallet/application/command"
+	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/presentation/dto"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"strconv"
+)
+
+type ScheduleHandler struct {
+	logger           logger.Logger
+	createHandler    *command.CreateScheduleCommandHandler
+	pauseHandler     *command.PauseScheduleCommandHandler
+	resumeHandler    *command.ResumeScheduleCommandHandler
+	deleteHandler    *command.DeleteScheduleCommandHandler
+	schedulesHandler *query.GetSchedulesQueryHandler
+	clock            clock.Clock
+}
+
+func NewScheduleHandler(logger logger.Logger, createHandler *command.CreateScheduleCommandHandler,
+	pauseHandler *command.PauseScheduleCommandHandler, resumeHandler *command.ResumeScheduleCommandHandler,
+	deleteHandler *command.DeleteScheduleCommandHandler, schedulesHandler *query.GetSchedulesQueryHandler,
+	clock clock.Clock) *ScheduleHandler {
+	return &ScheduleHandler{
+		logger:           logger,
+		createHandler:    createHandler,
+		pauseHandler:     pauseHandler,
+		resumeHandler:    resumeHandler,
+		deleteHandler:    deleteHandler,
+		schedulesHandler: schedulesHandler,
+		clock:            clock,
+	}
+}
+
+// RegisterRoutes registers the schedule routes
+func (h *ScheduleHandler) RegisterRoutes(app *fiber.App) {
+	group := app.Group("/api/v1/wallet/:userid/schedules")
+	h.logger.Info().Msg("Registering schedule routes")
+	group.Post("/", h.CreateSchedule)
+	group.Get("/", h.GetSchedules)
+	group.Post("/:id/pause", h.PauseSchedule)
+	group.Post("/:id/resume", h.ResumeSchedule)
+	group.Delete("/:id", h.DeleteSchedule)
+	h.logger.Info().Msg("schedule routes registered successfully")
+}
+
+func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+	request := dto.Schedule{}
+	if err := c.BodyParser(&request); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse the json")
+	}
+
+	now := h.clock.Now()
+	startAt := now
+	if request.StartAt != nil {
+		startAt = *request.StartAt
+	}
+	cmd := command.CreateScheduleCommand{
+		UserId:        userID,
+		Type:          request.Type,
+		Amount:        request.Amount,
+		Frequency:     request.Frequency,
+		Interval:      request.Interval,
+		DestinationID: request.DestinationID,
+		StartAt:       startAt,
+		EndAt:         request.EndAt,
+	}
+	if err := cmd.Err(now); err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Invalid schedule")
+	}
+	schedule, err := h.createHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not create schedule")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(schedule))
+}
+
+func (h *ScheduleHandler) GetSchedules(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid")
+	}
+
+	schedules, err := h.schedulesHandler.Handle(ctx, query.GetSchedulesQuery{UserID: userID})
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not fetch schedules")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(schedules))
+}
+
+func (h *ScheduleHandler) PauseSchedule(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	cmd, err := parseScheduleParams(c)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid or schedule id")
+	}
+
+	schedule, err := h.pauseHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not pause schedule")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(schedule))
+}
+
+func (h *ScheduleHandler) ResumeSchedule(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	cmd, err := parseScheduleParams(c)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid or schedule id")
+	}
+
+	schedule, err := h.resumeHandler.Handle(ctx, cmd)
+	if err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not resume schedule")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponse(schedule))
+}
+
+func (h *ScheduleHandler) DeleteSchedule(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	cmd, err := parseScheduleParams(c)
+	if err != nil {
+		return respondError(h.logger, c, http.StatusBadRequest, err, "Could not parse userid or schedule id")
+	}
+
+	if err := h.deleteHandler.Handle(ctx, cmd); err != nil {
+		return respondError(h.logger, c, errorStatus(err), err, "Could not delete schedule")
+	}
+
+	return c.Status(http.StatusOK).JSON(dto.ToResponseWithMessage("deleted", "schedule deleted"))
+}
+
+func parseScheduleParams(c *fiber.Ctx) (command.ChangeScheduleCommand, error) {
+	userID, err := strconv.ParseInt(c.Params("userid"), 10, 64)
+	if err != nil {
+		return command.ChangeScheduleCommand{}, err
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return command.ChangeScheduleCommand{}, err
+	}
+	return command.ChangeScheduleCommand{UserId: userID, ScheduleID: id}, nil
+}
@@ -1,53 +1,394 @@
 package user
 
 import (
+	"fmt"
 	"github.com/MaisamV/wallet/internal/wallet/application/command"
 	"github.com/MaisamV/wallet/internal/wallet/application/query"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
 	infrastructure "github.com/MaisamV/wallet/internal/wallet/infrastructure/repo"
 	service2 "github.com/MaisamV/wallet/internal/wallet/infrastructure/service"
+	"github.com/MaisamV/wallet/internal/wallet/ports/service"
 	"github.com/MaisamV/wallet/internal/wallet/presentation/http"
+	"github.com/MaisamV/wallet/platform/clock"
 	"github.com/MaisamV/wallet/platform/config"
 	"github.com/MaisamV/wallet/platform/logger"
 	"github.com/google/wire"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func ProvideWalletRepository(logger logger.Logger, db *pgxpool.Pool) *infrastructure.PgxWalletRepo {
-	return infrastructure.NewPgxWalletRepo(logger, db)
+func ProvideWalletRepository(logger logger.Logger, db *pgxpool.Pool, clock clock.Clock) *infrastructure.PgxWalletRepo {
+	return infrastructure.NewPgxWalletRepo(logger, db, clock)
 }
 
-func ProvideShaparakMockService(logger logger.Logger) *service2.ShaparakMockService {
-	return service2.NewShaparakMockService(logger)
+// ProvideBankService provides the bank selected by bank.provider
+func ProvideBankService(logger logger.Logger, cfg *config.Config, clock clock.Clock) (service.BankService, error) {
+	switch cfg.Bank.Provider {
+	case "shaparak_mock":
+		return service2.NewShaparakMockService(logger), nil
+	case "fault":
+		return provideFaultBankService(logger, cfg.Bank.Fault, clock)
+	default:
+		return nil, fmt.Errorf("unknown bank provider %q", cfg.Bank.Provider)
+	}
 }
 
-func ProvideChargeCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.ChargeCommandHandler {
-	return command.NewChargeCommandHandler(logger, repo)
+func provideFaultBankService(logger logger.Logger, fault config.FaultBankConfig, clock clock.Clock) (service.BankService, error) {
+	options := service2.FaultBankOptions{
+		Seed:    fault.Seed,
+		Timeout: fault.Timeout,
+		Latency: service2.Latency{
+			Distribution: fault.Latency.Distribution,
+			Min:          fault.Latency.Min,
+			Max:          fault.Latency.Max,
+			Mean:         fault.Latency.Mean,
+			StdDev:       fault.Latency.StdDev,
+		},
+		ErrorRates: service2.BankErrorRates{
+			Timeout:               fault.ErrorRates.Timeout,
+			Declined:              fault.ErrorRates.Declined,
+			InsufficientLiquidity: fault.ErrorRates.InsufficientLiquidity,
+			Duplicate:             fault.ErrorRates.Duplicate,
+		},
+		Record: fault.Record,
+	}
+	for _, s := range fault.Scripts {
+		options.Scripts = append(options.Scripts, service2.BankScript{
+			UserID:    s.UserID,
+			MinAmount: s.MinAmount,
+			MaxAmount: s.MaxAmount,
+			Outcome:   s.Outcome,
+		})
+	}
+	for _, o := range fault.Outages {
+		options.Outages = append(options.Outages, service2.BankOutage{After: o.After, For: o.For, Rate: o.Rate, Outcome: o.Outcome})
+	}
+	bank, err := service2.NewFaultBankService(logger, clock, options)
+	if err != nil {
+		// a nil *FaultBankService would not be a nil BankService
+		return nil, err
+	}
+	return bank, nil
 }
 
-func ProvideDebitCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.DebitCommandHandler {
-	return command.NewDebitCommandHandler(logger, repo)
+func ProvideChargeCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, clock clock.Clock) *command.ChargeCommandHandler {
+	return command.NewChargeCommandHandler(logger, repo, clock)
+}
+
+func ProvideRiskRules(cfg *config.Config) (entity.RiskRules, error) {
+	rules := make(entity.RiskRules, 0, len(cfg.Risk.Rules))
+	for _, r := range cfg.Risk.Rules {
+		rules = append(rules, entity.RiskRule{
+			Name:       r.Name,
+			Type:       r.Type,
+			Outcome:    r.Outcome,
+			Amount:     r.Amount,
+			Window:     r.Window,
+			Multiplier: r.Multiplier,
+			MinHistory: r.MinHistory,
+		})
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid risk rule: %w", err)
+	}
+	return rules, nil
+}
+
+func ProvideScheduleFeeCalculator(cfg *config.Config) (*service2.ScheduleFeeCalculator, error) {
+	schedules := make([]entity.FeeSchedule, 0, len(cfg.Fees.Schedules))
+	for _, s := range cfg.Fees.Schedules {
+		tiers := make([]entity.FeeTier, 0, len(s.Tiers))
+		for _, t := range s.Tiers {
+			tiers = append(tiers, entity.FeeTier{UpTo: t.UpTo, Flat: t.Flat, RateBps: t.RateBps})
+		}
+		schedules = append(schedules, entity.FeeSchedule{
+			Currency: s.Currency,
+			Flat:     s.Flat,
+			RateBps:  s.RateBps,
+			Tiers:    tiers,
+			Min:      s.Min,
+			Max:      s.Max,
+		})
+	}
+	return service2.NewScheduleFeeCalculator(cfg.Fees.Currency, schedules)
+}
+
+func ProvideDebitCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, rules entity.RiskRules,
+	fees *service2.ScheduleFeeCalculator, clock clock.Clock) *command.DebitCommandHandler {
+	// without rules the debit skips reading the risk profile
+	var risk service.RiskEvaluator
+	if len(rules) > 0 {
+		risk = rules
+	}
+	return command.NewDebitCommandHandler(logger, repo, repo, risk, fees, clock)
 }
 
 func ProvideReleaseCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.ReleaseCommandHandler {
 	return command.NewReleaseCommandHandler(logger, repo)
 }
 
-func ProvideWithdrawCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, service *service2.ShaparakMockService, cfg *config.Config) *command.WithdrawCommandHandler {
-	return command.NewWithdrawCommandHandler(logger, repo, service, cfg.Withdraw.WorkerCount)
+func ProvideWithdrawCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, bank service.BankService, cfg *config.Config) *command.WithdrawCommandHandler {
+	return command.NewWithdrawCommandHandler(logger, repo, bank, cfg.Withdraw.WorkerCount)
+}
+
+func ProvideFreezeWalletCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.FreezeWalletCommandHandler {
+	return command.NewFreezeWalletCommandHandler(logger, repo)
+}
+
+func ProvideUnfreezeWalletCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.UnfreezeWalletCommandHandler {
+	return command.NewUnfreezeWalletCommandHandler(logger, repo)
+}
+
+func ProvideCloseWalletCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.CloseWalletCommandHandler {
+	return command.NewCloseWalletCommandHandler(logger, repo)
+}
+
+func ProvideCancelDebitCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.CancelDebitCommandHandler {
+	return command.NewCancelDebitCommandHandler(logger, repo)
+}
+
+func ProvideReinquireCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.ReinquireCommandHandler {
+	return command.NewReinquireCommandHandler(logger, repo)
+}
+
+func ProvideRequestAdjustmentCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.RequestAdjustmentCommandHandler {
+	return command.NewRequestAdjustmentCommandHandler(logger, repo)
+}
+
+func ProvideApproveAdjustmentCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.ApproveAdjustmentCommandHandler {
+	return command.NewApproveAdjustmentCommandHandler(logger, repo)
+}
+
+func ProvideRejectAdjustmentCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.RejectAdjustmentCommandHandler {
+	return command.NewRejectAdjustmentCommandHandler(logger, repo)
+}
+
+func ProvideSetDefaultLimitsCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.SetDefaultLimitsCommandHandler {
+	return command.NewSetDefaultLimitsCommandHandler(logger, repo)
+}
+
+func ProvideSetUserLimitsCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.SetUserLimitsCommandHandler {
+	return command.NewSetUserLimitsCommandHandler(logger, repo)
+}
+
+func ProvideDeleteUserLimitsCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.DeleteUserLimitsCommandHandler {
+	return command.NewDeleteUserLimitsCommandHandler(logger, repo)
+}
+
+func ProvideApproveRiskReviewCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.ApproveRiskReviewCommandHandler {
+	return command.NewApproveRiskReviewCommandHandler(logger, repo)
+}
+
+func ProvideRejectRiskReviewCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.RejectRiskReviewCommandHandler {
+	return command.NewRejectRiskReviewCommandHandler(logger, repo)
+}
+
+func ProvideAddDestinationCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.AddDestinationCommandHandler {
+	return command.NewAddDestinationCommandHandler(logger, repo)
+}
+
+func ProvideSetDefaultDestinationCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.SetDefaultDestinationCommandHandler {
+	return command.NewSetDefaultDestinationCommandHandler(logger, repo)
+}
+
+func ProvideVerifyDestinationCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.VerifyDestinationCommandHandler {
+	return command.NewVerifyDestinationCommandHandler(logger, repo)
 }
 
 func ProvideGetBalanceQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetBalanceQueryHandler {
 	return query.NewGetBalanceQueryHandler(logger, repo)
 }
 
+func ProvideSnapshotCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, clock clock.Clock) *command.SnapshotCommandHandler {
+	return command.NewSnapshotCommandHandler(logger, repo, clock)
+}
+
+func ProvideGetBalanceAtQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, clock clock.Clock) *query.GetBalanceAtQueryHandler {
+	return query.NewGetBalanceAtQueryHandler(logger, repo, clock)
+}
+
 func ProvideGetTransactionPageQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetTransactionPageQueryHandler {
 	return query.NewGetTransactionPageQueryHandler(logger, repo)
 }
 
+func ProvideSearchWalletsQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.SearchWalletsQueryHandler {
+	return query.NewSearchWalletsQueryHandler(logger, repo)
+}
+
+func ProvideGetTransactionQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetTransactionQueryHandler {
+	return query.NewGetTransactionQueryHandler(logger, repo)
+}
+
+func ProvideGetAdjustmentsQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetAdjustmentsQueryHandler {
+	return query.NewGetAdjustmentsQueryHandler(logger, repo)
+}
+
+func ProvideGetLimitsQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetLimitsQueryHandler {
+	return query.NewGetLimitsQueryHandler(logger, repo)
+}
+
+func ProvideGetRiskReviewsQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetRiskReviewsQueryHandler {
+	return query.NewGetRiskReviewsQueryHandler(logger, repo)
+}
+
+func ProvideGetDestinationsQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetDestinationsQueryHandler {
+	return query.NewGetDestinationsQueryHandler(logger, repo)
+}
+
+func ProvideQuoteFeeQueryHandler(logger logger.Logger, fees *service2.ScheduleFeeCalculator) *query.QuoteFeeQueryHandler {
+	return query.NewQuoteFeeQueryHandler(logger, fees)
+}
+
 func ProvideWalletHandler(logger logger.Logger, withdrawHandler *command.DebitCommandHandler,
 	chargeHandler *command.ChargeCommandHandler, balanceHandler *query.GetBalanceQueryHandler,
-	transactionPageHandler *query.GetTransactionPageQueryHandler) *http.WalletHandler {
-	return http.NewWalletHandler(logger, withdrawHandler, chargeHandler, balanceHandler, transactionPageHandler)
+	balanceAtHandler *query.GetBalanceAtQueryHandler,
+	transactionPageHandler *query.GetTransactionPageQueryHandler, quoteFeeHandler *query.QuoteFeeQueryHandler,
+	cancelDebitHandler *command.CancelDebitCommandHandler) *http.WalletHandler {
+	return http.NewWalletHandler(logger, withdrawHandler, chargeHandler, balanceHandler, balanceAtHandler, transactionPageHandler,
+		quoteFeeHandler, cancelDebitHandler)
+}
+
+func ProvideWalletAdminHandler(logger logger.Logger, freezeHandler *command.FreezeWalletCommandHandler,
+	unfreezeHandler *command.UnfreezeWalletCommandHandler, closeHandler *command.CloseWalletCommandHandler,
+	cancelDebitHandler *command.CancelDebitCommandHandler, reinquireHandler *command.ReinquireCommandHandler,
+	searchHandler *query.SearchWalletsQueryHandler, transactionHandler *query.GetTransactionQueryHandler) *http.WalletAdminHandler {
+	return http.NewWalletAdminHandler(logger, freezeHandler, unfreezeHandler, closeHandler, cancelDebitHandler, reinquireHandler,
+		searchHandler, transactionHandler)
+}
+
+func ProvideAdjustmentHandler(logger logger.Logger, requestHandler *command.RequestAdjustmentCommandHandler,
+	approveHandler *command.ApproveAdjustmentCommandHandler, rejectHandler *command.RejectAdjustmentCommandHandler,
+	adjustmentsHandler *query.GetAdjustmentsQueryHandler) *http.AdjustmentHandler {
+	return http.NewAdjustmentHandler(logger, requestHandler, approveHandler, rejectHandler, adjustmentsHandler)
+}
+
+func ProvideLimitsHandler(logger logger.Logger, limitsHandler *query.GetLimitsQueryHandler,
+	setDefaultsHandler *command.SetDefaultLimitsCommandHandler, setUserHandler *command.SetUserLimitsCommandHandler,
+	deleteUserHandler *command.DeleteUserLimitsCommandHandler) *http.LimitsHandler {
+	return http.NewLimitsHandler(logger, limitsHandler, setDefaultsHandler, setUserHandler, deleteUserHandler)
+}
+
+func ProvideRiskReviewHandler(logger logger.Logger, reviewsList *query.GetRiskReviewsQueryHandler,
+	approveHandler *command.ApproveRiskReviewCommandHandler, rejectHandler *command.RejectRiskReviewCommandHandler) *http.RiskReviewHandler {
+	return http.NewRiskReviewHandler(logger, reviewsList, approveHandler, rejectHandler)
+}
+
+func ProvideDestinationHandler(logger logger.Logger, addHandler *command.AddDestinationCommandHandler,
+	setDefaultHandler *command.SetDefaultDestinationCommandHandler, verifyHandler *command.VerifyDestinationCommandHandler,
+	destinationsList *query.GetDestinationsQueryHandler) *http.DestinationHandler {
+	return http.NewDestinationHandler(logger, addHandler, setDefaultHandler, verifyHandler, destinationsList)
+}
+
+func ProvidePlaceHoldCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, clock clock.Clock) *command.PlaceHoldCommandHandler {
+	return command.NewPlaceHoldCommandHandler(logger, repo, clock)
+}
+
+func ProvideCaptureHoldCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.CaptureHoldCommandHandler {
+	return command.NewCaptureHoldCommandHandler(logger, repo)
+}
+
+func ProvideVoidHoldCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.VoidHoldCommandHandler {
+	return command.NewVoidHoldCommandHandler(logger, repo)
+}
+
+func ProvideExpireHoldsCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.ExpireHoldsCommandHandler {
+	return command.NewExpireHoldsCommandHandler(logger, repo)
+}
+
+func ProvideGetHoldQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetHoldQueryHandler {
+	return query.NewGetHoldQueryHandler(logger, repo)
+}
+
+func ProvideHoldHandler(logger logger.Logger, placeHandler *command.PlaceHoldCommandHandler,
+	captureHandler *command.CaptureHoldCommandHandler, voidHandler *command.VoidHoldCommandHandler,
+	holdHandler *query.GetHoldQueryHandler, clock clock.Clock) *http.HoldHandler {
+	return http.NewHoldHandler(logger, placeHandler, captureHandler, voidHandler, holdHandler, clock)
+}
+
+func ProvideCreateScheduleCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, clock clock.Clock) *command.CreateScheduleCommandHandler {
+	return command.NewCreateScheduleCommandHandler(logger, repo, clock)
+}
+
+func ProvidePauseScheduleCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.PauseScheduleCommandHandler {
+	return command.NewPauseScheduleCommandHandler(logger, repo)
+}
+
+func ProvideResumeScheduleCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, clock clock.Clock) *command.ResumeScheduleCommandHandler {
+	return command.NewResumeScheduleCommandHandler(logger, repo, clock)
+}
+
+func ProvideDeleteScheduleCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.DeleteScheduleCommandHandler {
+	return command.NewDeleteScheduleCommandHandler(logger, repo)
+}
+
+func ProvideRunSchedulesCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, charge *command.ChargeCommandHandler,
+	debit *command.DebitCommandHandler, cfg *config.Config, clock clock.Clock) *command.RunSchedulesCommandHandler {
+	return command.NewRunSchedulesCommandHandler(logger, repo, charge, debit, cfg.Scheduler.MaxCatchUp,
+		cfg.Scheduler.PayoutDelay, cfg.Scheduler.ClaimFor, clock)
+}
+
+func ProvideGetSchedulesQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetSchedulesQueryHandler {
+	return query.NewGetSchedulesQueryHandler(logger, repo)
+}
+
+func ProvideScheduleHandler(logger logger.Logger, createHandler *command.CreateScheduleCommandHandler,
+	pauseHandler *command.PauseScheduleCommandHandler, resumeHandler *command.ResumeScheduleCommandHandler,
+	deleteHandler *command.DeleteScheduleCommandHandler, schedulesHandler *query.GetSchedulesQueryHandler,
+	clock clock.Clock) *http.ScheduleHandler {
+	return http.NewScheduleHandler(logger, createHandler, pauseHandler, resumeHandler, deleteHandler, schedulesHandler, clock)
+}
+
+func ProvideBatchFileReader(logger logger.Logger) *service2.BatchFileReader {
+	return service2.NewBatchFileReader(logger)
+}
+
+func ProvideCreateBatchCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, reader *service2.BatchFileReader,
+	cfg *config.Config) *command.CreateBatchCommandHandler {
+	return command.NewCreateBatchCommandHandler(logger, repo, reader, cfg.Batch.MaxRows)
+}
+
+func ProvideProcessBatchCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, rules entity.RiskRules,
+	fees *service2.ScheduleFeeCalculator, cfg *config.Config) *command.ProcessBatchCommandHandler {
+	// debit rows are screened like the debit API
+	var risk service.RiskEvaluator
+	if len(rules) > 0 {
+		risk = rules
+	}
+	return command.NewProcessBatchCommandHandler(logger, repo, risk, fees, cfg.Batch.ClaimFor)
+}
+
+func ProvideGetBatchesQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetBatchesQueryHandler {
+	return query.NewGetBatchesQueryHandler(logger, repo)
+}
+
+func ProvideGetBatchQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetBatchQueryHandler {
+	return query.NewGetBatchQueryHandler(logger, repo)
+}
+
+func ProvideGetBatchRowsQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetBatchRowsQueryHandler {
+	return query.NewGetBatchRowsQueryHandler(logger, repo)
+}
+
+func ProvideBatchHandler(logger logger.Logger, createHandler *command.CreateBatchCommandHandler,
+	batchesList *query.GetBatchesQueryHandler, batchHandler *query.GetBatchQueryHandler,
+	rowsHandler *query.GetBatchRowsQueryHandler) *http.BatchHandler {
+	return http.NewBatchHandler(logger, createHandler, batchesList, batchHandler, rowsHandler)
+}
+
+func ProvideGrantPromoCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo, clock clock.Clock) *command.GrantPromoCommandHandler {
+	return command.NewGrantPromoCommandHandler(logger, repo, clock)
+}
+
+func ProvideExpirePromoGrantsCommandHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *command.ExpirePromoGrantsCommandHandler {
+	return command.NewExpirePromoGrantsCommandHandler(logger, repo)
+}
+
+func ProvideGetPromoGrantsQueryHandler(logger logger.Logger, repo *infrastructure.PgxWalletRepo) *query.GetPromoGrantsQueryHandler {
+	return query.NewGetPromoGrantsQueryHandler(logger, repo)
+}
+
+func ProvidePromoHandler(logger logger.Logger, grantHandler *command.GrantPromoCommandHandler,
+	grantsList *query.GetPromoGrantsQueryHandler, clock clock.Clock) *http.PromoHandler {
+	return http.NewPromoHandler(logger, grantHandler, grantsList, clock)
 }
 
 // WalletSet is a wire provider set for all user dependencies
@@ -57,8 +398,64 @@ var WalletSet = wire.NewSet(
 	ProvideChargeCommandHandler,
 	ProvideReleaseCommandHandler,
 	ProvideWithdrawCommandHandler,
-	ProvideShaparakMockService,
+	ProvideSnapshotCommandHandler,
+	ProvideFreezeWalletCommandHandler,
+	ProvideUnfreezeWalletCommandHandler,
+	ProvideCloseWalletCommandHandler,
+	ProvideCancelDebitCommandHandler,
+	ProvideReinquireCommandHandler,
+	ProvideRequestAdjustmentCommandHandler,
+	ProvideApproveAdjustmentCommandHandler,
+	ProvideRejectAdjustmentCommandHandler,
+	ProvideSetDefaultLimitsCommandHandler,
+	ProvideSetUserLimitsCommandHandler,
+	ProvideDeleteUserLimitsCommandHandler,
+	ProvideApproveRiskReviewCommandHandler,
+	ProvideRejectRiskReviewCommandHandler,
+	ProvideAddDestinationCommandHandler,
+	ProvideSetDefaultDestinationCommandHandler,
+	ProvideVerifyDestinationCommandHandler,
+	ProvidePlaceHoldCommandHandler,
+	ProvideCaptureHoldCommandHandler,
+	ProvideVoidHoldCommandHandler,
+	ProvideExpireHoldsCommandHandler,
+	ProvideCreateScheduleCommandHandler,
+	ProvidePauseScheduleCommandHandler,
+	ProvideResumeScheduleCommandHandler,
+	ProvideDeleteScheduleCommandHandler,
+	ProvideRunSchedulesCommandHandler,
+	ProvideCreateBatchCommandHandler,
+	ProvideProcessBatchCommandHandler,
+	ProvideGrantPromoCommandHandler,
+	ProvideExpirePromoGrantsCommandHandler,
+	ProvideBankService,
+	ProvideRiskRules,
+	ProvideScheduleFeeCalculator,
+	ProvideBatchFileReader,
 	ProvideGetBalanceQueryHandler,
+	ProvideGetBalanceAtQueryHandler,
 	ProvideGetTransactionPageQueryHandler,
+	ProvideSearchWalletsQueryHandler,
+	ProvideGetTransactionQueryHandler,
+	ProvideGetAdjustmentsQueryHandler,
+	ProvideGetLimitsQueryHandler,
+	ProvideGetRiskReviewsQueryHandler,
+	ProvideQuoteFeeQueryHandler,
+	ProvideGetDestinationsQueryHandler,
+	ProvideGetHoldQueryHandler,
+	ProvideGetSchedulesQueryHandler,
+	ProvideGetBatchesQueryHandler,
+	ProvideGetBatchQueryHandler,
+	ProvideGetBatchRowsQueryHandler,
+	ProvideGetPromoGrantsQueryHandler,
 	ProvideWalletHandler,
+	ProvideWalletAdminHandler,
+	ProvideAdjustmentHandler,
+	ProvideLimitsHandler,
+	ProvideRiskReviewHandler,
+	ProvideDestinationHandler,
+	ProvideHoldHandler,
+	ProvideScheduleHandler,
+	ProvideBatchHandler,
+	ProvidePromoHandler,
 )
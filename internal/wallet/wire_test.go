@@ -0,0 +1,44 @@
+package user
+
+import (
+	service2 "github.com/MaisamV/wallet/internal/wallet/infrastructure/service"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/MaisamV/wallet/platform/logger"
+	"testing"
+	"time"
+)
+
+func TestProvideBankService(t *testing.T) {
+	log := logger.NewNoopLogger()
+	clk := clock.NewSystemClock()
+
+	cfg := &config.Config{Bank: config.BankConfig{Provider: "shaparak_mock"}}
+	bank, err := ProvideBankService(log, cfg, clk)
+	if err != nil {
+		t.Fatalf("provide bank service failed: %v", err)
+	}
+	if _, ok := bank.(*service2.ShaparakMockService); !ok {
+		t.Fatalf("expected the shaparak mock, got %T", bank)
+	}
+
+	cfg.Bank = config.BankConfig{Provider: "fault", Fault: config.FaultBankConfig{Seed: 1, Timeout: time.Second}}
+	bank, err = ProvideBankService(log, cfg, clk)
+	if err != nil {
+		t.Fatalf("provide bank service failed: %v", err)
+	}
+	if _, ok := bank.(*service2.FaultBankService); !ok {
+		t.Fatalf("expected the fault bank, got %T", bank)
+	}
+
+	// invalid fault options are rejected without a half built bank
+	cfg.Bank.Fault.Timeout = 0
+	if bank, err := ProvideBankService(log, cfg, clk); err == nil || bank != nil {
+		t.Fatalf("expected invalid fault options to fail, got %v and %v", bank, err)
+	}
+
+	cfg.Bank = config.BankConfig{Provider: "unknown"}
+	if bank, err := ProvideBankService(log, cfg, clk); err == nil || bank != nil {
+		t.Fatalf("expected an unknown provider to fail, got %v and %v", bank, err)
+	}
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+// Info describes who is behind a change. It is written to the audit log by the database triggers.
+type Info struct {
+	Actor     string
+	RequestID string
+	SourceIP  string
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying info
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// FromContext returns the audit information carried by ctx
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(contextKey{}).(Info)
+	return info
+}
+
+// UserValueSetter is implemented by contexts which keep their values in a map, like fasthttp.RequestCtx
+type UserValueSetter interface {
+	SetUserValue(key any, value any)
+}
+
+// Store attaches info to a request context so FromContext can read it back
+func Store(ctx UserValueSetter, info Info) {
+	ctx.SetUserValue(contextKey{}, info)
+}
+
+// TxBeginner is implemented by pgxpool.Pool and pgx.Conn
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// InTx runs fn in a database transaction tagged with the audit information of ctx and the given action,
+// so the audit triggers can attribute every row changed by fn
+func InTx(ctx context.Context, db TxBeginner, action string, fn func(tx pgx.Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := Tag(ctx, tx, action); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Tag sets the audit information of ctx on an already open transaction
+func Tag(ctx context.Context, tx pgx.Tx, action string) error {
+	info := FromContext(ctx)
+	_, err := tx.Exec(ctx, tagTransaction, info.Actor, action, info.RequestID, info.SourceIP)
+	if err != nil {
+		return fmt.Errorf("could not tag transaction for audit: %w", err)
+	}
+	return nil
+}
+
+const (
+	tagTransaction = `
+SELECT set_config('audit.actor', $1, true),
+       set_config('audit.action', $2, true),
+       set_config('audit.request_id', $3, true),
+       set_config('audit.source_ip', $4, true)
+`
+)
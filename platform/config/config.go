@@ -1,6 +1,12 @@
 package config
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -9,14 +15,26 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server       ServerConfig   `mapstructure:"server"`
-	Database     DatabaseConfig `mapstructure:"database"`
-	TestDatabase DatabaseConfig `mapstructure:"test_database"`
-	Release      WorkerConfig   `mapstructure:"release_worker"`
-	Withdraw     WorkerConfig   `mapstructure:"withdraw_worker"`
-	Logging      LoggingConfig  `mapstructure:"logging"`
-	Health       HealthConfig   `mapstructure:"health"`
-	Swagger      SwaggerConfig  `mapstructure:"swagger"`
+	Profile      string          `mapstructure:"profile"`
+	Server       ServerConfig    `mapstructure:"server"`
+	Database     DatabaseConfig  `mapstructure:"database"`
+	TestDatabase DatabaseConfig  `mapstructure:"test_database"`
+	Release      WorkerConfig    `mapstructure:"release_worker"`
+	Withdraw     WorkerConfig    `mapstructure:"withdraw_worker"`
+	Snapshot     WorkerConfig    `mapstructure:"snapshot_worker"`
+	HoldExpiry   WorkerConfig    `mapstructure:"hold_expiry_worker"`
+	PromoExpiry  WorkerConfig    `mapstructure:"promo_expiry_worker"`
+	Scheduler    SchedulerConfig `mapstructure:"scheduler_worker"`
+	Batch        BatchConfig     `mapstructure:"batch_worker"`
+	Integrity    IntegrityConfig `mapstructure:"integrity_worker"`
+	Reconcile    ReconcileConfig `mapstructure:"reconciliation"`
+	Admin        AdminConfig     `mapstructure:"admin"`
+	Risk         RiskConfig      `mapstructure:"risk"`
+	Fees         FeesConfig      `mapstructure:"fees"`
+	Bank         BankConfig      `mapstructure:"bank"`
+	Logging      LoggingConfig   `mapstructure:"logging"`
+	Health       HealthConfig    `mapstructure:"health"`
+	Swagger      SwaggerConfig   `mapstructure:"swagger"`
 }
 
 // ServerConfig holds server-related configuration
@@ -32,17 +50,20 @@ type ServerConfig struct {
 	MaxAge           int           `mapstructure:"max_age"`
 }
 
-// DatabaseConfig holds database-related configuration
+// DatabaseConfig holds database-related configuration, AutoMigrate applies pending migrations on startup.
+// PasswordFile replaces Password with the content of the file when set.
 type DatabaseConfig struct {
 	Host            string        `mapstructure:"host"`
 	Port            int           `mapstructure:"port"`
 	User            string        `mapstructure:"user"`
 	Password        string        `mapstructure:"password"`
+	PasswordFile    string        `mapstructure:"password_file"`
 	DBName          string        `mapstructure:"dbname"`
 	SSLMode         string        `mapstructure:"sslmode"`
 	MaxOpenConns    int           `mapstructure:"max_open_conns"`
 	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	AutoMigrate     bool          `mapstructure:"auto_migrate"`
 }
 
 type WorkerConfig struct {
@@ -51,6 +72,164 @@ type WorkerConfig struct {
 	Interval    time.Duration `mapstructure:"interval"`
 }
 
+// SchedulerConfig holds the scheduler worker configuration, MaxCatchUp limits the missed occurrences
+// of a schedule run at once and PayoutDelay is the release delay of scheduled payouts
+type SchedulerConfig struct {
+	BatchSize   int           `mapstructure:"batch_size"`
+	Interval    time.Duration `mapstructure:"interval"`
+	MaxCatchUp  int           `mapstructure:"max_catch_up"`
+	PayoutDelay time.Duration `mapstructure:"payout_delay"`
+	ClaimFor    time.Duration `mapstructure:"claim_for"`
+}
+
+// BatchConfig holds the bulk charge and debit configuration, MaxRows limits the rows of an uploaded file
+// and ChunkSize is the number of rows of a partial batch the worker reads at once
+type BatchConfig struct {
+	Interval  time.Duration `mapstructure:"interval"`
+	ChunkSize int           `mapstructure:"chunk_size"`
+	ClaimFor  time.Duration `mapstructure:"claim_for"`
+	MaxRows   int           `mapstructure:"max_rows"`
+}
+
+// IntegrityConfig holds balance integrity checker configuration
+type IntegrityConfig struct {
+	BatchSize      int           `mapstructure:"batch_size"`
+	Interval       time.Duration `mapstructure:"interval"`
+	AutoQuarantine bool          `mapstructure:"auto_quarantine"`
+}
+
+// ReconcileConfig holds the layout of bank settlement files
+type ReconcileConfig struct {
+	Delimiter       string            `mapstructure:"delimiter"`
+	Columns         SettlementColumns `mapstructure:"columns"`
+	SuccessStatuses []string          `mapstructure:"success_statuses"`
+}
+
+// SettlementColumns maps settlement file header names to the fields used for matching
+type SettlementColumns struct {
+	Idempotency   string `mapstructure:"idempotency"`
+	BankReference string `mapstructure:"bank_reference"`
+	Amount        string `mapstructure:"amount"`
+	Status        string `mapstructure:"status"`
+}
+
+// AdminConfig holds the operators allowed to use the admin API
+type AdminConfig struct {
+	Users []AdminUser `mapstructure:"users"`
+}
+
+// AdminUser is a support operator identified by an API key, APIKeyFile replaces APIKey with the content
+// of the file when set
+type AdminUser struct {
+	Name       string `mapstructure:"name"`
+	APIKey     string `mapstructure:"api_key"`
+	APIKeyFile string `mapstructure:"api_key_file"`
+}
+
+// AdminUserByKey returns the operator the API key belongs to, the keys are compared in constant time
+func AdminUserByKey(users []AdminUser, key string) (AdminUser, bool) {
+	if key == "" {
+		return AdminUser{}, false
+	}
+	for _, user := range users {
+		if user.APIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(user.APIKey)) == 1 {
+			return user, true
+		}
+	}
+	return AdminUser{}, false
+}
+
+// RiskConfig holds the rules withdrawals are screened with, no rules allows every withdrawal
+type RiskConfig struct {
+	Rules []RiskRule `mapstructure:"rules"`
+}
+
+// RiskRule is a single screening rule, only the fields used by its type are read
+type RiskRule struct {
+	Name       string        `mapstructure:"name"`
+	Type       string        `mapstructure:"type"`
+	Outcome    string        `mapstructure:"outcome"`
+	Amount     int64         `mapstructure:"amount"`
+	Window     time.Duration `mapstructure:"window"`
+	Multiplier float64       `mapstructure:"multiplier"`
+	MinHistory int64         `mapstructure:"min_history"`
+}
+
+// FeesConfig holds the withdrawal fee schedules, the schedule of the wallet currency is applied and
+// no schedule for it makes withdrawals free
+type FeesConfig struct {
+	Currency  string        `mapstructure:"currency"`
+	Schedules []FeeSchedule `mapstructure:"schedules"`
+}
+
+// FeeSchedule is the withdrawal fee of a currency, rates are in basis points
+type FeeSchedule struct {
+	Currency string    `mapstructure:"currency"`
+	Flat     int64     `mapstructure:"flat"`
+	RateBps  int64     `mapstructure:"rate_bps"`
+	Tiers    []FeeTier `mapstructure:"tiers"`
+	Min      int64     `mapstructure:"min"`
+	Max      int64     `mapstructure:"max"`
+}
+
+// FeeTier overrides the flat fee and rate of withdrawals up to UpTo, zero leaves the tier unbounded
+type FeeTier struct {
+	UpTo    int64 `mapstructure:"up_to"`
+	Flat    int64 `mapstructure:"flat"`
+	RateBps int64 `mapstructure:"rate_bps"`
+}
+
+// BankConfig selects the bank withdrawals are paid out through, provider is shaparak_mock or fault
+type BankConfig struct {
+	Provider string          `mapstructure:"provider"`
+	Fault    FaultBankConfig `mapstructure:"fault"`
+}
+
+// FaultBankConfig holds the outcomes of the fault injecting bank. Scripts are matched first, then the
+// outages, and the error rates decide the remaining calls. A zero seed picks a random one.
+type FaultBankConfig struct {
+	Seed       int64          `mapstructure:"seed"`
+	Timeout    time.Duration  `mapstructure:"timeout"`
+	Latency    BankLatency    `mapstructure:"latency"`
+	ErrorRates BankErrorRates `mapstructure:"error_rates"`
+	Scripts    []BankScript   `mapstructure:"scripts"`
+	Outages    []BankOutage   `mapstructure:"outages"`
+	Record     bool           `mapstructure:"record"`
+}
+
+// BankLatency is the latency distribution of bank calls, fixed, uniform or normal
+type BankLatency struct {
+	Distribution string        `mapstructure:"distribution"`
+	Min          time.Duration `mapstructure:"min"`
+	Max          time.Duration `mapstructure:"max"`
+	Mean         time.Duration `mapstructure:"mean"`
+	StdDev       time.Duration `mapstructure:"std_dev"`
+}
+
+// BankErrorRates are the chances of a bank call failing with each error class
+type BankErrorRates struct {
+	Timeout               float64 `mapstructure:"timeout"`
+	Declined              float64 `mapstructure:"declined"`
+	InsufficientLiquidity float64 `mapstructure:"insufficient_liquidity"`
+	Duplicate             float64 `mapstructure:"duplicate"`
+}
+
+// BankScript fixes the outcome of the calls of a user or an amount range, zero values match everything
+type BankScript struct {
+	UserID    int64  `mapstructure:"user_id"`
+	MinAmount int64  `mapstructure:"min_amount"`
+	MaxAmount int64  `mapstructure:"max_amount"`
+	Outcome   string `mapstructure:"outcome"`
+}
+
+// BankOutage fails rate of the calls with outcome from after the start of the process for the duration for
+type BankOutage struct {
+	After   time.Duration `mapstructure:"after"`
+	For     time.Duration `mapstructure:"for"`
+	Rate    float64       `mapstructure:"rate"`
+	Outcome string        `mapstructure:"outcome"`
+}
+
 // LoggingConfig holds logging-related configuration
 type LoggingConfig struct {
 	Level string `mapstructure:"level"`
@@ -68,7 +247,22 @@ type SwaggerConfig struct {
 	SwaggerFilePath string `mapstructure:"swagger_file_path"`
 }
 
-// Load loads configuration from environment variables and config files
+// file is the config file given on the command line, empty reads resources/config.yaml
+var file string
+
+// SetFile makes Load read the config file at path instead of resources/config.yaml
+func SetFile(path string) {
+	file = path
+}
+
+// Override sets key to value for the following loads, it takes precedence over the environment,
+// the config file and the defaults. Command line flags are applied through it.
+func Override(key string, value any) {
+	viper.Set(key, value)
+}
+
+// Load loads configuration from environment variables and config files. The file of the profile, when
+// one is set, is layered on the config file, secrets are read from their files and the result is validated.
 func Load() (*Config, error) {
 	setDefaults()
 
@@ -76,28 +270,106 @@ func Load() (*Config, error) {
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("./resources")
+	if file != "" {
+		viper.SetConfigFile(file)
+	} else {
+		viper.SetConfigName("config")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath("./resources")
+	}
+	return read()
+}
 
+// read reads the config file and the profile file and decodes them, it is how a reload starts over too
+func read() (*Config, error) {
 	// Reading config file is optional
 	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			panic(err)
-			return nil, err
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("could not read config file: %w", err)
+		}
+	}
+	if profile := viper.GetString("profile"); profile != "" && !slices.Contains(Profiles, profile) {
+		return nil, fmt.Errorf("invalid config:\n  profile: %q is not one of %s", profile, strings.Join(Profiles, ", "))
+	}
+	if path, ok := profileFile(); ok {
+		profile, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read profile %s: %w", viper.GetString("profile"), err)
+		}
+		defer profile.Close()
+		if err := viper.MergeConfig(profile); err != nil {
+			return nil, fmt.Errorf("could not read profile %s: %w", viper.GetString("profile"), err)
 		}
 	}
 
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	if err := viper.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := config.readSecrets(); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
 		return nil, err
 	}
-
 	return &config, nil
 }
 
+// profileFile is the file of the configured profile next to the config file, config.prod.yaml for
+// config.yaml. It tells false when no profile is set.
+func profileFile() (string, bool) {
+	profile := viper.GetString("profile")
+	if profile == "" {
+		return "", false
+	}
+	base := viper.ConfigFileUsed()
+	if base == "" {
+		base = filepath.Join("resources", "config.yaml")
+	}
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + profile + ext, true
+}
+
+// readSecrets replaces the settings which have their file set with the content of the file, the way
+// docker and kubernetes mount secrets
+func (c *Config) readSecrets() error {
+	if err := readSecret(&c.Database.Password, c.Database.PasswordFile, "database.password_file"); err != nil {
+		return err
+	}
+	if err := readSecret(&c.TestDatabase.Password, c.TestDatabase.PasswordFile, "test_database.password_file"); err != nil {
+		return err
+	}
+	for i := range c.Admin.Users {
+		user := &c.Admin.Users[i]
+		if err := readSecret(&user.APIKey, user.APIKeyFile, fmt.Sprintf("admin.users[%d].api_key_file", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSecret(value *string, path string, key string) error {
+	if path == "" {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%s: could not read secret: %w", key, err)
+	}
+	secret := strings.TrimSpace(string(content))
+	if secret == "" {
+		return fmt.Errorf("%s: secret file %s is empty", key, path)
+	}
+	*value = secret
+	return nil
+}
+
 // setDefaults sets default values for all configuration sections
 func setDefaults() {
+	// No profile reads the config file alone
+	viper.SetDefault("profile", "")
+
 	// Server defaults
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.read_timeout", "10s")
@@ -114,17 +386,20 @@ func setDefaults() {
 	viper.SetDefault("database.port", 5432)
 	viper.SetDefault("database.user", "postgres")
 	viper.SetDefault("database.password", "postgres")
+	viper.SetDefault("database.password_file", "")
 	viper.SetDefault("database.dbname", "wallet_db")
 	viper.SetDefault("database.sslmode", "disable")
 	viper.SetDefault("database.max_open_conns", 25)
 	viper.SetDefault("database.max_idle_conns", 5)
 	viper.SetDefault("database.conn_max_lifetime", "5m")
+	viper.SetDefault("database.auto_migrate", false)
 
 	// Test database defaults
 	viper.SetDefault("test_database.host", "localhost")
 	viper.SetDefault("test_database.port", 5433)
 	viper.SetDefault("test_database.user", "postgres")
 	viper.SetDefault("test_database.password", "postgres")
+	viper.SetDefault("test_database.password_file", "")
 	viper.SetDefault("test_database.dbname", "wallet_db")
 	viper.SetDefault("test_database.sslmode", "disable")
 	viper.SetDefault("test_database.max_open_conns", 25)
@@ -137,9 +412,57 @@ func setDefaults() {
 	viper.SetDefault("release_worker.interval", "1s")
 
 	// Withdraw worker defaults
-	viper.SetDefault("release_worker.worker_count", "1")
-	viper.SetDefault("release_worker.batch_size", "200")
-	viper.SetDefault("release_worker.interval", "1s")
+	viper.SetDefault("withdraw_worker.worker_count", "1")
+	viper.SetDefault("withdraw_worker.batch_size", "200")
+	viper.SetDefault("withdraw_worker.interval", "1s")
+
+	// Hold expiry worker defaults
+	viper.SetDefault("hold_expiry_worker.worker_count", "1")
+	viper.SetDefault("hold_expiry_worker.batch_size", "200")
+	viper.SetDefault("hold_expiry_worker.interval", "5s")
+
+	// Promo expiry worker defaults
+	viper.SetDefault("promo_expiry_worker.worker_count", "1")
+	viper.SetDefault("promo_expiry_worker.batch_size", "200")
+	viper.SetDefault("promo_expiry_worker.interval", "1m")
+
+	// Scheduler worker defaults
+	viper.SetDefault("scheduler_worker.batch_size", "100")
+	viper.SetDefault("scheduler_worker.interval", "10s")
+	viper.SetDefault("scheduler_worker.max_catch_up", "10")
+	viper.SetDefault("scheduler_worker.payout_delay", "1m")
+	viper.SetDefault("scheduler_worker.claim_for", "1m")
+
+	// Batch worker defaults
+	viper.SetDefault("batch_worker.interval", "5s")
+	viper.SetDefault("batch_worker.chunk_size", "200")
+	viper.SetDefault("batch_worker.claim_for", "5m")
+	viper.SetDefault("batch_worker.max_rows", "10000")
+
+	// Snapshot worker defaults
+	viper.SetDefault("snapshot_worker.worker_count", "1")
+	viper.SetDefault("snapshot_worker.batch_size", "500")
+	viper.SetDefault("snapshot_worker.interval", "1m")
+
+	// Integrity worker defaults
+	viper.SetDefault("integrity_worker.batch_size", "500")
+	viper.SetDefault("integrity_worker.interval", "1h")
+	viper.SetDefault("integrity_worker.auto_quarantine", false)
+
+	// Reconciliation defaults
+	viper.SetDefault("reconciliation.delimiter", ",")
+	viper.SetDefault("reconciliation.columns.idempotency", "idempotency_key")
+	viper.SetDefault("reconciliation.columns.bank_reference", "bank_reference")
+	viper.SetDefault("reconciliation.columns.amount", "amount")
+	viper.SetDefault("reconciliation.columns.status", "status")
+	viper.SetDefault("reconciliation.success_statuses", []string{"settled", "success"})
+
+	// Fee defaults
+	viper.SetDefault("fees.currency", "IRR")
+
+	// Bank defaults
+	viper.SetDefault("bank.provider", "shaparak_mock")
+	viper.SetDefault("bank.fault.timeout", "5s")
 
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
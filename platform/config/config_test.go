@@ -0,0 +1,198 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/spf13/viper"
+)
+
+// load writes files into a temporary directory and loads its config.yaml
+func load(t *testing.T, files map[string]string) (*Config, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		writeFile(t, filepath.Join(dir, name), content)
+	}
+	viper.Reset()
+	SetFile(filepath.Join(dir, "config.yaml"))
+	t.Cleanup(func() {
+		SetFile("")
+		viper.Reset()
+	})
+	return Load()
+}
+
+// writeFile replaces the file at once, so a watcher never reads it half written
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad_withdrawWorkerHasItsOwnDefaults(t *testing.T) {
+	cfg, err := load(t, map[string]string{"config.yaml": "release_worker:\n  batch_size: 50\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Withdraw.WorkerCount != 1 || cfg.Withdraw.BatchSize != 200 || cfg.Withdraw.Interval != time.Second {
+		t.Errorf("withdraw worker is %+v, want the defaults", cfg.Withdraw)
+	}
+	if cfg.Release.BatchSize != 50 {
+		t.Errorf("release batch size is %d, want 50", cfg.Release.BatchSize)
+	}
+}
+
+func TestLoad_malformedFileIsAnError(t *testing.T) {
+	_, err := load(t, map[string]string{"config.yaml": "server:\n  port: [\n"})
+	if err == nil || !strings.Contains(err.Error(), "could not read config file") {
+		t.Errorf("error is %v, want a read error", err)
+	}
+}
+
+func TestLoad_unknownKeyIsAnError(t *testing.T) {
+	_, err := load(t, map[string]string{"config.yaml": "relase_worker:\n  interval: 1s\n"})
+	if err == nil || !strings.Contains(err.Error(), "relase_worker") {
+		t.Errorf("error is %v, want the unknown key named", err)
+	}
+}
+
+func TestLoad_everyInvalidSettingIsReported(t *testing.T) {
+	_, err := load(t, map[string]string{"config.yaml": `
+server:
+  port: "http"
+database:
+  sslmode: "sometimes"
+withdraw_worker:
+  worker_count: 0
+  interval: "-1s"
+admin:
+  users:
+    - name: "a"
+      api_key: "same"
+    - name: "b"
+      api_key: "same"
+logging:
+  level: "loud"
+`})
+	if err == nil {
+		t.Fatal("invalid config loaded")
+	}
+	for _, key := range []string{"server.port", "database.sslmode", "withdraw_worker.worker_count",
+		"withdraw_worker.interval", "admin.users[1].api_key", "logging.level"} {
+		if !strings.Contains(err.Error(), key+":") {
+			t.Errorf("error does not report %s:\n%v", key, err)
+		}
+	}
+}
+
+func TestLoad_payoutDelayMustBePositive(t *testing.T) {
+	_, err := load(t, map[string]string{"config.yaml": "scheduler_worker:\n  payout_delay: 0s\n"})
+	if err == nil || !strings.Contains(err.Error(), "scheduler_worker.payout_delay:") {
+		t.Errorf("error is %v, want a payout delay of 0 refused", err)
+	}
+}
+
+func TestLoad_profileIsLayeredOnTheFile(t *testing.T) {
+	t.Setenv("WALLET_PROFILE", "staging")
+	cfg, err := load(t, map[string]string{
+		"config.yaml":         "logging:\n  level: debug\nrelease_worker:\n  batch_size: 50\n",
+		"config.staging.yaml": "release_worker:\n  batch_size: 500\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profile != "staging" || cfg.Release.BatchSize != 500 || cfg.Logging.Level != "debug" {
+		t.Errorf("config is profile %q, batch size %d and level %q, want staging, 500 and debug",
+			cfg.Profile, cfg.Release.BatchSize, cfg.Logging.Level)
+	}
+}
+
+func TestLoad_profileMustBeKnown(t *testing.T) {
+	t.Setenv("WALLET_PROFILE", "qa")
+	_, err := load(t, map[string]string{"config.yaml": ""})
+	if err == nil || !strings.Contains(err.Error(), "profile") {
+		t.Errorf("error is %v, want the profile refused", err)
+	}
+}
+
+func TestLoad_prodRefusesDevelopmentSettings(t *testing.T) {
+	t.Setenv("WALLET_PROFILE", "prod")
+	_, err := load(t, map[string]string{
+		"config.yaml":      "admin:\n  users:\n    - name: a\n      api_key: dev-key\n",
+		"config.prod.yaml": "",
+	})
+	if err == nil || !strings.Contains(err.Error(), "database.sslmode") || !strings.Contains(err.Error(), "admin.users[0].api_key") {
+		t.Errorf("error is %v, want sslmode and the short key refused", err)
+	}
+}
+
+func TestLoad_secretsAreReadFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "db_password"), "s3cret\n")
+	writeFile(t, filepath.Join(dir, "api_key"), "key-from-file\n")
+	t.Setenv("WALLET_DATABASE_PASSWORD_FILE", filepath.Join(dir, "db_password"))
+	cfg, err := load(t, map[string]string{"config.yaml": `
+admin:
+  users:
+    - name: "a"
+      api_key_file: "` + filepath.Join(dir, "api_key") + `"
+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.Password != "s3cret" {
+		t.Errorf("database password is %q, want s3cret", cfg.Database.Password)
+	}
+	if cfg.Admin.Users[0].APIKey != "key-from-file" {
+		t.Errorf("api key is %q, want key-from-file", cfg.Admin.Users[0].APIKey)
+	}
+
+	t.Setenv("WALLET_DATABASE_PASSWORD_FILE", filepath.Join(dir, "missing"))
+	if _, err := load(t, map[string]string{"config.yaml": ""}); err == nil || !strings.Contains(err.Error(), "database.password_file") {
+		t.Errorf("error is %v, want the missing secret reported", err)
+	}
+}
+
+func TestWatch_onlyReloadableSettingsAreTakenOver(t *testing.T) {
+	cfg, err := load(t, map[string]string{"config.yaml": "server:\n  port: \"8080\"\nrelease_worker:\n  interval: 1s\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan *Config, 4)
+	Watch(cfg, logger.NewNoopLogger(), func(next *Config) { changes <- next })
+
+	writeFile(t, viper.ConfigFileUsed(), "server:\n  port: \"9090\"\nrelease_worker:\n  interval: 5s\n")
+	select {
+	case next := <-changes:
+		if next.Release.Interval != 5*time.Second {
+			t.Errorf("release interval is %s, want 5s", next.Release.Interval)
+		}
+		if next.Server.Port != "8080" {
+			t.Errorf("port is %s, want 8080 until a restart", next.Server.Port)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config was not reloaded")
+	}
+}
+
+func TestAdminUserByKey(t *testing.T) {
+	users := []AdminUser{{Name: "alice", APIKey: "alice-key"}, {Name: "nokey"}, {Name: "bob", APIKey: "bob-key"}}
+	if user, ok := AdminUserByKey(users, "bob-key"); !ok || user.Name != "bob" {
+		t.Errorf("user is %q, %v, want bob", user.Name, ok)
+	}
+	for _, key := range []string{"", "unknown", "alice"} {
+		if user, ok := AdminUserByKey(users, key); ok {
+			t.Errorf("key %q matched %q, want no user", key, user.Name)
+		}
+	}
+}
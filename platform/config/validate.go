@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profiles are the environments a profile file can be layered on the config file for
+var Profiles = []string{"dev", "staging", "prod"}
+
+var (
+	sslModes  = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	logLevels = []string{"trace", "debug", "info", "warn", "error"}
+)
+
+// prodKeyLength is the shortest admin API key accepted by the prod profile
+const prodKeyLength = 32
+
+// Validate checks the settings every command relies on and reports all the invalid ones at once. The risk
+// rules, fee schedules and bank are checked by the components built from them.
+func (c *Config) Validate() error {
+	v := &validator{}
+	v.check(c.Profile == "" || slices.Contains(Profiles, c.Profile), "profile", "must be one of %s", strings.Join(Profiles, ", "))
+
+	v.port("server.port", c.Server.Port)
+	v.positive("server.read_timeout", c.Server.ReadTimeout)
+	v.positive("server.write_timeout", c.Server.WriteTimeout)
+	v.positive("server.idle_timeout", c.Server.IdleTimeout)
+	v.check(c.Server.MaxAge >= 0, "server.max_age", "must not be negative")
+
+	v.database("database", c.Database)
+
+	v.worker("release_worker", c.Release)
+	v.worker("withdraw_worker", c.Withdraw)
+	v.worker("snapshot_worker", c.Snapshot)
+	v.worker("hold_expiry_worker", c.HoldExpiry)
+	v.worker("promo_expiry_worker", c.PromoExpiry)
+	v.check(c.Scheduler.BatchSize > 0, "scheduler_worker.batch_size", "must be positive")
+	v.positive("scheduler_worker.interval", c.Scheduler.Interval)
+	v.check(c.Scheduler.MaxCatchUp > 0, "scheduler_worker.max_catch_up", "must be positive")
+	// a payout is a debit, which must be released after the time it is made
+	v.positive("scheduler_worker.payout_delay", c.Scheduler.PayoutDelay)
+	v.positive("scheduler_worker.claim_for", c.Scheduler.ClaimFor)
+	v.positive("batch_worker.interval", c.Batch.Interval)
+	v.check(c.Batch.ChunkSize > 0, "batch_worker.chunk_size", "must be positive")
+	v.positive("batch_worker.claim_for", c.Batch.ClaimFor)
+	v.check(c.Batch.MaxRows > 0, "batch_worker.max_rows", "must be positive")
+	v.check(c.Integrity.BatchSize > 0, "integrity_worker.batch_size", "must be positive")
+	v.positive("integrity_worker.interval", c.Integrity.Interval)
+
+	v.check(len([]rune(c.Reconcile.Delimiter)) == 1, "reconciliation.delimiter", "must be a single character")
+	v.check(c.Reconcile.Columns.Idempotency != "", "reconciliation.columns.idempotency", "must not be empty")
+	v.check(c.Reconcile.Columns.BankReference != "", "reconciliation.columns.bank_reference", "must not be empty")
+	v.check(c.Reconcile.Columns.Amount != "", "reconciliation.columns.amount", "must not be empty")
+	v.check(c.Reconcile.Columns.Status != "", "reconciliation.columns.status", "must not be empty")
+	v.check(len(c.Reconcile.SuccessStatuses) > 0, "reconciliation.success_statuses", "must not be empty")
+
+	keys := make(map[string]bool, len(c.Admin.Users))
+	for i, user := range c.Admin.Users {
+		key := fmt.Sprintf("admin.users[%d]", i)
+		v.check(strings.TrimSpace(user.Name) != "", key+".name", "must not be empty")
+		v.check(user.APIKey != "", key+".api_key", "must not be empty")
+		v.check(!keys[user.APIKey], key+".api_key", "is used by another operator")
+		keys[user.APIKey] = true
+		if c.Profile == "prod" {
+			v.check(len(user.APIKey) >= prodKeyLength, key+".api_key", "must be at least %d characters in prod", prodKeyLength)
+		}
+	}
+
+	v.check(slices.Contains(logLevels, c.Logging.Level), "logging.level", "must be one of %s", strings.Join(logLevels, ", "))
+	v.positive("health.database_timeout", c.Health.DatabaseTimeout)
+	if c.Swagger.Enabled {
+		v.check(c.Swagger.SwaggerFilePath != "", "swagger.swagger_file_path", "must be set when swagger is enabled")
+		v.check(c.Swagger.OpenApiFilePath != "", "swagger.openapi_file_path", "must be set when swagger is enabled")
+	}
+
+	if c.Profile == "prod" {
+		v.check(c.Database.SSLMode != "disable", "database.sslmode", "must not be disable in prod")
+	}
+	return v.err()
+}
+
+// validator collects the problems of a config, every problem names the key it is about
+type validator struct {
+	problems []string
+}
+
+func (v *validator) check(ok bool, key string, format string, args ...any) {
+	if !ok {
+		v.problems = append(v.problems, key+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+func (v *validator) positive(key string, d time.Duration) {
+	v.check(d > 0, key, "must be a positive duration")
+}
+
+func (v *validator) port(key string, port string) {
+	n, err := strconv.Atoi(port)
+	v.check(err == nil && n > 0 && n < 65536, key, "%q is not a port number", port)
+}
+
+func (v *validator) database(key string, db DatabaseConfig) {
+	v.check(db.Host != "", key+".host", "must not be empty")
+	v.check(db.Port > 0 && db.Port < 65536, key+".port", "%d is not a port number", db.Port)
+	v.check(db.User != "", key+".user", "must not be empty")
+	v.check(db.DBName != "", key+".dbname", "must not be empty")
+	v.check(slices.Contains(sslModes, db.SSLMode), key+".sslmode", "must be one of %s", strings.Join(sslModes, ", "))
+	v.check(db.MaxOpenConns > 0, key+".max_open_conns", "must be positive")
+	v.check(db.MaxIdleConns >= 0 && db.MaxIdleConns <= db.MaxOpenConns, key+".max_idle_conns", "must be between 0 and max_open_conns")
+	v.check(db.ConnMaxLifetime >= 0, key+".conn_max_lifetime", "must not be negative")
+}
+
+func (v *validator) worker(key string, worker WorkerConfig) {
+	v.check(worker.WorkerCount > 0, key+".worker_count", "must be positive")
+	v.check(worker.BatchSize > 0, key+".batch_size", "must be positive")
+	v.positive(key+".interval", worker.Interval)
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid config:\n  %s", strings.Join(v.problems, "\n  "))
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Watch reloads the config when the config file or the profile file changes and calls onChange with the
+// updated config. Only the reloadable settings are taken from the files: the log level, the interval and
+// batch size of the workers, the batch row limit and the scheduler catch up limit. Other changes are
+// logged and wait for a restart, and a config failing to load is logged and dropped.
+// It must be called after Load, the config given to onChange must not be modified.
+func Watch(current *Config, log logger.Logger, onChange func(*Config)) {
+	var mu sync.Mutex
+	reload := func(event fsnotify.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		next, err := read()
+		if err != nil {
+			log.Error().Err(err).Str("file", event.Name).Msg("config change ignored")
+			return
+		}
+		pending := *next
+		pending.applyReloadable(current)
+		if !reflect.DeepEqual(&pending, current) {
+			log.Warn().Str("file", event.Name).Msg("config change needs a restart to take effect")
+		}
+		updated := *current
+		updated.applyReloadable(next)
+		if reflect.DeepEqual(&updated, current) {
+			return
+		}
+		current = &updated
+		log.Info().Str("file", event.Name).Msg("config reloaded")
+		onChange(current)
+	}
+
+	// every file gets a viper of its own to watch it, so the config is only read again under the lock
+	files := []string{viper.ConfigFileUsed()}
+	if path, ok := profileFile(); ok {
+		files = append(files, path)
+	}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		watcher := viper.New()
+		watcher.SetConfigFile(file)
+		watcher.OnConfigChange(reload)
+		watcher.WatchConfig()
+	}
+}
+
+// applyReloadable takes over the settings which can change while running from next
+func (c *Config) applyReloadable(next *Config) {
+	c.Logging.Level = next.Logging.Level
+	c.Release.Interval, c.Release.BatchSize = next.Release.Interval, next.Release.BatchSize
+	c.Withdraw.Interval, c.Withdraw.BatchSize = next.Withdraw.Interval, next.Withdraw.BatchSize
+	c.Snapshot.Interval, c.Snapshot.BatchSize = next.Snapshot.Interval, next.Snapshot.BatchSize
+	c.HoldExpiry.Interval, c.HoldExpiry.BatchSize = next.HoldExpiry.Interval, next.HoldExpiry.BatchSize
+	c.PromoExpiry.Interval, c.PromoExpiry.BatchSize = next.PromoExpiry.Interval, next.PromoExpiry.BatchSize
+	c.Scheduler.Interval, c.Scheduler.BatchSize = next.Scheduler.Interval, next.Scheduler.BatchSize
+	c.Scheduler.MaxCatchUp = next.Scheduler.MaxCatchUp
+	c.Batch.Interval, c.Batch.ChunkSize = next.Batch.Interval, next.Batch.ChunkSize
+	c.Batch.MaxRows = next.Batch.MaxRows
+	c.Integrity.Interval, c.Integrity.BatchSize = next.Integrity.Interval, next.Integrity.BatchSize
+}
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// APIKeyHeader carries the key of the calling support operator
+	APIKeyHeader = "X-API-Key"
+	adminUserKey = "admin_user"
+)
+
+// adminAuth rejects requests which do not carry the API key of a configured operator
+// and stores the operator name for the handlers
+func adminAuth(users []config.AdminUser) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := config.AdminUserByKey(users, c.Get(APIKeyHeader))
+		if !ok {
+			return fiber.NewError(fiber.StatusUnauthorized, "missing or invalid API key")
+		}
+		c.Locals(adminUserKey, user.Name)
+		info := audit.FromContext(c.Context())
+		info.Actor = user.Name
+		audit.Store(c.Context(), info)
+		return c.Next()
+	}
+}
+
+// AdminUser returns the name of the authenticated operator of an admin request
+func AdminUser(c *fiber.Ctx) string {
+	name, _ := c.Locals(adminUserKey).(string)
+	return name
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/gofiber/fiber/v2"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuth(t *testing.T) {
+	app := fiber.New()
+	admin := app.Group("/admin", adminAuth([]config.AdminUser{
+		{Name: "support-1", APIKey: "key-1"},
+		{Name: "no-key", APIKey: ""},
+	}))
+	admin.Get("/whoami", func(c *fiber.Ctx) error {
+		return c.SendString(AdminUser(c))
+	})
+
+	cases := []struct {
+		name   string
+		key    string
+		status int
+	}{
+		{"valid key", "key-1", fiber.StatusOK},
+		{"wrong key", "key-2", fiber.StatusUnauthorized},
+		{"missing key", "", fiber.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/whoami", nil)
+			if tc.key != "" {
+				req.Header.Set(APIKeyHeader, tc.key)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, resp.StatusCode)
+			}
+		})
+	}
+}
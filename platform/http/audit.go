@@ -0,0 +1,22 @@
+package http
+
+import (
+	"github.com/MaisamV/wallet/platform/audit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiActor is recorded for changes made through the public API, which has no user authentication yet
+const apiActor = "api"
+
+// auditInfo attaches the request ID and caller address to the request context for the audit log
+func auditInfo() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID, _ := c.Locals("requestid").(string)
+		audit.Store(c.Context(), audit.Info{
+			Actor:     apiActor,
+			RequestID: requestID,
+			SourceIP:  c.IP(),
+		})
+		return c.Next()
+	}
+}
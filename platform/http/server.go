@@ -14,12 +14,13 @@ import (
 // Server represents the HTTP server configuration
 type Server struct {
 	app    *fiber.App
+	admin  fiber.Router
 	port   string
 	logger logger.Logger
 }
 
 // NewServer creates a new HTTP server with common middleware
-func NewServer(cfg config.ServerConfig, log logger.Logger) *Server {
+func NewServer(cfg config.ServerConfig, adminCfg config.AdminConfig, log logger.Logger) *Server {
 	log.Info().Str("port", cfg.Port).Msg("Initializing HTTP server")
 
 	app := fiber.New(fiber.Config{
@@ -35,6 +36,7 @@ func NewServer(cfg config.ServerConfig, log logger.Logger) *Server {
 	log.Debug().Msg("Configuring HTTP server middleware")
 	app.Use(recover.New())
 	app.Use(requestid.New())
+	app.Use(auditInfo())
 	app.Use(fiberLogger.New(fiberLogger.Config{
 		Format: "${time} ${status} - ${method} ${path} ${latency}\n",
 	}))
@@ -46,9 +48,15 @@ func NewServer(cfg config.ServerConfig, log logger.Logger) *Server {
 		MaxAge:           cfg.MaxAge,
 	}))
 
+	if len(adminCfg.Users) == 0 {
+		log.Warn().Msg("No admin users configured, admin API will reject every request")
+	}
+	admin := app.Group("/api/v1/admin", adminAuth(adminCfg.Users))
+
 	log.Info().Msg("HTTP server initialized successfully")
 	return &Server{
 		app:    app,
+		admin:  admin,
 		port:   cfg.Port,
 		logger: log,
 	}
@@ -59,6 +67,11 @@ func (s *Server) GetApp() *fiber.App {
 	return s.app
 }
 
+// GetAdminRouter returns the authenticated /api/v1/admin route group
+func (s *Server) GetAdminRouter() fiber.Router {
+	return s.admin
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	s.logger.Info().Str("port", s.port).Msg("Starting HTTP server")
@@ -98,13 +98,18 @@ func New() Logger {
 
 // NewWithLevel creates a new logger with specified level
 func NewWithLevel(level string) Logger {
+	SetLevel(level)
+	return New()
+}
+
+// SetLevel changes the level of every logger, an unknown level logs at info
+func SetLevel(level string) {
 	logLevel, err := zerolog.ParseLevel(level)
 	if err != nil {
 		logLevel = zerolog.InfoLevel
 	}
 
 	zerolog.SetGlobalLevel(logLevel)
-	return New()
 }
 
 type noopEvent struct {
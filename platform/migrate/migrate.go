@@ -0,0 +1,316 @@
+// Package migrate applies the SQL migrations to the database. The applied version is kept in the
+// schema_migrations table the way golang-migrate keeps it, so either tool can take over from the other.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockKey is the advisory lock held while migrating, so replicas starting together do not race
+const lockKey int64 = 7_312_904_221
+
+// NoVersion is the version of a database no migration was applied to
+const NoVersion int64 = -1
+
+var (
+	ErrDirty          = errors.New("a migration failed half way, fix the schema and force its version")
+	ErrSchemaOutdated = errors.New("database schema is older than this binary, run the migrations first")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is the version a database is at compared to the migrations the binary knows
+type Status struct {
+	Version int64
+	Dirty   bool
+	Latest  int64
+	Pending []Migration
+}
+
+type Migrator struct {
+	logger     logger.Logger
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+// New reads the migrations in the root of migrations
+func New(logger logger.Logger, pool *pgxpool.Pool, migrations fs.FS) (*Migrator, error) {
+	list, err := load(migrations)
+	if err != nil {
+		return nil, fmt.Errorf("could not read migrations: %w", err)
+	}
+	return &Migrator{
+		logger:     logger,
+		pool:       pool,
+		migrations: list,
+	}, nil
+}
+
+func load(migrations fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		script, err := fs.ReadFile(migrations, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Latest is the version of the newest migration, NoVersion when there are none
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return NoVersion
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration in order and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("version %d: %w", version, ErrDirty)
+		}
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+			m.logger.Info().Int64("version", migration.Version).Str("name", migration.Name).Msg("applying migration")
+			if err := run(ctx, conn, migration.Version, migration.Up, migration.Version); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns how many were reverted
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("version %d: %w", version, ErrDirty)
+		}
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+			if migration.Version < version {
+				return fmt.Errorf("database is at version %d which is not a known migration", version)
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+			}
+			previous := NoVersion
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			m.logger.Info().Int64("version", migration.Version).Str("name", migration.Name).Msg("reverting migration")
+			if err := run(ctx, conn, migration.Version, migration.Down, previous); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			version = previous
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force sets the version without running any migration and clears the dirty flag, it is how a failed
+// migration is recovered from once the schema has been fixed by hand
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		return setVersion(ctx, conn, version, false)
+	})
+}
+
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Version: version, Dirty: dirty, Latest: m.Latest()}
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+	return status, nil
+}
+
+// Check refuses a database which is dirty or older than the newest migration. A newer schema is accepted,
+// it is what a replica still running the previous release sees during a rollout.
+func (m *Migrator) Check(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("could not read schema version: %w", err)
+	}
+	if status.Dirty {
+		return fmt.Errorf("version %d: %w", status.Version, ErrDirty)
+	}
+	if status.Version < status.Latest {
+		return fmt.Errorf("database is at version %d and %d is needed: %w", status.Version, status.Latest, ErrSchemaOutdated)
+	}
+	if status.Version > status.Latest {
+		m.logger.Warn().Int64("version", status.Version).Int64("latest", status.Latest).Msg("database schema is newer than this binary")
+	}
+	return nil
+}
+
+// withLock runs fn on a single connection holding the migration advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("could not take the migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			// closing the session is what releases the lock then
+			m.logger.Error().Err(err).Msg("could not release the migration lock")
+			_ = conn.Conn().Close(context.Background())
+		}
+	}()
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// run marks the database dirty at version, runs the script and marks it clean at next. The scripts manage
+// their own transactions, so a failing script leaves the database dirty the way golang-migrate does.
+func run(ctx context.Context, conn *pgxpool.Conn, version int64, script string, next int64) error {
+	if err := setVersion(ctx, conn, version, true); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, script, pgx.QueryExecModeSimpleProtocol); err != nil {
+		// a script failing inside its own transaction leaves the session in it
+		_, _ = conn.Exec(context.Background(), "ROLLBACK")
+		return err
+	}
+	return setVersion(ctx, conn, next, false)
+}
+
+func ensureTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, createTableQuery)
+	if err != nil {
+		return fmt.Errorf("could not create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// readVersion reads the applied version, a database without schema_migrations has none
+func readVersion(ctx context.Context, conn *pgxpool.Conn) (int64, bool, error) {
+	var exists bool
+	if err := conn.QueryRow(ctx, tableExistsQuery).Scan(&exists); err != nil {
+		return 0, false, fmt.Errorf("could not read schema version: %w", err)
+	}
+	if !exists {
+		return NoVersion, false, nil
+	}
+	var version int64
+	var dirty bool
+	err := conn.QueryRow(ctx, readVersionQuery).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NoVersion, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("could not read schema version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+func setVersion(ctx context.Context, conn *pgxpool.Conn, version int64, dirty bool) error {
+	batch := &pgx.Batch{}
+	batch.Queue("TRUNCATE schema_migrations")
+	if version != NoVersion {
+		batch.Queue("INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)", version, dirty)
+	}
+	if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("could not set schema version: %w", err)
+	}
+	return nil
+}
+
+const (
+	createTableQuery = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version bigint NOT NULL PRIMARY KEY,
+    dirty boolean NOT NULL
+)`
+
+	tableExistsQuery = `SELECT to_regclass('schema_migrations') IS NOT NULL`
+
+	readVersionQuery = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+)
@@ -0,0 +1,47 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/MaisamV/wallet/scripts/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000010_b.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"000010_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"000002_a.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+		"000002_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"migrations.go":     {Data: []byte("package migrations")},
+	}
+	list, err := load(fsys)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(list) != 2 || list[0].Version != 2 || list[1].Version != 10 {
+		t.Fatalf("expected migrations 2 and 10 in order, got %+v", list)
+	}
+	if list[0].Name != "a" || list[0].Up != "CREATE TABLE a ();" || list[0].Down != "DROP TABLE a;" {
+		t.Fatalf("migration 2 is not read correctly: %+v", list[0])
+	}
+
+	if _, err := load(fstest.MapFS{"000003_c.down.sql": {Data: []byte("DROP TABLE c;")}}); err == nil {
+		t.Fatal("expected an error for a migration without an up script")
+	}
+}
+
+func TestLoad_embedded(t *testing.T) {
+	list, err := load(migrations.FS)
+	if err != nil {
+		t.Fatalf("could not read the embedded migrations: %v", err)
+	}
+	if len(list) == 0 {
+		t.Fatal("expected the migrations to be embedded")
+	}
+	for _, m := range list {
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+// Package openapi checks HTTP requests and responses against the hand-written OpenAPI document,
+// it backs the contract tests of the HTTP handlers. Only the parts of OpenAPI 3.0 the document uses
+// are supported, and objects without additionalProperties reject fields they do not document.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid/v5"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type node = map[string]any
+
+type Spec struct {
+	doc   node
+	paths []pathTemplate
+}
+
+type pathTemplate struct {
+	template string
+	segments []string
+	literals int
+}
+
+// Load reads the OpenAPI document at file
+func Load(file string) (*Spec, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read openapi document: %w", err)
+	}
+	var doc node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse openapi document: %w", err)
+	}
+	paths, ok := doc["paths"].(node)
+	if !ok {
+		return nil, fmt.Errorf("openapi document has no paths")
+	}
+
+	spec := &Spec{doc: doc}
+	for template := range paths {
+		p := pathTemplate{template: template, segments: strings.Split(strings.Trim(template, "/"), "/")}
+		for _, segment := range p.segments {
+			if !strings.HasPrefix(segment, "{") {
+				p.literals++
+			}
+		}
+		spec.paths = append(spec.paths, p)
+	}
+	return spec, nil
+}
+
+// ValidateRequest checks the parameters and the body of a request against its documented operation
+func (s *Spec) ValidateRequest(req *http.Request, body []byte) error {
+	operation, pathParams, err := s.operation(req.Method, req.URL.Path)
+	if err != nil {
+		return err
+	}
+
+	parameters, _ := operation["parameters"].([]any)
+	for _, p := range parameters {
+		param := s.resolve(p.(node))
+		name, _ := param["name"].(string)
+		required, _ := param["required"].(bool)
+		var value string
+		var present bool
+		switch param["in"] {
+		case "path":
+			value, present = pathParams[name]
+		case "query":
+			present = req.URL.Query().Has(name)
+			value = req.URL.Query().Get(name)
+		default:
+			continue
+		}
+		if !present {
+			if required {
+				return fmt.Errorf("%s %s: required %s parameter %s is missing", req.Method, req.URL.Path, param["in"], name)
+			}
+			continue
+		}
+		if err := s.validateParameter(s.resolve(param["schema"].(node)), value); err != nil {
+			return fmt.Errorf("%s %s: parameter %s: %w", req.Method, req.URL.Path, name, err)
+		}
+	}
+
+	requestBody, ok := operation["requestBody"].(node)
+	if !ok {
+		if len(body) != 0 {
+			return fmt.Errorf("%s %s: request body is not documented", req.Method, req.URL.Path)
+		}
+		return nil
+	}
+	requestBody = s.resolve(requestBody)
+	if len(body) == 0 {
+		if required, _ := requestBody["required"].(bool); required {
+			return fmt.Errorf("%s %s: request body is required", req.Method, req.URL.Path)
+		}
+		return nil
+	}
+	schema, err := s.jsonSchema(requestBody)
+	if err != nil {
+		return fmt.Errorf("%s %s: request: %w", req.Method, req.URL.Path, err)
+	}
+	if err := s.validateBody(schema, body); err != nil {
+		return fmt.Errorf("%s %s: request body: %w", req.Method, req.URL.Path, err)
+	}
+	return nil
+}
+
+// ValidateResponse checks the status code and the body of a response against the operation of the request
+func (s *Spec) ValidateResponse(req *http.Request, resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	operation, _, err := s.operation(req.Method, req.URL.Path)
+	if err != nil {
+		return err
+	}
+	responses, _ := operation["responses"].(node)
+	response, ok := responses[strconv.Itoa(resp.StatusCode)].(node)
+	if !ok {
+		response, ok = responses["default"].(node)
+	}
+	if !ok {
+		return fmt.Errorf("%s %s: status %d is not documented", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	response = s.resolve(response)
+	if _, ok := response["content"]; !ok {
+		if len(body) != 0 {
+			return fmt.Errorf("%s %s: status %d has no documented body", req.Method, req.URL.Path, resp.StatusCode)
+		}
+		return nil
+	}
+	schema, err := s.jsonSchema(response)
+	if err != nil {
+		return fmt.Errorf("%s %s: status %d: %w", req.Method, req.URL.Path, resp.StatusCode, err)
+	}
+	if err := s.validateBody(schema, body); err != nil {
+		return fmt.Errorf("%s %s: status %d: %w", req.Method, req.URL.Path, resp.StatusCode, err)
+	}
+	return nil
+}
+
+// operation finds the operation documented for method and path. Templates with more literal segments
+// win, so /wallet/{userid}/withdraw/fee is not taken for a path parameter.
+func (s *Spec) operation(method string, path string) (node, map[string]string, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var best *pathTemplate
+	var bestParams map[string]string
+	for i := range s.paths {
+		p := &s.paths[i]
+		params, ok := p.match(segments)
+		if ok && (best == nil || p.literals > best.literals) {
+			best, bestParams = p, params
+		}
+	}
+	if best == nil {
+		return nil, nil, fmt.Errorf("path %s is not documented", path)
+	}
+	operation, ok := s.doc["paths"].(node)[best.template].(node)[strings.ToLower(method)].(node)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s %s is not documented", method, best.template)
+	}
+	return operation, bestParams, nil
+}
+
+func (p *pathTemplate) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(p.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range p.segments {
+		if strings.HasPrefix(segment, "{") {
+			params[strings.Trim(segment, "{}")] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// jsonSchema returns the application/json schema of a request body or a response
+func (s *Spec) jsonSchema(object node) (node, error) {
+	content, _ := object["content"].(node)
+	media, ok := content["application/json"].(node)
+	if !ok {
+		return nil, fmt.Errorf("no application/json content is documented")
+	}
+	schema, ok := media["schema"].(node)
+	if !ok {
+		return nil, fmt.Errorf("application/json content has no schema")
+	}
+	return schema, nil
+}
+
+func (s *Spec) validateBody(schema node, body []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("body is not json: %w", err)
+	}
+	return s.validate(schema, value, "$")
+}
+
+// resolve follows a local $ref such as #/components/schemas/Transaction
+func (s *Spec) resolve(object node) node {
+	for {
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return object
+		}
+		var target any = s.doc
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			target = target.(node)[key]
+		}
+		object = target.(node)
+	}
+}
+
+// validateParameter checks the raw value of a path or query parameter
+func (s *Spec) validateParameter(schema node, value string) error {
+	var parsed any = value
+	switch schema["type"] {
+	case "integer", "number":
+		parsed = json.Number(value)
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		parsed = b
+	}
+	return s.validate(schema, parsed, "$")
+}
+
+func (s *Spec) validate(schema node, value any, at string) error {
+	schema = s.resolve(schema)
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", at)
+	}
+
+	schemaType, _ := schema["type"].(string)
+	if schemaType == "" {
+		if _, ok := schema["properties"]; ok {
+			schemaType = "object"
+		}
+	}
+	switch schemaType {
+	case "object":
+		return s.validateObject(schema, value, at)
+	case "array":
+		list, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %T", at, value)
+		}
+		items, _ := schema["items"].(node)
+		for i, item := range list {
+			if err := s.validate(items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a string, got %T", at, value)
+		}
+		if err := validateFormat(schema["format"], str); err != nil {
+			return fmt.Errorf("%s: %w", at, err)
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: expected an integer, got %T", at, value)
+		}
+		n, err := number.Int64()
+		if err != nil {
+			return fmt.Errorf("%s: %s is not an integer", at, number)
+		}
+		if minimum, ok := schema["minimum"].(int); ok && n < int64(minimum) {
+			return fmt.Errorf("%s: %d is less than the minimum %d", at, n, minimum)
+		}
+		if maximum, ok := schema["maximum"].(int); ok && n > int64(maximum) {
+			return fmt.Errorf("%s: %d is more than the maximum %d", at, n, maximum)
+		}
+	case "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: expected a number, got %T", at, value)
+		}
+		if _, err := number.Float64(); err != nil {
+			return fmt.Errorf("%s: %s is not a number", at, number)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %T", at, value)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: %v is not one of %v", at, value, enum)
+	}
+	return nil
+}
+
+func (s *Spec) validateObject(schema node, value any, at string) error {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: expected an object, got %T", at, value)
+	}
+	required, _ := schema["required"].([]any)
+	for _, name := range required {
+		if _, ok := object[name.(string)]; !ok {
+			return fmt.Errorf("%s: required field %s is missing", at, name)
+		}
+	}
+
+	properties, _ := schema["properties"].(node)
+	for name, field := range object {
+		fieldAt := at + "." + name
+		if property, ok := properties[name].(node); ok {
+			if err := s.validate(property, field, fieldAt); err != nil {
+				return err
+			}
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: field is not documented", fieldAt)
+			}
+		case node:
+			if err := s.validate(additional, field, fieldAt); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: field is not documented", fieldAt)
+		}
+	}
+	return nil
+}
+
+func validateFormat(format any, value string) error {
+	switch format {
+	case "uuid":
+		if _, err := uuid.FromString(value); err != nil {
+			return fmt.Errorf("%q is not a uuid", value)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+			return fmt.Errorf("%q is not a date-time", value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const document = `
+openapi: 3.0.3
+paths:
+  /items/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Item'
+  /items/new:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Item'
+      responses:
+        '201':
+          description: created
+components:
+  schemas:
+    Item:
+      type: object
+      required: [ name ]
+      properties:
+        name:
+          type: string
+        key:
+          type: string
+          format: uuid
+        count:
+          type: integer
+          minimum: 1
+`
+
+func loadDocument(t *testing.T) *Spec {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "openapi.yaml")
+	if err := os.WriteFile(file, []byte(document), 0o600); err != nil {
+		t.Fatalf("could not write document: %v", err)
+	}
+	spec, err := Load(file)
+	if err != nil {
+		t.Fatalf("could not load document: %v", err)
+	}
+	return spec
+}
+
+func TestValidateRequest(t *testing.T) {
+	spec := loadDocument(t)
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		wantErr string
+	}{
+		{"valid", http.MethodPost, "/items/new", `{"name": "a", "count": 2}`, ""},
+		{"literal segment wins", http.MethodPost, "/items/new", `{"name": "a"}`, ""},
+		{"invalid path parameter", http.MethodGet, "/items/abc", "", "not an integer"},
+		{"undocumented path", http.MethodGet, "/things/1", "", "not documented"},
+		{"undocumented method", http.MethodDelete, "/items/1", "", "not documented"},
+		{"missing body", http.MethodPost, "/items/new", "", "body is required"},
+		{"missing field", http.MethodPost, "/items/new", `{"count": 2}`, "required field name"},
+		{"undocumented field", http.MethodPost, "/items/new", `{"name": "a", "extra": 1}`, "$.extra: field is not documented"},
+		{"wrong type", http.MethodPost, "/items/new", `{"name": 1}`, "expected a string"},
+		{"wrong format", http.MethodPost, "/items/new", `{"name": "a", "key": "abc"}`, "not a uuid"},
+		{"under minimum", http.MethodPost, "/items/new", `{"name": "a", "count": 0}`, "less than the minimum"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			err := spec.ValidateRequest(req, []byte(tt.body))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	spec := loadDocument(t)
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	response := func(status int, body string) *http.Response {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	resp := response(http.StatusOK, `{"name": "a"}`)
+	if err := spec.ValidateResponse(req, resp); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// the body can still be read by the test
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"name": "a"}` {
+		t.Fatalf("expected the body to be restored, got %s", body)
+	}
+	if err := spec.ValidateResponse(req, response(http.StatusNotFound, `{"name": "a"}`)); err == nil || !strings.Contains(err.Error(), "status 404 is not documented") {
+		t.Fatalf("expected an undocumented status, got %v", err)
+	}
+	if err := spec.ValidateResponse(req, response(http.StatusOK, `{"name": "a", "RetryCount": 0}`)); err == nil || !strings.Contains(err.Error(), "field is not documented") {
+		t.Fatalf("expected an undocumented field, got %v", err)
+	}
+}
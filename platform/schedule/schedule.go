@@ -0,0 +1,50 @@
+// Package schedule holds the interval and batch size of a background worker, both can change while the
+// worker runs when the config is reloaded.
+package schedule
+
+import (
+	"sync"
+	"time"
+)
+
+type Schedule struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	batchSize int
+	changed   chan struct{}
+}
+
+func New(interval time.Duration, batchSize int) *Schedule {
+	return &Schedule{
+		interval:  interval,
+		batchSize: batchSize,
+		changed:   make(chan struct{}),
+	}
+}
+
+// Interval returns the current interval and a channel closed when the schedule changes, a worker
+// waiting for its next run resets its ticker then
+func (s *Schedule) Interval() (time.Duration, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.interval, s.changed
+}
+
+func (s *Schedule) BatchSize() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batchSize
+}
+
+// Set changes the interval and batch size, the batch size is used from the next run on
+func (s *Schedule) Set(interval time.Duration, batchSize int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if interval == s.interval && batchSize == s.batchSize {
+		return
+	}
+	s.interval = interval
+	s.batchSize = batchSize
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
@@ -0,0 +1,32 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_setWakesTheWaitingWorkers(t *testing.T) {
+	s := New(time.Minute, 100)
+	interval, changed := s.Interval()
+	if interval != time.Minute || s.BatchSize() != 100 {
+		t.Fatalf("schedule is %s and %d, want 1m and 100", interval, s.BatchSize())
+	}
+
+	s.Set(time.Second, 50)
+	select {
+	case <-changed:
+	default:
+		t.Fatal("changed is not closed after Set")
+	}
+	interval, changed = s.Interval()
+	if interval != time.Second || s.BatchSize() != 50 {
+		t.Errorf("schedule is %s and %d, want 1s and 50", interval, s.BatchSize())
+	}
+
+	s.Set(time.Second, 50)
+	select {
+	case <-changed:
+		t.Error("changed is closed by a Set which changed nothing")
+	default:
+	}
+}
@@ -1,12 +1,18 @@
 package platform
 
 import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/platform/clock"
 	"github.com/MaisamV/wallet/platform/config"
 	"github.com/MaisamV/wallet/platform/database"
 	"github.com/MaisamV/wallet/platform/http"
 	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/MaisamV/wallet/platform/migrate"
+	"github.com/MaisamV/wallet/scripts/migrations"
 	"github.com/google/wire"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
 )
 
 // ProvideLogger provides a logger instance
@@ -19,14 +25,56 @@ func ProvideConfig() (*config.Config, error) {
 	return config.Load()
 }
 
-// ProvideDatabase provides a database connection pool
+// ProvideDatabase provides a database connection pool, migrated when auto_migrate is on and refused when
+// its schema is older than the embedded migrations
 func ProvideDatabase(cfg *config.Config, log logger.Logger) (*pgxpool.Pool, error) {
-	return database.NewConnection(cfg.Database, log)
+	pool, err := database.NewConnection(cfg.Database, log)
+	if err != nil {
+		return nil, err
+	}
+	if err := prepareSchema(cfg.Database, log, pool); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+func prepareSchema(cfg config.DatabaseConfig, log logger.Logger, pool *pgxpool.Pool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	migrator, err := migrate.New(log, pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+	if cfg.AutoMigrate {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+		log.Info().Int("applied", applied).Int64("version", migrator.Latest()).Msg("Database schema is up to date")
+	}
+	return migrator.Check(ctx)
+}
+
+// WatchConfig reloads the config when its files change, applies the new log level and passes the
+// reloaded config to onChange for the settings a command applies itself
+func WatchConfig(cfg *config.Config, log logger.Logger, onChange func(*config.Config)) {
+	config.Watch(cfg, log, func(next *config.Config) {
+		logger.SetLevel(next.Logging.Level)
+		if onChange != nil {
+			onChange(next)
+		}
+	})
+}
+
+// ProvideClock provides the clock every component reads the current time from
+func ProvideClock() clock.Clock {
+	return clock.NewSystemClock()
 }
 
 // ProvideHTTPServer provides an HTTP server instance
 func ProvideHTTPServer(cfg *config.Config, log logger.Logger) *http.Server {
-	return http.NewServer(cfg.Server, log)
+	return http.NewServer(cfg.Server, cfg.Admin, log)
 }
 
 // PlatformSet is a wire provider set for all platform dependencies
@@ -35,4 +83,5 @@ var PlatformSet = wire.NewSet(
 	ProvideConfig,
 	ProvideDatabase,
 	ProvideHTTPServer,
+	ProvideClock,
 )
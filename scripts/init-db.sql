@@ -13,17 +13,7 @@ WHERE NOT EXISTS (SELECT FROM pg_database WHERE datname = 'wallet_db')\gexec
 -- Enable UUID extension for generating UUIDs
 -- CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
--- Create a schema_migrations table for golang-migrate
--- This will be used by golang-migrate to track migration versions
-CREATE TABLE IF NOT EXISTS schema_migrations (
-    version bigint NOT NULL PRIMARY KEY,
-    dirty boolean NOT NULL
-);
-
--- Add some helpful comments
-COMMENT ON TABLE schema_migrations IS 'Tracks database migration versions for golang-migrate';
-COMMENT ON COLUMN schema_migrations.version IS 'Migration version number';
-COMMENT ON COLUMN schema_migrations.dirty IS 'Indicates if migration failed and needs manual intervention';
+-- schema_migrations is created by the migrate command of the app, which embeds the migrations
 
 -- Log successful initialization
 \echo 'Database initialization completed successfully';
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS balance_snapshots;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS released_at;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,24 @@
+BEGIN;
+
+-- released_at records when a transaction actually affected the balances that
+-- are deferred by a release time, so historical balances can be rebuilt.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS released_at TIMESTAMPTZ NULL;
+UPDATE transactions SET released_at = updated_at WHERE released = TRUE AND released_at IS NULL;
+
+CREATE INDEX idx_txn_user_released_at ON transactions (user_id, released_at);
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    wallet_id BIGINT NOT NULL REFERENCES wallets(id),
+    user_id BIGINT NOT NULL,
+    snapshot_date DATE NOT NULL,
+    total_balance bigint NOT NULL,
+    available_balance bigint NOT NULL,
+    taken_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_snapshot_wallet_date ON balance_snapshots (wallet_id, snapshot_date);
+CREATE INDEX idx_snapshot_user_taken_at ON balance_snapshots (user_id, taken_at DESC);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,32 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id BIGSERIAL PRIMARY KEY,
+    file_name VARCHAR(255) NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+    bank_records int NOT NULL DEFAULT 0,
+    local_records int NOT NULL DEFAULT 0,
+    matched int NOT NULL DEFAULT 0,
+    mismatched int NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_items (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES reconciliation_runs(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('missing_at_bank', 'missing_locally', 'amount_mismatch', 'status_mismatch')),
+    transaction_id UUID NULL,
+    idempotency_key UUID NULL,
+    bank_reference VARCHAR(64) NOT NULL DEFAULT '',
+    local_amount bigint NULL,
+    bank_amount bigint NULL,
+    local_status VARCHAR(20) NOT NULL DEFAULT '',
+    bank_status VARCHAR(20) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_reconciliation_items_run_id ON reconciliation_items (run_id, id);
+CREATE INDEX idx_txn_type_created ON transactions (type, created_at);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_txn_wallet_id;
+DROP TABLE IF EXISTS wallet_quarantines;
+DROP TABLE IF EXISTS integrity_discrepancies;
+DROP TABLE IF EXISTS integrity_check_runs;
+
+COMMIT;
\ No newline at end of file
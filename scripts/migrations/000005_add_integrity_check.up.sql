@@ -0,0 +1,39 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS integrity_check_runs (
+    id BIGSERIAL PRIMARY KEY,
+    checked_wallets int NOT NULL DEFAULT 0,
+    discrepancies int NOT NULL DEFAULT 0,
+    quarantined int NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS integrity_discrepancies (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES integrity_check_runs(id) ON DELETE CASCADE,
+    wallet_id BIGINT NOT NULL REFERENCES wallets(id),
+    user_id BIGINT NOT NULL,
+    total_balance bigint NOT NULL,
+    expected_total_balance bigint NOT NULL,
+    available_balance bigint NOT NULL,
+    expected_available_balance bigint NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_integrity_discrepancies_run_id ON integrity_discrepancies (run_id);
+
+-- A wallet with an active quarantine (released_at IS NULL) cannot be debited
+CREATE TABLE IF NOT EXISTS wallet_quarantines (
+    id BIGSERIAL PRIMARY KEY,
+    wallet_id BIGINT NOT NULL REFERENCES wallets(id),
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    released_at TIMESTAMPTZ NULL
+);
+
+CREATE UNIQUE INDEX idx_wallet_quarantines_active ON wallet_quarantines (wallet_id)
+    WHERE released_at IS NULL;
+CREATE INDEX idx_txn_wallet_id ON transactions (wallet_id);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS wallet_status_history;
+ALTER TABLE wallets DROP COLUMN IF EXISTS status;
+
+COMMIT;
\ No newline at end of file
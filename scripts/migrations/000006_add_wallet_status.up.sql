@@ -0,0 +1,20 @@
+BEGIN;
+
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'frozen_debit', 'frozen_all', 'closed'));
+
+CREATE TABLE IF NOT EXISTS wallet_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    wallet_id BIGINT NOT NULL REFERENCES wallets(id),
+    user_id BIGINT NOT NULL,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    actor VARCHAR(100) NOT NULL,
+    payout_transaction_id UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_wallet_status_history_wallet_id ON wallet_status_history (wallet_id, created_at DESC);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,13 @@
+BEGIN;
+
+DROP TABLE IF EXISTS adjustments;
+DROP TABLE IF EXISTS transaction_attempts;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_source_check;
+ALTER TABLE transactions DROP COLUMN IF EXISTS source;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'failed', 'success'));
+
+COMMIT;
\ No newline at end of file
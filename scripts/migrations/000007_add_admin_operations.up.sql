@@ -0,0 +1,44 @@
+BEGIN;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'failed', 'success', 'cancelled'));
+
+-- source tells user initiated transactions apart from back office adjustments that never reach the bank
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'user';
+ALTER TABLE transactions ADD CONSTRAINT transactions_source_check
+    CHECK (source IN ('user', 'adjustment'));
+
+CREATE TABLE IF NOT EXISTS transaction_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    attempt int NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    bank_response_id UUID NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_transaction_attempts_transaction_id ON transaction_attempts (transaction_id, id);
+
+CREATE TABLE IF NOT EXISTS adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('credit', 'debit')),
+    amount bigint NOT NULL CHECK (amount > 0),
+    reason_code VARCHAR(20) NOT NULL CHECK (reason_code IN ('goodwill', 'correction', 'chargeback', 'fee_refund', 'other')),
+    note TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    idempotency_key UUID NOT NULL UNIQUE,
+    requested_by VARCHAR(100) NOT NULL,
+    decided_by VARCHAR(100) NULL,
+    transaction_id UUID NULL REFERENCES transactions(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    decided_at TIMESTAMPTZ NULL,
+    -- four-eyes principle: nobody approves their own adjustment
+    CHECK (status <> 'approved' OR decided_by <> requested_by)
+);
+
+CREATE INDEX idx_adjustments_status ON adjustments (status, id);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS audit_adjustments ON adjustments;
+DROP TRIGGER IF EXISTS audit_wallet_quarantines ON wallet_quarantines;
+DROP TRIGGER IF EXISTS audit_wallet_status_history ON wallet_status_history;
+DROP TRIGGER IF EXISTS audit_transactions ON transactions;
+DROP TRIGGER IF EXISTS audit_wallets ON wallets;
+
+DROP FUNCTION IF EXISTS audit_record_change();
+DROP FUNCTION IF EXISTS audit_log_hash(audit_log);
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+
+COMMIT;
\ No newline at end of file
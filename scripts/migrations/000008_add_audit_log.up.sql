@@ -0,0 +1,101 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    action VARCHAR(100) NOT NULL,
+    table_name VARCHAR(63) NOT NULL,
+    record_id TEXT NOT NULL,
+    before_value JSONB NULL,
+    after_value JSONB NULL,
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    source_ip VARCHAR(64) NOT NULL DEFAULT '',
+    prev_hash BYTEA NOT NULL,
+    hash BYTEA NOT NULL
+);
+
+CREATE INDEX idx_audit_log_record ON audit_log (table_name, record_id, id);
+
+-- audit_log_hash is the single definition of an entry hash, used both when writing and when verifying the chain.
+-- Fields are serialized as a JSON array so no value can bleed into its neighbour.
+CREATE OR REPLACE FUNCTION audit_log_hash(entry audit_log) RETURNS BYTEA
+LANGUAGE sql STABLE AS $$
+    SELECT sha256(convert_to(jsonb_build_array(
+        entry.id,
+        (extract(epoch FROM entry.occurred_at) * 1000000)::bigint,
+        entry.actor,
+        entry.action,
+        entry.table_name,
+        entry.record_id,
+        entry.before_value,
+        entry.after_value,
+        entry.request_id,
+        entry.source_ip,
+        encode(entry.prev_hash, 'hex')
+    )::text, 'UTF8'))
+$$;
+
+-- audit_record_change appends one entry per changed row. The actor, action, request ID and source IP are
+-- taken from the transaction local audit.* settings written by the application, falling back to the
+-- database user so manual changes are attributed too. The advisory lock serializes writers so every
+-- entry is chained to the one committed right before it.
+CREATE OR REPLACE FUNCTION audit_record_change() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+DECLARE
+    entry audit_log;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        entry.before_value := to_jsonb(OLD);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        entry.after_value := to_jsonb(NEW);
+    END IF;
+    IF entry.before_value = entry.after_value THEN
+        RETURN NULL;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('audit_log'));
+
+    entry.id := nextval(pg_get_serial_sequence('audit_log', 'id'));
+    entry.occurred_at := clock_timestamp();
+    entry.actor := COALESCE(NULLIF(current_setting('audit.actor', true), ''), session_user);
+    entry.action := COALESCE(NULLIF(current_setting('audit.action', true), ''), lower(TG_OP));
+    entry.table_name := TG_TABLE_NAME;
+    entry.record_id := COALESCE(entry.after_value, entry.before_value) ->> 'id';
+    entry.request_id := COALESCE(current_setting('audit.request_id', true), '');
+    entry.source_ip := COALESCE(current_setting('audit.source_ip', true), '');
+
+    SELECT hash INTO entry.prev_hash FROM audit_log ORDER BY id DESC LIMIT 1;
+    entry.prev_hash := COALESCE(entry.prev_hash, decode(repeat('00', 32), 'hex'));
+    entry.hash := audit_log_hash(entry);
+
+    INSERT INTO audit_log VALUES (entry.*);
+    RETURN NULL;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append only';
+END;
+$$;
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_wallets AFTER INSERT OR UPDATE OR DELETE ON wallets
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+CREATE TRIGGER audit_transactions AFTER INSERT OR UPDATE OR DELETE ON transactions
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+CREATE TRIGGER audit_wallet_status_history AFTER INSERT OR UPDATE OR DELETE ON wallet_status_history
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+CREATE TRIGGER audit_wallet_quarantines AFTER INSERT OR UPDATE OR DELETE ON wallet_quarantines
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+CREATE TRIGGER audit_adjustments AFTER INSERT OR UPDATE OR DELETE ON adjustments
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+
+COMMIT;
\ No newline at end of file
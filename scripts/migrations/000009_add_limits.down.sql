@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS user_limits;
+DROP TABLE IF EXISTS limit_defaults;
+
+COMMIT;
\ No newline at end of file
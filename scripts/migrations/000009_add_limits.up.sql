@@ -0,0 +1,33 @@
+BEGIN;
+
+-- limit_defaults holds a single row with the limits of every wallet without an override. NULL means unlimited.
+CREATE TABLE IF NOT EXISTS limit_defaults (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    max_single_withdrawal BIGINT NULL CHECK (max_single_withdrawal > 0),
+    daily_withdrawal_total BIGINT NULL CHECK (daily_withdrawal_total > 0),
+    monthly_withdrawal_total BIGINT NULL CHECK (monthly_withdrawal_total > 0),
+    max_balance BIGINT NULL CHECK (max_balance > 0),
+    hourly_withdrawal_count BIGINT NULL CHECK (hourly_withdrawal_count > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO limit_defaults (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+-- user_limits overrides the defaults per user. NULL falls back to the default.
+CREATE TABLE IF NOT EXISTS user_limits (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL UNIQUE,
+    max_single_withdrawal BIGINT NULL CHECK (max_single_withdrawal > 0),
+    daily_withdrawal_total BIGINT NULL CHECK (daily_withdrawal_total > 0),
+    monthly_withdrawal_total BIGINT NULL CHECK (monthly_withdrawal_total > 0),
+    max_balance BIGINT NULL CHECK (max_balance > 0),
+    hourly_withdrawal_count BIGINT NULL CHECK (hourly_withdrawal_count > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER audit_limit_defaults AFTER INSERT OR UPDATE OR DELETE ON limit_defaults
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+CREATE TRIGGER audit_user_limits AFTER INSERT OR UPDATE OR DELETE ON user_limits
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,23 @@
+BEGIN;
+
+DROP TABLE IF EXISTS risk_assessments;
+DROP INDEX IF EXISTS idx_transactions_review;
+
+-- withdrawals still waiting for review are given back to their wallets before the state disappears
+UPDATE wallets w
+SET available_balance = w.available_balance - r.amount,
+    updated_at = NOW()
+FROM (
+    SELECT wallet_id, SUM(amount) AS amount
+    FROM transactions
+    WHERE status = 'review'
+    GROUP BY wallet_id
+) r
+WHERE w.id = r.wallet_id;
+UPDATE transactions SET status = 'cancelled', updated_at = NOW() WHERE status = 'review';
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'failed', 'success', 'cancelled'));
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,28 @@
+BEGIN;
+
+-- review holds a withdrawal out of the withdraw job until an admin approves it
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'failed', 'success', 'cancelled', 'review'));
+
+-- risk_assessments keeps every withdrawal the risk rules did not allow, denied ones have no transaction
+CREATE TABLE IF NOT EXISTS risk_assessments (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    transaction_id UUID NULL UNIQUE REFERENCES transactions(id),
+    idempotency_key UUID NOT NULL,
+    amount bigint NOT NULL,
+    decision VARCHAR(20) NOT NULL CHECK (decision IN ('review', 'deny')),
+    reasons TEXT[] NOT NULL DEFAULT '{}',
+    decided_by VARCHAR(100) NULL,
+    decided_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_risk_assessments_user_id ON risk_assessments (user_id, id);
+CREATE INDEX idx_transactions_review ON transactions (id) WHERE status = 'review';
+
+CREATE TRIGGER audit_risk_assessments AFTER INSERT OR UPDATE OR DELETE ON risk_assessments
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+
+COMMIT;
\ No newline at end of file
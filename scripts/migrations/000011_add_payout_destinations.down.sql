@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS destination_id;
+DROP TABLE IF EXISTS payout_destinations;
+
+COMMIT;
\ No newline at end of file
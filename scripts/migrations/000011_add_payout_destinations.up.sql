@@ -0,0 +1,27 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS payout_destinations (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('iban', 'card')),
+    number VARCHAR(34) NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    verified_by VARCHAR(100) NULL,
+    verified_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, number)
+);
+
+-- a user has at most one default destination
+CREATE UNIQUE INDEX idx_payout_destinations_default ON payout_destinations (user_id) WHERE is_default;
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS destination_id BIGINT NULL REFERENCES payout_destinations(id);
+
+CREATE INDEX idx_transactions_destination_id ON transactions (destination_id) WHERE destination_id IS NOT NULL;
+
+CREATE TRIGGER audit_payout_destinations AFTER INSERT OR UPDATE OR DELETE ON payout_destinations
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,13 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_transactions_refund;
+DROP INDEX IF EXISTS idx_transactions_related_transaction_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS related_transaction_id;
+
+-- fees and refunds already moved balances, they are kept as adjustments
+UPDATE transactions SET source = 'adjustment' WHERE source IN ('fee', 'refund');
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_source_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_source_check
+    CHECK (source IN ('user', 'adjustment'));
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,15 @@
+BEGIN;
+
+-- fees are debits charged for a withdrawal and refunds are credits giving a fee back, neither reaches the bank
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_source_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_source_check
+    CHECK (source IN ('user', 'adjustment', 'fee', 'refund'));
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS related_transaction_id UUID NULL REFERENCES transactions(id);
+
+CREATE INDEX idx_transactions_related_transaction_id ON transactions (related_transaction_id) WHERE related_transaction_id IS NOT NULL;
+
+-- a transaction is refunded at most once
+CREATE UNIQUE INDEX idx_transactions_refund ON transactions (related_transaction_id) WHERE source = 'refund';
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,18 @@
+BEGIN;
+
+-- active holds give their amount back before they are dropped
+UPDATE wallets w
+SET available_balance = w.available_balance + h.amount,
+    updated_at = NOW()
+FROM (SELECT wallet_id, SUM(amount) AS amount FROM holds WHERE status = 'active' GROUP BY wallet_id) h
+WHERE w.id = h.wallet_id;
+
+DROP TABLE IF EXISTS holds;
+
+-- captures already moved balances, they are kept as adjustments
+UPDATE transactions SET source = 'adjustment' WHERE source = 'hold';
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_source_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_source_check
+    CHECK (source IN ('user', 'adjustment', 'fee', 'refund'));
+
+COMMIT;
\ No newline at end of file
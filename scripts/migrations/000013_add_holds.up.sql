@@ -0,0 +1,30 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    wallet_id BIGINT NOT NULL REFERENCES wallets(id),
+    user_id BIGINT NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    captured_amount BIGINT NOT NULL DEFAULT 0 CHECK (captured_amount >= 0 AND captured_amount <= amount),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'voided', 'expired')),
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    idempotency_key UUID NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    transaction_id UUID NULL REFERENCES transactions(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX idx_holds_user_id ON holds (user_id, created_at);
+CREATE INDEX idx_holds_expires_at ON holds (expires_at) WHERE status = 'active';
+
+-- captured holds are recorded as debits which never reach the bank
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_source_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_source_check
+    CHECK (source IN ('user', 'adjustment', 'fee', 'refund', 'hold'));
+
+CREATE TRIGGER audit_holds AFTER INSERT OR UPDATE OR DELETE ON holds
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+
+COMMIT;
\ No newline at end of file
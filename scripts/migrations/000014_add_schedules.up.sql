@@ -0,0 +1,30 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS schedules (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('credit', 'debit')),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+    interval_count INT NOT NULL DEFAULT 1 CHECK (interval_count > 0),
+    destination_id BIGINT NULL REFERENCES payout_destinations(id),
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ NULL,
+    -- occurrence is the number of the next occurrence, next_run_at is when it is due
+    occurrence BIGINT NOT NULL DEFAULT 0,
+    next_run_at TIMESTAMPTZ NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'completed', 'deleted')),
+    last_error TEXT NOT NULL DEFAULT '',
+    last_run_at TIMESTAMPTZ NULL,
+    claimed_until TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_schedules_user_id ON schedules (user_id, id);
+CREATE INDEX idx_schedules_due ON schedules (next_run_at) WHERE status = 'active';
+
+CREATE TRIGGER audit_schedules AFTER INSERT OR UPDATE OR DELETE ON schedules
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+
+COMMIT;
\ No newline at end of file
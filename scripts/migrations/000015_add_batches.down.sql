@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP TABLE IF EXISTS batch_rows;
+DROP TABLE IF EXISTS batches;
+
+-- applied rows already moved balances, they are kept as adjustments
+UPDATE transactions SET source = 'adjustment' WHERE source = 'batch';
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_source_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_source_check
+    CHECK (source IN ('user', 'adjustment', 'fee', 'refund', 'hold'));
+
+COMMIT;
\ No newline at end of file
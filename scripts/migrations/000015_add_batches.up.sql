@@ -0,0 +1,43 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS batches (
+    id BIGSERIAL PRIMARY KEY,
+    file_name VARCHAR(255) NOT NULL,
+    mode VARCHAR(20) NOT NULL CHECK (mode IN ('partial', 'all_or_nothing')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+    total_rows INT NOT NULL CHECK (total_rows > 0),
+    created_by VARCHAR(100) NOT NULL,
+    claimed_until TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ NULL
+);
+
+CREATE TABLE IF NOT EXISTS batch_rows (
+    id BIGSERIAL PRIMARY KEY,
+    batch_id BIGINT NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    line INT NOT NULL,
+    user_id BIGINT NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('credit', 'debit')),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    idempotency_key UUID NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed', 'rolled_back')),
+    error TEXT NOT NULL DEFAULT '',
+    transaction_id UUID NULL REFERENCES transactions(id),
+    processed_at TIMESTAMPTZ NULL,
+    UNIQUE (batch_id, line)
+);
+
+CREATE INDEX idx_batches_claimable ON batches (id) WHERE status IN ('pending', 'processing');
+CREATE INDEX idx_batch_rows_status ON batch_rows (batch_id, status, line);
+
+-- batch rows move both balances at once and never reach the bank
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_source_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_source_check
+    CHECK (source IN ('user', 'adjustment', 'fee', 'refund', 'hold', 'batch'));
+
+-- the transactions of the rows are audited, the rows themselves are not
+CREATE TRIGGER audit_batches AFTER INSERT OR UPDATE OR DELETE ON batches
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+
+COMMIT;
\ No newline at end of file
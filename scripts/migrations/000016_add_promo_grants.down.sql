@@ -0,0 +1,16 @@
+BEGIN;
+
+DROP TABLE IF EXISTS promo_grants;
+
+ALTER TABLE holds DROP COLUMN IF EXISTS promo_amount;
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_promo_check;
+ALTER TABLE wallets DROP COLUMN IF EXISTS promo_reserved;
+ALTER TABLE wallets DROP COLUMN IF EXISTS promo_balance;
+
+-- granted and expired promotional credit already moved balances, it is kept as adjustments
+UPDATE transactions SET source = 'adjustment' WHERE source = 'promo';
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_source_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_source_check
+    CHECK (source IN ('user', 'adjustment', 'fee', 'refund', 'hold', 'batch'));
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,42 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS promo_grants (
+    id BIGSERIAL PRIMARY KEY,
+    wallet_id BIGINT NOT NULL REFERENCES wallets(id),
+    user_id BIGINT NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    remaining BIGINT NOT NULL CHECK (remaining >= 0),
+    expired_amount BIGINT NOT NULL DEFAULT 0 CHECK (expired_amount >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'spent', 'expired')),
+    reason VARCHAR(255) NOT NULL,
+    created_by VARCHAR(100) NOT NULL,
+    idempotency_key UUID NOT NULL UNIQUE,
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ NULL,
+    CHECK (remaining + expired_amount <= amount)
+);
+
+CREATE INDEX idx_promo_grants_user_id ON promo_grants (user_id, created_at);
+CREATE INDEX idx_promo_grants_spending ON promo_grants (wallet_id, expires_at, id) WHERE status = 'active';
+CREATE INDEX idx_promo_grants_expires_at ON promo_grants (expires_at) WHERE status = 'active';
+
+-- promo_balance is the promotional part of total_balance, promo_reserved the part of it reserved by active holds
+ALTER TABLE wallets ADD COLUMN promo_balance BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD COLUMN promo_reserved BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD CONSTRAINT wallets_promo_check CHECK (promo_reserved >= 0 AND promo_reserved <= promo_balance);
+
+-- holds reserve promotional credit before cash
+ALTER TABLE holds ADD COLUMN promo_amount BIGINT NOT NULL DEFAULT 0 CHECK (promo_amount >= 0);
+
+-- promotional credit is granted and taken back with transactions which never reach the bank
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_source_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_source_check
+    CHECK (source IN ('user', 'adjustment', 'fee', 'refund', 'hold', 'batch', 'promo'));
+
+CREATE TRIGGER audit_promo_grants AFTER INSERT OR UPDATE OR DELETE ON promo_grants
+    FOR EACH ROW EXECUTE FUNCTION audit_record_change();
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS sent_to_bank;
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+-- sent_to_bank is set when the withdraw job claims a debit and never reset, last_retry only paces the retries.
+-- A debit the bank may have seen can neither be cancelled nor refunded by anything but the bank outcome.
+ALTER TABLE transactions ADD COLUMN sent_to_bank BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE transactions t
+SET sent_to_bank = TRUE
+WHERE t.type = 'debit'
+  AND (t.last_retry IS NOT NULL
+    OR t.bank_response_id IS NOT NULL
+    OR EXISTS (SELECT 1 FROM transaction_attempts a WHERE a.transaction_id = t.id));
+
+COMMIT;
@@ -0,0 +1,43 @@
+BEGIN;
+
+-- ids are taken from the sequence again, it continues after the last entry
+SELECT setval(pg_get_serial_sequence('audit_log', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM audit_log;
+
+CREATE OR REPLACE FUNCTION audit_record_change() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+DECLARE
+    entry audit_log;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        entry.before_value := to_jsonb(OLD);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        entry.after_value := to_jsonb(NEW);
+    END IF;
+    IF entry.before_value = entry.after_value THEN
+        RETURN NULL;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('audit_log'));
+
+    entry.id := nextval(pg_get_serial_sequence('audit_log', 'id'));
+    entry.occurred_at := clock_timestamp();
+    entry.actor := COALESCE(NULLIF(current_setting('audit.actor', true), ''), session_user);
+    entry.action := COALESCE(NULLIF(current_setting('audit.action', true), ''), lower(TG_OP));
+    entry.table_name := TG_TABLE_NAME;
+    entry.record_id := COALESCE(entry.after_value, entry.before_value) ->> 'id';
+    entry.request_id := COALESCE(current_setting('audit.request_id', true), '');
+    entry.source_ip := COALESCE(current_setting('audit.source_ip', true), '');
+
+    SELECT hash INTO entry.prev_hash FROM audit_log ORDER BY id DESC LIMIT 1;
+    entry.prev_hash := COALESCE(entry.prev_hash, decode(repeat('00', 32), 'hex'));
+    entry.hash := audit_log_hash(entry);
+
+    INSERT INTO audit_log VALUES (entry.*);
+    RETURN NULL;
+END;
+$$;
+
+DROP TABLE IF EXISTS audit_chain_head;
+
+COMMIT;
@@ -0,0 +1,57 @@
+BEGIN;
+
+-- The head is the last entry of the audit chain. Every writer locks the head row instead of taking the advisory
+-- lock, chains its entry to the head and moves the head to it. Ids are taken from the head as well, so a rolled
+-- back write leaves no gap and a missing id is a removed entry. Entries removed from the end of the chain leave
+-- the head behind. Ids up to gapless_after were taken from a sequence and may skip rolled back writes.
+CREATE TABLE IF NOT EXISTS audit_chain_head (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_id BIGINT NOT NULL,
+    last_hash BYTEA NOT NULL,
+    gapless_after BIGINT NOT NULL
+);
+
+INSERT INTO audit_chain_head (last_id, last_hash, gapless_after)
+SELECT COALESCE(MAX(id), 0),
+       COALESCE((SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1), decode(repeat('00', 32), 'hex')),
+       COALESCE(MAX(id), 0)
+FROM audit_log;
+
+CREATE TRIGGER audit_chain_head_no_delete BEFORE DELETE ON audit_chain_head
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE TRIGGER audit_chain_head_no_truncate BEFORE TRUNCATE ON audit_chain_head
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+CREATE OR REPLACE FUNCTION audit_record_change() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+DECLARE
+    entry audit_log;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        entry.before_value := to_jsonb(OLD);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        entry.after_value := to_jsonb(NEW);
+    END IF;
+    IF entry.before_value = entry.after_value THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT last_id + 1, last_hash INTO entry.id, entry.prev_hash FROM audit_chain_head FOR UPDATE;
+
+    entry.occurred_at := clock_timestamp();
+    entry.actor := COALESCE(NULLIF(current_setting('audit.actor', true), ''), session_user);
+    entry.action := COALESCE(NULLIF(current_setting('audit.action', true), ''), lower(TG_OP));
+    entry.table_name := TG_TABLE_NAME;
+    entry.record_id := COALESCE(entry.after_value, entry.before_value) ->> 'id';
+    entry.request_id := COALESCE(current_setting('audit.request_id', true), '');
+    entry.source_ip := COALESCE(current_setting('audit.source_ip', true), '');
+    entry.hash := audit_log_hash(entry);
+
+    INSERT INTO audit_log VALUES (entry.*);
+    UPDATE audit_chain_head SET last_id = entry.id, last_hash = entry.hash;
+    RETURN NULL;
+END;
+$$;
+
+COMMIT;
@@ -0,0 +1,39 @@
+BEGIN;
+
+-- staged rows are applied in full, their credits are already in the total balance and their debits reserved
+WITH staged AS (
+    UPDATE batch_rows
+    SET status = 'succeeded', processed_at = NOW()
+    WHERE status = 'staged'
+    RETURNING transaction_id
+),
+released_txn AS (
+    UPDATE transactions t
+    SET released = TRUE, released_at = NOW(), updated_at = NOW()
+    FROM staged s
+    WHERE t.id = s.transaction_id AND t.type = 'credit'
+    RETURNING t.wallet_id, t.amount
+),
+due_debits AS (
+    UPDATE transactions t
+    SET release_time = NOW(), updated_at = NOW()
+    FROM staged s
+    WHERE (t.id = s.transaction_id OR t.related_transaction_id = s.transaction_id)
+      AND t.type = 'debit' AND t.release_time IS NULL
+),
+per_wallet AS (
+    SELECT wallet_id, SUM(amount) AS amount
+    FROM released_txn
+    GROUP BY wallet_id
+)
+UPDATE wallets w
+SET available_balance = w.available_balance + p.amount,
+    updated_at = NOW()
+FROM per_wallet p
+WHERE w.id = p.wallet_id;
+
+ALTER TABLE batch_rows DROP CONSTRAINT IF EXISTS batch_rows_status_check;
+ALTER TABLE batch_rows ADD CONSTRAINT batch_rows_status_check
+    CHECK (status IN ('pending', 'succeeded', 'failed', 'rolled_back'));
+
+COMMIT;
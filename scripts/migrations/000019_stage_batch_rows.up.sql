@@ -0,0 +1,8 @@
+BEGIN;
+
+-- staged rows of an all or nothing batch are applied without being released and wait for the rest of the batch
+ALTER TABLE batch_rows DROP CONSTRAINT IF EXISTS batch_rows_status_check;
+ALTER TABLE batch_rows ADD CONSTRAINT batch_rows_status_check
+    CHECK (status IN ('pending', 'staged', 'succeeded', 'failed', 'rolled_back'));
+
+COMMIT;
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations so every binary can apply and check the schema it needs.
+// The files follow the golang-migrate naming, {version}_{name}.up.sql and {version}_{name}.down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
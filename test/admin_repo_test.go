@@ -0,0 +1,231 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"testing"
+)
+
+// quarantine blocks the debits of the wallet of the user like the integrity job does
+func quarantine(t *testing.T, userId int64) {
+	t.Helper()
+	_, err := db.Exec(context.Background(), `
+INSERT INTO wallet_quarantines (wallet_id, reason)
+SELECT id, 'integrity check' FROM wallets WHERE user_id = $1
+`, userId)
+	if err != nil {
+		t.Fatalf("could not quarantine wallet: %v", err)
+	}
+}
+
+func TestSearchWallets(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	for userId := int64(1); userId <= 3; userId++ {
+		repotest.Charge(t, subject, userId, userId*100)
+	}
+	if _, err := repo.UpdateWalletStatus(ctx, 2, entity.FROZEN_ALL, "fraud", "admin"); err != nil {
+		t.Fatalf("update wallet status failed: %v", err)
+	}
+	quarantine(t, 3)
+
+	all, err := repo.SearchWallets(ctx, nil, "", 0, 10)
+	if err != nil {
+		t.Fatalf("search wallets failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 wallets, got %d", len(all))
+	}
+	if !all[2].Quarantined || all[0].Quarantined {
+		t.Fatalf("expected only the third wallet to be quarantined, got %+v", all)
+	}
+
+	page, err := repo.SearchWallets(ctx, nil, "", all[0].ID, 1)
+	if err != nil {
+		t.Fatalf("search wallets failed: %v", err)
+	}
+	if len(page) != 1 || page[0].UserID != 2 {
+		t.Fatalf("expected the page after the first wallet to hold user 2, got %+v", page)
+	}
+
+	frozen, err := repo.SearchWallets(ctx, nil, entity.FROZEN_ALL, 0, 10)
+	if err != nil {
+		t.Fatalf("search wallets failed: %v", err)
+	}
+	if len(frozen) != 1 || frozen[0].UserID != 2 {
+		t.Fatalf("expected only user 2 to be frozen, got %+v", frozen)
+	}
+
+	userId := int64(3)
+	byUser, err := repo.SearchWallets(ctx, &userId, "", 0, 10)
+	if err != nil {
+		t.Fatalf("search wallets failed: %v", err)
+	}
+	if len(byUser) != 1 || byUser[0].TotalBalance != 300 {
+		t.Fatalf("expected the wallet of user 3, got %+v", byUser)
+	}
+}
+
+func TestQuarantinedWallet(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	quarantine(t, 1)
+
+	if _, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), 100, 0, repotest.Later(subject), nil, nil); !errors.Is(err, entity.ErrWalletQuarantined) {
+		t.Fatalf("expected ErrWalletQuarantined for a debit, got %v", err)
+	}
+	if _, err := repo.PlaceHold(ctx, 1, repotest.NewKey(t), 100, "order", *repotest.Later(subject)); !errors.Is(err, entity.ErrWalletQuarantined) {
+		t.Fatalf("expected ErrWalletQuarantined for a hold, got %v", err)
+	}
+	// quarantined wallets are still charged
+	repotest.Charge(t, subject, 1, 100)
+	repotest.ExpectBalance(t, subject, 1, 1100, 1100)
+}
+
+func TestGetTransaction(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+
+	if _, err := repo.GetTransaction(ctx, repotest.NewKey(t)); !errors.Is(err, entity.ErrTransactionNotFound) {
+		t.Fatalf("expected ErrTransactionNotFound, got %v", err)
+	}
+	id := repotest.Charge(t, subject, 1, 100)
+	details, err := repo.GetTransaction(ctx, id)
+	if err != nil {
+		t.Fatalf("get transaction failed: %v", err)
+	}
+	if details.ID != *id || details.UserID != 1 || details.Type != entity.CREDIT || details.Amount != 100 ||
+		details.Source != entity.USER_SOURCE || !details.Released || len(details.Attempts) != 0 {
+		t.Fatalf("unexpected transaction %+v", details)
+	}
+}
+
+func TestReinquireTransaction(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	credit := repotest.Charge(t, subject, 1, 1000)
+	id := repotest.Debit(t, subject, 1, 100, 0)
+
+	// a debit the bank has never seen has nothing to inquire about
+	if err := repo.ReinquireTransaction(ctx, id); !errors.Is(err, entity.ErrNotPending) {
+		t.Fatalf("expected ErrNotPending for a debit not sent to the bank, got %v", err)
+	}
+	repotest.MakeDue(subject)
+	repotest.Release(t, subject)
+	if _, err := repo.GetPendingTransactions(ctx, 10); err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if err := repo.ReinquireTransaction(ctx, id); err != nil {
+		t.Fatalf("reinquire transaction failed: %v", err)
+	}
+	// the bank may still pay the reinquired debit out, so it must not be refunded by a cancel
+	if err := repo.CancelDebit(ctx, 1, id); !errors.Is(err, entity.ErrNotCancellable) {
+		t.Fatalf("expected ErrNotCancellable for a reinquired debit, got %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 900, 900)
+	if details, err := repo.GetTransaction(ctx, id); err != nil || !details.SentToBank {
+		t.Fatalf("expected the debit to stay marked as sent to the bank, got %+v and %v", details, err)
+	}
+	claimed, err := repo.GetPendingTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != *id {
+		t.Fatalf("expected the reinquired debit to be claimed again, got %+v", claimed)
+	}
+
+	if err := repo.ReinquireTransaction(ctx, credit); !errors.Is(err, entity.ErrNotPending) {
+		t.Fatalf("expected ErrNotPending for a credit, got %v", err)
+	}
+	if err := repo.UpdateTransactionStatus(ctx, id, entity.SUCCESS, repotest.NewKey(t)); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	if err := repo.ReinquireTransaction(ctx, id); !errors.Is(err, entity.ErrNotPending) {
+		t.Fatalf("expected ErrNotPending for a settled debit, got %v", err)
+	}
+}
+
+func TestAdjustments(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 100)
+
+	credit := &entity.Adjustment{UserID: 1, Type: entity.CREDIT, Amount: 50, ReasonCode: entity.GOODWILL, Note: "sorry",
+		Idempotency: *repotest.NewKey(t), RequestedBy: "alice"}
+	creditID, err := repo.CreateAdjustment(ctx, credit)
+	if err != nil {
+		t.Fatalf("create adjustment failed: %v", err)
+	}
+	pending, err := repo.GetAdjustments(ctx, entity.ADJUSTMENT_PENDING, 10)
+	if err != nil {
+		t.Fatalf("get adjustments failed: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != creditID {
+		t.Fatalf("expected the pending adjustment, got %+v", pending)
+	}
+	// a requested adjustment does not touch the wallet until it is approved
+	repotest.ExpectBalance(t, subject, 1, 100, 100)
+
+	if _, err := repo.ApproveAdjustment(ctx, creditID, "alice"); !errors.Is(err, entity.ErrSelfApproval) {
+		t.Fatalf("expected ErrSelfApproval, got %v", err)
+	}
+	approved, err := repo.ApproveAdjustment(ctx, creditID, "bob")
+	if err != nil {
+		t.Fatalf("approve adjustment failed: %v", err)
+	}
+	if approved.Status != entity.ADJUSTMENT_APPROVED || approved.TransactionID == nil || *approved.DecidedBy != "bob" {
+		t.Fatalf("unexpected approved adjustment %+v", approved)
+	}
+	// adjustments move both balances at once
+	repotest.ExpectBalance(t, subject, 1, 150, 150)
+	if _, err := repo.ApproveAdjustment(ctx, creditID, "bob"); !errors.Is(err, entity.ErrAdjustmentDecided) {
+		t.Fatalf("expected ErrAdjustmentDecided, got %v", err)
+	}
+	if _, err := repo.RejectAdjustment(ctx, creditID, "bob"); !errors.Is(err, entity.ErrAdjustmentDecided) {
+		t.Fatalf("expected ErrAdjustmentDecided, got %v", err)
+	}
+
+	overdraw := &entity.Adjustment{UserID: 1, Type: entity.DEBIT, Amount: 151, ReasonCode: entity.CHARGEBACK,
+		Idempotency: *repotest.NewKey(t), RequestedBy: "alice"}
+	overdrawID, err := repo.CreateAdjustment(ctx, overdraw)
+	if err != nil {
+		t.Fatalf("create adjustment failed: %v", err)
+	}
+	if _, err := repo.ApproveAdjustment(ctx, overdrawID, "bob"); !errors.Is(err, entity.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	rejected, err := repo.RejectAdjustment(ctx, overdrawID, "bob")
+	if err != nil {
+		t.Fatalf("reject adjustment failed: %v", err)
+	}
+	if rejected.Status != entity.ADJUSTMENT_REJECTED || rejected.TransactionID != nil {
+		t.Fatalf("unexpected rejected adjustment %+v", rejected)
+	}
+	repotest.ExpectBalance(t, subject, 1, 150, 150)
+
+	debitID, err := repo.CreateAdjustment(ctx, &entity.Adjustment{UserID: 1, Type: entity.DEBIT, Amount: 150,
+		ReasonCode: entity.CORRECTION, Idempotency: *repotest.NewKey(t), RequestedBy: "alice"})
+	if err != nil {
+		t.Fatalf("create adjustment failed: %v", err)
+	}
+	if _, err := repo.ApproveAdjustment(ctx, debitID, "bob"); err != nil {
+		t.Fatalf("approve adjustment failed: %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 0, 0)
+
+	if _, err := repo.ApproveAdjustment(ctx, 999, "bob"); !errors.Is(err, entity.ErrAdjustmentNotFound) {
+		t.Fatalf("expected ErrAdjustmentNotFound, got %v", err)
+	}
+	decided, err := repo.GetAdjustments(ctx, entity.ADJUSTMENT_REJECTED, 10)
+	if err != nil {
+		t.Fatalf("get adjustments failed: %v", err)
+	}
+	if len(decided) != 1 || decided[0].ID != overdrawID {
+		t.Fatalf("expected the rejected adjustment, got %+v", decided)
+	}
+}
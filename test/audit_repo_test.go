@@ -0,0 +1,119 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"github.com/MaisamV/wallet/internal/audit/application/command"
+	"github.com/MaisamV/wallet/internal/audit/entity"
+	auditInfrastructure "github.com/MaisamV/wallet/internal/audit/infrastructure"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"github.com/MaisamV/wallet/platform/logger"
+	"testing"
+)
+
+func verifyAuditChain(t *testing.T) *entity.VerifyResult {
+	t.Helper()
+	handler := command.NewVerifyChainCommandHandler(logger.NewNoopLogger(), auditInfrastructure.NewPgxAuditRepo(logger.NewNoopLogger(), db))
+	result, err := handler.Handle(context.Background(), command.VerifyChainCommand{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("verify chain failed: %v", err)
+	}
+	return result
+}
+
+// removeAuditEntries deletes the audit entries matched by where around the append only trigger and puts them back
+// once verify has run, the audit log is shared by every test
+func removeAuditEntries(t *testing.T, where string, verify func()) {
+	t.Helper()
+	ctx := context.Background()
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("could not acquire connection: %v", err)
+	}
+	defer conn.Release()
+	exec := func(sql string) {
+		t.Helper()
+		if _, err := conn.Exec(ctx, sql); err != nil {
+			t.Fatalf("could not run %q: %v", sql, err)
+		}
+	}
+
+	exec("CREATE TEMP TABLE removed_audit_entries AS SELECT * FROM audit_log WHERE " + where)
+	exec("ALTER TABLE audit_log DISABLE TRIGGER audit_log_no_update")
+	defer func() {
+		exec("INSERT INTO audit_log SELECT * FROM removed_audit_entries")
+		exec("ALTER TABLE audit_log ENABLE TRIGGER audit_log_no_update")
+		exec("DROP TABLE removed_audit_entries")
+	}()
+	exec("DELETE FROM audit_log WHERE id IN (SELECT id FROM removed_audit_entries)")
+	verify()
+}
+
+func expectBreak(t *testing.T, result *entity.VerifyResult, breakType entity.BreakType) {
+	t.Helper()
+	for _, b := range result.Breaks {
+		if b.Type == breakType {
+			return
+		}
+	}
+	t.Fatalf("expected a %s break, got %+v", breakType, result.Breaks)
+}
+
+func TestAuditChain(t *testing.T) {
+	reset(t)
+	repotest.Charge(t, subject, 1, 1000)
+	repotest.Charge(t, subject, 2, 1000)
+	repotest.Debit(t, subject, 1, 100, 0)
+
+	// every audited write is chained to the one committed right before it and takes the next id
+	result := verifyAuditChain(t)
+	if !result.Intact() || result.Checked == 0 {
+		t.Fatalf("expected an intact chain, got %+v", result)
+	}
+	var lastID int64
+	if err := db.QueryRow(context.Background(), "SELECT last_id FROM audit_chain_head").Scan(&lastID); err != nil {
+		t.Fatalf("could not read audit chain head: %v", err)
+	}
+	if result.LastID != lastID {
+		t.Fatalf("expected the chain to be walked up to its head %d, got %d", lastID, result.LastID)
+	}
+}
+
+func TestAuditChain_removedEntries(t *testing.T) {
+	reset(t)
+	repotest.Charge(t, subject, 1, 1000)
+	repotest.Charge(t, subject, 2, 1000)
+	repotest.Debit(t, subject, 1, 100, 0)
+	repotest.Charge(t, subject, 3, 500)
+
+	t.Run("last entries of a record", func(t *testing.T) {
+		removeAuditEntries(t, `id IN (
+    SELECT id FROM audit_log
+    WHERE table_name = 'wallets' AND record_id = (SELECT id::text FROM wallets WHERE user_id = 1)
+    ORDER BY id DESC LIMIT 2)`, func() {
+			result := verifyAuditChain(t)
+			expectBreak(t, result, entity.ID_GAP)
+			expectBreak(t, result, entity.LINK_BROKEN)
+		})
+	})
+	t.Run("every entry of a record", func(t *testing.T) {
+		removeAuditEntries(t, `table_name = 'wallets' AND record_id = (SELECT id::text FROM wallets WHERE user_id = 2)`, func() {
+			result := verifyAuditChain(t)
+			expectBreak(t, result, entity.ID_GAP)
+			expectBreak(t, result, entity.LINK_BROKEN)
+		})
+	})
+	t.Run("newest entries", func(t *testing.T) {
+		removeAuditEntries(t, `id > (SELECT last_id - 3 FROM audit_chain_head)`, func() {
+			result := verifyAuditChain(t)
+			if len(result.Breaks) != 1 || result.Breaks[0].Type != entity.TAIL_MISSING {
+				t.Fatalf("expected only the tail to be missing, got %+v", result.Breaks)
+			}
+		})
+	})
+
+	if result := verifyAuditChain(t); !result.Intact() {
+		t.Fatalf("expected the restored chain to be intact, got %+v", result.Breaks)
+	}
+}
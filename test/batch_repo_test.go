@@ -0,0 +1,433 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	walletrepo "github.com/MaisamV/wallet/internal/wallet/ports/repo"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"testing"
+	"time"
+)
+
+func createBatch(t *testing.T, mode entity.BatchMode, rows []entity.BatchRow) *entity.Batch {
+	t.Helper()
+	batch, err := repo.CreateBatch(context.Background(), &entity.Batch{FileName: "payouts.csv", Mode: mode, CreatedBy: "ops"}, rows)
+	if err != nil {
+		t.Fatalf("create batch failed: %v", err)
+	}
+	return batch
+}
+
+// noDebits is handed to batches of credits only
+var noDebits = repotest.BatchDebits(0, nil)
+
+func batchRow(t *testing.T, line int, userId int64, txType entity.TransactionType, amount int64) entity.BatchRow {
+	return entity.BatchRow{Line: line, UserID: userId, Type: txType, Amount: amount, Idempotency: *repotest.NewKey(t)}
+}
+
+func TestBatch_partial(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 2, 100)
+	if _, err := repo.UpdateWalletStatus(ctx, 2, entity.FROZEN_ALL, "fraud", "admin"); err != nil {
+		t.Fatalf("update wallet status failed: %v", err)
+	}
+
+	batch := createBatch(t, entity.BATCH_PARTIAL, []entity.BatchRow{
+		batchRow(t, 1, 1, entity.CREDIT, 100),
+		batchRow(t, 2, 1, entity.CREDIT, 50),
+		batchRow(t, 3, 2, entity.CREDIT, 500),
+	})
+	if batch.Status != entity.BATCH_PENDING || batch.TotalRows != 3 || batch.Pending != 3 {
+		t.Fatalf("unexpected batch %+v", batch)
+	}
+	batches, err := repo.GetBatches(ctx, 10)
+	if err != nil {
+		t.Fatalf("get batches failed: %v", err)
+	}
+	if len(batches) != 1 || batches[0].ID != batch.ID {
+		t.Fatalf("expected the created batch, got %+v", batches)
+	}
+	if _, err := repo.GetBatch(ctx, 999); !errors.Is(err, entity.ErrBatchNotFound) {
+		t.Fatalf("expected ErrBatchNotFound, got %v", err)
+	}
+
+	claimed, err := repo.ClaimBatch(ctx, time.Minute)
+	if err != nil {
+		t.Fatalf("claim batch failed: %v", err)
+	}
+	if claimed == nil || claimed.ID != batch.ID || claimed.Status != entity.BATCH_PROCESSING {
+		t.Fatalf("expected the batch to be claimed, got %+v", claimed)
+	}
+	if again, err := repo.ClaimBatch(ctx, time.Minute); err != nil || again != nil {
+		t.Fatalf("expected a claimed batch not to be claimed twice, got %+v, %v", again, err)
+	}
+
+	rows, err := repo.GetBatchRows(ctx, batch.ID, entity.BATCH_ROW_PENDING, 0, 10)
+	if err != nil {
+		t.Fatalf("get batch rows failed: %v", err)
+	}
+	for _, row := range rows {
+		_, err := repo.ApplyBatchRow(ctx, row, noDebits)
+		if err == nil {
+			continue
+		}
+		if !entity.IsRejection(err) {
+			t.Fatalf("line %d: expected a rejection, got %v", row.Line, err)
+		}
+		if err := repo.FailBatchRow(ctx, row.ID, err.Error()); err != nil {
+			t.Fatalf("fail batch row failed: %v", err)
+		}
+	}
+	// a row is never applied twice
+	if _, err := repo.ApplyBatchRow(ctx, rows[0], noDebits); err == nil {
+		t.Fatalf("expected an applied row not to be applied again")
+	}
+
+	completed, err := repo.CompleteBatch(ctx, batch.ID)
+	if err != nil {
+		t.Fatalf("complete batch failed: %v", err)
+	}
+	if completed.Status != entity.BATCH_COMPLETED || completed.Succeeded != 2 || completed.Failed != 1 || completed.Pending != 0 {
+		t.Fatalf("unexpected completed batch %+v", completed)
+	}
+	repotest.ExpectBalance(t, subject, 1, 150, 150)
+	repotest.ExpectBalance(t, subject, 2, 100, 100)
+
+	failed, err := repo.GetBatchRows(ctx, batch.ID, entity.BATCH_ROW_FAILED, 0, 10)
+	if err != nil {
+		t.Fatalf("get batch rows failed: %v", err)
+	}
+	if len(failed) != 1 || failed[0].Line != 3 || failed[0].Error == "" {
+		t.Fatalf("expected line 3 to fail with its reason, got %+v", failed)
+	}
+	after, err := repo.GetBatchRows(ctx, batch.ID, "", 1, 10)
+	if err != nil {
+		t.Fatalf("get batch rows failed: %v", err)
+	}
+	if len(after) != 2 || after[0].Line != 2 || after[0].TransactionID == nil {
+		t.Fatalf("expected the rows after line 1, got %+v", after)
+	}
+}
+
+// stageAll stages the pending rows of a batch one row per database transaction
+func stageAll(t *testing.T, batchID int64, debits walletrepo.BatchDebits) {
+	t.Helper()
+	for {
+		staged, err := repo.StageBatchRows(context.Background(), batchID, 1, debits)
+		if err != nil {
+			t.Fatalf("stage batch rows failed: %v", err)
+		}
+		if staged == 0 {
+			return
+		}
+	}
+}
+
+func TestBatch_allOrNothing(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 2, 100)
+	if _, err := repo.UpdateWalletStatus(ctx, 2, entity.FROZEN_ALL, "fraud", "admin"); err != nil {
+		t.Fatalf("update wallet status failed: %v", err)
+	}
+
+	rejected := createBatch(t, entity.BATCH_ALL_OR_NOTHING, []entity.BatchRow{
+		batchRow(t, 1, 1, entity.CREDIT, 100),
+		batchRow(t, 2, 1, entity.CREDIT, 50),
+		batchRow(t, 3, 2, entity.CREDIT, 500),
+	})
+	if _, err := repo.ClaimBatch(ctx, time.Minute); err != nil {
+		t.Fatalf("claim batch failed: %v", err)
+	}
+	stageAll(t, rejected.ID, noDebits)
+	staged, err := repo.GetBatch(ctx, rejected.ID)
+	if err != nil {
+		t.Fatalf("get batch failed: %v", err)
+	}
+	// staged credits count towards the total balance only and are never released by the release worker
+	if staged.Staged != 2 || staged.Failed != 1 || staged.Pending != 0 {
+		t.Fatalf("unexpected staged batch %+v", staged)
+	}
+	if still, err := repo.CompleteBatch(ctx, rejected.ID); err != nil || still.Status != entity.BATCH_PROCESSING {
+		t.Fatalf("expected a batch with staged rows not to complete, got %+v, %v", still, err)
+	}
+	repotest.Release(t, subject)
+	repotest.ExpectBalance(t, subject, 1, 150, 0)
+
+	// a single rejected row rolls the whole batch back
+	for {
+		rolledBack, err := repo.RollBackBatchRows(ctx, rejected.ID, 1)
+		if err != nil {
+			t.Fatalf("roll back batch rows failed: %v", err)
+		}
+		if rolledBack == 0 {
+			break
+		}
+	}
+	failed, err := repo.FailBatch(ctx, rejected.ID)
+	if err != nil {
+		t.Fatalf("fail batch failed: %v", err)
+	}
+	if failed.Status != entity.BATCH_FAILED || failed.Failed != 1 || failed.RolledBack != 2 || failed.Staged != 0 {
+		t.Fatalf("unexpected failed batch %+v", failed)
+	}
+	repotest.ExpectBalance(t, subject, 1, 0, 0)
+	repotest.ExpectBalance(t, subject, 2, 100, 100)
+
+	accepted := createBatch(t, entity.BATCH_ALL_OR_NOTHING, []entity.BatchRow{
+		batchRow(t, 1, 1, entity.CREDIT, 100),
+		batchRow(t, 2, 1, entity.CREDIT, 50),
+		batchRow(t, 3, 3, entity.CREDIT, 100),
+	})
+	if _, err := repo.ClaimBatch(ctx, time.Minute); err != nil {
+		t.Fatalf("claim batch failed: %v", err)
+	}
+	stageAll(t, accepted.ID, noDebits)
+	released, err := repo.ReleaseBatchRows(ctx, accepted.ID, 10)
+	if err != nil {
+		t.Fatalf("release batch rows failed: %v", err)
+	}
+	if released != 3 {
+		t.Fatalf("expected 3 released rows, got %d", released)
+	}
+	completed, err := repo.CompleteBatch(ctx, accepted.ID)
+	if err != nil {
+		t.Fatalf("complete batch failed: %v", err)
+	}
+	if completed.Status != entity.BATCH_COMPLETED || completed.Succeeded != 3 {
+		t.Fatalf("unexpected completed batch %+v", completed)
+	}
+	repotest.ExpectBalance(t, subject, 1, 150, 150)
+	repotest.ExpectBalance(t, subject, 3, 100, 100)
+}
+
+func TestBatch_idempotencyCollision(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	key := repotest.NewKey(t)
+	if _, err := repo.Charge(ctx, 1, key, 100, nil); err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+	row := batchRow(t, 1, 2, entity.CREDIT, 100)
+	createBatch(t, entity.BATCH_PARTIAL, []entity.BatchRow{row})
+
+	candidates := []entity.BatchRow{
+		{Line: 1, UserID: 1, Type: entity.CREDIT, Amount: 100, Idempotency: *key},
+		{Line: 2, UserID: 3, Type: entity.CREDIT, Amount: 100, Idempotency: *key},
+		{Line: 3, UserID: 2, Type: entity.CREDIT, Amount: 100, Idempotency: row.Idempotency},
+		batchRow(t, 4, 2, entity.CREDIT, 100),
+	}
+	used, err := repo.UsedIdempotencies(ctx, candidates)
+	if err != nil {
+		t.Fatalf("used idempotencies failed: %v", err)
+	}
+	// the key of the charge is only used for its own user, the key of the batch row for everyone
+	if len(used) != 2 {
+		t.Fatalf("expected 2 used idempotencies, got %v", used)
+	}
+
+	_, err = repo.CreateBatch(ctx, &entity.Batch{FileName: "again.csv", Mode: entity.BATCH_PARTIAL, CreatedBy: "ops"},
+		[]entity.BatchRow{{Line: 1, UserID: 2, Type: entity.CREDIT, Amount: 100, Idempotency: row.Idempotency}})
+	if !errors.Is(err, entity.ErrIdempotencyUsed) {
+		t.Fatalf("expected ErrIdempotencyUsed, got %v", err)
+	}
+
+	// a row whose idempotency was used by a transaction since the upload is rejected
+	collision := createBatch(t, entity.BATCH_PARTIAL, []entity.BatchRow{batchRow(t, 1, 1, entity.CREDIT, 100)})
+	rows, err := repo.GetBatchRows(ctx, collision.ID, entity.BATCH_ROW_PENDING, 0, 10)
+	if err != nil {
+		t.Fatalf("get batch rows failed: %v", err)
+	}
+	if _, err := repo.Charge(ctx, 1, &rows[0].Idempotency, 100, nil); err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+	if _, err := repo.ApplyBatchRow(ctx, rows[0], noDebits); !errors.Is(err, entity.ErrIdempotencyUsed) {
+		t.Fatalf("expected ErrIdempotencyUsed, got %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 200, 200)
+}
+
+// addDefaultDestination gives the user a verified default destination for the debit rows of a batch
+func addDefaultDestination(t *testing.T, userId int64, number string) {
+	t.Helper()
+	d := addDestination(t, userId, number)
+	if _, err := repo.VerifyDestination(context.Background(), d.ID, "support"); err != nil {
+		t.Fatalf("verify destination failed: %v", err)
+	}
+}
+
+func TestBatch_partialDebits(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	repotest.Charge(t, subject, 2, 1000)
+	addDefaultDestination(t, 1, "IR000000000000000000000001")
+	debits := repotest.BatchDebits(10, nil)
+
+	batch := createBatch(t, entity.BATCH_PARTIAL, []entity.BatchRow{
+		batchRow(t, 1, 3, entity.CREDIT, 100),
+		batchRow(t, 2, 1, entity.DEBIT, 300),
+		// user 2 has nowhere to be paid out to
+		batchRow(t, 3, 2, entity.DEBIT, 100),
+		batchRow(t, 4, 1, entity.DEBIT, 900),
+	})
+	if _, err := repo.ClaimBatch(ctx, time.Minute); err != nil {
+		t.Fatalf("claim batch failed: %v", err)
+	}
+	rows, err := repo.GetBatchRows(ctx, batch.ID, entity.BATCH_ROW_PENDING, 0, 10)
+	if err != nil {
+		t.Fatalf("get batch rows failed: %v", err)
+	}
+	expected := []error{nil, nil, entity.ErrDestinationNotFound, entity.ErrInsufficientFunds}
+	for i, row := range rows {
+		_, err := repo.ApplyBatchRow(ctx, row, debits)
+		if !errors.Is(err, expected[i]) || (err == nil) != (expected[i] == nil) {
+			t.Fatalf("line %d: expected %v, got %v", row.Line, expected[i], err)
+		}
+		if err != nil {
+			if err := repo.FailBatchRow(ctx, row.ID, err.Error()); err != nil {
+				t.Fatalf("fail batch row failed: %v", err)
+			}
+		}
+	}
+	completed, err := repo.CompleteBatch(ctx, batch.ID)
+	if err != nil {
+		t.Fatalf("complete batch failed: %v", err)
+	}
+	if completed.Status != entity.BATCH_COMPLETED || completed.Succeeded != 2 || completed.Failed != 2 {
+		t.Fatalf("unexpected completed batch %+v", completed)
+	}
+	// the debit reserves its amount with the fee like a withdrawal and is due at once
+	repotest.ExpectBalance(t, subject, 1, 1000, 690)
+	repotest.ExpectBalance(t, subject, 2, 1000, 1000)
+	repotest.ExpectBalance(t, subject, 3, 100, 100)
+	if released := repotest.Release(t, subject); released != 2 {
+		t.Fatalf("expected the debit and its fee to be released, got %d", released)
+	}
+	repotest.ExpectBalance(t, subject, 1, 690, 690)
+	claimed, err := repo.GetPendingTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Amount != -300 || claimed[0].Destination == nil {
+		t.Fatalf("expected the debit to be claimed with its destination, got %+v", claimed)
+	}
+
+	// a denied debit row is failed and its denial kept
+	denied := createBatch(t, entity.BATCH_PARTIAL, []entity.BatchRow{batchRow(t, 1, 1, entity.DEBIT, 100)})
+	if _, err := repo.ClaimBatch(ctx, time.Minute); err != nil {
+		t.Fatalf("claim batch failed: %v", err)
+	}
+	rows, err = repo.GetBatchRows(ctx, denied.ID, entity.BATCH_ROW_PENDING, 0, 10)
+	if err != nil {
+		t.Fatalf("get batch rows failed: %v", err)
+	}
+	if _, err := repo.ApplyBatchRow(ctx, rows[0], repotest.BatchDebits(10, repotest.Decide(entity.DENY, "velocity"))); !errors.Is(err, entity.ErrRiskDenied) {
+		t.Fatalf("expected ErrRiskDenied, got %v", err)
+	}
+	failed, err := repo.GetBatchRows(ctx, denied.ID, entity.BATCH_ROW_FAILED, 0, 10)
+	if err != nil {
+		t.Fatalf("get batch rows failed: %v", err)
+	}
+	if len(failed) != 1 || failed[0].Error == "" {
+		t.Fatalf("expected the denied row to fail with its reason, got %+v", failed)
+	}
+	recorded, err := repo.OccurrenceRecorded(ctx, &rows[0].Idempotency)
+	if err != nil {
+		t.Fatalf("occurrence recorded failed: %v", err)
+	}
+	if !recorded {
+		t.Fatalf("expected the denied idempotency to be recorded")
+	}
+	repotest.ExpectBalance(t, subject, 1, 690, 690)
+}
+
+func TestBatch_allOrNothingDebits(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	repotest.Charge(t, subject, 2, 100)
+	addDefaultDestination(t, 1, "IR000000000000000000000001")
+	addDefaultDestination(t, 2, "IR000000000000000000000002")
+	debits := repotest.BatchDebits(10, nil)
+
+	// the debit of user 2 is more than the wallet holds and rolls back the whole batch
+	rejected := createBatch(t, entity.BATCH_ALL_OR_NOTHING, []entity.BatchRow{
+		batchRow(t, 1, 3, entity.CREDIT, 200),
+		batchRow(t, 2, 1, entity.DEBIT, 300),
+		batchRow(t, 3, 2, entity.DEBIT, 500),
+	})
+	if _, err := repo.ClaimBatch(ctx, time.Minute); err != nil {
+		t.Fatalf("claim batch failed: %v", err)
+	}
+	stageAll(t, rejected.ID, debits)
+	staged, err := repo.GetBatch(ctx, rejected.ID)
+	if err != nil {
+		t.Fatalf("get batch failed: %v", err)
+	}
+	if staged.Staged != 2 || staged.Failed != 1 || staged.Pending != 0 {
+		t.Fatalf("unexpected staged batch %+v", staged)
+	}
+	// a staged debit reserves its amount and fee but is never released by the release worker
+	repotest.ExpectBalance(t, subject, 1, 1000, 690)
+	repotest.MakeDue(subject)
+	if released := repotest.Release(t, subject); released != 0 {
+		t.Fatalf("expected no staged row to be released, got %d", released)
+	}
+	rows, err := repo.GetBatchRows(ctx, rejected.ID, entity.BATCH_ROW_STAGED, 0, 10)
+	if err != nil {
+		t.Fatalf("get batch rows failed: %v", err)
+	}
+	for {
+		rolledBack, err := repo.RollBackBatchRows(ctx, rejected.ID, 1)
+		if err != nil {
+			t.Fatalf("roll back batch rows failed: %v", err)
+		}
+		if rolledBack == 0 {
+			break
+		}
+	}
+	failed, err := repo.FailBatch(ctx, rejected.ID)
+	if err != nil {
+		t.Fatalf("fail batch failed: %v", err)
+	}
+	if failed.Status != entity.BATCH_FAILED || failed.Failed != 1 || failed.RolledBack != 2 {
+		t.Fatalf("unexpected failed batch %+v", failed)
+	}
+	if status := transactionStatus(t, rows[1].TransactionID); status != entity.CANCELLED {
+		t.Fatalf("expected the staged debit to be cancelled, got %s", status)
+	}
+	repotest.ExpectBalance(t, subject, 1, 1000, 1000)
+	repotest.ExpectBalance(t, subject, 2, 100, 100)
+	repotest.ExpectBalance(t, subject, 3, 0, 0)
+
+	accepted := createBatch(t, entity.BATCH_ALL_OR_NOTHING, []entity.BatchRow{
+		batchRow(t, 1, 3, entity.CREDIT, 200),
+		batchRow(t, 2, 1, entity.DEBIT, 300),
+	})
+	if _, err := repo.ClaimBatch(ctx, time.Minute); err != nil {
+		t.Fatalf("claim batch failed: %v", err)
+	}
+	stageAll(t, accepted.ID, debits)
+	released, err := repo.ReleaseBatchRows(ctx, accepted.ID, 10)
+	if err != nil {
+		t.Fatalf("release batch rows failed: %v", err)
+	}
+	if released != 2 {
+		t.Fatalf("expected 2 released rows, got %d", released)
+	}
+	if _, err := repo.CompleteBatch(ctx, accepted.ID); err != nil {
+		t.Fatalf("complete batch failed: %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 3, 200, 200)
+	repotest.ExpectBalance(t, subject, 1, 1000, 690)
+	// a released batch debit is left to the release job with its fee
+	if released := repotest.Release(t, subject); released != 2 {
+		t.Fatalf("expected the debit and its fee to be released, got %d", released)
+	}
+	repotest.ExpectBalance(t, subject, 1, 690, 690)
+}
@@ -0,0 +1,200 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"github.com/gofrs/uuid/v5"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// race runs fn from n goroutines which all start together and waits for them to finish
+func race(n int, fn func(i int)) {
+	var ready, done sync.WaitGroup
+	ready.Add(n)
+	done.Add(n)
+	barrier := make(chan any)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer done.Done()
+			ready.Done()
+			<-barrier
+			fn(i)
+		}(i)
+	}
+	ready.Wait()
+	close(barrier)
+	done.Wait()
+}
+
+func TestDebit_concurrentOverdraw(t *testing.T) {
+	reset(t)
+	repotest.Charge(t, subject, 1, 1000)
+
+	var succeeded, insufficient, failed atomic.Int64
+	race(20, func(i int) {
+		key, _ := uuid.NewV7()
+		_, _, err := repo.Debit(context.Background(), 1, &key, 100, 0, repotest.Later(subject), nil, nil)
+		switch {
+		case err == nil:
+			succeeded.Add(1)
+		case errors.Is(err, entity.ErrInsufficientFunds):
+			insufficient.Add(1)
+		default:
+			failed.Add(1)
+			t.Logf("unexpected debit error: %v", err)
+		}
+	})
+
+	if failed.Load() != 0 {
+		t.Fatalf("%d debits failed for another reason than insufficient funds", failed.Load())
+	}
+	if succeeded.Load() != 10 || insufficient.Load() != 10 {
+		t.Fatalf("expected 10 debits to succeed and 10 to be rejected, got %d and %d", succeeded.Load(), insufficient.Load())
+	}
+	repotest.ExpectBalance(t, subject, 1, 1000, 0)
+}
+
+func TestCharge_concurrentSameIdempotency(t *testing.T) {
+	reset(t)
+	key := repotest.NewKey(t)
+
+	var succeeded atomic.Int64
+	race(10, func(i int) {
+		if _, err := repo.Charge(context.Background(), 1, key, 100, nil); err == nil {
+			succeeded.Add(1)
+		}
+	})
+
+	if succeeded.Load() != 1 {
+		t.Fatalf("expected exactly one charge to succeed, got %d", succeeded.Load())
+	}
+	repotest.ExpectBalance(t, subject, 1, 100, 100)
+}
+
+func TestCharge_concurrentFirstCharges(t *testing.T) {
+	reset(t)
+
+	// the first charges of a user race to create the wallet
+	var failed atomic.Int64
+	race(10, func(i int) {
+		key, _ := uuid.NewV7()
+		if _, err := repo.Charge(context.Background(), 1, &key, 100, nil); err != nil {
+			failed.Add(1)
+			t.Logf("unexpected charge error: %v", err)
+		}
+	})
+
+	if failed.Load() != 0 {
+		t.Fatalf("%d charges failed", failed.Load())
+	}
+	repotest.ExpectBalance(t, subject, 1, 1000, 1000)
+}
+
+func TestReleaseDueTransactions_concurrentWorkers(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+
+	const users = 50
+	for i := int64(1); i <= users; i++ {
+		if _, err := repo.Charge(ctx, i, repotest.NewKey(t), 100, repotest.Later(subject)); err != nil {
+			t.Fatalf("charge with release time failed: %v", err)
+		}
+	}
+	repotest.MakeDue(subject)
+
+	var released, failed atomic.Int64
+	race(5, func(i int) {
+		for {
+			list, err := repo.ReleaseDueTransactions(context.Background(), 7)
+			if err != nil {
+				failed.Add(1)
+				t.Logf("unexpected release error: %v", err)
+				return
+			}
+			if len(list) == 0 {
+				return
+			}
+			released.Add(int64(len(list)))
+		}
+	})
+
+	if failed.Load() != 0 {
+		t.Fatalf("%d release batches failed", failed.Load())
+	}
+	if released.Load() != users {
+		t.Fatalf("expected every credit to be released exactly once, got %d releases", released.Load())
+	}
+	for i := int64(1); i <= users; i++ {
+		repotest.ExpectBalance(t, subject, i, 100, 100)
+	}
+}
+
+func TestHold_concurrentCaptureAndVoid(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	hold, err := repo.PlaceHold(ctx, 1, repotest.NewKey(t), 400, "order", *repotest.Later(subject))
+	if err != nil {
+		t.Fatalf("place hold failed: %v", err)
+	}
+
+	var captured, voided, notActive atomic.Int64
+	race(10, func(i int) {
+		var err error
+		if i%2 == 0 {
+			if _, err = repo.CaptureHold(context.Background(), 1, &hold.ID, 300); err == nil {
+				captured.Add(1)
+			}
+		} else {
+			if _, err = repo.VoidHold(context.Background(), 1, &hold.ID); err == nil {
+				voided.Add(1)
+			}
+		}
+		if errors.Is(err, entity.ErrHoldNotActive) {
+			notActive.Add(1)
+		}
+	})
+
+	if captured.Load()+voided.Load() != 1 || notActive.Load() != 9 {
+		t.Fatalf("expected a single resolution of the hold, got %d captures, %d voids and %d rejections",
+			captured.Load(), voided.Load(), notActive.Load())
+	}
+	if captured.Load() == 1 {
+		repotest.ExpectBalance(t, subject, 1, 700, 700)
+	} else {
+		repotest.ExpectBalance(t, subject, 1, 1000, 1000)
+	}
+}
+
+func TestBatchRow_concurrentApply(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	rows := []entity.BatchRow{{Line: 1, UserID: 1, Type: entity.CREDIT, Amount: 100, Idempotency: *repotest.NewKey(t)}}
+	batch, err := repo.CreateBatch(ctx, &entity.Batch{FileName: "race.csv", Mode: entity.BATCH_PARTIAL, CreatedBy: "ops"}, rows)
+	if err != nil {
+		t.Fatalf("create batch failed: %v", err)
+	}
+	pending, err := repo.GetBatchRows(ctx, batch.ID, entity.BATCH_ROW_PENDING, 0, 10)
+	if err != nil {
+		t.Fatalf("get batch rows failed: %v", err)
+	}
+
+	// two workers holding the same row must not apply it twice
+	var applied atomic.Int64
+	race(2, func(i int) {
+		if _, err := repo.ApplyBatchRow(context.Background(), pending[0], noDebits); err == nil {
+			applied.Add(1)
+		}
+	})
+
+	if applied.Load() != 1 {
+		t.Fatalf("expected the row to be applied once, got %d", applied.Load())
+	}
+	repotest.ExpectBalance(t, subject, 1, 100, 100)
+}
@@ -0,0 +1,15 @@
+//go:build integration
+
+package test
+
+import (
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"testing"
+)
+
+func TestPgxWalletRepo_conformance(t *testing.T) {
+	repotest.RunWalletRepoSuite(t, func(t *testing.T) repotest.Subject {
+		reset(t)
+		return subject
+	})
+}
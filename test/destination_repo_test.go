@@ -0,0 +1,195 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"sync"
+	"testing"
+)
+
+func addDestination(t *testing.T, userId int64, number string) *entity.Destination {
+	t.Helper()
+	d, err := repo.AddDestination(context.Background(), &entity.Destination{UserID: userId, Type: entity.IBAN, Number: number})
+	if err != nil {
+		t.Fatalf("add destination failed: %v", err)
+	}
+	return d
+}
+
+func TestDestinations(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+
+	first := addDestination(t, 1, "IR000000000000000000000001")
+	if !first.IsDefault || first.Verified {
+		t.Fatalf("expected the first destination to be the unverified default, got %+v", first)
+	}
+	second := addDestination(t, 1, "IR000000000000000000000002")
+	if second.IsDefault {
+		t.Fatalf("expected only the first destination to be the default")
+	}
+	if _, err := repo.AddDestination(ctx, &entity.Destination{UserID: 1, Type: entity.IBAN, Number: second.Number}); !errors.Is(err, entity.ErrDestinationExists) {
+		t.Fatalf("expected ErrDestinationExists, got %v", err)
+	}
+	// the same number may belong to another user
+	addDestination(t, 2, second.Number)
+
+	if err := repo.SetDefaultDestination(ctx, 1, second.ID); err != nil {
+		t.Fatalf("set default destination failed: %v", err)
+	}
+	list, err := repo.GetDestinations(ctx, 1)
+	if err != nil {
+		t.Fatalf("get destinations failed: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != second.ID || !list[0].IsDefault || list[1].IsDefault {
+		t.Fatalf("expected the new default destination first, got %+v", list)
+	}
+	d, err := repo.GetDestination(ctx, 1, nil)
+	if err != nil {
+		t.Fatalf("get destination failed: %v", err)
+	}
+	if d.ID != second.ID {
+		t.Fatalf("expected the default destination, got %d", d.ID)
+	}
+
+	if err := repo.SetDefaultDestination(ctx, 2, first.ID); !errors.Is(err, entity.ErrDestinationNotFound) {
+		t.Fatalf("expected ErrDestinationNotFound for the destination of another user, got %v", err)
+	}
+	if _, err := repo.GetDestination(ctx, 2, &first.ID); !errors.Is(err, entity.ErrDestinationNotFound) {
+		t.Fatalf("expected ErrDestinationNotFound for the destination of another user, got %v", err)
+	}
+	if _, err := repo.GetDestination(ctx, 3, nil); !errors.Is(err, entity.ErrDestinationNotFound) {
+		t.Fatalf("expected ErrDestinationNotFound for a user without destinations, got %v", err)
+	}
+
+	verified, err := repo.VerifyDestination(ctx, first.ID, "support")
+	if err != nil {
+		t.Fatalf("verify destination failed: %v", err)
+	}
+	if !verified.Verified || verified.VerifiedBy == nil || *verified.VerifiedBy != "support" || verified.VerifiedAt == nil {
+		t.Fatalf("unexpected verified destination %+v", verified)
+	}
+	if _, err := repo.VerifyDestination(ctx, 999, "support"); !errors.Is(err, entity.ErrDestinationNotFound) {
+		t.Fatalf("expected ErrDestinationNotFound, got %v", err)
+	}
+}
+
+func TestDestinations_firstDefaultAddedAtOnce(t *testing.T) {
+	reset(t)
+	numbers := []string{"IR000000000000000000000001", "IR000000000000000000000002", "IR000000000000000000000003"}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(numbers))
+	for _, number := range numbers {
+		wg.Add(1)
+		go func(number string) {
+			defer wg.Done()
+			_, err := repo.AddDestination(context.Background(), &entity.Destination{UserID: 1, Type: entity.IBAN, Number: number})
+			errs <- err
+		}(number)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("add destination failed: %v", err)
+		}
+	}
+
+	list, err := repo.GetDestinations(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("get destinations failed: %v", err)
+	}
+	defaults := 0
+	for _, d := range list {
+		if d.IsDefault {
+			defaults++
+		}
+	}
+	if len(list) != 3 || defaults != 1 {
+		t.Fatalf("expected 3 destinations with a single default, got %+v", list)
+	}
+}
+
+func TestDestinations_cardNumberIsMasked(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	d, err := repo.AddDestination(ctx, &entity.Destination{UserID: 1, Type: entity.CARD, Number: "6037991234567893"})
+	if err != nil {
+		t.Fatalf("add destination failed: %v", err)
+	}
+	if d.Number != "************7893" {
+		t.Fatalf("expected a masked card number, got %s", d.Number)
+	}
+	list, err := repo.GetDestinations(ctx, 1)
+	if err != nil {
+		t.Fatalf("get destinations failed: %v", err)
+	}
+	if len(list) != 1 || list[0].Number != "************7893" {
+		t.Fatalf("expected the listed card number to be masked, got %+v", list)
+	}
+
+	// the withdraw job still pays out to the full number
+	if _, err := repo.VerifyDestination(ctx, d.ID, "support"); err != nil {
+		t.Fatalf("verify destination failed: %v", err)
+	}
+	repotest.Charge(t, subject, 1, 1000)
+	if _, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), 100, 0, repotest.Later(subject), &d.ID, nil); err != nil {
+		t.Fatalf("debit failed: %v", err)
+	}
+	repotest.MakeDue(subject)
+	repotest.Release(t, subject)
+	claimed, err := repo.GetPendingTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Destination == nil || claimed[0].Destination.Number != "6037991234567893" {
+		t.Fatalf("expected the debit to be claimed with the full card number, got %+v", claimed)
+	}
+}
+
+func TestWithdraw_toDestination(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	d := addDestination(t, 1, "IR000000000000000000000001")
+	if _, err := repo.VerifyDestination(ctx, d.ID, "support"); err != nil {
+		t.Fatalf("verify destination failed: %v", err)
+	}
+
+	id, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), 100, 0, repotest.Later(subject), &d.ID, nil)
+	if err != nil {
+		t.Fatalf("debit failed: %v", err)
+	}
+	repotest.MakeDue(subject)
+	repotest.Release(t, subject)
+	claimed, err := repo.GetPendingTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != *id || claimed[0].Destination == nil || claimed[0].Destination.Number != d.Number {
+		t.Fatalf("expected the debit to be claimed with its destination, got %+v", claimed)
+	}
+
+	if err := repo.UpdateTransactionStatus(ctx, id, entity.SUCCESS, repotest.NewKey(t)); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+
+	// the final payout of a closed wallet goes to the verified default destination
+	payoutID, err := repo.CloseWallet(ctx, 1, "request", "admin", repotest.NewKey(t))
+	if err != nil {
+		t.Fatalf("close wallet failed: %v", err)
+	}
+	page, err := repo.GetTransactionList(ctx, 1, nil, 10)
+	if err != nil {
+		t.Fatalf("get transaction list failed: %v", err)
+	}
+	for _, txn := range page.TransactionList {
+		if txn.ID == *payoutID && (txn.DestinationID == nil || *txn.DestinationID != d.ID) {
+			t.Fatalf("expected the final payout to go to the default destination, got %+v", txn)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"github.com/gofrs/uuid/v5"
+	"testing"
+	"time"
+)
+
+// expireHold moves the expiry of a hold to the past
+func expireHold(t *testing.T, id uuid.UUID) {
+	t.Helper()
+	if _, err := db.Exec(context.Background(), "UPDATE holds SET expires_at = NOW() - INTERVAL '1 second' WHERE id = $1", id); err != nil {
+		t.Fatalf("could not expire hold: %v", err)
+	}
+}
+
+func TestPlaceHold(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	key := repotest.NewKey(t)
+
+	hold, err := repo.PlaceHold(ctx, 1, key, 400, "order-1", *repotest.Later(subject))
+	if err != nil {
+		t.Fatalf("place hold failed: %v", err)
+	}
+	if hold.Status != entity.HOLD_ACTIVE || hold.Amount != 400 || hold.Reference != "order-1" {
+		t.Fatalf("unexpected hold %+v", hold)
+	}
+	repotest.ExpectBalance(t, subject, 1, 1000, 600)
+
+	// placing the hold again returns the first one without reserving twice
+	again, err := repo.PlaceHold(ctx, 1, key, 400, "order-1", *repotest.Later(subject))
+	if err != nil {
+		t.Fatalf("place hold failed: %v", err)
+	}
+	if again.ID != hold.ID {
+		t.Fatalf("expected the same hold, got %s and %s", hold.ID, again.ID)
+	}
+	repotest.ExpectBalance(t, subject, 1, 1000, 600)
+
+	if _, err := repo.PlaceHold(ctx, 1, repotest.NewKey(t), 601, "order-2", *repotest.Later(subject)); !errors.Is(err, entity.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if _, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), 601, 0, repotest.Later(subject), nil, nil); !errors.Is(err, entity.ErrInsufficientFunds) {
+		t.Fatalf("expected held funds not to be withdrawable, got %v", err)
+	}
+
+	invalid := []struct {
+		name        string
+		idempotency *uuid.UUID
+		amount      int64
+		expiresAt   time.Time
+	}{
+		{name: "no idempotency", idempotency: nil, amount: 100, expiresAt: *repotest.Later(subject)},
+		{name: "zero amount", idempotency: repotest.NewKey(t), amount: 0, expiresAt: *repotest.Later(subject)},
+		{name: "expiry in the past", idempotency: repotest.NewKey(t), amount: 100, expiresAt: subject.Clock.Now().Add(-time.Minute)},
+	}
+	for _, c := range invalid {
+		if _, err := repo.PlaceHold(ctx, 1, c.idempotency, c.amount, "order", c.expiresAt); err == nil {
+			t.Fatalf("%s: expected place hold to fail", c.name)
+		}
+	}
+
+	if _, err := repo.UpdateWalletStatus(ctx, 1, entity.FROZEN_DEBIT, "dispute", "admin"); err != nil {
+		t.Fatalf("update wallet status failed: %v", err)
+	}
+	if _, err := repo.PlaceHold(ctx, 1, repotest.NewKey(t), 100, "order-3", *repotest.Later(subject)); !errors.Is(err, entity.ErrWalletFrozen) {
+		t.Fatalf("expected ErrWalletFrozen, got %v", err)
+	}
+}
+
+func TestCaptureHold(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	hold, err := repo.PlaceHold(ctx, 1, repotest.NewKey(t), 400, "order", *repotest.Later(subject))
+	if err != nil {
+		t.Fatalf("place hold failed: %v", err)
+	}
+
+	if _, err := repo.CaptureHold(ctx, 1, &hold.ID, 401); !errors.Is(err, entity.ErrCaptureExceedsHold) {
+		t.Fatalf("expected ErrCaptureExceedsHold, got %v", err)
+	}
+	if _, err := repo.CaptureHold(ctx, 2, &hold.ID, 100); !errors.Is(err, entity.ErrHoldNotFound) {
+		t.Fatalf("expected ErrHoldNotFound for the hold of another user, got %v", err)
+	}
+
+	// the captured amount is debited and the rest of the hold is available again
+	captured, err := repo.CaptureHold(ctx, 1, &hold.ID, 300)
+	if err != nil {
+		t.Fatalf("capture hold failed: %v", err)
+	}
+	if captured.Status != entity.HOLD_CAPTURED || captured.CapturedAmount != 300 || captured.TransactionID == nil || captured.ResolvedAt == nil {
+		t.Fatalf("unexpected captured hold %+v", captured)
+	}
+	repotest.ExpectBalance(t, subject, 1, 700, 700)
+	details, err := repo.GetTransaction(ctx, captured.TransactionID)
+	if err != nil {
+		t.Fatalf("get transaction failed: %v", err)
+	}
+	if details.Source != entity.HOLD_SOURCE || details.Amount != -300 || details.Status != entity.SUCCESS {
+		t.Fatalf("unexpected capture debit %+v", details)
+	}
+
+	if _, err := repo.CaptureHold(ctx, 1, &hold.ID, 100); !errors.Is(err, entity.ErrHoldNotActive) {
+		t.Fatalf("expected ErrHoldNotActive, got %v", err)
+	}
+	if _, err := repo.VoidHold(ctx, 1, &hold.ID); !errors.Is(err, entity.ErrHoldNotActive) {
+		t.Fatalf("expected ErrHoldNotActive, got %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 700, 700)
+}
+
+func TestVoidHold(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	hold, err := repo.PlaceHold(ctx, 1, repotest.NewKey(t), 400, "order", *repotest.Later(subject))
+	if err != nil {
+		t.Fatalf("place hold failed: %v", err)
+	}
+
+	if _, err := repo.VoidHold(ctx, 1, repotest.NewKey(t)); !errors.Is(err, entity.ErrHoldNotFound) {
+		t.Fatalf("expected ErrHoldNotFound, got %v", err)
+	}
+	voided, err := repo.VoidHold(ctx, 1, &hold.ID)
+	if err != nil {
+		t.Fatalf("void hold failed: %v", err)
+	}
+	if voided.Status != entity.HOLD_VOIDED || voided.TransactionID != nil {
+		t.Fatalf("unexpected voided hold %+v", voided)
+	}
+	repotest.ExpectBalance(t, subject, 1, 1000, 1000)
+
+	got, err := repo.GetHold(ctx, 1, &hold.ID)
+	if err != nil {
+		t.Fatalf("get hold failed: %v", err)
+	}
+	if got.Status != entity.HOLD_VOIDED {
+		t.Fatalf("expected a voided hold, got %s", got.Status)
+	}
+	if _, err := repo.GetHold(ctx, 2, &hold.ID); !errors.Is(err, entity.ErrHoldNotFound) {
+		t.Fatalf("expected ErrHoldNotFound for the hold of another user, got %v", err)
+	}
+}
+
+func TestExpireHolds(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	repotest.Charge(t, subject, 2, 1000)
+
+	ids := make([]uuid.UUID, 0, 3)
+	for _, userId := range []int64{1, 1, 2} {
+		hold, err := repo.PlaceHold(ctx, userId, repotest.NewKey(t), 100, "order", *repotest.Later(subject))
+		if err != nil {
+			t.Fatalf("place hold failed: %v", err)
+		}
+		ids = append(ids, hold.ID)
+	}
+	if count, err := repo.ExpireHolds(ctx, 10); err != nil || count != 0 {
+		t.Fatalf("expected no hold to expire yet, got %d, %v", count, err)
+	}
+
+	expireHold(t, ids[0])
+	expireHold(t, ids[1])
+	// an overdue hold can not be captured even before the expiry worker picks it up
+	if _, err := repo.CaptureHold(ctx, 1, &ids[0], 100); !errors.Is(err, entity.ErrHoldNotActive) {
+		t.Fatalf("expected ErrHoldNotActive, got %v", err)
+	}
+
+	count, err := repo.ExpireHolds(ctx, 10)
+	if err != nil {
+		t.Fatalf("expire holds failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 expired holds, got %d", count)
+	}
+	repotest.ExpectBalance(t, subject, 1, 1000, 1000)
+	repotest.ExpectBalance(t, subject, 2, 1000, 900)
+
+	hold, err := repo.GetHold(ctx, 1, &ids[0])
+	if err != nil {
+		t.Fatalf("get hold failed: %v", err)
+	}
+	if hold.Status != entity.HOLD_EXPIRED {
+		t.Fatalf("expected an expired hold, got %s", hold.Status)
+	}
+}
@@ -0,0 +1,141 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/integrity/application/command"
+	integrityEntity "github.com/MaisamV/wallet/internal/integrity/entity"
+	integrityInfrastructure "github.com/MaisamV/wallet/internal/integrity/infrastructure"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"github.com/MaisamV/wallet/platform/logger"
+	"testing"
+	"time"
+)
+
+func newIntegrityRepo() *integrityInfrastructure.PgxIntegrityRepo {
+	return integrityInfrastructure.NewPgxIntegrityRepo(logger.NewNoopLogger(), db, subject.Clock)
+}
+
+// expectConsistent recomputes the wallet of the user and fails when it does not match the stored balances
+func expectConsistent(t *testing.T, userId int64) {
+	t.Helper()
+	b, err := newIntegrityRepo().RecomputeBalance(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("recompute balance failed: %v", err)
+	}
+	if !b.Consistent() {
+		t.Fatalf("expected the balances to match the transactions, got %+v", b)
+	}
+}
+
+func TestIntegrity_followsTheLiveBalanceRules(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 10000)
+
+	// a pending withdrawal and its fee reserve the available balance only
+	pending := repotest.Debit(t, subject, 1, 1000, 50)
+	repotest.ExpectBalance(t, subject, 1, 10000, 8950)
+	expectConsistent(t, 1)
+
+	// a credit with a release time is in the total balance only, it is due long after the withdrawals
+	tomorrow := subject.Clock.Now().AddDate(0, 0, 1)
+	if _, err := repo.Charge(ctx, 1, repotest.NewKey(t), 300, &tomorrow); err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+	expectConsistent(t, 1)
+
+	// a cancelled withdrawal gives its fee back with a refund
+	cancelled := repotest.Debit(t, subject, 1, 500, 20)
+	if err := repo.CancelDebit(ctx, 1, cancelled); err != nil {
+		t.Fatalf("cancel debit failed: %v", err)
+	}
+	expectConsistent(t, 1)
+
+	// a released withdrawal and its fee leave the total balance, a failed one comes back with its fee
+	repotest.MakeDue(subject)
+	repotest.Release(t, subject)
+	repotest.ExpectBalance(t, subject, 1, 9250, 8950)
+	expectConsistent(t, 1)
+	if _, err := repo.GetPendingTransactions(ctx, 10); err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if err := repo.UpdateTransactionStatus(ctx, pending, entity.FAILED, nil); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 10300, 10000)
+	expectConsistent(t, 1)
+
+	// active holds reserve the available balance, a partly captured hold gives the rest back
+	captured, err := repo.PlaceHold(ctx, 1, repotest.NewKey(t), 400, "order-1", subject.Clock.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("place hold failed: %v", err)
+	}
+	if _, err := repo.PlaceHold(ctx, 1, repotest.NewKey(t), 200, "order-2", subject.Clock.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("place hold failed: %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 10300, 9400)
+	expectConsistent(t, 1)
+	if _, err := repo.CaptureHold(ctx, 1, &captured.ID, 150); err != nil {
+		t.Fatalf("capture hold failed: %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 10150, 9650)
+	expectConsistent(t, 1)
+
+	handler := command.NewCheckIntegrityCommandHandler(logger.NewNoopLogger(), newIntegrityRepo(), subject.Clock)
+	run, err := handler.Handle(ctx, command.CheckIntegrityCommand{BatchSize: 10, Quarantine: true})
+	if err != nil {
+		t.Fatalf("integrity check failed: %v", err)
+	}
+	if run.CheckedWallets != 1 || len(run.Discrepancies) != 0 || run.Quarantined != 0 {
+		t.Fatalf("expected a clean run, got %+v", run)
+	}
+}
+
+func TestIntegrity_releaseQuarantine(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	if _, err := db.Exec(ctx, `UPDATE wallets SET available_balance = available_balance + 500 WHERE user_id = 1`); err != nil {
+		t.Fatalf("could not corrupt wallet: %v", err)
+	}
+
+	check := command.NewCheckIntegrityCommandHandler(logger.NewNoopLogger(), newIntegrityRepo(), subject.Clock)
+	run, err := check.Handle(ctx, command.CheckIntegrityCommand{BatchSize: 10, Quarantine: true})
+	if err != nil {
+		t.Fatalf("integrity check failed: %v", err)
+	}
+	if len(run.Discrepancies) != 1 || run.Quarantined != 1 {
+		t.Fatalf("expected the wallet to be quarantined, got %+v", run)
+	}
+
+	handler := command.NewReleaseQuarantineCommandHandler(logger.NewNoopLogger(), newIntegrityRepo())
+	if _, err := handler.Handle(ctx, command.ReleaseQuarantineCommand{UserID: 2, Actor: "ops"}); !errors.Is(err, integrityEntity.ErrWalletNotFound) {
+		t.Fatalf("expected ErrWalletNotFound, got %v", err)
+	}
+	// a wallet still not matching its transactions stays quarantined
+	if _, err := handler.Handle(ctx, command.ReleaseQuarantineCommand{UserID: 1, Actor: "ops"}); !errors.Is(err, integrityEntity.ErrBalanceMismatch) {
+		t.Fatalf("expected ErrBalanceMismatch, got %v", err)
+	}
+	if _, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), 100, 0, repotest.Later(subject), nil, nil); !errors.Is(err, entity.ErrWalletQuarantined) {
+		t.Fatalf("expected ErrWalletQuarantined, got %v", err)
+	}
+
+	if _, err := db.Exec(ctx, `UPDATE wallets SET available_balance = available_balance - 500 WHERE user_id = 1`); err != nil {
+		t.Fatalf("could not correct wallet: %v", err)
+	}
+	released, err := handler.Handle(ctx, command.ReleaseQuarantineCommand{UserID: 1, Actor: "ops"})
+	if err != nil {
+		t.Fatalf("release quarantine failed: %v", err)
+	}
+	if !released.Consistent() {
+		t.Fatalf("expected consistent balances, got %+v", released)
+	}
+	if _, err := handler.Handle(ctx, command.ReleaseQuarantineCommand{UserID: 1, Actor: "ops"}); !errors.Is(err, integrityEntity.ErrNotQuarantined) {
+		t.Fatalf("expected ErrNotQuarantined, got %v", err)
+	}
+	repotest.Debit(t, subject, 1, 100, 0)
+}
@@ -0,0 +1,126 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"github.com/gofrs/uuid/v5"
+	"sync/atomic"
+	"testing"
+)
+
+func limit(v int64) *int64 {
+	return &v
+}
+
+func expectLimit(t *testing.T, err error, limitType entity.LimitType) {
+	t.Helper()
+	var limitErr *entity.LimitExceededError
+	if !errors.As(err, &limitErr) || limitErr.Limit != limitType {
+		t.Fatalf("expected the %s limit to be exceeded, got %v", limitType, err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+
+	settings, err := repo.GetLimits(ctx, 1)
+	if err != nil {
+		t.Fatalf("get limits failed: %v", err)
+	}
+	if settings.Override != nil || settings.Effective.MaxBalance != nil || settings.Effective.MaxSingleWithdrawal != nil {
+		t.Fatalf("expected no limits, got %+v", settings)
+	}
+
+	if err := repo.SetDefaultLimits(ctx, entity.Limits{MaxSingleWithdrawal: limit(500), MaxBalance: limit(2000)}); err != nil {
+		t.Fatalf("set default limits failed: %v", err)
+	}
+	if err := repo.SetUserLimits(ctx, 1, entity.Limits{MaxSingleWithdrawal: limit(1000)}); err != nil {
+		t.Fatalf("set user limits failed: %v", err)
+	}
+	settings, err = repo.GetLimits(ctx, 1)
+	if err != nil {
+		t.Fatalf("get limits failed: %v", err)
+	}
+	// the override only replaces the limits it sets
+	if settings.Override == nil || *settings.Effective.MaxSingleWithdrawal != 1000 || *settings.Effective.MaxBalance != 2000 {
+		t.Fatalf("unexpected limits %+v", settings)
+	}
+
+	_, err = repo.Charge(ctx, 1, repotest.NewKey(t), 2001, nil)
+	expectLimit(t, err, entity.MAX_BALANCE)
+	repotest.Charge(t, subject, 1, 1500)
+	repotest.Charge(t, subject, 2, 1500)
+	_, err = repo.Charge(ctx, 1, repotest.NewKey(t), 501, nil)
+	expectLimit(t, err, entity.MAX_BALANCE)
+
+	repotest.Debit(t, subject, 1, 800, 0)
+	_, _, err = repo.Debit(ctx, 2, repotest.NewKey(t), 600, 0, repotest.Later(subject), nil, nil)
+	expectLimit(t, err, entity.MAX_SINGLE_WITHDRAWAL)
+
+	if err := repo.DeleteUserLimits(ctx, 1); err != nil {
+		t.Fatalf("delete user limits failed: %v", err)
+	}
+	_, _, err = repo.Debit(ctx, 1, repotest.NewKey(t), 600, 0, repotest.Later(subject), nil, nil)
+	expectLimit(t, err, entity.MAX_SINGLE_WITHDRAWAL)
+	repotest.ExpectBalance(t, subject, 1, 1500, 700)
+}
+
+func TestLimits_velocity(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 10000)
+	repotest.Charge(t, subject, 2, 10000)
+
+	if err := repo.SetUserLimits(ctx, 1, entity.Limits{DailyWithdrawalTotal: limit(1000)}); err != nil {
+		t.Fatalf("set user limits failed: %v", err)
+	}
+	first := repotest.Debit(t, subject, 1, 800, 0)
+	_, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), 300, 0, repotest.Later(subject), nil, nil)
+	expectLimit(t, err, entity.DAILY_WITHDRAWAL_TOTAL)
+	// cancelled withdrawals do not use the limit
+	if err := repo.CancelDebit(ctx, 1, first); err != nil {
+		t.Fatalf("cancel debit failed: %v", err)
+	}
+	repotest.Debit(t, subject, 1, 300, 0)
+
+	if err := repo.SetUserLimits(ctx, 2, entity.Limits{HourlyWithdrawalCount: limit(2)}); err != nil {
+		t.Fatalf("set user limits failed: %v", err)
+	}
+	repotest.Debit(t, subject, 2, 10, 0)
+	repotest.Debit(t, subject, 2, 10, 0)
+	_, _, err = repo.Debit(ctx, 2, repotest.NewKey(t), 10, 0, repotest.Later(subject), nil, nil)
+	expectLimit(t, err, entity.HOURLY_WITHDRAWAL_COUNT)
+}
+
+func TestLimits_concurrentDebits(t *testing.T) {
+	reset(t)
+	repotest.Charge(t, subject, 1, 10000)
+	if err := repo.SetUserLimits(context.Background(), 1, entity.Limits{DailyWithdrawalTotal: limit(500)}); err != nil {
+		t.Fatalf("set user limits failed: %v", err)
+	}
+
+	// the wallet lock makes concurrent debits see each other's usage
+	var succeeded, exceeded atomic.Int64
+	race(10, func(i int) {
+		key, _ := uuid.NewV7()
+		_, _, err := repo.Debit(context.Background(), 1, &key, 100, 0, repotest.Later(subject), nil, nil)
+		switch {
+		case err == nil:
+			succeeded.Add(1)
+		case errors.Is(err, entity.ErrLimitExceeded):
+			exceeded.Add(1)
+		default:
+			t.Logf("unexpected debit error: %v", err)
+		}
+	})
+
+	if succeeded.Load() != 5 || exceeded.Load() != 5 {
+		t.Fatalf("expected 5 debits within the limit and 5 over it, got %d and %d", succeeded.Load(), exceeded.Load())
+	}
+	repotest.ExpectBalance(t, subject, 1, 10000, 9500)
+}
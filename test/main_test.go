@@ -0,0 +1,59 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"fmt"
+	infrastructure "github.com/MaisamV/wallet/internal/wallet/infrastructure/repo"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"github.com/MaisamV/wallet/platform/clock"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/MaisamV/wallet/test/testdb"
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"os"
+	"testing"
+	"time"
+)
+
+var (
+	db   *pgxpool.Pool
+	repo *infrastructure.PgxWalletRepo
+	// subject hands the Postgres repo to the fixtures of the conformance suite
+	subject repotest.Subject
+)
+
+// TestMain connects to the test database and rebuilds its schema, see testdb.Open
+func TestMain(m *testing.M) {
+	var err error
+	db, err = testdb.Open(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fake := clock.NewFake(time.Now())
+	repo = infrastructure.NewPgxWalletRepo(logger.NewNoopLogger(), db, fake)
+	subject = repotest.Subject{Repo: repo, Clock: fake}
+
+	code := m.Run()
+	db.Close()
+	os.Exit(code)
+}
+
+// reset empties the database and sets the clock back to the current time, earlier tests may have
+// advanced it
+func reset(t *testing.T) {
+	t.Helper()
+	testdb.Reset(t, db)
+	subject.Clock.Set(time.Now())
+}
+
+func transactionStatus(t *testing.T, id *uuid.UUID) string {
+	t.Helper()
+	details, err := repo.GetTransaction(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get transaction failed: %v", err)
+	}
+	return details.Status
+}
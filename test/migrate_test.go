@@ -0,0 +1,74 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/MaisamV/wallet/platform/migrate"
+	"github.com/MaisamV/wallet/scripts/migrations"
+	"testing"
+)
+
+func TestMigrator_roundTrip(t *testing.T) {
+	ctx := context.Background()
+	migrator, err := migrate.New(logger.NewNoopLogger(), db, migrations.FS)
+	if err != nil {
+		t.Fatalf("could not read migrations: %v", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatalf("expected the rebuilt schema to pass the check, got %v", err)
+	}
+
+	latest := migrator.Latest()
+	if _, err := migrator.Down(ctx, 1); err != nil {
+		t.Fatalf("down failed: %v", err)
+	}
+	if err := migrator.Check(ctx); !errors.Is(err, migrate.ErrSchemaOutdated) {
+		t.Fatalf("expected an outdated schema after reverting one migration, got %v", err)
+	}
+
+	// every down script must undo its up script so the whole chain can be applied again
+	reverted, err := migrator.Down(ctx, int(latest))
+	if err != nil {
+		t.Fatalf("down failed after %d migrations: %v", reverted, err)
+	}
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if status.Version != migrate.NoVersion || len(status.Pending) != int(latest) {
+		t.Fatalf("expected no applied migrations, got version %d with %d pending", status.Version, len(status.Pending))
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up failed: %v", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatalf("expected the migrated schema to pass the check, got %v", err)
+	}
+}
+
+func TestMigrator_dirty(t *testing.T) {
+	ctx := context.Background()
+	migrator, err := migrate.New(logger.NewNoopLogger(), db, migrations.FS)
+	if err != nil {
+		t.Fatalf("could not read migrations: %v", err)
+	}
+	latest := migrator.Latest()
+	if _, err := db.Exec(ctx, "UPDATE schema_migrations SET dirty = TRUE"); err != nil {
+		t.Fatalf("could not mark the schema dirty: %v", err)
+	}
+	if err := migrator.Check(ctx); !errors.Is(err, migrate.ErrDirty) {
+		t.Fatalf("expected a dirty schema to be refused, got %v", err)
+	}
+	if _, err := migrator.Up(ctx); !errors.Is(err, migrate.ErrDirty) {
+		t.Fatalf("expected up to refuse a dirty schema, got %v", err)
+	}
+	if err := migrator.Force(ctx, latest); err != nil {
+		t.Fatalf("force failed: %v", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatalf("expected the forced schema to pass the check, got %v", err)
+	}
+}
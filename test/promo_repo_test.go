@@ -0,0 +1,238 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"testing"
+	"time"
+)
+
+func grantPromo(t *testing.T, userId int64, amount int64) *entity.PromoGrant {
+	t.Helper()
+	grant, err := repo.GrantPromo(context.Background(), &entity.PromoGrant{UserID: userId, Amount: amount, Reason: "campaign",
+		CreatedBy: "marketing", Idempotency: *repotest.NewKey(t), ExpiresAt: *repotest.Later(subject)})
+	if err != nil {
+		t.Fatalf("grant promo failed: %v", err)
+	}
+	return grant
+}
+
+// expirePromo moves the expiry of a grant to the past
+func expirePromo(t *testing.T, id int64) {
+	t.Helper()
+	if _, err := db.Exec(context.Background(), "UPDATE promo_grants SET expires_at = NOW() - INTERVAL '1 second' WHERE id = $1", id); err != nil {
+		t.Fatalf("could not expire promo grant: %v", err)
+	}
+}
+
+func expectBuckets(t *testing.T, userId int64, cash int64, promo int64, withdrawable int64) {
+	t.Helper()
+	w, err := repo.GetBalance(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("get balance failed: %v", err)
+	}
+	if w.Buckets.Cash != cash || w.Buckets.Promo != promo || w.Buckets.Withdrawable != withdrawable {
+		t.Fatalf("expected cash %d, promo %d and withdrawable %d, got %+v", cash, promo, withdrawable, w.Buckets)
+	}
+}
+
+func TestGrantPromo(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+
+	grant := &entity.PromoGrant{UserID: 1, Amount: 100, Reason: "welcome", CreatedBy: "marketing", Idempotency: *repotest.NewKey(t),
+		ExpiresAt: *repotest.Later(subject)}
+	granted, err := repo.GrantPromo(ctx, grant)
+	if err != nil {
+		t.Fatalf("grant promo failed: %v", err)
+	}
+	if granted.Status != entity.PROMO_ACTIVE || granted.Remaining != 100 || granted.TransactionID == nil {
+		t.Fatalf("unexpected grant %+v", granted)
+	}
+	repotest.ExpectBalance(t, subject, 1, 100, 100)
+	expectBuckets(t, 1, 0, 100, 0)
+
+	// granting again with the same idempotency returns the first grant
+	again, err := repo.GrantPromo(ctx, grant)
+	if err != nil {
+		t.Fatalf("grant promo failed: %v", err)
+	}
+	if again.ID != granted.ID {
+		t.Fatalf("expected the same grant, got %d and %d", granted.ID, again.ID)
+	}
+	repotest.ExpectBalance(t, subject, 1, 100, 100)
+
+	// the grant idempotency is shared with the transactions of the user
+	key := repotest.NewKey(t)
+	if _, err := repo.Charge(ctx, 1, key, 50, nil); err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+	collision := &entity.PromoGrant{UserID: 1, Amount: 100, Reason: "welcome", CreatedBy: "marketing", Idempotency: *key,
+		ExpiresAt: *repotest.Later(subject)}
+	if _, err := repo.GrantPromo(ctx, collision); !errors.Is(err, entity.ErrIdempotencyUsed) {
+		t.Fatalf("expected ErrIdempotencyUsed, got %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 150, 150)
+
+	grants, err := repo.GetPromoGrants(ctx, 1)
+	if err != nil {
+		t.Fatalf("get promo grants failed: %v", err)
+	}
+	if len(grants) != 1 || grants[0].ID != granted.ID {
+		t.Fatalf("expected the single grant, got %+v", grants)
+	}
+
+	invalid := []*entity.PromoGrant{
+		{UserID: 1, Amount: 0, Idempotency: *repotest.NewKey(t), ExpiresAt: *repotest.Later(subject)},
+		{UserID: 1, Amount: 100, Idempotency: *repotest.NewKey(t), ExpiresAt: subject.Clock.Now().Add(-time.Minute)},
+	}
+	for _, g := range invalid {
+		if _, err := repo.GrantPromo(ctx, g); err == nil {
+			t.Fatalf("expected grant %+v to fail", g)
+		}
+	}
+}
+
+func TestPromo_notWithdrawable(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	grantPromo(t, 1, 100)
+
+	if _, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), 1, 0, repotest.Later(subject), nil, nil); !errors.Is(err, entity.ErrInsufficientFunds) {
+		t.Fatalf("expected promotional credit not to be withdrawable, got %v", err)
+	}
+	repotest.Charge(t, subject, 1, 500)
+	expectBuckets(t, 1, 500, 100, 500)
+	repotest.Debit(t, subject, 1, 500, 0)
+	if _, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), 1, 0, repotest.Later(subject), nil, nil); !errors.Is(err, entity.ErrInsufficientFunds) {
+		t.Fatalf("expected promotional credit not to be withdrawable, got %v", err)
+	}
+}
+
+func TestPromo_spentBeforeCash(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	grant := grantPromo(t, 1, 100)
+	repotest.Charge(t, subject, 1, 500)
+
+	hold, err := repo.PlaceHold(ctx, 1, repotest.NewKey(t), 150, "order", *repotest.Later(subject))
+	if err != nil {
+		t.Fatalf("place hold failed: %v", err)
+	}
+	if hold.PromoAmount != 100 {
+		t.Fatalf("expected the hold to reserve the whole promotional credit, got %d", hold.PromoAmount)
+	}
+	// the cash reserved by the hold is the only cash not withdrawable
+	expectBuckets(t, 1, 500, 100, 450)
+
+	if _, err := repo.CaptureHold(ctx, 1, &hold.ID, 120); err != nil {
+		t.Fatalf("capture hold failed: %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 480, 480)
+	expectBuckets(t, 1, 480, 0, 480)
+
+	grants, err := repo.GetPromoGrants(ctx, 1)
+	if err != nil {
+		t.Fatalf("get promo grants failed: %v", err)
+	}
+	if grants[0].ID != grant.ID || grants[0].Status != entity.PROMO_SPENT || grants[0].Remaining != 0 {
+		t.Fatalf("expected the grant to be spent, got %+v", grants[0])
+	}
+}
+
+func TestExpirePromoGrants(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	grant := grantPromo(t, 1, 100)
+	repotest.Charge(t, subject, 1, 500)
+
+	if count, err := repo.ExpirePromoGrants(ctx, 10); err != nil || count != 0 {
+		t.Fatalf("expected no grant to expire yet, got %d, %v", count, err)
+	}
+	expirePromo(t, grant.ID)
+	count, err := repo.ExpirePromoGrants(ctx, 10)
+	if err != nil {
+		t.Fatalf("expire promo grants failed: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 expired grant, got %d", count)
+	}
+	repotest.ExpectBalance(t, subject, 1, 500, 500)
+	expectBuckets(t, 1, 500, 0, 500)
+
+	grants, err := repo.GetPromoGrants(ctx, 1)
+	if err != nil {
+		t.Fatalf("get promo grants failed: %v", err)
+	}
+	if grants[0].Status != entity.PROMO_EXPIRED || grants[0].Expired != 100 {
+		t.Fatalf("expected the grant to be expired, got %+v", grants[0])
+	}
+	if count, _ := repo.ExpirePromoGrants(ctx, 10); count != 0 {
+		t.Fatalf("expected nothing left to expire, got %d", count)
+	}
+}
+
+func TestExpirePromoGrants_reservedByHold(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	grant := grantPromo(t, 1, 100)
+	hold, err := repo.PlaceHold(ctx, 1, repotest.NewKey(t), 60, "order", *repotest.Later(subject))
+	if err != nil {
+		t.Fatalf("place hold failed: %v", err)
+	}
+
+	// only the promotional credit which is not reserved by the hold is taken back
+	expirePromo(t, grant.ID)
+	if count, err := repo.ExpirePromoGrants(ctx, 10); err != nil || count != 1 {
+		t.Fatalf("expected the unreserved credit to expire, got %d, %v", count, err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 60, 0)
+	if count, _ := repo.ExpirePromoGrants(ctx, 10); count != 0 {
+		t.Fatalf("expected the reserved credit to be kept, got %d", count)
+	}
+
+	if _, err := repo.VoidHold(ctx, 1, &hold.ID); err != nil {
+		t.Fatalf("void hold failed: %v", err)
+	}
+	if count, err := repo.ExpirePromoGrants(ctx, 10); err != nil || count != 1 {
+		t.Fatalf("expected the released credit to expire, got %d, %v", count, err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 0, 0)
+
+	grants, err := repo.GetPromoGrants(ctx, 1)
+	if err != nil {
+		t.Fatalf("get promo grants failed: %v", err)
+	}
+	if grants[0].Status != entity.PROMO_EXPIRED || grants[0].Expired != 100 || grants[0].Remaining != 0 {
+		t.Fatalf("expected the grant to be expired, got %+v", grants[0])
+	}
+}
+
+func TestCloseWallet_forfeitsPromo(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	grantPromo(t, 1, 100)
+	repotest.Charge(t, subject, 1, 50)
+
+	payoutID, err := repo.CloseWallet(ctx, 1, "request", "admin", repotest.NewKey(t))
+	if err != nil {
+		t.Fatalf("close wallet failed: %v", err)
+	}
+	details, err := repo.GetTransaction(ctx, payoutID)
+	if err != nil {
+		t.Fatalf("get transaction failed: %v", err)
+	}
+	if details.Amount != -50 {
+		t.Fatalf("expected only the cash to be paid out, got %d", details.Amount)
+	}
+	repotest.ExpectBalance(t, subject, 1, 50, 0)
+
+	if _, err := repo.GrantPromo(ctx, &entity.PromoGrant{UserID: 1, Amount: 100, Idempotency: *repotest.NewKey(t),
+		ExpiresAt: *repotest.Later(subject)}); !errors.Is(err, entity.ErrWalletClosed) {
+		t.Fatalf("expected ErrWalletClosed, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaisamV/wallet/internal/reconciliation/application/command"
+	recon "github.com/MaisamV/wallet/internal/reconciliation/entity"
+	reconrepo "github.com/MaisamV/wallet/internal/reconciliation/infrastructure"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/MaisamV/wallet/platform/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReconciliation_runNotFound(t *testing.T) {
+	reset(t)
+	reconciliations := reconrepo.NewPgxReconciliationRepo(logger.NewNoopLogger(), db)
+	if _, err := reconciliations.GetRun(context.Background(), 1); !errors.Is(err, recon.ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
+}
+
+func TestReconciliation_closedAndMissingWallets(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	log := logger.NewNoopLogger()
+	reconciliations := reconrepo.NewPgxReconciliationRepo(log, db)
+	reader := reconrepo.NewCsvSettlementReader(log, config.ReconcileConfig{
+		Columns:         config.SettlementColumns{Idempotency: "tracking_id", BankReference: "reference", Amount: "amount", Status: "status"},
+		SuccessStatuses: []string{"settled"},
+	})
+	handler := command.NewReconcileCommandHandler(log, reconciliations, reader, subject.Clock)
+
+	// the final payout of a closed wallet is a withdrawal like any other
+	repotest.Charge(t, subject, 1, 1000)
+	payoutKey := repotest.NewKey(t)
+	payoutID, err := repo.CloseWallet(ctx, 1, "request", "admin", payoutKey)
+	if err != nil {
+		t.Fatalf("close wallet failed: %v", err)
+	}
+	bankID := repotest.NewKey(t)
+	if err := repo.UpdateTransactionStatus(ctx, payoutID, entity.SUCCESS, bankID); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+	// the bank settled a withdrawal of a wallet which does not exist here
+	unknown := repotest.NewKey(t)
+
+	file := fmt.Sprintf("tracking_id,reference,amount,status\n%s,%s,1000,settled\n%s,ref-2,500,settled\n", payoutKey, bankID, unknown)
+	run, err := handler.Handle(ctx, command.ReconcileCommand{
+		FileName: "settlement.csv",
+		File:     strings.NewReader(file),
+		From:     subject.Clock.Now().Add(-time.Hour),
+		To:       subject.Clock.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if run.Matched != 1 || run.Mismatched != 1 {
+		t.Fatalf("expected 1 matched and 1 mismatched record, got %d and %d", run.Matched, run.Mismatched)
+	}
+	saved, err := reconciliations.GetRun(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("get reconciliation run failed: %v", err)
+	}
+	if len(saved.Items) != 1 || saved.Items[0].Type != recon.MissingLocally {
+		t.Fatalf("unexpected reconciliation items %+v", saved.Items)
+	}
+
+	// a malformed file is rejected before anything is saved
+	_, err = handler.Handle(ctx, command.ReconcileCommand{
+		FileName: "broken.csv",
+		File:     strings.NewReader("tracking_id,reference,amount,status\n,ref-3,ten,settled\n"),
+		From:     subject.Clock.Now().Add(-time.Hour),
+		To:       subject.Clock.Now().Add(time.Hour),
+	})
+	if err == nil {
+		t.Fatalf("expected a malformed file to be rejected")
+	}
+	runs, err := reconciliations.GetRuns(ctx, 10)
+	if err != nil {
+		t.Fatalf("get reconciliation runs failed: %v", err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("expected only the first run to be saved, got %d", len(runs))
+	}
+}
@@ -0,0 +1,172 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"github.com/MaisamV/wallet/internal/wallet/ports/repo/repotest"
+	"testing"
+	"time"
+)
+
+func TestRiskReview_approve(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+
+	id, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), 300, 10, repotest.Later(subject), nil, repotest.Decide(entity.REVIEW, "large withdrawal"))
+	if err != nil {
+		t.Fatalf("debit failed: %v", err)
+	}
+	if status := transactionStatus(t, id); status != entity.IN_REVIEW {
+		t.Fatalf("expected the debit to wait for review, got %s", status)
+	}
+	repotest.ExpectBalance(t, subject, 1, 1000, 690)
+
+	reviews, err := repo.GetRiskReviews(ctx, 10)
+	if err != nil {
+		t.Fatalf("get risk reviews failed: %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].TransactionID != *id || reviews[0].Amount != 300 || len(reviews[0].Reasons) != 1 {
+		t.Fatalf("unexpected risk reviews %+v", reviews)
+	}
+
+	// a debit in review is neither withdrawn nor released, and neither is its fee
+	claimed, err := repo.GetPendingTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Fatalf("expected no claimable debit, got %d", len(claimed))
+	}
+	repotest.MakeDue(subject)
+	if released := repotest.Release(t, subject); released != 0 {
+		t.Fatalf("expected nothing to release, got %d", released)
+	}
+
+	if err := repo.ApproveRiskReview(ctx, id, "analyst"); err != nil {
+		t.Fatalf("approve risk review failed: %v", err)
+	}
+	if err := repo.ApproveRiskReview(ctx, id, "analyst"); !errors.Is(err, entity.ErrNotInReview) {
+		t.Fatalf("expected ErrNotInReview, got %v", err)
+	}
+	if err := repo.ApproveRiskReview(ctx, repotest.NewKey(t), "analyst"); !errors.Is(err, entity.ErrTransactionNotFound) {
+		t.Fatalf("expected ErrTransactionNotFound, got %v", err)
+	}
+	if released := repotest.Release(t, subject); released != 2 {
+		t.Fatalf("expected the approved debit and its fee to be released, got %d", released)
+	}
+	claimed, err = repo.GetPendingTransactions(ctx, 10)
+	if err != nil {
+		t.Fatalf("get pending transactions failed: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != *id {
+		t.Fatalf("expected the approved debit to be claimed, got %+v", claimed)
+	}
+	repotest.ExpectBalance(t, subject, 1, 690, 690)
+}
+
+func TestRiskReview_reject(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+
+	id, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), 300, 10, repotest.Later(subject), nil, repotest.Decide(entity.REVIEW, "new destination"))
+	if err != nil {
+		t.Fatalf("debit failed: %v", err)
+	}
+	repotest.ExpectBalance(t, subject, 1, 1000, 690)
+	// the fee stays reserved until the review is decided
+	repotest.MakeDue(subject)
+	if released := repotest.Release(t, subject); released != 0 {
+		t.Fatalf("expected nothing to release, got %d", released)
+	}
+	repotest.ExpectBalance(t, subject, 1, 1000, 690)
+
+	if err := repo.RejectRiskReview(ctx, id, "analyst"); err != nil {
+		t.Fatalf("reject risk review failed: %v", err)
+	}
+	// the amount comes back and the fee is refunded without ever leaving the total balance
+	repotest.ExpectBalance(t, subject, 1, 1000, 1000)
+	if released := repotest.Release(t, subject); released != 0 {
+		t.Fatalf("expected nothing to release after the rejection, got %d", released)
+	}
+	if status := transactionStatus(t, id); status != entity.CANCELLED {
+		t.Fatalf("expected a cancelled debit, got %s", status)
+	}
+	if err := repo.RejectRiskReview(ctx, id, "analyst"); !errors.Is(err, entity.ErrNotInReview) {
+		t.Fatalf("expected ErrNotInReview, got %v", err)
+	}
+	reviews, err := repo.GetRiskReviews(ctx, 10)
+	if err != nil {
+		t.Fatalf("get risk reviews failed: %v", err)
+	}
+	if len(reviews) != 0 {
+		t.Fatalf("expected no review left, got %d", len(reviews))
+	}
+}
+
+func TestDebit_riskDenialIsRecorded(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	key := repotest.NewKey(t)
+
+	if _, _, err := repo.Debit(ctx, 1, key, 500, 0, repotest.Later(subject), nil, repotest.Decide(entity.DENY, "velocity")); !errors.Is(err, entity.ErrRiskDenied) {
+		t.Fatalf("expected ErrRiskDenied, got %v", err)
+	}
+	// a denial creates no transaction but its idempotency counts as recorded
+	repotest.ExpectBalance(t, subject, 1, 1000, 1000)
+	recorded, err := repo.OccurrenceRecorded(ctx, key)
+	if err != nil {
+		t.Fatalf("occurrence recorded failed: %v", err)
+	}
+	if !recorded {
+		t.Fatalf("expected the denied idempotency to be recorded")
+	}
+}
+
+func TestGetRiskProfile(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	repotest.Charge(t, subject, 1, 1000)
+	repotest.Charge(t, subject, 1, 500)
+	known := addDestination(t, 1, "IR000000000000000000000001")
+	unknown := addDestination(t, 1, "IR000000000000000000000002")
+
+	for _, amount := range []int64{100, 300} {
+		id, _, err := repo.Debit(ctx, 1, repotest.NewKey(t), amount, 0, repotest.Later(subject), &known.ID, nil)
+		if err != nil {
+			t.Fatalf("debit failed: %v", err)
+		}
+		if err := repo.UpdateTransactionStatus(ctx, id, entity.SUCCESS, repotest.NewKey(t)); err != nil {
+			t.Fatalf("update transaction status failed: %v", err)
+		}
+	}
+	// withdrawals which did not succeed are not part of the history
+	failed := repotest.Debit(t, subject, 1, 1000, 0)
+	if err := repo.UpdateTransactionStatus(ctx, failed, entity.FAILED, nil); err != nil {
+		t.Fatalf("update transaction status failed: %v", err)
+	}
+
+	profile, err := repo.GetRiskProfile(ctx, 1, &known.ID, subject.Clock.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("get risk profile failed: %v", err)
+	}
+	if profile.WithdrawalCount != 2 || profile.AverageWithdrawal != 200 || profile.NewDestination {
+		t.Fatalf("unexpected risk profile %+v", profile)
+	}
+	if len(profile.RecentCharges) != 2 {
+		t.Fatalf("expected 2 recent charges, got %d", len(profile.RecentCharges))
+	}
+
+	profile, err = repo.GetRiskProfile(ctx, 1, &unknown.ID, subject.Clock.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("get risk profile failed: %v", err)
+	}
+	if !profile.NewDestination || len(profile.RecentCharges) != 0 {
+		t.Fatalf("unexpected risk profile %+v", profile)
+	}
+}
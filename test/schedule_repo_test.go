@@ -0,0 +1,152 @@
+//go:build integration
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/MaisamV/wallet/internal/wallet/entity"
+	"testing"
+	"time"
+)
+
+func createSchedule(t *testing.T, userId int64, startAt time.Time) *entity.Schedule {
+	t.Helper()
+	s, err := repo.CreateSchedule(context.Background(), &entity.Schedule{UserID: userId, Type: entity.CREDIT, Amount: 100,
+		Frequency: entity.DAILY, Interval: 1, StartAt: startAt})
+	if err != nil {
+		t.Fatalf("create schedule failed: %v", err)
+	}
+	return s
+}
+
+func TestSchedules(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+
+	start := subject.Clock.Now().Add(-time.Minute)
+	s := createSchedule(t, 1, start)
+	if s.Status != entity.SCHEDULE_ACTIVE || s.NextRunAt == nil || !s.NextRunAt.Equal(s.StartAt) {
+		t.Fatalf("expected an active schedule due at its start, got %+v", s)
+	}
+	createSchedule(t, 1, subject.Clock.Now().Add(time.Hour))
+
+	list, err := repo.GetSchedules(ctx, 1)
+	if err != nil {
+		t.Fatalf("get schedules failed: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != s.ID {
+		t.Fatalf("expected both schedules of the user, got %+v", list)
+	}
+	if _, err := repo.GetSchedule(ctx, 2, s.ID); !errors.Is(err, entity.ErrScheduleNotFound) {
+		t.Fatalf("expected ErrScheduleNotFound for the schedule of another user, got %v", err)
+	}
+
+	if _, err := repo.PauseSchedule(ctx, 1, s.ID); err != nil {
+		t.Fatalf("pause schedule failed: %v", err)
+	}
+	if _, err := repo.PauseSchedule(ctx, 1, s.ID); !errors.Is(err, entity.ErrScheduleStatus) {
+		t.Fatalf("expected ErrScheduleStatus, got %v", err)
+	}
+	claimed, err := repo.ClaimDueSchedules(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("claim due schedules failed: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Fatalf("expected a paused schedule not to be claimed, got %d", len(claimed))
+	}
+
+	resumed, err := repo.ResumeSchedule(ctx, 1, s.ID, 3, &start)
+	if err != nil {
+		t.Fatalf("resume schedule failed: %v", err)
+	}
+	if resumed.Status != entity.SCHEDULE_ACTIVE || resumed.Occurrence != 3 {
+		t.Fatalf("unexpected resumed schedule %+v", resumed)
+	}
+	if _, err := repo.ResumeSchedule(ctx, 1, s.ID, 3, &start); !errors.Is(err, entity.ErrScheduleStatus) {
+		t.Fatalf("expected ErrScheduleStatus, got %v", err)
+	}
+
+	if err := repo.DeleteSchedule(ctx, 1, s.ID); err != nil {
+		t.Fatalf("delete schedule failed: %v", err)
+	}
+	if _, err := repo.GetSchedule(ctx, 1, s.ID); !errors.Is(err, entity.ErrScheduleNotFound) {
+		t.Fatalf("expected a deleted schedule to be gone, got %v", err)
+	}
+	if err := repo.DeleteSchedule(ctx, 1, s.ID); !errors.Is(err, entity.ErrScheduleNotFound) {
+		t.Fatalf("expected ErrScheduleNotFound, got %v", err)
+	}
+}
+
+func TestClaimDueSchedules(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	due := createSchedule(t, 1, subject.Clock.Now().Add(-time.Minute))
+	createSchedule(t, 2, subject.Clock.Now().Add(time.Hour))
+
+	claimed, err := repo.ClaimDueSchedules(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("claim due schedules failed: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != due.ID {
+		t.Fatalf("expected the due schedule to be claimed, got %+v", claimed)
+	}
+	// a claimed schedule is kept away from other scheduler workers
+	claimed, err = repo.ClaimDueSchedules(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("claim due schedules failed: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Fatalf("expected no schedule to be claimed twice, got %d", len(claimed))
+	}
+
+	next := subject.Clock.Now().Add(24 * time.Hour)
+	if err := repo.AdvanceSchedule(ctx, due.ID, 1, &next, "insufficient funds"); err != nil {
+		t.Fatalf("advance schedule failed: %v", err)
+	}
+	advanced, err := repo.GetSchedule(ctx, 1, due.ID)
+	if err != nil {
+		t.Fatalf("get schedule failed: %v", err)
+	}
+	if advanced.Occurrence != 1 || advanced.LastError != "insufficient funds" || advanced.LastRunAt == nil ||
+		advanced.Status != entity.SCHEDULE_ACTIVE {
+		t.Fatalf("unexpected advanced schedule %+v", advanced)
+	}
+
+	// a schedule without a next run is completed
+	if err := repo.AdvanceSchedule(ctx, due.ID, 2, nil, ""); err != nil {
+		t.Fatalf("advance schedule failed: %v", err)
+	}
+	completed, err := repo.GetSchedule(ctx, 1, due.ID)
+	if err != nil {
+		t.Fatalf("get schedule failed: %v", err)
+	}
+	if completed.Status != entity.SCHEDULE_COMPLETED || completed.NextRunAt != nil {
+		t.Fatalf("expected a completed schedule, got %+v", completed)
+	}
+}
+
+func TestOccurrenceRecorded(t *testing.T) {
+	reset(t)
+	ctx := context.Background()
+	s := createSchedule(t, 1, subject.Clock.Now().Add(-time.Minute))
+	key := s.Idempotency(0)
+
+	recorded, err := repo.OccurrenceRecorded(ctx, &key)
+	if err != nil {
+		t.Fatalf("occurrence recorded failed: %v", err)
+	}
+	if recorded {
+		t.Fatalf("expected the occurrence not to be recorded yet")
+	}
+	if _, err := repo.Charge(ctx, 1, &key, s.Amount, nil); err != nil {
+		t.Fatalf("charge failed: %v", err)
+	}
+	recorded, err = repo.OccurrenceRecorded(ctx, &key)
+	if err != nil {
+		t.Fatalf("occurrence recorded failed: %v", err)
+	}
+	if !recorded {
+		t.Fatalf("expected the charged occurrence to be recorded")
+	}
+}
@@ -0,0 +1,64 @@
+//go:build integration
+
+// Package testdb connects the integration tests of every package to the same test database
+package testdb
+
+import (
+	"context"
+	"fmt"
+	"github.com/MaisamV/wallet/platform/config"
+	"github.com/MaisamV/wallet/platform/database"
+	"github.com/MaisamV/wallet/platform/logger"
+	"github.com/MaisamV/wallet/platform/migrate"
+	"github.com/MaisamV/wallet/scripts/migrations"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"testing"
+)
+
+// Open connects to the test database, which is the postgres_test compose service unless WALLET_TEST_DATABASE_*
+// says otherwise, and rebuilds its schema from the migrations so every run starts from the same state.
+// The packages share the database, so they must not run side by side: go test -p 1.
+func Open(ctx context.Context) (*pgxpool.Pool, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("could not load config: %w", err)
+	}
+	db, err := database.NewConnection(cfg.TestDatabase, logger.NewNoopLogger())
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to the test database, is postgres_test up? %w", err)
+	}
+	if err := rebuildSchema(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not migrate the test database: %w", err)
+	}
+	return db, nil
+}
+
+// rebuildSchema drops everything in the public schema and applies the embedded migrations
+func rebuildSchema(ctx context.Context, db *pgxpool.Pool) error {
+	if _, err := db.Exec(ctx, "DROP SCHEMA public CASCADE; CREATE SCHEMA public;"); err != nil {
+		return fmt.Errorf("could not reset schema: %w", err)
+	}
+	migrator, err := migrate.New(logger.NewNoopLogger(), db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(ctx)
+	return err
+}
+
+// Reset empties every table the tests write to. The audit log is append only and is left as it is.
+func Reset(t *testing.T, db *pgxpool.Pool) {
+	t.Helper()
+	_, err := db.Exec(context.Background(), `
+TRUNCATE wallets, transactions, balance_snapshots, wallet_quarantines, wallet_status_history, transaction_attempts,
+    adjustments, user_limits, risk_assessments, payout_destinations, holds, schedules, batches, batch_rows, promo_grants,
+    reconciliation_runs, reconciliation_items
+    RESTART IDENTITY CASCADE;
+UPDATE limit_defaults SET max_single_withdrawal = NULL, daily_withdrawal_total = NULL, monthly_withdrawal_total = NULL,
+    max_balance = NULL, hourly_withdrawal_count = NULL;
+`)
+	if err != nil {
+		t.Fatalf("could not reset database: %v", err)
+	}
+}